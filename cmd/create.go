@@ -4,10 +4,16 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -18,6 +24,12 @@ import (
 var (
 	configFile          string
 	createInventoryFile string
+	createDryRun        bool
+	createOutput        string
+	createResume        bool
+	createOnFailure     string
+	createConcurrency   int
+	createENIConfigFile string
 )
 
 // createCmd represents the create command.
@@ -26,6 +38,22 @@ var createCmd = &cobra.Command{
 	Short: "Provision an EKS cluster in AWS",
 	Long:  `Provision an EKS cluster in AWS.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(createOutput); err != nil {
+			return err
+		}
+		if createDryRun && createResume {
+			return fmt.Errorf("--dry-run cannot be used with --resume")
+		}
+
+		// a failed resume keeps its resources unless told otherwise as they
+		// may include those from earlier runs
+		if createResume && !cmd.Flags().Changed("on-failure") {
+			createOnFailure = string(resource.FailurePolicyKeep)
+		}
+		failurePolicy, err := resource.ParseFailurePolicy(createOnFailure)
+		if err != nil {
+			return err
+		}
 
 		// load config resource config
 		resourceConfig := resource.NewResourceConfig()
@@ -39,6 +67,35 @@ var createCmd = &cobra.Command{
 			}
 		}
 
+		// lock the inventory so no other create or delete uses it - a dry run
+		// doesn't use the inventory
+		inventoryBackend, err := newInventoryBackend(createInventoryFile)
+		if err != nil {
+			return err
+		}
+		inventoryLocation := resource.RedactInventoryLocation(createInventoryFile)
+		unlock := func() {}
+		if !createDryRun {
+			unlock, err = lockInventory(inventoryBackend, createInventoryFile, "create")
+			if err != nil {
+				return err
+			}
+		}
+		defer unlock()
+
+		// load existing inventory when resuming
+		inventory := &resource.ResourceInventory{}
+		if createResume {
+			existingInventory, err := readInventory(inventoryBackend)
+			if err != nil {
+				return fmt.Errorf("failed to read eks cluster inventory to resume from: %w", err)
+			}
+			inventory = existingInventory
+			if resourceConfig.Region == "" {
+				resourceConfig.Region = inventory.Region
+			}
+		}
+
 		// load AWS config
 		awsConfig, err := resource.LoadAWSConfig(awsConfigEnv, awsConfigProfile, resourceConfig.Region, awsRoleArn, awsExternalId, awsSerialNumber)
 		if err != nil {
@@ -47,64 +104,98 @@ var createCmd = &cobra.Command{
 
 		// create resource client
 		resourceClient := resource.CreateResourceClient(awsConfig)
+		resourceClient.FailurePolicy = failurePolicy
+		resourceClient.Concurrency = createConcurrency
+		status := statusWriter(createOutput)
+		resourceClient.PauseFunc = func(createErr error) bool {
+			fmt.Fprintf(status, "Problem encountered creating resources: %s\n", createErr)
+			fmt.Fprintf(status, "Resources are recorded in inventory file '%s'\n", inventoryLocation)
+			fmt.Fprint(status, "Type 'delete' to delete the resources that were created, or press Enter to keep them: ")
 
-		// capture messages as resources are created and return to user
-		go func() {
-			for msg := range *resourceClient.MessageChan {
-				fmt.Println(msg)
+			// only an explicit answer deletes - anything else, including
+			// end of input, keeps the resources
+			answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return false
 			}
-		}()
+			return strings.TrimSpace(answer) == "delete"
+		}
 
-		// capture inventory and write to file as it is created
-		go func() {
-			for inventory := range *resourceClient.InventoryChan {
-				if err := resource.WriteInventory(createInventoryFile, &inventory); err != nil {
-					fmt.Printf("failed to write inventory file: %s", err)
-				}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// print the resources that would be created and exit
+		if createDryRun {
+			plan, err := resourceClient.PlanResourceStack(ctx, resourceConfig)
+			if err != nil {
+				return fmt.Errorf("failed to plan resource stack for eks cluster: %w", err)
 			}
-		}()
+			return printPlan(os.Stdout, plan, createOutput)
+		}
 
-		// delete inventory if interrupted
+		// capture messages or events as resources are created and return to
+		// user
+		printProgress(resourceClient, createOutput)
+
+		// capture inventory and write it as it is created - the last
+		// inventory is written once finishInventory or flushInventory returns
+		finishInventory, flushInventory := writeInventoryUpdates(resourceClient, inventoryBackend, status)
+
+		// stop creating resources if interrupted - the failure policy is then
+		// applied to the resources that were created
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 		go func() {
 			<-sigs
-			fmt.Println("\nReceived Ctrl+C, cleaning up resources...")
-			inventory, err := resource.ReadInventory(createInventoryFile)
-			if err != nil {
-				fmt.Printf("failed to read eks cluster inventory: %s\n", err)
-			}
-			if err = resourceClient.DeleteResourceStack(inventory); err != nil {
-				fmt.Printf("failed to delete eks cluster resources: %s\n", err)
-			}
+			fmt.Fprintln(status, "\nReceived Ctrl+C, stopping creation of resources - press Ctrl+C again to exit immediately")
+			cancel()
+			<-sigs
+			fmt.Fprintf(status, "\nReceived Ctrl+C, exiting - resources are recorded in inventory file '%s'\n", inventoryLocation)
+			flushInventory(inventoryFlushTimeout)
+			unlock()
+			os.Exit(1)
 		}()
 
-		fmt.Println("Running... Press Ctrl+C to exit")
+		fmt.Fprintln(status, "Running... Press Ctrl+C to exit")
 
 		// create resources
-		fmt.Println("Creating resources for EKS cluster...")
-		if err := resourceClient.CreateResourceStack(resourceConfig); err != nil {
-			fmt.Printf("Problem encountered creating resources - deleting resources that were created: %s\n", err)
-			// get inventory
-			inventory, invErr := resource.ReadInventory(createInventoryFile)
-			if invErr != nil {
-				return fmt.Errorf("Error creating resources: %w\nError reading eks cluster inventory: %w", err, invErr)
-			}
-			// delete resource stack
-			if deleteErr := resourceClient.DeleteResourceStack(inventory); deleteErr != nil {
-				return fmt.Errorf("Error creating resources: %w\nError deleting resources: %s", err, deleteErr)
-			}
-			// remove inventory file from filesystem
-			if err := os.Remove(deleteInventoryFile); err != nil {
-				return err
+		if createResume {
+			fmt.Fprintln(status, "Resuming creation of resources for EKS cluster...")
+		} else {
+			fmt.Fprintln(status, "Creating resources for EKS cluster...")
+		}
+		err = resourceClient.ResumeResourceStack(ctx, resourceConfig, inventory)
+		finishInventory()
+		if err != nil {
+			var createErr *resource.CreateFailedError
+			if errors.As(err, &createErr) && createErr.Deleted {
+				// remove inventory as its resources were deleted
+				if err := inventoryBackend.DeleteInventory(context.Background()); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(status, "Inventory file '%s' written - use it to resume creation or delete resources\n", inventoryLocation)
 			}
 
 			return fmt.Errorf("failed to create resource stack for eks cluster: %w", err)
 		}
 
-		fmt.Printf("Inventory file '%s' written\n", createInventoryFile)
+		fmt.Fprintf(status, "Inventory file '%s' written\n", inventoryLocation)
+
+		// write the ENIConfigs for VPC CNI custom networking
+		if createENIConfigFile != "" {
+			eniConfigs := resource.ENIConfigs(inventory)
+			if len(eniConfigs) == 0 {
+				fmt.Fprintln(status, "No pod subnets created - ENIConfig file not written")
+			} else {
+				if err := writeENIConfigs(createENIConfigFile, eniConfigs); err != nil {
+					return err
+				}
+				fmt.Fprintf(status, "ENIConfig file '%s' written\n", createENIConfigFile)
+			}
+		}
 
-		fmt.Println("EKS cluster created")
+		fmt.Fprintln(status, "EKS cluster created")
 
 		return nil
 	},
@@ -120,6 +211,219 @@ func init() {
 	createCmd.MarkFlagRequired("config-file")
 	createCmd.Flags().StringVarP(
 		&createInventoryFile, "inventory-file", "i", "eks-cluster-inventory.json",
-		"File to write resource inventory to",
+		"File or URL (s3://bucket/key, https://host/path) to write resource inventory to",
 	)
+	createCmd.Flags().BoolVar(
+		&createDryRun, "dry-run", false,
+		"Print the resources that would be created without creating them",
+	)
+	createCmd.Flags().StringVarP(
+		&createOutput, "output", "o", "text",
+		"Output format - one of: text, json.  With json, progress is printed as one event per line",
+	)
+	createCmd.Flags().StringVar(
+		&createOnFailure, "on-failure", string(resource.FailurePolicyDelete),
+		"What to do with created resources if creation fails or is interrupted - one of: delete, keep, pause.  Defaults to keep with --resume",
+	)
+	createCmd.Flags().BoolVar(
+		&createResume, "resume", false,
+		"Resume creation using the resources recorded in the inventory file",
+	)
+	createCmd.Flags().IntVar(
+		&createConcurrency, "concurrency", resource.DefaultConcurrency,
+		"Maximum number of resources to create at the same time",
+	)
+	createCmd.Flags().StringVar(
+		&createENIConfigFile, "eni-config-file", "",
+		"File to write ENIConfig manifests for the pod subnets to, for VPC CNI custom networking",
+	)
+}
+
+// eniConfigManifest is the Kubernetes manifest for a VPC CNI ENIConfig.
+type eniConfigManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Subnet         string   `yaml:"subnet"`
+		SecurityGroups []string `yaml:"securityGroups,omitempty"`
+	} `yaml:"spec"`
+}
+
+// writeENIConfigs writes the ENIConfigs to a file as Kubernetes manifests
+// ready to apply with kubectl.
+func writeENIConfigs(eniConfigFile string, eniConfigs []resource.ENIConfig) error {
+	var manifests []string
+	for _, eniConfig := range eniConfigs {
+		manifest := eniConfigManifest{
+			APIVersion: "crd.k8s.amazonaws.com/v1alpha1",
+			Kind:       "ENIConfig",
+		}
+		manifest.Metadata.Name = eniConfig.Name
+		manifest.Spec.Subnet = eniConfig.Subnet
+		manifest.Spec.SecurityGroups = eniConfig.SecurityGroups
+		manifestYAML, err := yaml.Marshal(&manifest)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ENIConfig %s to yaml: %w", eniConfig.Name, err)
+		}
+		manifests = append(manifests, string(manifestYAML))
+	}
+
+	if err := os.WriteFile(eniConfigFile, []byte(strings.Join(manifests, "---\n")), 0644); err != nil {
+		return fmt.Errorf("failed to write ENIConfig file: %w", err)
+	}
+
+	return nil
+}
+
+// printPlan writes a resource plan to w in the given output format.
+func printPlan(w io.Writer, plan *resource.ResourcePlan, output string) error {
+	if output == "json" {
+		planJSON, err := resource.MarshalPlan(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(planJSON))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Resources to be created for EKS cluster %s in %s:\n\n", plan.ClusterName, plan.Region)
+	fmt.Fprintln(tw, "RESOURCE\tNAME\tDETAILS")
+	if plan.VPC.ID != "" {
+		// existing networking resources are used, not created
+		fmt.Fprintf(tw, "Existing VPC\t%s\tcidr=%s\n", plan.VPC.ID, plan.VPC.CIDR)
+		for _, az := range plan.AvailabilityZones {
+			fmt.Fprintf(tw, "Existing private subnet\t%s\tid=%s cidr=%s\n", az.Zone, az.PrivateSubnetID, az.PrivateSubnetCIDR)
+			if az.PublicSubnetID != "" {
+				fmt.Fprintf(tw, "Existing public subnet\t%s\tid=%s cidr=%s\n", az.Zone, az.PublicSubnetID, az.PublicSubnetCIDR)
+			}
+		}
+	} else {
+		// IPv6 CIDR blocks are only known once the VPC is created
+		var ipv6Details, subnetIPv6Details string
+		if plan.VPC.AmazonProvidedIPv6CIDR {
+			ipv6Details = " ipv6-cidr=amazon-provided"
+			subnetIPv6Details = " ipv6-cidr=/64"
+		}
+		fmt.Fprintf(tw, "VPC\t%s\tcidr=%s%s\n", plan.ClusterName, plan.VPC.CIDR, ipv6Details)
+		if plan.VPC.SecondaryCIDR != "" {
+			fmt.Fprintf(tw, "VPC secondary CIDR\t%s\tcidr=%s\n", plan.ClusterName, plan.VPC.SecondaryCIDR)
+		}
+		if plan.InternetGateway {
+			fmt.Fprintf(tw, "Internet gateway\t%s\t\n", plan.ClusterName)
+		}
+		if plan.EgressOnlyInternetGateway {
+			fmt.Fprintf(tw, "Egress-only internet gateway\t%s\t\n", plan.ClusterName)
+		}
+		for _, az := range plan.AvailabilityZones {
+			fmt.Fprintf(tw, "Private subnet\t%s\tcidr=%s%s\n", az.Zone, az.PrivateSubnetCIDR, subnetIPv6Details)
+			fmt.Fprintf(tw, "Public subnet\t%s\tcidr=%s%s\n", az.Zone, az.PublicSubnetCIDR, subnetIPv6Details)
+			if az.PodSubnetCIDR != "" {
+				fmt.Fprintf(tw, "Pod subnet\t%s\tcidr=%s\n", az.Zone, az.PodSubnetCIDR)
+			}
+		}
+		if plan.ElasticIPCount > 0 {
+			fmt.Fprintf(tw, "Elastic IPs\t%s\tcount=%d\n", plan.ClusterName, plan.ElasticIPCount)
+		}
+	}
+	for _, natGateway := range plan.NATGateways {
+		fmt.Fprintf(tw, "NAT gateway\t%s\tsubnet=%s\n", natGateway.Zone, natGateway.PublicSubnetCIDR)
+	}
+	if attachment := plan.TransitGatewayAttachment; attachment != nil {
+		fmt.Fprintf(tw, "Transit gateway attachment\t%s\tsubnets=private destinations=%s\n", attachment.TransitGatewayID,
+			strings.Join(attachment.DestinationCIDRs, ","))
+	}
+	if peering := plan.VPCPeeringConnection; peering != nil {
+		acceptance := "manual"
+		if peering.AutoAccept {
+			acceptance = "auto"
+		}
+		var peerDetails string
+		if peering.PeerOwnerID != "" {
+			peerDetails += fmt.Sprintf(" owner=%s", peering.PeerOwnerID)
+		}
+		if peering.PeerRegion != "" {
+			peerDetails += fmt.Sprintf(" region=%s", peering.PeerRegion)
+		}
+		fmt.Fprintf(tw, "VPC peering connection\t%s\tdestinations=%s accept=%s%s\n", peering.PeerVPCID,
+			strings.Join(peering.DestinationCIDRs, ","), acceptance, peerDetails)
+	}
+	for _, routeTable := range plan.RouteTables {
+		defaultRouteTarget := routeTable.DefaultRouteTarget
+		if defaultRouteTarget == "" {
+			defaultRouteTarget = "none"
+		}
+		var ipv6DefaultRoute string
+		if routeTable.IPv6DefaultRouteTarget != "" {
+			ipv6DefaultRoute = fmt.Sprintf(" ipv6-default-route=%s", routeTable.IPv6DefaultRouteTarget)
+		}
+		var routes []string
+		for _, route := range routeTable.Routes {
+			routes = append(routes, fmt.Sprintf("%s->%s", route.DestinationCIDR, route.Target))
+		}
+		var additionalRoutes string
+		if len(routes) > 0 {
+			additionalRoutes = fmt.Sprintf(" routes=%s", strings.Join(routes, ","))
+		}
+		fmt.Fprintf(tw, "Route table (%s)\t%s\tsubnets=%s default-route=%s%s%s\n", routeTable.Tier,
+			strings.Join(routeTable.Zones, ","), strings.Join(routeTable.SubnetCIDRs, ","), defaultRouteTarget,
+			ipv6DefaultRoute, additionalRoutes)
+	}
+	for _, networkACL := range plan.NetworkACLs {
+		fmt.Fprintf(tw, "Network ACL (%s)\t%s\tsubnets=%s\n", networkACL.Tier, strings.Join(networkACL.Zones, ","),
+			strings.Join(networkACL.SubnetCIDRs, ","))
+		for _, rule := range networkACL.Rules {
+			var portRange string
+			if rule.PortRange != "" {
+				portRange = fmt.Sprintf(" ports=%s", rule.PortRange)
+			}
+			fmt.Fprintf(tw, "Network ACL rule (%s)\t%s/%d\t%s %s cidr=%s%s\n", networkACL.Tier, rule.Direction,
+				rule.RuleNumber, rule.Action, rule.Protocol, rule.CIDR, portRange)
+		}
+	}
+	if len(plan.VPCEndpoints) > 0 {
+		fmt.Fprintf(tw, "Security group\t%s-%s\tingress=tcp/443 from %s\n", resource.VPCEndpointSecurityGroupName,
+			plan.ClusterName, plan.VPC.CIDR)
+	}
+	for _, vpcEndpoint := range plan.VPCEndpoints {
+		fmt.Fprintf(tw, "VPC endpoint (%s)\t%s\tsubnets=private\n", strings.ToLower(vpcEndpoint.Type), vpcEndpoint.ServiceName)
+	}
+	if flowLog := plan.FlowLog; flowLog != nil {
+		if flowLog.Destination == resource.FlowLogDestinationS3 {
+			fmt.Fprintf(tw, "Flow log\t%s\ttraffic=%s destination=%s\n", plan.ClusterName, flowLog.TrafficType,
+				flowLog.S3BucketARN)
+		} else {
+			retention := "never expire"
+			if flowLog.RetentionDays != 0 {
+				retention = fmt.Sprintf("%d days", flowLog.RetentionDays)
+			}
+			fmt.Fprintf(tw, "Log group\t%s\tretention=%s\n", flowLog.LogGroupName, retention)
+			fmt.Fprintf(tw, "Flow log\t%s\ttraffic=%s destination=%s\n", plan.ClusterName, flowLog.TrafficType,
+				flowLog.LogGroupName)
+		}
+	}
+	for _, policy := range plan.Policies {
+		fmt.Fprintf(tw, "IAM policy\t%s\t\n", policy.PolicyName)
+	}
+	for _, role := range plan.Roles {
+		fmt.Fprintf(tw, "IAM role\t%s\tpolicies=%s\n", role.RoleName, strings.Join(role.Policies, ","))
+	}
+	fmt.Fprintf(tw, "EKS cluster\t%s\tversion=%s role=%s subnets=%s ip-family=%s\n", plan.Cluster.ClusterName,
+		plan.Cluster.KubernetesVersion, plan.Cluster.RoleName, plan.Cluster.SubnetTier, plan.Cluster.IPFamily)
+	for _, nodeGroup := range plan.NodeGroups {
+		fmt.Fprintf(tw, "Node group\t%s\tinstance-types=%s nodes=%d (min %d, max %d) subnets=%s\n",
+			nodeGroup.NodeGroupName, strings.Join(nodeGroup.InstanceTypes, ","), nodeGroup.InitialNodes,
+			nodeGroup.MinNodes, nodeGroup.MaxNodes, nodeGroup.SubnetTier)
+	}
+	if plan.OIDCProvider {
+		fmt.Fprintf(tw, "OIDC provider\t%s\t\n", plan.ClusterName)
+	}
+	for _, addon := range plan.Addons {
+		fmt.Fprintf(tw, "EKS addon\t%s\t\n", addon)
+	}
+
+	return tw.Flush()
 }
@@ -4,15 +4,21 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/nukleros/eks-cluster/pkg/resource"
 )
 
-var deleteInventoryFile string
+var (
+	deleteInventoryFile string
+	deleteConcurrency   int
+	deleteOutput        string
+)
 
 // deleteCmd represents the delete command.
 var deleteCmd = &cobra.Command{
@@ -20,8 +26,23 @@ var deleteCmd = &cobra.Command{
 	Short: "Remove an EKS cluster from AWS",
 	Long:  `Remove an EKS cluster from AWS.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(deleteOutput); err != nil {
+			return err
+		}
+
+		// lock the inventory so no other create or delete uses it
+		inventoryBackend, err := newInventoryBackend(deleteInventoryFile)
+		if err != nil {
+			return err
+		}
+		unlock, err := lockInventory(inventoryBackend, deleteInventoryFile, "delete")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		// load inventory
-		inventory, err := resource.ReadInventory(deleteInventoryFile)
+		inventory, err := readInventory(inventoryBackend)
 		if err != nil {
 			return fmt.Errorf("failed to read eks cluster inventory: %s", err)
 		}
@@ -34,37 +55,37 @@ var deleteCmd = &cobra.Command{
 
 		// create resource client
 		resourceClient := resource.CreateResourceClient(awsConfig)
+		resourceClient.Concurrency = deleteConcurrency
+		status := statusWriter(deleteOutput)
+
+		// capture messages or events as resources are deleted and return to
+		// user
+		printProgress(resourceClient, deleteOutput)
+
+		// capture inventory and write it as resources are deleted - the last
+		// inventory is written once finishInventory returns
+		finishInventory, _ := writeInventoryUpdates(resourceClient, inventoryBackend, status)
 
-		// capture messages as resources are created and return to user
-		go func() {
-			for msg := range *resourceClient.MessageChan {
-				fmt.Println(msg)
-			}
-		}()
-
-		// capture inventory and write to file as resources are deleted
-		go func() {
-			for inventory := range *resourceClient.InventoryChan {
-				if err := resource.WriteInventory(deleteInventoryFile, &inventory); err != nil {
-					fmt.Printf("failed to write inventory file: %s", err)
-				}
-			}
-		}()
+		// stop deleting resources if interrupted - the resources that remain
+		// are recorded in the inventory file
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
 
 		// delete eks cluster resources
-		err = resourceClient.DeleteResourceStack(inventory)
+		err = resourceClient.DeleteResourceStack(ctx, inventory)
+		finishInventory()
 		if err != nil {
 			return fmt.Errorf("failed to delete eks cluster resource stack: %w", err)
 		}
 
-		// remove inventory file from filesystem
-		if err := os.Remove(deleteInventoryFile); err != nil {
-			return fmt.Errorf("failed to remove eks cluster inventory file: %w", err)
+		// remove inventory as its resources were deleted
+		if err := inventoryBackend.DeleteInventory(context.Background()); err != nil {
+			return fmt.Errorf("failed to remove eks cluster inventory: %w", err)
 		}
 
-		fmt.Printf("Inventory file '%s' deleted\n", deleteInventoryFile)
+		fmt.Fprintf(status, "Inventory '%s' deleted\n", resource.RedactInventoryLocation(deleteInventoryFile))
 
-		fmt.Println("EKS cluster deleted")
+		fmt.Fprintln(status, "EKS cluster deleted")
 
 		return nil
 	},
@@ -75,6 +96,14 @@ func init() {
 
 	deleteCmd.Flags().StringVarP(
 		&deleteInventoryFile, "inventory-file", "i", "eks-cluster-inventory.json",
-		"File to read resource inventory from",
+		"File or URL (s3://bucket/key, https://host/path) to read resource inventory from",
+	)
+	deleteCmd.Flags().IntVar(
+		&deleteConcurrency, "concurrency", resource.DefaultConcurrency,
+		"Maximum number of resources to delete at the same time",
+	)
+	deleteCmd.Flags().StringVarP(
+		&deleteOutput, "output", "o", "text",
+		"Output format - one of: text, json.  With json, progress is printed as one event per line",
 	)
 }
@@ -0,0 +1,53 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var forceUnlockInventoryFile string
+
+// forceUnlockCmd represents the force-unlock command.
+var forceUnlockCmd = &cobra.Command{
+	Use:   "force-unlock LOCK_ID",
+	Short: "Release a stale lock on an EKS cluster inventory",
+	Long: `Release a stale lock on an EKS cluster inventory.
+
+A create or delete that is killed can leave its lock on the inventory behind,
+which stops any other operation from using the inventory.  Pass the lock ID
+reported when the inventory was found to be locked - the lock is only released
+if it is still the one held.  Make sure no other operation is using the
+inventory first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inventoryBackend, err := newInventoryBackend(forceUnlockInventoryFile)
+		if err != nil {
+			return err
+		}
+
+		lock := resource.InventoryLock{ID: args[0]}
+		if err := inventoryBackend.Unlock(context.Background(), &lock); err != nil {
+			return fmt.Errorf("failed to unlock eks cluster inventory: %w", err)
+		}
+
+		fmt.Printf("Lock %s released on inventory '%s'\n", lock.ID, resource.RedactInventoryLocation(forceUnlockInventoryFile))
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(forceUnlockCmd)
+
+	forceUnlockCmd.Flags().StringVarP(
+		&forceUnlockInventoryFile, "inventory-file", "i", "eks-cluster-inventory.json",
+		"File or URL (s3://bucket/key, https://host/path) of the locked resource inventory",
+	)
+}
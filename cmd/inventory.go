@@ -0,0 +1,141 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+// newInventoryBackend returns the inventory backend for an inventory file or
+// URL.  AWS credentials are only loaded for an S3 backend.
+func newInventoryBackend(location string) (resource.InventoryBackend, error) {
+	var awsConfig *aws.Config
+	if resource.IsS3InventoryLocation(location) {
+		s3Config, err := resource.LoadAWSConfig(awsConfigEnv, awsConfigProfile, "", awsRoleArn, awsExternalId, awsSerialNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config for inventory: %w", err)
+		}
+		awsConfig = s3Config
+	}
+
+	inventoryBackend, err := resource.NewInventoryBackend(location, awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up inventory backend: %w", err)
+	}
+
+	return inventoryBackend, nil
+}
+
+// readInventory reads the inventory from the inventory backend.  If a backup
+// of the previous inventory was read instead, the user is warned and the
+// backup is used.
+func readInventory(inventoryBackend resource.InventoryBackend) (*resource.ResourceInventory, error) {
+	inventory, err := inventoryBackend.ReadInventory(context.Background())
+	var backupErr *resource.InventoryBackupUsedError
+	if errors.As(err, &backupErr) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", backupErr)
+		return inventory, nil
+	}
+
+	return inventory, err
+}
+
+// lockInventory acquires the lock on the inventory for an operation and
+// returns a function that releases it.  If the inventory is already locked,
+// the error explains how to release a stale lock.
+func lockInventory(inventoryBackend resource.InventoryBackend, location, operation string) (func(), error) {
+	lock, err := resource.NewInventoryLock(operation)
+	if err != nil {
+		return nil, err
+	}
+	if err := inventoryBackend.Lock(context.Background(), lock); err != nil {
+		var lockedErr *resource.InventoryLockedError
+		if errors.As(err, &lockedErr) && lockedErr.Lock != nil {
+			return nil, fmt.Errorf(
+				"failed to lock eks cluster inventory: %w - if no other operation is using the inventory, release the lock with 'eks-cluster force-unlock %s -i %s'",
+				err, lockedErr.Lock.ID, resource.RedactInventoryLocation(location),
+			)
+		}
+		return nil, fmt.Errorf("failed to lock eks cluster inventory: %w", err)
+	}
+
+	return func() {
+		if err := inventoryBackend.Unlock(context.Background(), lock); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to unlock eks cluster inventory: %s\n", err)
+		}
+	}, nil
+}
+
+// inventoryFlushTimeout is how long to wait for the inventory to be written
+// when exiting before the resource client has finished.
+const inventoryFlushTimeout = 10 * time.Second
+
+// writeInventoryUpdates writes each inventory sent by the resource client to
+// the inventory backend, one at a time.  It returns a function to call once
+// the resource client has finished that waits for the last inventory to be
+// written, and a function to call instead before exiting while the resource
+// client is still running.  The latter writes the inventory waiting to be
+// sent, if any, and waits no longer than the given timeout.
+func writeInventoryUpdates(
+	resourceClient *resource.ResourceClient,
+	inventoryBackend resource.InventoryBackend,
+	status io.Writer,
+) (func(), func(timeout time.Duration)) {
+	inventoryChan := *resourceClient.InventoryChan
+	write := func(inventory resource.ResourceInventory) {
+		if err := inventoryBackend.WriteInventory(context.Background(), &inventory); err != nil {
+			fmt.Fprintf(status, "failed to write inventory file: %s\n", err)
+		}
+	}
+
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case inventory, ok := <-inventoryChan:
+				if !ok {
+					return
+				}
+				write(inventory)
+			case <-stop:
+				// the resource client sends the inventory with the stack
+				// locked so no more than one update is waiting
+				select {
+				case inventory, ok := <-inventoryChan:
+					if ok {
+						write(inventory)
+					}
+				default:
+				}
+				return
+			}
+		}
+	}()
+
+	finish := func() {
+		close(inventoryChan)
+		<-done
+	}
+	flush := func(timeout time.Duration) {
+		close(stop)
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			fmt.Fprintln(status, "timed out writing inventory file")
+		}
+	}
+
+	return finish, flush
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+// validateOutput returns an error if the output format is not supported.
+func validateOutput(output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format %s, must be one of: text, json", output)
+	}
+
+	return nil
+}
+
+// statusWriter returns the writer for status text in the given output
+// format.  JSON output is written to stdout so status text goes to stderr.
+func statusWriter(output string) io.Writer {
+	if output == "json" {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+// printProgress prints the resource client's progress as resources are
+// created and deleted.  Messages are printed for text output and events are
+// printed one JSON object per line for json output.
+func printProgress(resourceClient *resource.ResourceClient, output string) {
+	if output != "json" {
+		go func() {
+			for msg := range *resourceClient.MessageChan {
+				fmt.Println(msg)
+			}
+		}()
+		return
+	}
+
+	eventChan := make(chan resource.Event)
+	resourceClient.EventChan = &eventChan
+	go func() {
+		for range *resourceClient.MessageChan {
+		}
+	}()
+	go func() {
+		for event := range eventChan {
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to marshal event: %s\n", err)
+				continue
+			}
+			fmt.Println(string(eventJSON))
+		}
+	}()
+}
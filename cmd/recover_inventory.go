@@ -0,0 +1,152 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var (
+	recoverClusterName   string
+	recoverRegion        string
+	recoverInventoryFile string
+	recoverDelete        bool
+	recoverConcurrency   int
+	recoverOutput        string
+)
+
+// recoverInventoryCmd represents the recover-inventory command.
+var recoverInventoryCmd = &cobra.Command{
+	Use:     "recover-inventory",
+	Aliases: []string{"gc"},
+	Short:   "Rebuild a lost inventory from the resources tagged for an EKS cluster",
+	Long: `Rebuild a lost inventory from the resources tagged for an EKS cluster.
+
+EC2 resources are found by the kubernetes.io/cluster/cluster-name tag.  IAM
+roles and policies, the EKS cluster, its node groups and addons are found by the
+names they are created with.  IAM roles and policies and the EKS cluster must
+also be tagged with Name=<cluster name>, otherwise they are skipped.  The
+recovered inventory is written to the inventory file, which must not already
+exist.  With --delete, the recovered resources are then deleted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(recoverOutput); err != nil {
+			return err
+		}
+
+		// lock the inventory so no other create or delete uses it
+		inventoryBackend, err := newInventoryBackend(recoverInventoryFile)
+		if err != nil {
+			return err
+		}
+		unlock, err := lockInventory(inventoryBackend, recoverInventoryFile, "recover-inventory")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// never replace an existing inventory
+		_, err = readInventory(inventoryBackend)
+		if err == nil {
+			return fmt.Errorf("inventory '%s' already exists, use it to delete the cluster instead", resource.RedactInventoryLocation(recoverInventoryFile))
+		}
+		if !errors.Is(err, resource.ErrInventoryNotFound) {
+			return fmt.Errorf("failed to check for existing eks cluster inventory: %w", err)
+		}
+
+		// load AWS config
+		awsConfig, err := resource.LoadAWSConfig(awsConfigEnv, awsConfigProfile, recoverRegion, awsRoleArn, awsExternalId, awsSerialNumber)
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+
+		// create resource client
+		resourceClient := resource.CreateResourceClient(awsConfig)
+		resourceClient.Concurrency = recoverConcurrency
+		status := statusWriter(recoverOutput)
+
+		// capture messages or events as resources are recovered and deleted
+		// and return to user
+		printProgress(resourceClient, recoverOutput)
+
+		// find the cluster's resources and record them
+		inventory, err := resourceClient.RecoverInventory(context.Background(), recoverClusterName)
+		if err != nil {
+			return fmt.Errorf("failed to recover eks cluster inventory: %w", err)
+		}
+		if err := inventoryBackend.WriteInventory(context.Background(), inventory); err != nil {
+			return fmt.Errorf("failed to write eks cluster inventory: %w", err)
+		}
+
+		fmt.Fprintf(status, "Inventory for cluster %s recovered to '%s'\n", recoverClusterName, resource.RedactInventoryLocation(recoverInventoryFile))
+
+		if !recoverDelete {
+			return nil
+		}
+
+		// capture inventory and write it as resources are deleted - the last
+		// inventory is written once finishInventory returns
+		finishInventory, _ := writeInventoryUpdates(resourceClient, inventoryBackend, status)
+
+		// stop deleting resources if interrupted - the resources that remain
+		// are recorded in the inventory file
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// delete eks cluster resources
+		err = resourceClient.DeleteResourceStack(ctx, inventory)
+		finishInventory()
+		if err != nil {
+			return fmt.Errorf("failed to delete eks cluster resource stack: %w", err)
+		}
+
+		// remove inventory as its resources were deleted
+		if err := inventoryBackend.DeleteInventory(context.Background()); err != nil {
+			return fmt.Errorf("failed to remove eks cluster inventory: %w", err)
+		}
+
+		fmt.Fprintf(status, "Inventory '%s' deleted\n", resource.RedactInventoryLocation(recoverInventoryFile))
+
+		fmt.Fprintln(status, "EKS cluster deleted")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recoverInventoryCmd)
+
+	recoverInventoryCmd.Flags().StringVarP(
+		&recoverClusterName, "cluster-name", "n", "",
+		"Name of the EKS cluster to recover resources for",
+	)
+	recoverInventoryCmd.MarkFlagRequired("cluster-name")
+	recoverInventoryCmd.Flags().StringVar(
+		&recoverRegion, "region", "",
+		"AWS region the cluster was created in.  Defaults to the region from the AWS config",
+	)
+	recoverInventoryCmd.Flags().StringVarP(
+		&recoverInventoryFile, "inventory-file", "i", "eks-cluster-inventory.json",
+		"File or URL (s3://bucket/key, https://host/path) to write the recovered resource inventory to",
+	)
+	recoverInventoryCmd.Flags().BoolVar(
+		&recoverDelete, "delete", false,
+		"Delete the recovered resources and remove the inventory",
+	)
+	recoverInventoryCmd.Flags().IntVar(
+		&recoverConcurrency, "concurrency", resource.DefaultConcurrency,
+		"Maximum number of resources to delete at the same time",
+	)
+	recoverInventoryCmd.Flags().StringVarP(
+		&recoverOutput, "output", "o", "text",
+		"Output format - one of: text, json.  With json, progress is printed as one event per line",
+	)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var (
+	verifyInventoryFile string
+	verifyOutput        string
+)
+
+// errDriftDetected is returned by the verify command when resources have
+// drifted from the inventory so that it exits with a non-zero status.
+var errDriftDetected = errors.New("eks cluster resources have drifted from the inventory")
+
+// verifyCmd represents the verify command.
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the resources in an inventory against AWS",
+	Long: `Check the resources in an inventory against AWS.
+
+Each resource recorded in the inventory is checked to see if it still exists
+and matches the inventory.  Resources that are missing, have been modified, or
+that exist inside recorded resources without being recorded, such as a subnet
+added to the VPC, are reported.  The command exits with a non-zero status if
+any drift is found.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(verifyOutput); err != nil {
+			return err
+		}
+
+		// load inventory - verify only reads resources so the inventory is
+		// not locked
+		inventoryBackend, err := newInventoryBackend(verifyInventoryFile)
+		if err != nil {
+			return err
+		}
+		inventory, err := readInventory(inventoryBackend)
+		if err != nil {
+			return fmt.Errorf("failed to read eks cluster inventory: %s", err)
+		}
+
+		// load AWS config
+		awsConfig, err := resource.LoadAWSConfig(awsConfigEnv, awsConfigProfile, inventory.Region, awsRoleArn, awsExternalId, awsSerialNumber)
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+
+		// create resource client
+		resourceClient := resource.CreateResourceClient(awsConfig)
+
+		// check eks cluster resources
+		report, err := resourceClient.VerifyResourceStack(context.Background(), inventory)
+		if err != nil {
+			return fmt.Errorf("failed to verify eks cluster resource stack: %w", err)
+		}
+		if err := printDriftReport(os.Stdout, report, verifyOutput); err != nil {
+			return err
+		}
+
+		if report.Drifted {
+			return errDriftDetected
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVarP(
+		&verifyInventoryFile, "inventory-file", "i", "eks-cluster-inventory.json",
+		"File or URL (s3://bucket/key, https://host/path) to read resource inventory from",
+	)
+	verifyCmd.Flags().StringVarP(
+		&verifyOutput, "output", "o", "text",
+		"Output format - one of: text, json",
+	)
+}
+
+// printDriftReport writes a drift report to w in the given output format.
+// Text output only lists resources that have drifted.
+func printDriftReport(w io.Writer, report *resource.DriftReport, output string) error {
+	if output == "json" {
+		reportJSON, err := resource.MarshalDriftReport(report)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(reportJSON))
+		return nil
+	}
+
+	if !report.Drifted {
+		fmt.Fprintf(w, "All %d resources for EKS cluster %s in %s match the inventory\n",
+			len(report.Resources), report.ClusterName, report.Region)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Resources for EKS cluster %s in %s that have drifted from the inventory:\n\n", report.ClusterName, report.Region)
+	fmt.Fprintln(tw, "RESOURCE\tID\tSTATUS\tDETAILS")
+	for _, resourceDrift := range report.Resources {
+		if resourceDrift.Status == resource.DriftStatusInSync {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", resourceDrift.Kind, resourceDrift.ID, resourceDrift.Status,
+			strings.Join(resourceDrift.Details, "; "))
+	}
+
+	return tw.Flush()
+}
@@ -1,22 +1,30 @@
 package resource
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
 )
 
-const EBSStorageAddonName = "aws-ebs-csi-driver"
+const (
+	EBSStorageAddonName = "aws-ebs-csi-driver"
+	AddonCheckInterval  = 15 // check addon status every 15 seconds
+	AddonCheckMaxCount  = 40 // check 40 times before giving up (10 minutes)
+)
 
 // CreateEBSStorageAddon creates installs the EBS CSI driver addon on the EKS
 // cluster.
 func (c *ResourceClient) CreateEBSStorageAddon(
+	ctx context.Context,
 	tags *map[string]string,
 	clusterName string,
 	storageManagementRoleARN string,
 ) (*types.Addon, error) {
-	svc := eks.NewFromConfig(*c.AWSConfig)
+	svc := c.eksClient()
 
 	ebsAddonName := EBSStorageAddonName
 
@@ -26,10 +34,106 @@ func (c *ResourceClient) CreateEBSStorageAddon(
 		ServiceAccountRoleArn: &storageManagementRoleARN,
 		Tags:                  *tags,
 	}
-	resp, err := svc.CreateAddon(c.Context, &createEBSAddonInput)
+	resp, err := svc.CreateAddon(ctx, &createEBSAddonInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cluster: %w", err)
 	}
 
 	return resp.Addon, nil
 }
+
+// DeleteAddons deletes the addons with the given names from an EKS cluster.
+// If the cluster name is empty, or an addon is not found, it is skipped
+// without error.
+func (c *ResourceClient) DeleteAddons(ctx context.Context, clusterName string, addonNames []string) error {
+	// if no cluster name, there's nothing to delete
+	if clusterName == "" {
+		return nil
+	}
+
+	svc := c.eksClient()
+
+	for _, addonName := range addonNames {
+		deleteAddonInput := eks.DeleteAddonInput{
+			AddonName:   &addonName,
+			ClusterName: &clusterName,
+		}
+		_, err := svc.DeleteAddon(ctx, &deleteAddonInput)
+		if err != nil {
+			var notFoundErr *types.ResourceNotFoundException
+			if errors.As(err, &notFoundErr) {
+				// attempting to delete an addon that doesn't exist so move on
+				// to the next one
+				continue
+			} else {
+				return fmt.Errorf("failed to delete addon %s: %w", addonName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WaitForAddonsDeleted waits for the addons with the given names to be
+// deleted from an EKS cluster.
+func (c *ResourceClient) WaitForAddonsDeleted(ctx context.Context, clusterName string, addonNames []string) error {
+	// if no cluster name or addons, there's nothing to check
+	if clusterName == "" || len(addonNames) == 0 {
+		return nil
+	}
+
+	addonCheckCount := 0
+	for {
+		addonCheckCount += 1
+		if addonCheckCount > AddonCheckMaxCount {
+			return errors.New("addon deletion check timed out")
+		}
+
+		allDeleted := true
+		for _, addonName := range addonNames {
+			addon, err := c.getAddon(ctx, clusterName, addonName)
+			if err != nil {
+				if errors.Is(err, ErrResourceNotFound) {
+					continue
+				}
+				return fmt.Errorf("failed to get addon status while waiting for %s: %w", addonName, err)
+			}
+			if addon.Status == types.AddonStatusDeleteFailed {
+				return fmt.Errorf("failed to delete addon %s from cluster %s", addonName, clusterName)
+			}
+			allDeleted = false
+			break
+		}
+
+		if allDeleted {
+			break
+		}
+		if err := c.waitCheckInterval(ctx, time.Second*AddonCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for addons %s: %w", addonNames, err)
+		}
+	}
+
+	return nil
+}
+
+// getAddon retrieves an addon installed on an EKS cluster.  If the addon is not
+// found it returns ErrResourceNotFound.
+func (c *ResourceClient) getAddon(ctx context.Context, clusterName, addonName string) (*types.Addon, error) {
+	svc := c.eksClient()
+
+	describeAddonInput := eks.DescribeAddonInput{
+		AddonName:   &addonName,
+		ClusterName: &clusterName,
+	}
+	resp, err := svc.DescribeAddon(ctx, &describeAddonInput)
+	if err != nil {
+		var notFoundErr *types.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return nil, ErrResourceNotFound
+		} else {
+			return nil, fmt.Errorf("failed to describe addon %s: %w", addonName, err)
+		}
+	}
+
+	return resp.Addon, nil
+}
@@ -1,31 +1,125 @@
 package resource
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-const maxAZCount = int32(3)
-
-// defaultCIDRs returns a set of CIDR blocks for subnets.
-func defaultCIDRs() []string {
-	return []string{
-		"10.0.0.0/22",
-		"10.0.4.0/22",
-		"10.0.8.0/22",
-		"10.0.12.0/22",
-		"10.0.16.0/22",
-		"10.0.20.0/22",
-	}
+// eksUnsupportedZoneIDs are the availability zone IDs that EKS control planes
+// can't be created in.
+var eksUnsupportedZoneIDs = map[string]bool{
+	"use1-az3": true,
+	"usw1-az2": true,
+	"cac1-az3": true,
+}
+
+// regionZone is an availability zone in a region along with the reason it
+// can't be used for an EKS cluster, if any.
+type regionZone struct {
+	name       string
+	id         string
+	unusableBy string
 }
 
 // GetAvailabilityZonesForRegion gets the availability zones for a given region.
-func (c *ResourceClient) GetAvailabilityZonesForRegion(region string, desiredAZs int32) (*[]AvailabilityZone, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+// Local Zones, Wavelength Zones, zones that are not available, zones EKS
+// doesn't support and zones that don't offer all the instance types are
+// skipped.  The subnet CIDR blocks are not set on the availability zones -
+// they are carved out of the cluster CIDR when the resource config's
+// availability zones are set.
+func (c *ResourceClient) GetAvailabilityZonesForRegion(
+	ctx context.Context,
+	region string,
+	desiredAZs int32,
+	instanceTypes []string,
+) (*[]AvailabilityZone, error) {
 	var availabilityZones []AvailabilityZone
-	defaultCIDRs := defaultCIDRs()
+
+	zones, err := c.getRegionZones(ctx, region, instanceTypes)
+	if err != nil {
+		return &availabilityZones, err
+	}
+
+	var unusable []string
+	for _, zone := range zones {
+		if zone.unusableBy != "" {
+			unusable = append(unusable, fmt.Sprintf("%s (%s) %s", zone.name, zone.id, zone.unusableBy))
+			continue
+		}
+		if int32(len(availabilityZones)) < desiredAZs {
+			availabilityZones = append(availabilityZones, AvailabilityZone{
+				Zone:   zone.name,
+				ZoneID: zone.id,
+			})
+		}
+	}
+	if int32(len(availabilityZones)) < desiredAZs {
+		message := fmt.Sprintf("only %d of %d desired availability zones in region %s can be used",
+			len(availabilityZones), desiredAZs, region)
+		if len(unusable) > 0 {
+			message = fmt.Sprintf("%s - skipped %s", message, strings.Join(unusable, ", "))
+		}
+		return &availabilityZones, errors.New(message)
+	}
+
+	return &availabilityZones, nil
+}
+
+// resolveAvailabilityZones checks that availability zones given by zone name
+// or zone ID exist in a region and can be used for an EKS cluster, and sets
+// whichever of the zone name and ID is missing.
+func (c *ResourceClient) resolveAvailabilityZones(
+	ctx context.Context,
+	region string,
+	availabilityZones []AvailabilityZone,
+	instanceTypes []string,
+) error {
+	zones, err := c.getRegionZones(ctx, region, instanceTypes)
+	if err != nil {
+		return err
+	}
+
+	usedZones := make(map[string]bool)
+	for i, az := range availabilityZones {
+		var zone *regionZone
+		for j := range zones {
+			if (az.Zone != "" && zones[j].name == az.Zone) || (az.Zone == "" && az.ZoneID != "" && zones[j].id == az.ZoneID) {
+				zone = &zones[j]
+				break
+			}
+		}
+		switch {
+		case az.Zone == "" && az.ZoneID == "":
+			return fmt.Errorf("availability zone %d in resource config has neither a zone nor a zone ID", i+1)
+		case zone == nil && az.Zone != "":
+			return fmt.Errorf("availability zone %s not found in region %s", az.Zone, region)
+		case zone == nil:
+			return fmt.Errorf("availability zone ID %s not found in region %s", az.ZoneID, region)
+		case az.ZoneID != "" && az.ZoneID != zone.id:
+			return fmt.Errorf("availability zone %s has zone ID %s, not %s", zone.name, zone.id, az.ZoneID)
+		case zone.unusableBy != "":
+			return fmt.Errorf("availability zone %s (%s) can't be used for an EKS cluster as it %s",
+				zone.name, zone.id, zone.unusableBy)
+		case usedZones[zone.name]:
+			return fmt.Errorf("availability zone %s (%s) is set more than once in resource config", zone.name, zone.id)
+		}
+		usedZones[zone.name] = true
+		availabilityZones[i].Zone = zone.name
+		availabilityZones[i].ZoneID = zone.id
+	}
+
+	return nil
+}
+
+// getRegionZones returns the availability zones in a region along with the
+// reason each can't be used for an EKS cluster, if any.
+func (c *ResourceClient) getRegionZones(ctx context.Context, region string, instanceTypes []string) ([]regionZone, error) {
+	svc := c.ec2Client()
 
 	filterName := "region-name"
 	describeAZInput := ec2.DescribeAvailabilityZonesInput{
@@ -36,33 +130,87 @@ func (c *ResourceClient) GetAvailabilityZonesForRegion(region string, desiredAZs
 			},
 		},
 	}
-	resp, err := svc.DescribeAvailabilityZones(c.Context, &describeAZInput)
+	resp, err := svc.DescribeAvailabilityZones(ctx, &describeAZInput)
 	if err != nil {
-		return &availabilityZones, fmt.Errorf("failed to describe availability zones for region %s: %w", region, err)
+		return nil, fmt.Errorf("failed to describe availability zones for region %s: %w", region, err)
 	}
 
-	azsSet := int32(0)
-	var azCount int32
-	if desiredAZs > maxAZCount {
-		azCount = maxAZCount
-	} else {
-		azCount = desiredAZs
+	offerings, err := c.getInstanceTypeOfferings(ctx, instanceTypes)
+	if err != nil {
+		return nil, err
 	}
-	cidrIndex := 0
+
+	var zones []regionZone
 	for _, az := range resp.AvailabilityZones {
-		if azsSet < azCount {
-			newAZ := AvailabilityZone{
-				Zone:              *az.ZoneName,
-				PrivateSubnetCIDR: defaultCIDRs[cidrIndex],
-				PublicSubnetCIDR:  defaultCIDRs[cidrIndex+1],
+		zone := regionZone{
+			name: *az.ZoneName,
+			id:   *az.ZoneId,
+		}
+		var missingInstanceTypes []string
+		for _, instanceType := range instanceTypes {
+			if !offerings[zone.id][instanceType] {
+				missingInstanceTypes = append(missingInstanceTypes, instanceType)
 			}
-			availabilityZones = append(availabilityZones, newAZ)
-			cidrIndex = cidrIndex + 2
-			azsSet += 1
-		} else {
+		}
+		switch {
+		case az.ZoneType != nil && *az.ZoneType != "availability-zone":
+			zone.unusableBy = fmt.Sprintf("is a %s", *az.ZoneType)
+		case az.State != types.AvailabilityZoneStateAvailable:
+			zone.unusableBy = fmt.Sprintf("is %s", az.State)
+		case az.OptInStatus == types.AvailabilityZoneOptInStatusNotOptedIn:
+			zone.unusableBy = "is not opted in"
+		case eksUnsupportedZoneIDs[zone.id]:
+			zone.unusableBy = "doesn't support EKS control planes"
+		case len(missingInstanceTypes) > 0:
+			zone.unusableBy = fmt.Sprintf("doesn't offer instance types %s", strings.Join(missingInstanceTypes, ", "))
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+// getInstanceTypeOfferings returns the instance types offered in each
+// availability zone, by zone ID, for the given instance types.
+func (c *ResourceClient) getInstanceTypeOfferings(ctx context.Context, instanceTypes []string) (map[string]map[string]bool, error) {
+	offerings := make(map[string]map[string]bool)
+
+	// if there are no instance types there is nothing to check
+	if len(instanceTypes) == 0 {
+		return offerings, nil
+	}
+
+	svc := c.ec2Client()
+
+	filterName := "instance-type"
+	describeOfferingsInput := ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZoneId,
+		Filters: []types.Filter{
+			{
+				Name:   &filterName,
+				Values: instanceTypes,
+			},
+		},
+	}
+	for {
+		resp, err := svc.DescribeInstanceTypeOfferings(ctx, &describeOfferingsInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instance type offerings for %s: %w", strings.Join(instanceTypes, ", "), err)
+		}
+		for _, offering := range resp.InstanceTypeOfferings {
+			if offering.Location == nil {
+				continue
+			}
+			if offerings[*offering.Location] == nil {
+				offerings[*offering.Location] = make(map[string]bool)
+			}
+			offerings[*offering.Location][string(offering.InstanceType)] = true
+		}
+		if resp.NextToken == nil {
 			break
 		}
+		describeOfferingsInput.NextToken = resp.NextToken
 	}
 
-	return &availabilityZones, nil
+	return offerings, nil
 }
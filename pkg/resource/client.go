@@ -2,6 +2,7 @@ package resource
 
 import (
 	"context"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 )
@@ -17,19 +18,97 @@ type ResourceClient struct {
 	// as resources are created and deleted.
 	InventoryChan *chan ResourceInventory
 
-	// A context object available for passing data across operations.
-	Context context.Context
+	// A channel for events to be passed to client as resources are created and
+	// deleted.  If nil, no events are sent.
+	EventChan *chan Event
 
 	// The AWS configuration for default settings and credentials.
 	AWSConfig *aws.Config
+
+	// The EC2 API used to manage networking resources.  If nil, a client is
+	// created from AWSConfig as needed.
+	EC2Client EC2API
+
+	// The EKS API used to manage clusters, node groups and addons.  If nil, a
+	// client is created from AWSConfig as needed.
+	EKSClient EKSAPI
+
+	// The IAM API used to manage roles, policies and OIDC providers.  If nil,
+	// a client is created from AWSConfig as needed.
+	IAMClient IAMAPI
+
+	// The CloudWatch Logs API used to manage the log group for VPC flow logs.
+	// If nil, a client is created from AWSConfig as needed.
+	CloudWatchLogsClient CloudWatchLogsAPI
+
+	// A function that returns the certificate thumbprint for an OIDC
+	// provider URL.  If nil, GetOIDCThumbprint is used.
+	ThumbprintFunc func(ctx context.Context, providerURL string) (string, error)
+
+	// The maximum number of resources created or deleted at the same time.
+	// Resources that don't depend on each other are created and deleted
+	// concurrently.  If zero, DefaultConcurrency is used.
+	Concurrency int
+
+	// The interval between status checks while waiting on resources.  If
+	// zero, the default interval for each resource type is used.
+	CheckInterval time.Duration
+
+	// What to do with the resources that were created when creating a
+	// resource stack fails.  If empty, FailurePolicyKeep is used.
+	FailurePolicy FailurePolicy
+
+	// A function called with the creation error when FailurePolicy is
+	// FailurePolicyPause.  The resources are deleted if it returns true and
+	// kept otherwise.  If nil, the resources are kept.
+	PauseFunc func(createErr error) bool
 }
 
+// DefaultConcurrency is the maximum number of resources created or deleted at
+// the same time if the resource client's Concurrency is not set.
+const DefaultConcurrency = 4
+
 // CreateResourceClient configures a resource client and returns it.
 func CreateResourceClient(awsConfig *aws.Config) *ResourceClient {
 	msgChan := make(chan string)
 	invChan := make(chan ResourceInventory)
-	ctx := context.Background()
-	resourceClient := ResourceClient{&msgChan, &invChan, ctx, awsConfig}
+	resourceClient := ResourceClient{
+		MessageChan:   &msgChan,
+		InventoryChan: &invChan,
+		AWSConfig:     awsConfig,
+	}
 
 	return &resourceClient
 }
+
+// checkInterval returns the interval to wait between status checks, using
+// the resource client's CheckInterval if set.
+func (c *ResourceClient) checkInterval(defaultInterval time.Duration) time.Duration {
+	if c.CheckInterval != 0 {
+		return c.CheckInterval
+	}
+	return defaultInterval
+}
+
+// waitCheckInterval waits for the interval between status checks.  It returns
+// the context's error if the context is cancelled before the interval is up.
+func (c *ResourceClient) waitCheckInterval(ctx context.Context, defaultInterval time.Duration) error {
+	timer := time.NewTimer(c.checkInterval(defaultInterval))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// concurrency returns the maximum number of resources to create or delete at
+// the same time, using the resource client's Concurrency if set.
+func (c *ResourceClient) concurrency() int {
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return DefaultConcurrency
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -18,15 +19,18 @@ const (
 	ClusterCheckMaxCount    = 60 // check 60 times before giving up (15 minutes)
 )
 
-// CreateCluster creates a new EKS Cluster.
+// CreateCluster creates a new EKS Cluster.  Pods and services are given
+// addresses from the IP family, which defaults to IPv4.
 func (c *ResourceClient) CreateCluster(
+	ctx context.Context,
 	tags *map[string]string,
 	clusterName string,
 	kubernetesVersion string,
 	roleARN string,
 	subnetIDs []string,
+	ipFamily IPFamily,
 ) (*types.Cluster, error) {
-	svc := eks.NewFromConfig(*c.AWSConfig)
+	svc := c.eksClient()
 
 	privateAccess := true
 	publicAccess := true
@@ -36,14 +40,19 @@ func (c *ResourceClient) CreateCluster(
 		SubnetIds:             subnetIDs,
 	}
 
+	kubernetesNetworkConfig := types.KubernetesNetworkConfigRequest{
+		IpFamily: types.IpFamily(ipFamilyOrDefault(ipFamily)),
+	}
+
 	createClusterInput := eks.CreateClusterInput{
-		Name:               &clusterName,
-		ResourcesVpcConfig: &vpcConfig,
-		RoleArn:            &roleARN,
-		Version:            &kubernetesVersion,
-		Tags:               *tags,
+		Name:                    &clusterName,
+		ResourcesVpcConfig:      &vpcConfig,
+		KubernetesNetworkConfig: &kubernetesNetworkConfig,
+		RoleArn:                 &roleARN,
+		Version:                 &kubernetesVersion,
+		Tags:                    *tags,
 	}
-	resp, err := svc.CreateCluster(c.Context, &createClusterInput)
+	resp, err := svc.CreateCluster(ctx, &createClusterInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cluster: %w", err)
 	}
@@ -53,16 +62,16 @@ func (c *ResourceClient) CreateCluster(
 
 // DeleteCluster deletes an EKS cluster.  If  an empty cluster name is supplied,
 // or if the cluster is not found it returns without error.
-func (c *ResourceClient) DeleteCluster(clusterName string) error {
+func (c *ResourceClient) DeleteCluster(ctx context.Context, clusterName string) error {
 	// if clusterName is empty, there's nothing to delete
 	if clusterName == "" {
 		return nil
 	}
 
-	svc := eks.NewFromConfig(*c.AWSConfig)
+	svc := c.eksClient()
 
 	deleteClusterInput := eks.DeleteClusterInput{Name: &clusterName}
-	_, err := svc.DeleteCluster(c.Context, &deleteClusterInput)
+	_, err := svc.DeleteCluster(ctx, &deleteClusterInput)
 	if err != nil {
 		var notFoundErr *types.ResourceNotFoundException
 		if errors.As(err, &notFoundErr) {
@@ -79,6 +88,7 @@ func (c *ResourceClient) DeleteCluster(clusterName string) error {
 // * ClusterConditionCreated
 // * ClusterConditionDeleted
 func (c *ResourceClient) WaitForCluster(
+	ctx context.Context,
 	clusterName string,
 	clusterCondition ClusterCondition,
 ) (string, error) {
@@ -96,7 +106,7 @@ func (c *ResourceClient) WaitForCluster(
 			return oicdIssuer, errors.New("cluster condition check timed out")
 		}
 
-		cluster, err := c.getCluster(clusterName)
+		cluster, err := c.getCluster(ctx, clusterName)
 		if err != nil {
 			if errors.Is(err, ErrResourceNotFound) && clusterCondition == ClusterConditionDeleted {
 				// resource was not found and we're waiting for it to be
@@ -112,20 +122,22 @@ func (c *ResourceClient) WaitForCluster(
 			oicdIssuer = *cluster.Identity.Oidc.Issuer
 			break
 		}
-		time.Sleep(time.Second * 15)
+		if err := c.waitCheckInterval(ctx, time.Second*ClusterCheckInterval); err != nil {
+			return oicdIssuer, fmt.Errorf("stopped waiting for cluster %s: %w", clusterName, err)
+		}
 	}
 
 	return oicdIssuer, nil
 }
 
 // getCluster retrieves the cluster for a given cluster name.
-func (c *ResourceClient) getCluster(clusterName string) (*types.Cluster, error) {
-	svc := eks.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) getCluster(ctx context.Context, clusterName string) (*types.Cluster, error) {
+	svc := c.eksClient()
 
 	describeClusterInput := eks.DescribeClusterInput{
 		Name: &clusterName,
 	}
-	resp, err := svc.DescribeCluster(c.Context, &describeClusterInput)
+	resp, err := svc.DescribeCluster(ctx, &describeClusterInput)
 	if err != nil {
 		var notFoundErr *types.ResourceNotFoundException
 		if errors.As(err, &notFoundErr) {
@@ -24,6 +24,20 @@ type ResourceConfig struct {
 	ClusterCIDR                      string                           `yaml:"clusterCIDR"`
 	DesiredAZCount                   int32                            `yaml:"desiredAZCount"`
 	AvailabilityZones                []AvailabilityZone               `yaml:"availabilityZones"`
+	PrivateSubnetPrefixLength        int32                            `yaml:"privateSubnetPrefixLength"`
+	PublicSubnetPrefixLength         int32                            `yaml:"publicSubnetPrefixLength"`
+	SecondaryCIDR                    string                           `yaml:"secondaryCIDR"`
+	PodSubnetPrefixLength            int32                            `yaml:"podSubnetPrefixLength"`
+	VPCID                            string                           `yaml:"vpcID"`
+	PrivateSubnetIDs                 []string                         `yaml:"privateSubnetIDs"`
+	PublicSubnetIDs                  []string                         `yaml:"publicSubnetIDs"`
+	NATGatewayMode                   NATGatewayMode                   `yaml:"natGatewayMode"`
+	VPCEndpoints                     bool                             `yaml:"vpcEndpoints"`
+	IPFamily                         IPFamily                         `yaml:"ipFamily"`
+	FlowLogs                         *FlowLogsConfig                  `yaml:"flowLogs"`
+	TransitGateway                   *TransitGatewayConfig            `yaml:"transitGateway"`
+	VPCPeering                       *VPCPeeringConfig                `yaml:"vpcPeering"`
+	NetworkACLs                      *NetworkACLsConfig               `yaml:"networkACLs"`
 	InstanceTypes                    []string                         `yaml:"instanceTypes"`
 	InitialNodes                     int32                            `yaml:"initialNodes"`
 	MinNodes                         int32                            `yaml:"minNodes"`
@@ -43,12 +57,22 @@ type ResourceConfig struct {
 // networking.  It also contains resource ID fields used internally during
 // creation.
 type AvailabilityZone struct {
-	Zone              string `yaml:"zone"`
-	PrivateSubnetCIDR string `yaml:"privateSubnetCIDR"`
-	PrivateSubnetID   string
-	PublicSubnetCIDR  string `yaml:"publicSubnetCIDR"`
-	PublicSubnetID    string
-	NATGatewayID      string
+	Zone                           string `yaml:"zone" json:"zone"`
+	ZoneID                         string `yaml:"zoneID" json:"zoneID"`
+	PrivateSubnetCIDR              string `yaml:"privateSubnetCIDR" json:"privateSubnetCIDR"`
+	PrivateSubnetID                string `json:"privateSubnetID"`
+	PublicSubnetCIDR               string `yaml:"publicSubnetCIDR" json:"publicSubnetCIDR"`
+	PublicSubnetID                 string `json:"publicSubnetID"`
+	PrivateSubnetIPv6CIDR          string `json:"privateSubnetIPv6CIDR,omitempty"`
+	PublicSubnetIPv6CIDR           string `json:"publicSubnetIPv6CIDR,omitempty"`
+	PodSubnetCIDR                  string `yaml:"podSubnetCIDR" json:"podSubnetCIDR,omitempty"`
+	PodSubnetID                    string `json:"podSubnetID,omitempty"`
+	ElasticIPID                    string `json:"elasticIPID"`
+	NATGatewayID                   string `json:"natGatewayID"`
+	PrivateRouteTableID            string `json:"privateRouteTableID"`
+	PrivateRouteTableAssociationID string `json:"privateRouteTableAssociationID"`
+	PublicRouteTableAssociationID  string `json:"publicRouteTableAssociationID"`
+	PodRouteTableAssociationID     string `json:"podRouteTableAssociationID,omitempty"`
 }
 
 // DNSManagementServiceAccount contains the name and namespace for the
@@ -80,6 +104,62 @@ type StorageManagementServiceAccount struct {
 	Namespace string `yaml:"namespace"`
 }
 
+// FlowLogsConfig contains the options for the flow log created for the VPC.
+// Flow logs are published to a CloudWatch Logs log group created for the
+// cluster or to an existing S3 bucket.
+type FlowLogsConfig struct {
+	Destination   FlowLogDestination `yaml:"destination"`
+	TrafficType   string             `yaml:"trafficType"`
+	LogGroupName  string             `yaml:"logGroupName"`
+	RetentionDays int32              `yaml:"retentionDays"`
+	S3BucketARN   string             `yaml:"s3BucketARN"`
+}
+
+// TransitGatewayConfig contains the options for attaching the VPC to an
+// existing transit gateway.  Traffic to the destination CIDRs is routed to the
+// transit gateway from the private and public subnets.
+type TransitGatewayConfig struct {
+	TransitGatewayID string   `yaml:"transitGatewayID"`
+	DestinationCIDRs []string `yaml:"destinationCIDRs"`
+}
+
+// VPCPeeringConfig contains the options for the peering connection created
+// from the VPC to another VPC.  If the peer VPC is in another account or
+// region the connection must be accepted by its owner.  Traffic to the
+// destination CIDRs is routed to the peering connection from the private and
+// public subnets.
+type VPCPeeringConfig struct {
+	PeerVPCID        string   `yaml:"peerVPCID"`
+	PeerOwnerID      string   `yaml:"peerOwnerID"`
+	PeerRegion       string   `yaml:"peerRegion"`
+	DestinationCIDRs []string `yaml:"destinationCIDRs"`
+}
+
+// NetworkACLsConfig contains the rules for the network ACLs created for each
+// tier of subnets.  The subnets in a tier with rules are associated with a
+// network ACL created for it.  Those in a tier without rules keep the VPC's
+// default network ACL, which allows all traffic.
+type NetworkACLsConfig struct {
+	Public  []NetworkACLRule `yaml:"public"`
+	Private []NetworkACLRule `yaml:"private"`
+	Pod     []NetworkACLRule `yaml:"pod"`
+}
+
+// NetworkACLRule is an inbound or outbound rule in a network ACL.  Rules are
+// evaluated in order of rule number and traffic that matches none of them is
+// denied.  The protocol is tcp, udp, icmp, icmpv6, all or an IP protocol
+// number.  The port range only applies to TCP and UDP and includes all ports
+// if not set.
+type NetworkACLRule struct {
+	RuleNumber int32  `yaml:"ruleNumber"`
+	Egress     bool   `yaml:"egress"`
+	Action     string `yaml:"action"`
+	Protocol   string `yaml:"protocol"`
+	CIDR       string `yaml:"cidr"`
+	FromPort   int32  `yaml:"fromPort"`
+	ToPort     int32  `yaml:"toPort"`
+}
+
 // NewResourceConfig returns a ResourceConfig with default values set.
 func NewResourceConfig() *ResourceConfig {
 	return &ResourceConfig{
@@ -313,34 +393,48 @@ func AssumeRole(
 }
 
 // SetAvailabilityZones sets the availability zones for the resource config.
-func (r *ResourceConfig) SetAvailabilityZones(resourceClient *ResourceClient) error {
+func (r *ResourceConfig) SetAvailabilityZones(ctx context.Context, resourceClient *ResourceClient) error {
 	// ensure region is in resource config
 	if r.Region == "" {
 		return errors.New("region is not set in resource config")
 	}
 
-	// if availability zones provided, nothing to do
-	if len(r.AvailabilityZones) > 0 {
-		return nil
+	// when using an existing VPC, the availability zones are those of its
+	// subnets
+	if r.UsesExistingVPC() {
+		return r.setExistingNetwork(ctx, resourceClient)
+	}
+	if len(r.PrivateSubnetIDs) > 0 || len(r.PublicSubnetIDs) > 0 {
+		return errors.New("subnet IDs cannot be set in resource config without an existing VPC ID")
 	}
 
-	// set no. availability zones - default to 1 if not specified
-	var desiredAZs int32
-	if r.DesiredAZCount == 0 {
-		desiredAZs = 2
+	// if availability zones provided, check them and fill in their zone names
+	// and IDs, otherwise set based on number of desired availability zones
+	if len(r.AvailabilityZones) > 0 {
+		if err := resourceClient.resolveAvailabilityZones(ctx, r.Region, r.AvailabilityZones, r.InstanceTypes); err != nil {
+			return err
+		}
 	} else {
-		desiredAZs = r.DesiredAZCount
+		// set no. availability zones - default to 1 if not specified
+		var desiredAZs int32
+		if r.DesiredAZCount == 0 {
+			desiredAZs = 2
+		} else {
+			desiredAZs = r.DesiredAZCount
+		}
+
+		availabilityZones, err := resourceClient.GetAvailabilityZonesForRegion(ctx, r.Region, desiredAZs, r.InstanceTypes)
+		if err != nil {
+			return fmt.Errorf("failed to get availability zones for region %s: %w", r.Region, err)
+		}
+		r.AvailabilityZones = *availabilityZones
 	}
 
-	// otherwise set based on number of desired availability zones
-	availabilityZones, err := resourceClient.GetAvailabilityZonesForRegion(r.Region, desiredAZs)
-	if err != nil {
-		return fmt.Errorf(
-			fmt.Sprintf("failed to get availability zones for region %s", r.Region),
-			err,
-		)
+	// carve out subnet CIDR blocks that aren't set from the cluster CIDR, and
+	// pod subnet CIDR blocks from the secondary CIDR
+	if err := r.setSubnetCIDRs(); err != nil {
+		return err
 	}
-	r.AvailabilityZones = *availabilityZones
 
-	return nil
+	return r.setPodSubnetCIDRs()
 }
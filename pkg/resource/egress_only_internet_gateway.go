@@ -0,0 +1,115 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// CreateEgressOnlyInternetGateway creates an egress-only internet gateway for
+// the VPC in which an EKS cluster is provisioned.  It gives the private
+// subnets outbound-only IPv6 access to the internet.
+func (c *ResourceClient) CreateEgressOnlyInternetGateway(
+	ctx context.Context,
+	tags *[]types.Tag,
+	vpcID string,
+) (*types.EgressOnlyInternetGateway, error) {
+	svc := c.ec2Client()
+
+	createEIGWInput := ec2.CreateEgressOnlyInternetGatewayInput{
+		VpcId: &vpcID,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeEgressOnlyInternetGateway,
+				Tags:         *tags,
+			},
+		},
+	}
+	resp, err := svc.CreateEgressOnlyInternetGateway(ctx, &createEIGWInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create egress-only internet gateway for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return resp.EgressOnlyInternetGateway, nil
+}
+
+// DeleteEgressOnlyInternetGateway deletes an egress-only internet gateway.  If
+// an empty ID is supplied, or if the egress-only internet gateway is not found,
+// it returns without error.
+func (c *ResourceClient) DeleteEgressOnlyInternetGateway(ctx context.Context, egressOnlyInternetGatewayID string) error {
+	// if egressOnlyInternetGatewayID is empty, there's nothing to delete
+	if egressOnlyInternetGatewayID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteEIGWInput := ec2.DeleteEgressOnlyInternetGatewayInput{
+		EgressOnlyInternetGatewayId: &egressOnlyInternetGatewayID,
+	}
+	_, err := svc.DeleteEgressOnlyInternetGateway(ctx, &deleteEIGWInput)
+	if err != nil {
+		if egressOnlyInternetGatewayNotFound(err) {
+			// attempting to delete an egress-only internet gateway that
+			// doesn't exist so return without error
+			return nil
+		}
+		return fmt.Errorf("failed to delete egress-only internet gateway with ID %s: %w", egressOnlyInternetGatewayID, err)
+	}
+
+	return nil
+}
+
+// getEgressOnlyInternetGateway retrieves the egress-only internet gateway with
+// the given ID.  If the egress-only internet gateway is not found it returns
+// ErrResourceNotFound.
+func (c *ResourceClient) getEgressOnlyInternetGateway(
+	ctx context.Context,
+	egressOnlyInternetGatewayID string,
+) (*types.EgressOnlyInternetGateway, error) {
+	svc := c.ec2Client()
+
+	describeEIGWsInput := ec2.DescribeEgressOnlyInternetGatewaysInput{
+		EgressOnlyInternetGatewayIds: []string{egressOnlyInternetGatewayID},
+	}
+	resp, err := svc.DescribeEgressOnlyInternetGateways(ctx, &describeEIGWsInput)
+	if err != nil {
+		if egressOnlyInternetGatewayNotFound(err) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to describe egress-only internet gateway with ID %s: %w",
+			egressOnlyInternetGatewayID, err)
+	}
+	if len(resp.EgressOnlyInternetGateways) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.EgressOnlyInternetGateways[0], nil
+}
+
+// egressOnlyInternetGatewayAttached returns true if the egress-only internet
+// gateway is attached to the VPC.
+func egressOnlyInternetGatewayAttached(egressOnlyInternetGateway *types.EgressOnlyInternetGateway, vpcID string) bool {
+	for _, attachment := range egressOnlyInternetGateway.Attachments {
+		if attachment.VpcId != nil && *attachment.VpcId == vpcID && attachment.State == types.AttachmentStatusAttached {
+			return true
+		}
+	}
+
+	return false
+}
+
+// egressOnlyInternetGatewayNotFound returns true if an error is because an
+// egress-only internet gateway doesn't exist.
+func egressOnlyInternetGatewayNotFound(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+
+	return ae.ErrorCode() == "InvalidEgressOnlyInternetGatewayId.NotFound" || ae.ErrorCode() == "InvalidGatewayID.NotFound"
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -11,10 +12,11 @@ import (
 
 // CreateElasticIPs allocates elastic IP addresses for use by NAT gateways.
 func (c *ResourceClient) CreateElasticIPs(
+	ctx context.Context,
 	tags *[]types.Tag,
 	publicSubnetIDs []string,
 ) ([]string, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	var elasticIPIDs []string
 
@@ -28,7 +30,7 @@ func (c *ResourceClient) CreateElasticIPs(
 				},
 			},
 		}
-		resp, err := svc.AllocateAddress(c.Context, &allocateAddressInput)
+		resp, err := svc.AllocateAddress(ctx, &allocateAddressInput)
 		if err != nil {
 			return elasticIPIDs, fmt.Errorf("failed to create elastic IP: %w", err)
 		}
@@ -40,17 +42,17 @@ func (c *ResourceClient) CreateElasticIPs(
 
 // DeleteElasticIPs releases elastic IP addresses.  If no IDs are supplied, or
 // if the address IDs are not found it exits without error.
-func (c *ResourceClient) DeleteElasticIPs(elasticIPIDs []string) error {
+func (c *ResourceClient) DeleteElasticIPs(ctx context.Context, elasticIPIDs []string) error {
 	// if elasticIPIDs are empty, there's nothing to delete
 	if len(elasticIPIDs) == 0 {
 		return nil
 	}
 
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	for _, elasticIPID := range elasticIPIDs {
 		deleteElasticIPInput := ec2.ReleaseAddressInput{AllocationId: &elasticIPID}
-		_, err := svc.ReleaseAddress(c.Context, &deleteElasticIPInput)
+		_, err := svc.ReleaseAddress(ctx, &deleteElasticIPInput)
 		if err != nil {
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
@@ -69,3 +71,30 @@ func (c *ResourceClient) DeleteElasticIPs(elasticIPIDs []string) error {
 
 	return nil
 }
+
+// getElasticIPs retrieves the elastic IP addresses with the given allocation
+// IDs.  Addresses that are not found are not included in the result.
+func (c *ResourceClient) getElasticIPs(ctx context.Context, elasticIPIDs []string) ([]types.Address, error) {
+	// if elasticIPIDs are empty, there's nothing to get
+	if len(elasticIPIDs) == 0 {
+		return []types.Address{}, nil
+	}
+
+	svc := c.ec2Client()
+
+	filterName := "allocation-id"
+	describeAddressesInput := ec2.DescribeAddressesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &filterName,
+				Values: elasticIPIDs,
+			},
+		},
+	}
+	resp, err := svc.DescribeAddresses(ctx, &describeAddressesInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe elastic IPs %s: %w", elasticIPIDs, err)
+	}
+
+	return resp.Addresses, nil
+}
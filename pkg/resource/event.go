@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ResourceKind is a type of resource managed by a resource client.
+type ResourceKind string
+
+const (
+	ResourceKindVPC                       ResourceKind = "VPC"
+	ResourceKindVPCCIDRBlock              ResourceKind = "VPCCIDRBlock"
+	ResourceKindInternetGateway           ResourceKind = "InternetGateway"
+	ResourceKindEgressOnlyInternetGateway ResourceKind = "EgressOnlyInternetGateway"
+	ResourceKindSubnet                    ResourceKind = "Subnet"
+	ResourceKindElasticIP                 ResourceKind = "ElasticIP"
+	ResourceKindNATGateway                ResourceKind = "NATGateway"
+	ResourceKindRouteTable                ResourceKind = "RouteTable"
+	ResourceKindVPCEndpoint               ResourceKind = "VPCEndpoint"
+	ResourceKindTransitGatewayAttachment  ResourceKind = "TransitGatewayAttachment"
+	ResourceKindVPCPeeringConnection      ResourceKind = "VPCPeeringConnection"
+	ResourceKindNetworkACL                ResourceKind = "NetworkACL"
+	ResourceKindFlowLog                   ResourceKind = "FlowLog"
+	ResourceKindLogGroup                  ResourceKind = "LogGroup"
+	ResourceKindPolicy                    ResourceKind = "Policy"
+	ResourceKindRole                      ResourceKind = "Role"
+	ResourceKindCluster                   ResourceKind = "Cluster"
+	ResourceKindSecurityGroup             ResourceKind = "SecurityGroup"
+	ResourceKindNodeGroup                 ResourceKind = "NodeGroup"
+	ResourceKindOIDCProvider              ResourceKind = "OIDCProvider"
+	ResourceKindAddon                     ResourceKind = "Addon"
+)
+
+// EventAction is the action being taken on a resource.
+type EventAction string
+
+const (
+	EventActionCreate EventAction = "create"
+	EventActionDelete EventAction = "delete"
+)
+
+// EventPhase is the progress of an action on a resource.
+type EventPhase string
+
+const (
+	EventPhaseStarted   EventPhase = "started"
+	EventPhaseWaiting   EventPhase = "waiting"
+	EventPhaseSucceeded EventPhase = "succeeded"
+	EventPhaseFailed    EventPhase = "failed"
+)
+
+// Event reports progress creating or deleting a resource.
+type Event struct {
+	// The kind of resource.
+	Kind ResourceKind `json:"kind"`
+
+	// The ID, name or ARN of the resource.  Empty if not yet known, e.g. when
+	// starting to create a resource.
+	ID string `json:"id,omitempty"`
+
+	// The action being taken on the resource.
+	Action EventAction `json:"action"`
+
+	// The progress of the action.
+	Phase EventPhase `json:"phase"`
+
+	// The time the event occurred.
+	Time time.Time `json:"time"`
+
+	// The error encountered if the phase is EventPhaseFailed.
+	Err error `json:"-"`
+}
+
+// MarshalJSON returns the JSON encoding of an event with the error as a
+// string.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	eventJSON := struct {
+		event
+		Error string `json:"error,omitempty"`
+	}{event: event(e)}
+	if e.Err != nil {
+		eventJSON.Error = e.Err.Error()
+	}
+
+	return json.Marshal(eventJSON)
+}
+
+// sendEvent sends an event back to the client as resources are created and
+// deleted.  The event time is set if not already set.
+func (c *ResourceClient) sendEvent(event Event) {
+	if c.EventChan == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	*c.EventChan <- event
+}
+
+// sendResourceEvents sends an event for each of the given resource IDs.
+// Empty IDs are skipped.
+func (c *ResourceClient) sendResourceEvents(
+	kind ResourceKind,
+	action EventAction,
+	phase EventPhase,
+	ids ...string,
+) {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		c.sendEvent(Event{Kind: kind, ID: id, Action: action, Phase: phase})
+	}
+}
@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// PublicELBSubnetTag is the tag that marks a subnet as usable for
+	// internet-facing load balancers.
+	PublicELBSubnetTag = "kubernetes.io/role/elb"
+
+	// InternalELBSubnetTag is the tag that marks a subnet as usable for
+	// internal load balancers.
+	InternalELBSubnetTag = "kubernetes.io/role/internal-elb"
+
+	// minExistingAZCount is the number of availability zones EKS requires the
+	// cluster's subnets to span.
+	minExistingAZCount = 2
+)
+
+// UsesExistingVPC returns true if the resource config uses an existing VPC and
+// subnets rather than creating them.
+func (r *ResourceConfig) UsesExistingVPC() bool {
+	return r.VPCID != ""
+}
+
+// setExistingNetwork checks that the existing VPC and subnets in the resource
+// config can be used for an EKS cluster and sets the availability zones from
+// the subnets.  The VPC's CIDR block is used as the cluster CIDR.
+func (r *ResourceConfig) setExistingNetwork(ctx context.Context, resourceClient *ResourceClient) error {
+	if len(r.AvailabilityZones) > 0 {
+		return errors.New("availability zones cannot be set in resource config when using an existing VPC")
+	}
+
+	vpc, err := resourceClient.getVPC(ctx, r.VPCID)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return fmt.Errorf("existing VPC with ID %s not found", r.VPCID)
+		}
+		return err
+	}
+	if vpc.CidrBlock != nil {
+		r.ClusterCIDR = *vpc.CidrBlock
+	}
+
+	availabilityZones, err := resourceClient.getExistingAvailabilityZones(ctx, r.VPCID, r.PrivateSubnetIDs, r.PublicSubnetIDs)
+	if err != nil {
+		return err
+	}
+	r.AvailabilityZones = availabilityZones
+
+	return nil
+}
+
+// getExistingAvailabilityZones checks the existing private and public subnets
+// in a VPC and returns an availability zone for each zone they are in.  Private
+// subnets must span at least two availability zones, have one subnet per
+// zone, be tagged for internal load balancers and route to a NAT gateway.
+// Public subnets are optional but must be in the same zones as the private
+// subnets, be tagged for internet-facing load balancers and route to an
+// internet gateway.
+func (c *ResourceClient) getExistingAvailabilityZones(
+	ctx context.Context,
+	vpcID string,
+	privateSubnetIDs []string,
+	publicSubnetIDs []string,
+) ([]AvailabilityZone, error) {
+	if len(privateSubnetIDs) == 0 {
+		return nil, errors.New("private subnet IDs must be set in resource config when using an existing VPC")
+	}
+
+	subnets, err := c.getSubnets(ctx, vpcID, append(append([]string{}, privateSubnetIDs...), publicSubnetIDs...))
+	if err != nil {
+		return nil, err
+	}
+	subnetsByID := make(map[string]types.Subnet)
+	for _, subnet := range subnets {
+		subnetsByID[*subnet.SubnetId] = subnet
+	}
+
+	routeTables, err := c.getVPCRouteTables(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	azsByZone := make(map[string]*AvailabilityZone)
+	var zones []string
+
+	for _, subnetID := range privateSubnetIDs {
+		subnet, ok := subnetsByID[subnetID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("private subnet %s not found in VPC %s", subnetID, vpcID))
+			continue
+		}
+		if !subnetTagged(subnet, InternalELBSubnetTag) {
+			problems = append(problems, fmt.Sprintf("private subnet %s is missing the %s tag", subnetID, InternalELBSubnetTag))
+		}
+		if !subnetDefaultRoute(subnetRouteTable(routeTables, subnetID), "nat-") {
+			problems = append(problems, fmt.Sprintf("private subnet %s has no default route to a NAT gateway", subnetID))
+		}
+		zone := *subnet.AvailabilityZone
+		if az, ok := azsByZone[zone]; ok {
+			problems = append(problems, fmt.Sprintf("private subnets %s and %s are both in availability zone %s",
+				az.PrivateSubnetID, subnetID, zone))
+			continue
+		}
+		azsByZone[zone] = &AvailabilityZone{
+			Zone:              zone,
+			ZoneID:            aws.ToString(subnet.AvailabilityZoneId),
+			PrivateSubnetID:   subnetID,
+			PrivateSubnetCIDR: *subnet.CidrBlock,
+		}
+		zones = append(zones, zone)
+	}
+	if len(zones) < minExistingAZCount {
+		problems = append(problems, fmt.Sprintf("private subnets must span at least %d availability zones", minExistingAZCount))
+	}
+
+	for _, subnetID := range publicSubnetIDs {
+		subnet, ok := subnetsByID[subnetID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("public subnet %s not found in VPC %s", subnetID, vpcID))
+			continue
+		}
+		if !subnetTagged(subnet, PublicELBSubnetTag) {
+			problems = append(problems, fmt.Sprintf("public subnet %s is missing the %s tag", subnetID, PublicELBSubnetTag))
+		}
+		if !subnetDefaultRoute(subnetRouteTable(routeTables, subnetID), "igw-") {
+			problems = append(problems, fmt.Sprintf("public subnet %s has no default route to an internet gateway", subnetID))
+		}
+		zone := *subnet.AvailabilityZone
+		az, ok := azsByZone[zone]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("public subnet %s is in availability zone %s which has no private subnet",
+				subnetID, zone))
+		case az.PublicSubnetID != "":
+			problems = append(problems, fmt.Sprintf("public subnets %s and %s are both in availability zone %s",
+				az.PublicSubnetID, subnetID, zone))
+		default:
+			az.PublicSubnetID = subnetID
+			az.PublicSubnetCIDR = *subnet.CidrBlock
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("existing VPC %s cannot be used for an EKS cluster: %s", vpcID, strings.Join(problems, "; "))
+	}
+
+	sort.Strings(zones)
+	var availabilityZones []AvailabilityZone
+	for _, zone := range zones {
+		availabilityZones = append(availabilityZones, *azsByZone[zone])
+	}
+
+	return availabilityZones, nil
+}
+
+// getVPCRouteTables retrieves all the route tables in a VPC.
+func (c *ResourceClient) getVPCRouteTables(ctx context.Context, vpcID string) ([]types.RouteTable, error) {
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &vpcFilterName,
+				Values: []string{vpcID},
+			},
+		},
+	}
+	resp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return resp.RouteTables, nil
+}
+
+// subnetRouteTable returns the route table that applies to a subnet - the
+// route table explicitly associated with it, or else the VPC's main route
+// table.  It returns nil if there is neither.
+func subnetRouteTable(routeTables []types.RouteTable, subnetID string) *types.RouteTable {
+	var main *types.RouteTable
+	for i, routeTable := range routeTables {
+		for _, association := range routeTable.Associations {
+			if association.SubnetId != nil && *association.SubnetId == subnetID {
+				return &routeTables[i]
+			}
+		}
+		if mainRouteTable(routeTable) {
+			main = &routeTables[i]
+		}
+	}
+
+	return main
+}
+
+// subnetDefaultRoute returns true if a route table has an active default
+// route to a gateway with the given ID prefix.
+func subnetDefaultRoute(routeTable *types.RouteTable, gatewayPrefix string) bool {
+	if routeTable == nil {
+		return false
+	}
+	for _, route := range routeTable.Routes {
+		if route.DestinationCidrBlock == nil || *route.DestinationCidrBlock != "0.0.0.0/0" ||
+			route.State == types.RouteStateBlackhole {
+			continue
+		}
+		if route.NatGatewayId != nil && strings.HasPrefix(*route.NatGatewayId, gatewayPrefix) {
+			return true
+		}
+		if route.GatewayId != nil && strings.HasPrefix(*route.GatewayId, gatewayPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subnetTagged returns true if a subnet has the given tag with a value of "1"
+// or an empty value, as expected by the AWS load balancer controller.
+func subnetTagged(subnet types.Subnet, key string) bool {
+	value, ok := ec2TagMap(subnet.Tags)[key]
+
+	return ok && (value == "1" || value == "")
+}
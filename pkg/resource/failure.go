@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FailurePolicy determines what happens to the resources that were created
+// when creating a resource stack fails.
+type FailurePolicy string
+
+const (
+	// FailurePolicyDelete deletes the resources that were created.
+	FailurePolicyDelete FailurePolicy = "delete"
+
+	// FailurePolicyKeep leaves the resources that were created in place.  The
+	// inventory can be used to resume creation or delete them later.
+	FailurePolicyKeep FailurePolicy = "keep"
+
+	// FailurePolicyPause calls the resource client's PauseFunc so the
+	// resources can be inspected before deciding whether to delete them.
+	FailurePolicyPause FailurePolicy = "pause"
+)
+
+// ParseFailurePolicy returns the failure policy for a string.  One of:
+// delete, keep, pause.
+func ParseFailurePolicy(policy string) (FailurePolicy, error) {
+	switch FailurePolicy(policy) {
+	case FailurePolicyDelete, FailurePolicyKeep, FailurePolicyPause:
+		return FailurePolicy(policy), nil
+	}
+
+	return "", fmt.Errorf("invalid failure policy %s, must be one of: delete, keep, pause", policy)
+}
+
+// CreateFailedError is returned when creating a resource stack fails.  It
+// records whether the resources that were created were deleted according to
+// the resource client's failure policy.
+type CreateFailedError struct {
+	// The error encountered creating resources.
+	Err error
+
+	// True if the resources that were created have been deleted.
+	Deleted bool
+
+	// The error encountered deleting resources, if any.
+	DeleteErr error
+}
+
+// Error returns the error message including any deletion error.
+func (e *CreateFailedError) Error() string {
+	if e.DeleteErr != nil {
+		return fmt.Sprintf("failed to create resources: %s: failed to delete resources: %s", e.Err, e.DeleteErr)
+	}
+
+	return fmt.Sprintf("failed to create resources: %s", e.Err)
+}
+
+// Unwrap returns the error encountered creating resources.
+func (e *CreateFailedError) Unwrap() error {
+	return e.Err
+}
+
+// handleCreateFailure applies the resource client's failure policy to the
+// resources in the inventory after creation fails and returns a
+// CreateFailedError.
+func (c *ResourceClient) handleCreateFailure(ctx context.Context, inventory *ResourceInventory, createErr error) error {
+	failedErr := CreateFailedError{Err: createErr}
+
+	deleteResources := false
+	switch c.FailurePolicy {
+	case FailurePolicyDelete:
+		deleteResources = true
+	case FailurePolicyPause:
+		if c.PauseFunc != nil {
+			deleteResources = c.PauseFunc(createErr)
+		}
+	}
+
+	if !deleteResources {
+		c.sendMessage(fmt.Sprintf("Problem encountered creating resources - resources that were created have been kept: %s\n", createErr))
+		return &failedErr
+	}
+
+	c.sendMessage(fmt.Sprintf("Problem encountered creating resources - deleting resources that were created: %s\n", createErr))
+	if err := c.DeleteResourceStack(uncancelledContext{ctx}, inventory); err != nil {
+		failedErr.DeleteErr = err
+		return &failedErr
+	}
+	failedErr.Deleted = true
+
+	return &failedErr
+}
+
+// uncancelledContext is a context with the values of its parent that is never
+// cancelled.  It is used to delete resources after creating them was stopped
+// by cancelling the parent context.
+type uncancelledContext struct {
+	context.Context
+}
+
+// Deadline returns no deadline.
+func (uncancelledContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil so the context is never done.
+func (uncancelledContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil since the context is never cancelled.
+func (uncancelledContext) Err() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+func TestCreateResourceStackNodegroupFailure(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failurePolicy resource.FailurePolicy
+		pauseDelete   *bool
+		wantDeleted   bool
+	}{
+		{
+			name:          "delete",
+			failurePolicy: resource.FailurePolicyDelete,
+			wantDeleted:   true,
+		},
+		{
+			name:          "keep",
+			failurePolicy: resource.FailurePolicyKeep,
+		},
+		{
+			name:          "pause then delete",
+			failurePolicy: resource.FailurePolicyPause,
+			pauseDelete:   aws.Bool(true),
+			wantDeleted:   true,
+		},
+		{
+			name:          "pause then keep",
+			failurePolicy: resource.FailurePolicyPause,
+			pauseDelete:   aws.Bool(false),
+		},
+		{
+			name:          "pause without a pause function",
+			failurePolicy: resource.FailurePolicyPause,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := fake.NewBackend(testRegion)
+			backend.FailNodegroups("instances failed to join the kubernetes cluster")
+			c := backend.ResourceClient()
+			c.FailurePolicy = tc.failurePolicy
+			var paused bool
+			if tc.pauseDelete != nil {
+				c.PauseFunc = func(error) bool {
+					paused = true
+					return *tc.pauseDelete
+				}
+			}
+
+			var r fake.Recorder
+			r.Record(c)
+			err := c.CreateResourceStack(context.Background(), testConfig())
+			r.Stop()
+
+			var failedErr *resource.CreateFailedError
+			if !errors.As(err, &failedErr) {
+				t.Fatalf("expected CreateFailedError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "instances failed to join") {
+				t.Errorf("expected node group health issue in error, got %v", err)
+			}
+			if failedErr.DeleteErr != nil {
+				t.Fatalf("expected resources to be deleted without error, got %v", failedErr.DeleteErr)
+			}
+			if tc.pauseDelete != nil && !paused {
+				t.Error("expected pause function to be called")
+			}
+			if failedErr.Deleted != tc.wantDeleted {
+				t.Errorf("expected deleted %t, got %t", tc.wantDeleted, failedErr.Deleted)
+			}
+			snapshot := backend.Snapshot()
+			if snapshot.Empty() != tc.wantDeleted {
+				t.Errorf("expected no resources %t, got %+v", tc.wantDeleted, snapshot)
+			}
+
+			inventory := r.Inventory()
+			if tc.wantDeleted {
+				if inventory.VPCID != "" || inventory.Cluster.ClusterName != "" {
+					t.Errorf("expected deleted resources to be removed from inventory, got %+v", inventory)
+				}
+				return
+			}
+
+			// the kept resources are recorded so they can be deleted later
+			if !reflect.DeepEqual(snapshot.NodegroupNames, inventory.NodeGroupNames) {
+				t.Errorf("expected failed node group %v in inventory, got %v", snapshot.NodegroupNames, inventory.NodeGroupNames)
+			}
+			deleteResourceStack(t, backend, c, inventory)
+		})
+	}
+}
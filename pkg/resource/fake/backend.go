@@ -0,0 +1,391 @@
+// Package fake provides an in-memory AWS backend that implements the EC2, EKS,
+// IAM, CloudWatch Logs and S3 APIs used by the resource package.  It keeps the
+// state of every resource created through it and simulates the asynchronous
+// state transitions of NAT gateways, clusters and node groups so that complete
+// resource stacks can be created and deleted without AWS.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+// DefaultAccountID is the AWS account ID used by backends created with
+// NewBackend.
+const DefaultAccountID = "123456789012"
+
+// Thumbprint is the certificate thumbprint returned for every OIDC provider.
+const Thumbprint = "9e99a48a9960b14926bb7f3b02e22da2b0ab7280"
+
+// Backend contains the state of all fake AWS resources.  The EC2, EKS, IAM,
+// CloudWatchLogs and S3 fields implement the corresponding APIs of the
+// resource package and share this state.
+type Backend struct {
+	// The region reported in ARNs, availability zones and OIDC issuers.
+	Region string
+
+	// The account ID reported in ARNs.
+	AccountID string
+
+	// The number of status checks for which a resource remains in a
+	// transitional state, e.g. a NAT gateway in "pending" or a cluster in
+	// "CREATING", before reaching its final state.
+	Polls int
+
+	EC2            *EC2
+	EKS            *EKS
+	IAM            *IAM
+	CloudWatchLogs *CloudWatchLogs
+	S3             *S3
+
+	mu        sync.Mutex
+	idCounter int
+	calls     []string
+	failures  map[string][]error
+
+	// ec2 state
+	availabilityZones          []availabilityZone
+	unofferedInstanceTypes     map[string][]string
+	vpcs                       map[string]*vpc
+	subnets                    map[string]*subnet
+	internetGateways           map[string]*internetGateway
+	egressOnlyInternetGateways map[string]*egressOnlyInternetGateway
+	addresses                  map[string]*address
+	natGateways                map[string]*natGateway
+	routeTables                map[string]*routeTable
+	securityGroups             map[string]*securityGroup
+	vpcEndpoints               map[string]*vpcEndpoint
+	flowLogs                   map[string]*flowLog
+	transitGateways            map[string]*transitGateway
+	transitGatewayAttachments  map[string]*transitGatewayAttachment
+	vpcPeeringConnections      map[string]*vpcPeeringConnection
+	networkACLs                map[string]*networkACL
+
+	// eks state
+	clusters         map[string]*cluster
+	nodegroupFailure string
+
+	// iam state
+	roles         map[string]*role
+	policies      map[string]*policy
+	oidcProviders map[string]string
+
+	// cloudwatch logs state
+	logGroups map[string]*logGroup
+
+	// s3 state, keyed by bucket/key
+	objects map[string][]byte
+}
+
+// NewBackend returns an empty backend for the given region with three
+// availability zones and a Local Zone.
+func NewBackend(region string) *Backend {
+	b := &Backend{
+		Region:                     region,
+		AccountID:                  DefaultAccountID,
+		Polls:                      1,
+		failures:                   make(map[string][]error),
+		vpcs:                       make(map[string]*vpc),
+		subnets:                    make(map[string]*subnet),
+		internetGateways:           make(map[string]*internetGateway),
+		egressOnlyInternetGateways: make(map[string]*egressOnlyInternetGateway),
+		addresses:                  make(map[string]*address),
+		natGateways:                make(map[string]*natGateway),
+		routeTables:                make(map[string]*routeTable),
+		securityGroups:             make(map[string]*securityGroup),
+		vpcEndpoints:               make(map[string]*vpcEndpoint),
+		flowLogs:                   make(map[string]*flowLog),
+		transitGateways:            make(map[string]*transitGateway),
+		transitGatewayAttachments:  make(map[string]*transitGatewayAttachment),
+		vpcPeeringConnections:      make(map[string]*vpcPeeringConnection),
+		networkACLs:                make(map[string]*networkACL),
+		clusters:                   make(map[string]*cluster),
+		roles:                      make(map[string]*role),
+		policies:                   make(map[string]*policy),
+		oidcProviders:              make(map[string]string),
+		logGroups:                  make(map[string]*logGroup),
+		objects:                    make(map[string][]byte),
+	}
+	b.EC2 = &EC2{b}
+	b.EKS = &EKS{b}
+	b.IAM = &IAM{b}
+	b.CloudWatchLogs = &CloudWatchLogs{b}
+	b.S3 = &S3{b}
+	for i, suffix := range []string{"a", "b", "c"} {
+		b.availabilityZones = append(b.availabilityZones, availabilityZone{
+			name:     region + suffix,
+			id:       fmt.Sprintf("%s-az%d", regionAbbreviation(region), i+1),
+			zoneType: "availability-zone",
+		})
+	}
+	b.availabilityZones = append(b.availabilityZones, availabilityZone{
+		name:     region + "-lcl-1a",
+		id:       regionAbbreviation(region) + "-lcl1-az1",
+		zoneType: "local-zone",
+	})
+
+	return b
+}
+
+// Configure sets a resource client to use the backend's APIs, return a fixed
+// OIDC thumbprint and check resource status without delay.
+func (b *Backend) Configure(c *resource.ResourceClient) {
+	c.EC2Client = b.EC2
+	c.EKSClient = b.EKS
+	c.IAMClient = b.IAM
+	c.CloudWatchLogsClient = b.CloudWatchLogs
+	c.ThumbprintFunc = func(context.Context, string) (string, error) { return Thumbprint, nil }
+	c.CheckInterval = time.Millisecond
+}
+
+// ResourceClient returns a resource client configured to use the backend.
+func (b *Backend) ResourceClient() *resource.ResourceClient {
+	c := resource.CreateResourceClient(&aws.Config{Region: b.Region})
+	b.Configure(c)
+
+	return c
+}
+
+// Fail causes the next call to the named operation, e.g. "CreateNatGateway",
+// to return the given error.  Calling Fail repeatedly for the same operation
+// queues errors for subsequent calls.
+func (b *Backend) Fail(operation string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[operation] = append(b.failures[operation], err)
+}
+
+// FailNodegroups causes node groups created from now on to reach the
+// CREATE_FAILED status with a health issue containing the given message.
+// Pass an empty message to restore normal behavior.
+func (b *Backend) FailNodegroups(message string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nodegroupFailure = message
+}
+
+// AddAvailabilityZones adds availability zones with the given name suffixes,
+// e.g. "d", to the backend's region.
+func (b *Backend) AddAvailabilityZones(suffixes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, az := range b.availabilityZones {
+		if az.zoneType == "availability-zone" {
+			count++
+		}
+	}
+	for _, suffix := range suffixes {
+		count++
+		b.availabilityZones = append(b.availabilityZones, availabilityZone{
+			name:     b.Region + suffix,
+			id:       fmt.Sprintf("%s-az%d", regionAbbreviation(b.Region), count),
+			zoneType: "availability-zone",
+		})
+	}
+}
+
+// AddTransitGateway adds a transit gateway, which eks-cluster never creates
+// itself, and returns its ID.
+func (b *Backend) AddTransitGateway() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tgw := &transitGateway{id: b.newID("tgw")}
+	b.transitGateways[tgw.id] = tgw
+
+	return tgw.id
+}
+
+// RemoveInstanceTypeOffering stops an instance type from being offered in the
+// availability zone with the given zone ID.
+func (b *Backend) RemoveInstanceTypeOffering(zoneID, instanceType string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unofferedInstanceTypes == nil {
+		b.unofferedInstanceTypes = make(map[string][]string)
+	}
+	b.unofferedInstanceTypes[zoneID] = append(b.unofferedInstanceTypes[zoneID], instanceType)
+}
+
+// Calls returns the names of the operations called on the backend in order.
+func (b *Backend) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string{}, b.calls...)
+}
+
+// Snapshot contains the IDs of the resources that currently exist in the
+// backend.  Deleted NAT gateways, VPC endpoints, transit gateway attachments and
+// VPC peering connections, main route tables, default security groups and
+// network ACLs, EKS-managed security groups and transit gateways are not
+// included.
+type Snapshot struct {
+	VPCIDs                       []string
+	SubnetIDs                    []string
+	InternetGatewayIDs           []string
+	EgressOnlyInternetGatewayIDs []string
+	AllocationIDs                []string
+	NATGatewayIDs                []string
+	RouteTableIDs                []string
+	SecurityGroupIDs             []string
+	VPCEndpointIDs               []string
+	FlowLogIDs                   []string
+	TransitGatewayAttachmentIDs  []string
+	VPCPeeringConnectionIDs      []string
+	NetworkACLIDs                []string
+	RoleNames                    []string
+	PolicyARNs                   []string
+	OIDCProviderARNs             []string
+	ClusterNames                 []string
+	NodegroupNames               []string
+	AddonNames                   []string
+	LogGroupNames                []string
+}
+
+// Empty returns true if the snapshot contains no resources.
+func (s *Snapshot) Empty() bool {
+	return len(s.VPCIDs)+len(s.SubnetIDs)+len(s.InternetGatewayIDs)+len(s.EgressOnlyInternetGatewayIDs)+
+		len(s.AllocationIDs)+len(s.NATGatewayIDs)+len(s.RouteTableIDs)+
+		len(s.SecurityGroupIDs)+len(s.VPCEndpointIDs)+len(s.FlowLogIDs)+
+		len(s.TransitGatewayAttachmentIDs)+len(s.VPCPeeringConnectionIDs)+len(s.NetworkACLIDs)+
+		len(s.RoleNames)+len(s.PolicyARNs)+len(s.OIDCProviderARNs)+
+		len(s.ClusterNames)+len(s.NodegroupNames)+len(s.AddonNames)+
+		len(s.LogGroupNames) == 0
+}
+
+// Snapshot returns the IDs of all resources that currently exist.
+func (b *Backend) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var s Snapshot
+	s.VPCIDs = sortedKeys(b.vpcs)
+	s.SubnetIDs = sortedKeys(b.subnets)
+	s.InternetGatewayIDs = sortedKeys(b.internetGateways)
+	s.EgressOnlyInternetGatewayIDs = sortedKeys(b.egressOnlyInternetGateways)
+	s.AllocationIDs = sortedKeys(b.addresses)
+	for _, id := range sortedKeys(b.natGateways) {
+		if !b.natGateways[id].deleted() {
+			s.NATGatewayIDs = append(s.NATGatewayIDs, id)
+		}
+	}
+	for _, id := range sortedKeys(b.routeTables) {
+		if !b.routeTables[id].main {
+			s.RouteTableIDs = append(s.RouteTableIDs, id)
+		}
+	}
+	for _, id := range sortedKeys(b.securityGroups) {
+		sg := b.securityGroups[id]
+		tagKeys, _ := tagFilterValues("tag-key", sg.tags)
+		if sg.name != "default" && !contains(tagKeys, "aws:eks:cluster-name") {
+			s.SecurityGroupIDs = append(s.SecurityGroupIDs, id)
+		}
+	}
+	for _, id := range sortedKeys(b.vpcEndpoints) {
+		if !b.vpcEndpoints[id].deleted() {
+			s.VPCEndpointIDs = append(s.VPCEndpointIDs, id)
+		}
+	}
+	s.FlowLogIDs = sortedKeys(b.flowLogs)
+	for _, id := range sortedKeys(b.transitGatewayAttachments) {
+		if !b.transitGatewayAttachments[id].deleted() {
+			s.TransitGatewayAttachmentIDs = append(s.TransitGatewayAttachmentIDs, id)
+		}
+	}
+	for _, id := range sortedKeys(b.vpcPeeringConnections) {
+		if b.vpcPeeringConnections[id].usable() {
+			s.VPCPeeringConnectionIDs = append(s.VPCPeeringConnectionIDs, id)
+		}
+	}
+	for _, id := range sortedKeys(b.networkACLs) {
+		if !b.networkACLs[id].isDefault {
+			s.NetworkACLIDs = append(s.NetworkACLIDs, id)
+		}
+	}
+	s.RoleNames = sortedKeys(b.roles)
+	s.PolicyARNs = sortedKeys(b.policies)
+	s.OIDCProviderARNs = sortedKeys(b.oidcProviders)
+	s.ClusterNames = sortedKeys(b.clusters)
+	for _, name := range s.ClusterNames {
+		for _, ng := range sortedKeys(b.clusters[name].nodegroups) {
+			s.NodegroupNames = append(s.NodegroupNames, ng)
+		}
+		for _, addon := range sortedKeys(b.clusters[name].addons) {
+			s.AddonNames = append(s.AddonNames, addon)
+		}
+	}
+	s.LogGroupNames = sortedKeys(b.logGroups)
+
+	return s
+}
+
+// call records an operation and returns a queued failure for it, if any.  If
+// the context is cancelled it returns the context's error, as the AWS clients
+// do.  The caller must hold the backend lock.
+func (b *Backend) call(ctx context.Context, operation string) error {
+	b.calls = append(b.calls, operation)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if queued := b.failures[operation]; len(queued) > 0 {
+		b.failures[operation] = queued[1:]
+		return queued[0]
+	}
+
+	return nil
+}
+
+// newID returns a unique resource ID with the given prefix in the format
+// used by EC2.  The caller must hold the backend lock.
+func (b *Backend) newID(prefix string) string {
+	b.idCounter++
+	return fmt.Sprintf("%s-%017x", prefix, b.idCounter)
+}
+
+// apiError returns a smithy API error with the given code, as returned by
+// services that do not model their errors as types.
+func apiError(code, format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order.  Because IDs are
+// generated from a counter this is also the order of creation.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// regionAbbreviation returns the abbreviation used in availability zone IDs
+// for a region, e.g. "use1" for "us-east-1".
+func regionAbbreviation(region string) string {
+	parts := strings.Split(region, "-")
+	if len(parts) != 3 || len(parts[0]) < 2 || len(parts[1]) < 1 {
+		return region
+	}
+
+	return parts[0][:2] + parts[1][:1] + parts[2]
+}
@@ -0,0 +1,377 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+const testRegion = "us-east-2"
+
+// assumeRolePolicy is a minimal trust policy for creating roles.
+const assumeRolePolicy = `{"Version":"2012-10-17","Statement":[]}`
+
+// createVPC creates a VPC with a subnet in each of the given availability
+// zones and returns the VPC ID and subnet IDs.
+func createVPC(t *testing.T, backend *fake.Backend, zones ...string) (string, []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	createVpcOutput, err := backend.EC2.CreateVpc(ctx, &ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")})
+	if err != nil {
+		t.Fatalf("failed to create VPC: %v", err)
+	}
+	vpcID := *createVpcOutput.Vpc.VpcId
+
+	var subnetIDs []string
+	for i, zone := range zones {
+		createSubnetInput := ec2.CreateSubnetInput{
+			VpcId:            aws.String(vpcID),
+			AvailabilityZone: aws.String(testRegion + zone),
+			CidrBlock:        aws.String([]string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"}[i]),
+		}
+		createSubnetOutput, err := backend.EC2.CreateSubnet(ctx, &createSubnetInput)
+		if err != nil {
+			t.Fatalf("failed to create subnet: %v", err)
+		}
+		subnetIDs = append(subnetIDs, *createSubnetOutput.Subnet.SubnetId)
+	}
+
+	return vpcID, subnetIDs
+}
+
+// errorCode returns the API error code of an error, if any.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+
+	return ""
+}
+
+func TestBackendFail(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewBackend(testRegion)
+	firstErr := errors.New("first failure")
+	secondErr := errors.New("second failure")
+	backend.Fail("CreateVpc", firstErr)
+	backend.Fail("CreateVpc", secondErr)
+
+	input := ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}
+	for _, wantErr := range []error{firstErr, secondErr, nil} {
+		_, err := backend.EC2.CreateVpc(ctx, &input)
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("expected error %v, got %v", wantErr, err)
+		}
+	}
+	if snapshot := backend.Snapshot(); len(snapshot.VPCIDs) != 1 {
+		t.Errorf("expected only the last call to create a VPC, got %v", snapshot.VPCIDs)
+	}
+	wantCalls := []string{"CreateVpc", "CreateVpc", "CreateVpc"}
+	if calls := backend.Calls(); !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("expected calls %v, got %v", wantCalls, calls)
+	}
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := backend.EC2.CreateVpc(cancelledCtx, &input); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
+
+func TestEC2DeleteVpc(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewBackend(testRegion)
+	vpcID, subnetIDs := createVPC(t, backend, "a")
+
+	deleteVpcInput := ec2.DeleteVpcInput{VpcId: aws.String(vpcID)}
+	if _, err := backend.EC2.DeleteVpc(ctx, &deleteVpcInput); errorCode(err) != "DependencyViolation" {
+		t.Fatalf("expected dependency violation while the subnet exists, got %v", err)
+	}
+	if _, err := backend.EC2.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(subnetIDs[0])}); err != nil {
+		t.Fatalf("failed to delete subnet: %v", err)
+	}
+	if _, err := backend.EC2.DeleteVpc(ctx, &deleteVpcInput); err != nil {
+		t.Fatalf("failed to delete VPC: %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+	if _, err := backend.EC2.DeleteVpc(ctx, &deleteVpcInput); errorCode(err) != "InvalidVpcID.NotFound" {
+		t.Errorf("expected VPC not found, got %v", err)
+	}
+}
+
+func TestEC2CreateSubnet(t *testing.T) {
+	testCases := []struct {
+		name             string
+		availabilityZone string
+		cidrBlock        string
+		wantCode         string
+	}{
+		{
+			name:             "valid",
+			availabilityZone: testRegion + "b",
+			cidrBlock:        "10.0.1.0/24",
+		},
+		{
+			name:             "outside the VPC CIDR",
+			availabilityZone: testRegion + "b",
+			cidrBlock:        "10.1.0.0/24",
+			wantCode:         "InvalidSubnet.Range",
+		},
+		{
+			name:             "overlapping subnet",
+			availabilityZone: testRegion + "b",
+			cidrBlock:        "10.0.0.0/23",
+			wantCode:         "InvalidSubnet.Conflict",
+		},
+		{
+			name:             "unknown availability zone",
+			availabilityZone: "us-west-2a",
+			cidrBlock:        "10.0.1.0/24",
+			wantCode:         "InvalidParameterValue",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := fake.NewBackend(testRegion)
+			vpcID, _ := createVPC(t, backend, "a")
+
+			createSubnetInput := ec2.CreateSubnetInput{
+				VpcId:            aws.String(vpcID),
+				AvailabilityZone: aws.String(tc.availabilityZone),
+				CidrBlock:        aws.String(tc.cidrBlock),
+			}
+			_, err := backend.EC2.CreateSubnet(context.Background(), &createSubnetInput)
+			if code := errorCode(err); code != tc.wantCode || (tc.wantCode == "" && err != nil) {
+				t.Errorf("expected error code %q, got %v", tc.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestIAMDeleteConflict(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewBackend(testRegion)
+
+	createRoleInput := iam.CreateRoleInput{
+		RoleName:                 aws.String("test-role"),
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicy),
+	}
+	if _, err := backend.IAM.CreateRole(ctx, &createRoleInput); err != nil {
+		t.Fatalf("failed to create role: %v", err)
+	}
+	createPolicyInput := iam.CreatePolicyInput{
+		PolicyName:     aws.String("test-policy"),
+		PolicyDocument: aws.String(assumeRolePolicy),
+	}
+	createPolicyOutput, err := backend.IAM.CreatePolicy(ctx, &createPolicyInput)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	policyARN := createPolicyOutput.Policy.Arn
+	attachRolePolicyInput := iam.AttachRolePolicyInput{RoleName: aws.String("test-role"), PolicyArn: policyARN}
+	if _, err := backend.IAM.AttachRolePolicy(ctx, &attachRolePolicyInput); err != nil {
+		t.Fatalf("failed to attach policy: %v", err)
+	}
+
+	var conflictErr *iamtypes.DeleteConflictException
+	if _, err := backend.IAM.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String("test-role")}); !errors.As(err, &conflictErr) {
+		t.Errorf("expected delete conflict for role with attached policy, got %v", err)
+	}
+	if _, err := backend.IAM.DeletePolicy(ctx, &iam.DeletePolicyInput{PolicyArn: policyARN}); !errors.As(err, &conflictErr) {
+		t.Errorf("expected delete conflict for attached policy, got %v", err)
+	}
+
+	detachRolePolicyInput := iam.DetachRolePolicyInput{RoleName: aws.String("test-role"), PolicyArn: policyARN}
+	if _, err := backend.IAM.DetachRolePolicy(ctx, &detachRolePolicyInput); err != nil {
+		t.Fatalf("failed to detach policy: %v", err)
+	}
+	if _, err := backend.IAM.DeletePolicy(ctx, &iam.DeletePolicyInput{PolicyArn: policyARN}); err != nil {
+		t.Errorf("failed to delete policy: %v", err)
+	}
+	if _, err := backend.IAM.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String("test-role")}); err != nil {
+		t.Errorf("failed to delete role: %v", err)
+	}
+
+	var notFoundErr *iamtypes.NoSuchEntityException
+	if _, err := backend.IAM.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String("test-role")}); !errors.As(err, &notFoundErr) {
+		t.Errorf("expected deleted role not to be found, got %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+}
+
+func TestEKSNodegroupStatus(t *testing.T) {
+	testCases := []struct {
+		name       string
+		failure    string
+		wantStatus ekstypes.NodegroupStatus
+	}{
+		{
+			name:       "healthy",
+			wantStatus: ekstypes.NodegroupStatusActive,
+		},
+		{
+			name:       "failed",
+			failure:    "instances failed to join the kubernetes cluster",
+			wantStatus: ekstypes.NodegroupStatusCreateFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := fake.NewBackend(testRegion)
+			backend.FailNodegroups(tc.failure)
+			_, subnetIDs := createVPC(t, backend, "a", "b")
+			createRoleInput := iam.CreateRoleInput{
+				RoleName:                 aws.String("test-role"),
+				AssumeRolePolicyDocument: aws.String(assumeRolePolicy),
+			}
+			createRoleOutput, err := backend.IAM.CreateRole(ctx, &createRoleInput)
+			if err != nil {
+				t.Fatalf("failed to create role: %v", err)
+			}
+			roleARN := createRoleOutput.Role.Arn
+
+			createClusterInput := eks.CreateClusterInput{
+				Name:               aws.String("test"),
+				RoleArn:            roleARN,
+				ResourcesVpcConfig: &ekstypes.VpcConfigRequest{SubnetIds: subnetIDs},
+			}
+			if _, err := backend.EKS.CreateCluster(ctx, &createClusterInput); err != nil {
+				t.Fatalf("failed to create cluster: %v", err)
+			}
+			createNodegroupInput := eks.CreateNodegroupInput{
+				ClusterName:   aws.String("test"),
+				NodegroupName: aws.String("test"),
+				NodeRole:      roleARN,
+				Subnets:       subnetIDs,
+			}
+			var invalidErr *ekstypes.InvalidRequestException
+			if _, err := backend.EKS.CreateNodegroup(ctx, &createNodegroupInput); !errors.As(err, &invalidErr) {
+				t.Fatalf("expected node group to be rejected while the cluster is creating, got %v", err)
+			}
+
+			// the cluster becomes active after the configured number of polls
+			describeClusterInput := eks.DescribeClusterInput{Name: aws.String("test")}
+			for i := 0; i <= backend.Polls; i++ {
+				describeClusterOutput, err := backend.EKS.DescribeCluster(ctx, &describeClusterInput)
+				if err != nil {
+					t.Fatalf("failed to describe cluster: %v", err)
+				}
+				wantStatus := ekstypes.ClusterStatusCreating
+				if i == backend.Polls {
+					wantStatus = ekstypes.ClusterStatusActive
+				}
+				if status := describeClusterOutput.Cluster.Status; status != wantStatus {
+					t.Fatalf("expected cluster status %s after %d polls, got %s", wantStatus, i+1, status)
+				}
+			}
+
+			if _, err := backend.EKS.CreateNodegroup(ctx, &createNodegroupInput); err != nil {
+				t.Fatalf("failed to create node group: %v", err)
+			}
+			describeNodegroupInput := eks.DescribeNodegroupInput{ClusterName: aws.String("test"), NodegroupName: aws.String("test")}
+			var nodegroup *ekstypes.Nodegroup
+			for i := 0; i <= backend.Polls; i++ {
+				describeNodegroupOutput, err := backend.EKS.DescribeNodegroup(ctx, &describeNodegroupInput)
+				if err != nil {
+					t.Fatalf("failed to describe node group: %v", err)
+				}
+				nodegroup = describeNodegroupOutput.Nodegroup
+			}
+			if nodegroup.Status != tc.wantStatus {
+				t.Errorf("expected node group status %s, got %s", tc.wantStatus, nodegroup.Status)
+			}
+			if tc.failure != "" && (len(nodegroup.Health.Issues) != 1 || *nodegroup.Health.Issues[0].Message != tc.failure) {
+				t.Errorf("expected health issue %q, got %+v", tc.failure, nodegroup.Health.Issues)
+			}
+		})
+	}
+}
+
+func TestCloudWatchLogsDescribeLogGroups(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewBackend(testRegion)
+	wantNames := []string{"/test/a", "/test/b", "/test/c", "/test/d", "/test/e"}
+	for _, name := range append([]string{"/other"}, wantNames...) {
+		if _, err := backend.CloudWatchLogs.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(name)}); err != nil {
+			t.Fatalf("failed to create log group %s: %v", name, err)
+		}
+	}
+	var existsErr *logtypes.ResourceAlreadyExistsException
+	if _, err := backend.CloudWatchLogs.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String("/test/a")}); !errors.As(err, &existsErr) {
+		t.Errorf("expected duplicate log group to be rejected, got %v", err)
+	}
+
+	// the results are paged, so follow the next token until it is empty
+	var names []string
+	var pages int
+	input := cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: aws.String("/test/")}
+	for {
+		output, err := backend.CloudWatchLogs.DescribeLogGroups(ctx, &input)
+		if err != nil {
+			t.Fatalf("failed to describe log groups: %v", err)
+		}
+		pages++
+		for _, logGroup := range output.LogGroups {
+			names = append(names, *logGroup.LogGroupName)
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("expected log groups %v, got %v", wantNames, names)
+	}
+	if pages < 2 {
+		t.Errorf("expected log groups to be paged, got %d page", pages)
+	}
+
+	var notFoundErr *logtypes.ResourceNotFoundException
+	if _, err := backend.CloudWatchLogs.DeleteLogGroup(ctx, &cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String("/missing")}); !errors.As(err, &notFoundErr) {
+		t.Errorf("expected missing log group not to be found, got %v", err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	c := fake.NewBackend(testRegion).ResourceClient()
+
+	var r fake.Recorder
+	for _, vpcID := range []string{"vpc-1", "vpc-2"} {
+		r.Record(c)
+		*c.MessageChan <- "creating " + vpcID
+		*c.InventoryChan <- resource.ResourceInventory{VPCID: vpcID}
+		r.Stop()
+
+		if inventory := r.Inventory(); inventory.VPCID != vpcID {
+			t.Errorf("expected latest inventory with VPC %s, got %+v", vpcID, inventory)
+		}
+	}
+
+	wantMessages := []string{"creating vpc-1", "creating vpc-2"}
+	if messages := r.Messages(); !reflect.DeepEqual(messages, wantMessages) {
+		t.Errorf("expected messages %v, got %v", wantMessages, messages)
+	}
+}
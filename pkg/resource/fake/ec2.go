@@ -0,0 +1,3079 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var _ resource.EC2API = (*EC2)(nil)
+
+// EC2 implements the resource package's EC2API against the backend state.
+type EC2 struct {
+	b *Backend
+}
+
+type availabilityZone struct {
+	name     string
+	id       string
+	zoneType string
+}
+
+type vpc struct {
+	id             string
+	cidr           netip.Prefix
+	ipv6CIDR       netip.Prefix
+	secondaryCIDRs []*vpcCIDRBlock
+	dnsHostnames   bool
+	dnsSupport     bool
+	tags           []types.Tag
+}
+
+type vpcCIDRBlock struct {
+	associationID string
+	cidr          netip.Prefix
+	state         types.VpcCidrBlockStateCode
+	polls         int
+}
+
+type subnet struct {
+	id                      string
+	vpcID                   string
+	zone                    availabilityZone
+	cidr                    netip.Prefix
+	ipv6CIDR                netip.Prefix
+	mapPublicIP             bool
+	assignIPv6              bool
+	networkACLID            string
+	networkACLAssociationID string
+	tags                    []types.Tag
+}
+
+type internetGateway struct {
+	id    string
+	vpcID string
+	tags  []types.Tag
+}
+
+type egressOnlyInternetGateway struct {
+	id    string
+	vpcID string
+	tags  []types.Tag
+}
+
+type address struct {
+	id       string
+	publicIP string
+	tags     []types.Tag
+}
+
+type natGateway struct {
+	id           string
+	vpcID        string
+	subnetID     string
+	allocationID string
+	state        types.NatGatewayState
+	polls        int
+	tags         []types.Tag
+}
+
+type routeTable struct {
+	id           string
+	vpcID        string
+	main         bool
+	routes       []types.Route
+	associations []types.RouteTableAssociation
+	tags         []types.Tag
+}
+
+type securityGroup struct {
+	id      string
+	name    string
+	vpcID   string
+	ingress []types.IpPermission
+	tags    []types.Tag
+}
+
+type vpcEndpoint struct {
+	id               string
+	vpcID            string
+	serviceName      string
+	endpointType     types.VpcEndpointType
+	routeTableIDs    []string
+	subnetIDs        []string
+	securityGroupIDs []string
+	privateDNS       bool
+	state            types.State
+	polls            int
+	tags             []types.Tag
+}
+
+type flowLog struct {
+	id              string
+	resourceID      string
+	trafficType     types.TrafficType
+	destinationType types.LogDestinationType
+	destination     string
+	logGroupName    string
+	deliveryRoleARN string
+	tags            []types.Tag
+}
+
+type transitGateway struct {
+	id string
+}
+
+type transitGatewayAttachment struct {
+	id               string
+	transitGatewayID string
+	vpcID            string
+	subnetIDs        []string
+	state            types.TransitGatewayAttachmentState
+	polls            int
+	tags             []types.Tag
+}
+
+type vpcPeeringConnection struct {
+	id             string
+	requesterVPCID string
+	accepterVPCID  string
+	accepterOwner  string
+	accepterRegion string
+	state          types.VpcPeeringConnectionStateReasonCode
+	polls          int
+	tags           []types.Tag
+}
+
+type networkACL struct {
+	id        string
+	vpcID     string
+	isDefault bool
+	entries   []types.NetworkAclEntry
+	tags      []types.Tag
+}
+
+// deleted returns true if the NAT gateway has reached the deleted state.
+func (n *natGateway) deleted() bool {
+	return n.state == types.NatGatewayStateDeleted
+}
+
+// advance moves a NAT gateway in a transitional state towards its final state
+// each time its status is checked.
+func (n *natGateway) advance() {
+	if n.state != types.NatGatewayStatePending && n.state != types.NatGatewayStateDeleting {
+		return
+	}
+	if n.polls > 0 {
+		n.polls--
+		return
+	}
+	if n.state == types.NatGatewayStatePending {
+		n.state = types.NatGatewayStateAvailable
+	} else {
+		n.state = types.NatGatewayStateDeleted
+	}
+}
+
+// associated returns true if the CIDR block has been associated with the VPC.
+func (c *vpcCIDRBlock) associated() bool {
+	return c.state == types.VpcCidrBlockStateCodeAssociated
+}
+
+// advance moves a CIDR block association in a transitional state towards its
+// final state each time its VPC is described.
+func (c *vpcCIDRBlock) advance() {
+	if c.state != types.VpcCidrBlockStateCodeAssociating {
+		return
+	}
+	if c.polls > 0 {
+		c.polls--
+		return
+	}
+	c.state = types.VpcCidrBlockStateCodeAssociated
+}
+
+// VPC endpoint states as returned by the API, which unlike the SDK's constants
+// are in lower case.
+var (
+	vpcEndpointStatePending   = types.State(strings.ToLower(string(types.StatePending)))
+	vpcEndpointStateAvailable = types.State(strings.ToLower(string(types.StateAvailable)))
+	vpcEndpointStateDeleting  = types.State(strings.ToLower(string(types.StateDeleting)))
+	vpcEndpointStateDeleted   = types.State(strings.ToLower(string(types.StateDeleted)))
+)
+
+// deleted returns true if the VPC endpoint has reached the deleted state.
+func (v *vpcEndpoint) deleted() bool {
+	return v.state == vpcEndpointStateDeleted
+}
+
+// advance moves a VPC endpoint in a transitional state towards its final
+// state each time its status is checked.
+func (v *vpcEndpoint) advance() {
+	if v.state != vpcEndpointStatePending && v.state != vpcEndpointStateDeleting {
+		return
+	}
+	if v.polls > 0 {
+		v.polls--
+		return
+	}
+	if v.state == vpcEndpointStatePending {
+		v.state = vpcEndpointStateAvailable
+	} else {
+		v.state = vpcEndpointStateDeleted
+	}
+}
+
+// deleted returns true if the transit gateway attachment has reached the
+// deleted state.
+func (a *transitGatewayAttachment) deleted() bool {
+	return a.state == types.TransitGatewayAttachmentStateDeleted
+}
+
+// advance moves a transit gateway attachment in a transitional state towards
+// its final state each time its status is checked.
+func (a *transitGatewayAttachment) advance() {
+	if a.state != types.TransitGatewayAttachmentStatePending && a.state != types.TransitGatewayAttachmentStateDeleting {
+		return
+	}
+	if a.polls > 0 {
+		a.polls--
+		return
+	}
+	if a.state == types.TransitGatewayAttachmentStatePending {
+		a.state = types.TransitGatewayAttachmentStateAvailable
+	} else {
+		a.state = types.TransitGatewayAttachmentStateDeleted
+	}
+}
+
+// usable returns true if the VPC peering connection is active or on its way
+// to becoming active.
+func (p *vpcPeeringConnection) usable() bool {
+	switch p.state {
+	case types.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+		types.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+		types.VpcPeeringConnectionStateReasonCodeProvisioning,
+		types.VpcPeeringConnectionStateReasonCodeActive:
+		return true
+	}
+
+	return false
+}
+
+// CreateVpc creates a VPC along with its main route table, default security
+// group and default network ACL.
+func (e *EC2) CreateVpc(ctx context.Context, params *ec2.CreateVpcInput, optFns ...func(*ec2.Options)) (*ec2.CreateVpcOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateVpc"); err != nil {
+		return nil, err
+	}
+
+	cidr, err := netip.ParsePrefix(stringValue(params.CidrBlock))
+	if err != nil {
+		return nil, apiError("InvalidParameterValue", "invalid CIDR block %q", stringValue(params.CidrBlock))
+	}
+	v := &vpc{
+		id:   b.newID("vpc"),
+		cidr: cidr.Masked(),
+		tags: tagsFor(params.TagSpecifications, types.ResourceTypeVpc),
+	}
+	if params.AmazonProvidedIpv6CidrBlock != nil && *params.AmazonProvidedIpv6CidrBlock {
+		v.ipv6CIDR = netip.MustParsePrefix(fmt.Sprintf("2600:1f14:%x:%x00::/56", b.idCounter>>8, b.idCounter&0xff))
+	}
+	b.vpcs[v.id] = v
+
+	mainRouteTable := &routeTable{
+		id:    b.newID("rtb"),
+		vpcID: v.id,
+		main:  true,
+	}
+	mainRouteTable.routes = v.localRoutes()
+	mainRouteTable.associations = []types.RouteTableAssociation{
+		{
+			Main:                    boolPtr(true),
+			RouteTableAssociationId: stringPtr(b.newID("rtbassoc")),
+			RouteTableId:            stringPtr(mainRouteTable.id),
+		},
+	}
+	b.routeTables[mainRouteTable.id] = mainRouteTable
+
+	defaultGroup := &securityGroup{
+		id:    b.newID("sg"),
+		name:  "default",
+		vpcID: v.id,
+	}
+	b.securityGroups[defaultGroup.id] = defaultGroup
+
+	defaultNetworkACL := &networkACL{
+		id:        b.newID("acl"),
+		vpcID:     v.id,
+		isDefault: true,
+		entries: []types.NetworkAclEntry{
+			networkACLEntry(100, false, types.RuleActionAllow),
+			networkACLEntry(100, true, types.RuleActionAllow),
+			networkACLEntry(32767, false, types.RuleActionDeny),
+			networkACLEntry(32767, true, types.RuleActionDeny),
+		},
+	}
+	b.networkACLs[defaultNetworkACL.id] = defaultNetworkACL
+
+	return &ec2.CreateVpcOutput{Vpc: v.toType()}, nil
+}
+
+// DescribeVpcs returns VPCs matching the given IDs and filters.
+func (e *EC2) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeVpcs"); err != nil {
+		return nil, err
+	}
+
+	var vpcs []types.Vpc
+	for _, id := range sortedKeys(b.vpcs) {
+		v := b.vpcs[id]
+		if len(params.VpcIds) > 0 && !contains(params.VpcIds, id) {
+			continue
+		}
+		for _, secondaryCIDR := range v.secondaryCIDRs {
+			secondaryCIDR.advance()
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{v.id}, true
+			case "cidr":
+				return []string{v.cidr.String()}, true
+			case "cidr-block-association.cidr-block":
+				cidrs := []string{v.cidr.String()}
+				for _, secondaryCIDR := range v.secondaryCIDRs {
+					cidrs = append(cidrs, secondaryCIDR.cidr.String())
+				}
+				return cidrs, true
+			case "state":
+				return []string{string(types.VpcStateAvailable)}, true
+			}
+			return tagFilterValues(name, v.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		vpcs = append(vpcs, *v.toType())
+	}
+
+	return &ec2.DescribeVpcsOutput{Vpcs: vpcs}, nil
+}
+
+// ModifyVpcAttribute sets the DNS attributes of a VPC.
+func (e *EC2) ModifyVpcAttribute(ctx context.Context, params *ec2.ModifyVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyVpcAttributeOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ModifyVpcAttribute"); err != nil {
+		return nil, err
+	}
+
+	v, ok := b.vpcs[stringValue(params.VpcId)]
+	if !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	if params.EnableDnsHostnames != nil && params.EnableDnsHostnames.Value != nil {
+		v.dnsHostnames = *params.EnableDnsHostnames.Value
+	}
+	if params.EnableDnsSupport != nil && params.EnableDnsSupport.Value != nil {
+		v.dnsSupport = *params.EnableDnsSupport.Value
+	}
+
+	return &ec2.ModifyVpcAttributeOutput{}, nil
+}
+
+// DeleteVpc deletes a VPC that has no remaining dependencies.
+func (e *EC2) DeleteVpc(ctx context.Context, params *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteVpc"); err != nil {
+		return nil, err
+	}
+
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	for _, s := range b.subnets {
+		if s.vpcID == vpcID {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, igw := range b.internetGateways {
+		if igw.vpcID == vpcID {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, eigw := range b.egressOnlyInternetGateways {
+		if eigw.vpcID == vpcID {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, n := range b.natGateways {
+		if n.vpcID == vpcID && !n.deleted() {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, rt := range b.routeTables {
+		if rt.vpcID == vpcID && !rt.main {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, sg := range b.securityGroups {
+		if sg.vpcID == vpcID && sg.name != "default" {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, v := range b.vpcEndpoints {
+		if v.vpcID == vpcID && !v.deleted() {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, a := range b.transitGatewayAttachments {
+		if a.vpcID == vpcID && !a.deleted() {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+	for _, n := range b.networkACLs {
+		if n.vpcID == vpcID && !n.isDefault {
+			return nil, dependencyViolation(vpcID)
+		}
+	}
+
+	for id, rt := range b.routeTables {
+		if rt.vpcID == vpcID {
+			delete(b.routeTables, id)
+		}
+	}
+	for id, sg := range b.securityGroups {
+		if sg.vpcID == vpcID {
+			delete(b.securityGroups, id)
+		}
+	}
+	for id, n := range b.networkACLs {
+		if n.vpcID == vpcID {
+			delete(b.networkACLs, id)
+		}
+	}
+	// flow logs are deleted along with the VPC they capture traffic for
+	for id, f := range b.flowLogs {
+		if f.resourceID == vpcID {
+			delete(b.flowLogs, id)
+		}
+	}
+	// peering connections are deleted along with either of their VPCs
+	for _, p := range b.vpcPeeringConnections {
+		if (p.requesterVPCID == vpcID || p.accepterVPCID == vpcID) && p.usable() {
+			p.state = types.VpcPeeringConnectionStateReasonCodeDeleted
+		}
+	}
+	delete(b.vpcs, vpcID)
+
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+// AssociateVpcCidrBlock associates a secondary IPv4 CIDR block with a VPC.
+// The association completes once the VPC has been described a number of
+// times.
+func (e *EC2) AssociateVpcCidrBlock(ctx context.Context, params *ec2.AssociateVpcCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.AssociateVpcCidrBlockOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AssociateVpcCidrBlock"); err != nil {
+		return nil, err
+	}
+
+	v, ok := b.vpcs[stringValue(params.VpcId)]
+	if !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	cidr, err := netip.ParsePrefix(stringValue(params.CidrBlock))
+	if err != nil || !cidr.Addr().Is4() {
+		return nil, apiError("InvalidParameterValue", "invalid CIDR block %q", stringValue(params.CidrBlock))
+	}
+	cidr = cidr.Masked()
+	if cidr.Overlaps(v.cidr) {
+		return nil, apiError("InvalidVpc.Range", "the CIDR '%s' overlaps the VPC's CIDR", cidr)
+	}
+	for _, secondaryCIDR := range v.secondaryCIDRs {
+		if cidr.Overlaps(secondaryCIDR.cidr) {
+			return nil, apiError("InvalidVpc.Range", "the CIDR '%s' overlaps the VPC's CIDR", cidr)
+		}
+	}
+
+	secondaryCIDR := &vpcCIDRBlock{
+		associationID: b.newID("vpc-cidr-assoc"),
+		cidr:          cidr,
+		state:         types.VpcCidrBlockStateCodeAssociating,
+		polls:         b.Polls,
+	}
+	v.secondaryCIDRs = append(v.secondaryCIDRs, secondaryCIDR)
+
+	return &ec2.AssociateVpcCidrBlockOutput{
+		CidrBlockAssociation: secondaryCIDR.toType(),
+		VpcId:                stringPtr(v.id),
+	}, nil
+}
+
+// DisassociateVpcCidrBlock removes a secondary CIDR block association from a
+// VPC that has no subnets left in the CIDR block.
+func (e *EC2) DisassociateVpcCidrBlock(ctx context.Context, params *ec2.DisassociateVpcCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateVpcCidrBlockOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DisassociateVpcCidrBlock"); err != nil {
+		return nil, err
+	}
+
+	associationID := stringValue(params.AssociationId)
+	for _, v := range b.vpcs {
+		for i, secondaryCIDR := range v.secondaryCIDRs {
+			if secondaryCIDR.associationID != associationID {
+				continue
+			}
+			for _, s := range b.subnets {
+				if s.vpcID == v.id && secondaryCIDR.cidr.Contains(s.cidr.Addr()) {
+					return nil, apiError("InvalidCidrBlock.InUse",
+						"the vpc CIDR block %s cannot be disassociated as it has subnets", secondaryCIDR.cidr)
+				}
+			}
+			v.secondaryCIDRs = append(v.secondaryCIDRs[:i], v.secondaryCIDRs[i+1:]...)
+			association := secondaryCIDR.toType()
+			association.CidrBlockState.State = types.VpcCidrBlockStateCodeDisassociating
+
+			return &ec2.DisassociateVpcCidrBlockOutput{CidrBlockAssociation: association, VpcId: stringPtr(v.id)}, nil
+		}
+	}
+
+	return nil, apiError("InvalidVpcCidrBlockAssociationID.NotFound",
+		"the vpc CIDR block association ID '%s' does not exist", associationID)
+}
+
+// CreateSubnet creates a subnet after checking that its CIDR block is inside
+// the VPC and does not overlap any other subnet.
+func (e *EC2) CreateSubnet(ctx context.Context, params *ec2.CreateSubnetInput, optFns ...func(*ec2.Options)) (*ec2.CreateSubnetOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateSubnet"); err != nil {
+		return nil, err
+	}
+
+	v, ok := b.vpcs[stringValue(params.VpcId)]
+	if !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	zone, ok := b.findZone(stringValue(params.AvailabilityZone), stringValue(params.AvailabilityZoneId))
+	if !ok {
+		return nil, apiError("InvalidParameterValue", "invalid availability zone %q",
+			stringValue(params.AvailabilityZone)+stringValue(params.AvailabilityZoneId))
+	}
+	cidr, err := netip.ParsePrefix(stringValue(params.CidrBlock))
+	if err != nil {
+		return nil, apiError("InvalidParameterValue", "invalid CIDR block %q", stringValue(params.CidrBlock))
+	}
+	cidr = cidr.Masked()
+	if !v.containsSubnetCIDR(cidr) {
+		return nil, apiError("InvalidSubnet.Range", "the CIDR '%s' is invalid", cidr)
+	}
+	for _, s := range b.subnets {
+		if s.vpcID == v.id && s.cidr.Overlaps(cidr) {
+			return nil, apiError("InvalidSubnet.Conflict", "the CIDR '%s' conflicts with another subnet", cidr)
+		}
+	}
+	var ipv6CIDR netip.Prefix
+	if params.Ipv6CidrBlock != nil {
+		ipv6CIDR, err = netip.ParsePrefix(*params.Ipv6CidrBlock)
+		if err != nil || !v.ipv6CIDR.IsValid() || ipv6CIDR.Bits() != 64 || !v.ipv6CIDR.Contains(ipv6CIDR.Addr()) {
+			return nil, apiError("InvalidSubnet.Range", "the IPv6 CIDR '%s' is invalid", *params.Ipv6CidrBlock)
+		}
+		ipv6CIDR = ipv6CIDR.Masked()
+		for _, s := range b.subnets {
+			if s.vpcID == v.id && s.ipv6CIDR == ipv6CIDR {
+				return nil, apiError("InvalidSubnet.Conflict", "the IPv6 CIDR '%s' conflicts with another subnet", ipv6CIDR)
+			}
+		}
+	}
+
+	s := &subnet{
+		id:       b.newID("subnet"),
+		vpcID:    v.id,
+		zone:     zone,
+		cidr:     cidr,
+		ipv6CIDR: ipv6CIDR,
+		tags:     tagsFor(params.TagSpecifications, types.ResourceTypeSubnet),
+	}
+	// subnets are associated with the VPC's default network ACL
+	for _, n := range b.networkACLs {
+		if n.vpcID == v.id && n.isDefault {
+			s.networkACLID = n.id
+			s.networkACLAssociationID = b.newID("aclassoc")
+		}
+	}
+	b.subnets[s.id] = s
+
+	return &ec2.CreateSubnetOutput{Subnet: s.toType()}, nil
+}
+
+// ModifySubnetAttribute sets whether a subnet maps public IPs on launch and
+// assigns IPv6 addresses on creation.
+func (e *EC2) ModifySubnetAttribute(ctx context.Context, params *ec2.ModifySubnetAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ModifySubnetAttribute"); err != nil {
+		return nil, err
+	}
+
+	s, ok := b.subnets[stringValue(params.SubnetId)]
+	if !ok {
+		return nil, apiError("InvalidSubnetID.NotFound", "the subnet ID '%s' does not exist", stringValue(params.SubnetId))
+	}
+	if params.MapPublicIpOnLaunch != nil && params.MapPublicIpOnLaunch.Value != nil {
+		s.mapPublicIP = *params.MapPublicIpOnLaunch.Value
+	}
+	if params.AssignIpv6AddressOnCreation != nil && params.AssignIpv6AddressOnCreation.Value != nil {
+		if !s.ipv6CIDR.IsValid() {
+			return nil, apiError("InvalidParameterValue", "subnet %s has no IPv6 CIDR block", s.id)
+		}
+		s.assignIPv6 = *params.AssignIpv6AddressOnCreation.Value
+	}
+
+	return &ec2.ModifySubnetAttributeOutput{}, nil
+}
+
+// DescribeSubnets returns subnets matching the given IDs and filters.
+func (e *EC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeSubnets"); err != nil {
+		return nil, err
+	}
+
+	var subnets []types.Subnet
+	for _, id := range sortedKeys(b.subnets) {
+		s := b.subnets[id]
+		if len(params.SubnetIds) > 0 && !contains(params.SubnetIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{s.vpcID}, true
+			case "subnet-id":
+				return []string{s.id}, true
+			case "availability-zone":
+				return []string{s.zone.name}, true
+			case "availability-zone-id":
+				return []string{s.zone.id}, true
+			case "cidr-block":
+				return []string{s.cidr.String()}, true
+			}
+			return tagFilterValues(name, s.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		subnets = append(subnets, *s.toType())
+	}
+
+	return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+}
+
+// DeleteSubnet deletes a subnet along with its route table and network ACL
+// associations.
+func (e *EC2) DeleteSubnet(ctx context.Context, params *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteSubnet"); err != nil {
+		return nil, err
+	}
+
+	subnetID := stringValue(params.SubnetId)
+	if _, ok := b.subnets[subnetID]; !ok {
+		return nil, apiError("InvalidSubnetID.NotFound", "the subnet ID '%s' does not exist", subnetID)
+	}
+	for _, n := range b.natGateways {
+		if n.subnetID == subnetID && !n.deleted() {
+			return nil, dependencyViolation(subnetID)
+		}
+	}
+	for _, v := range b.vpcEndpoints {
+		if contains(v.subnetIDs, subnetID) && !v.deleted() {
+			return nil, dependencyViolation(subnetID)
+		}
+	}
+
+	for _, rt := range b.routeTables {
+		var associations []types.RouteTableAssociation
+		for _, a := range rt.associations {
+			if stringValue(a.SubnetId) != subnetID {
+				associations = append(associations, a)
+			}
+		}
+		rt.associations = associations
+	}
+	delete(b.subnets, subnetID)
+
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+// CreateInternetGateway creates a detached internet gateway.
+func (e *EC2) CreateInternetGateway(ctx context.Context, params *ec2.CreateInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.CreateInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	igw := &internetGateway{
+		id:   b.newID("igw"),
+		tags: tagsFor(params.TagSpecifications, types.ResourceTypeInternetGateway),
+	}
+	b.internetGateways[igw.id] = igw
+
+	return &ec2.CreateInternetGatewayOutput{InternetGateway: igw.toType()}, nil
+}
+
+// AttachInternetGateway attaches an internet gateway to a VPC.
+func (e *EC2) AttachInternetGateway(ctx context.Context, params *ec2.AttachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.AttachInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AttachInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	igw, ok := b.internetGateways[stringValue(params.InternetGatewayId)]
+	if !ok {
+		return nil, apiError("InvalidInternetGatewayID.NotFound", "the internet gateway ID '%s' does not exist",
+			stringValue(params.InternetGatewayId))
+	}
+	if _, ok := b.vpcs[stringValue(params.VpcId)]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	if igw.vpcID != "" {
+		return nil, apiError("Resource.AlreadyAssociated", "resource %s is already attached to network %s", igw.id, igw.vpcID)
+	}
+	igw.vpcID = stringValue(params.VpcId)
+
+	return &ec2.AttachInternetGatewayOutput{}, nil
+}
+
+// DetachInternetGateway detaches an internet gateway from a VPC.  It fails
+// while public NAT gateways remain in the VPC.
+func (e *EC2) DetachInternetGateway(ctx context.Context, params *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DetachInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	igw, ok := b.internetGateways[stringValue(params.InternetGatewayId)]
+	if !ok {
+		return nil, apiError("InvalidInternetGatewayID.NotFound", "the internet gateway ID '%s' does not exist",
+			stringValue(params.InternetGatewayId))
+	}
+	if igw.vpcID == "" || igw.vpcID != stringValue(params.VpcId) {
+		return nil, apiError("Gateway.NotAttached", "resource %s is not attached to network %s",
+			igw.id, stringValue(params.VpcId))
+	}
+	for _, n := range b.natGateways {
+		if n.vpcID == igw.vpcID && !n.deleted() {
+			return nil, apiError("DependencyViolation", "network %s has some mapped public address(es)", igw.vpcID)
+		}
+	}
+	igw.vpcID = ""
+
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+// DescribeInternetGateways returns internet gateways matching the given IDs
+// and filters.
+func (e *EC2) DescribeInternetGateways(ctx context.Context, params *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeInternetGateways"); err != nil {
+		return nil, err
+	}
+
+	var internetGateways []types.InternetGateway
+	for _, id := range sortedKeys(b.internetGateways) {
+		igw := b.internetGateways[id]
+		if len(params.InternetGatewayIds) > 0 && !contains(params.InternetGatewayIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "internet-gateway-id":
+				return []string{igw.id}, true
+			case "attachment.vpc-id":
+				return []string{igw.vpcID}, true
+			}
+			return tagFilterValues(name, igw.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		internetGateways = append(internetGateways, *igw.toType())
+	}
+
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: internetGateways}, nil
+}
+
+// DeleteInternetGateway deletes a detached internet gateway.
+func (e *EC2) DeleteInternetGateway(ctx context.Context, params *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	igw, ok := b.internetGateways[stringValue(params.InternetGatewayId)]
+	if !ok {
+		return nil, apiError("InvalidInternetGatewayID.NotFound", "the internet gateway ID '%s' does not exist",
+			stringValue(params.InternetGatewayId))
+	}
+	if igw.vpcID != "" {
+		return nil, dependencyViolation(igw.id)
+	}
+	delete(b.internetGateways, igw.id)
+
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+// CreateEgressOnlyInternetGateway creates an egress-only internet gateway
+// attached to a VPC.
+func (e *EC2) CreateEgressOnlyInternetGateway(ctx context.Context, params *ec2.CreateEgressOnlyInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.CreateEgressOnlyInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateEgressOnlyInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	v, ok := b.vpcs[stringValue(params.VpcId)]
+	if !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	eigw := &egressOnlyInternetGateway{
+		id:    b.newID("eigw"),
+		vpcID: v.id,
+		tags:  tagsFor(params.TagSpecifications, types.ResourceTypeEgressOnlyInternetGateway),
+	}
+	b.egressOnlyInternetGateways[eigw.id] = eigw
+
+	return &ec2.CreateEgressOnlyInternetGatewayOutput{EgressOnlyInternetGateway: eigw.toType()}, nil
+}
+
+// DescribeEgressOnlyInternetGateways returns egress-only internet gateways
+// matching the given IDs and filters.
+func (e *EC2) DescribeEgressOnlyInternetGateways(ctx context.Context, params *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeEgressOnlyInternetGateways"); err != nil {
+		return nil, err
+	}
+
+	for _, id := range params.EgressOnlyInternetGatewayIds {
+		if _, ok := b.egressOnlyInternetGateways[id]; !ok {
+			return nil, apiError("InvalidEgressOnlyInternetGatewayId.NotFound",
+				"the egress-only internet gateway ID '%s' does not exist", id)
+		}
+	}
+	var egressOnlyInternetGateways []types.EgressOnlyInternetGateway
+	for _, id := range sortedKeys(b.egressOnlyInternetGateways) {
+		eigw := b.egressOnlyInternetGateways[id]
+		if len(params.EgressOnlyInternetGatewayIds) > 0 && !contains(params.EgressOnlyInternetGatewayIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			return tagFilterValues(name, eigw.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		egressOnlyInternetGateways = append(egressOnlyInternetGateways, *eigw.toType())
+	}
+
+	return &ec2.DescribeEgressOnlyInternetGatewaysOutput{EgressOnlyInternetGateways: egressOnlyInternetGateways}, nil
+}
+
+// DeleteEgressOnlyInternetGateway deletes an egress-only internet gateway.
+func (e *EC2) DeleteEgressOnlyInternetGateway(ctx context.Context, params *ec2.DeleteEgressOnlyInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteEgressOnlyInternetGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteEgressOnlyInternetGateway"); err != nil {
+		return nil, err
+	}
+
+	eigw, ok := b.egressOnlyInternetGateways[stringValue(params.EgressOnlyInternetGatewayId)]
+	if !ok {
+		return nil, apiError("InvalidEgressOnlyInternetGatewayId.NotFound",
+			"the egress-only internet gateway ID '%s' does not exist", stringValue(params.EgressOnlyInternetGatewayId))
+	}
+	delete(b.egressOnlyInternetGateways, eigw.id)
+
+	return &ec2.DeleteEgressOnlyInternetGatewayOutput{ReturnCode: boolPtr(true)}, nil
+}
+
+// AllocateAddress allocates an elastic IP address.
+func (e *EC2) AllocateAddress(ctx context.Context, params *ec2.AllocateAddressInput, optFns ...func(*ec2.Options)) (*ec2.AllocateAddressOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AllocateAddress"); err != nil {
+		return nil, err
+	}
+
+	a := &address{
+		id:   b.newID("eipalloc"),
+		tags: tagsFor(params.TagSpecifications, types.ResourceTypeElasticIp),
+	}
+	a.publicIP = fmt.Sprintf("203.0.113.%d", b.idCounter%256)
+	b.addresses[a.id] = a
+
+	return &ec2.AllocateAddressOutput{
+		AllocationId: stringPtr(a.id),
+		PublicIp:     stringPtr(a.publicIP),
+	}, nil
+}
+
+// ReleaseAddress releases an elastic IP address that is not in use by a NAT
+// gateway.
+func (e *EC2) ReleaseAddress(ctx context.Context, params *ec2.ReleaseAddressInput, optFns ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ReleaseAddress"); err != nil {
+		return nil, err
+	}
+
+	allocationID := stringValue(params.AllocationId)
+	if _, ok := b.addresses[allocationID]; !ok {
+		return nil, apiError("InvalidAllocationID.NotFound", "the allocation ID '%s' does not exist", allocationID)
+	}
+	for _, n := range b.natGateways {
+		if n.allocationID == allocationID && !n.deleted() {
+			return nil, apiError("InvalidIPAddress.InUse", "address %s is in use", allocationID)
+		}
+	}
+	delete(b.addresses, allocationID)
+
+	return &ec2.ReleaseAddressOutput{}, nil
+}
+
+// DescribeAddresses returns elastic IP addresses matching the given
+// allocation IDs and filters.
+func (e *EC2) DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeAddresses"); err != nil {
+		return nil, err
+	}
+
+	var addresses []types.Address
+	for _, id := range sortedKeys(b.addresses) {
+		a := b.addresses[id]
+		if len(params.AllocationIds) > 0 && !contains(params.AllocationIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "allocation-id":
+				return []string{a.id}, true
+			case "public-ip":
+				return []string{a.publicIP}, true
+			case "domain":
+				return []string{string(types.DomainTypeVpc)}, true
+			}
+			return tagFilterValues(name, a.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		addresses = append(addresses, types.Address{
+			AllocationId: stringPtr(a.id),
+			Domain:       types.DomainTypeVpc,
+			PublicIp:     stringPtr(a.publicIP),
+			Tags:         copyTags(a.tags),
+		})
+	}
+
+	return &ec2.DescribeAddressesOutput{Addresses: addresses}, nil
+}
+
+// CreateNatGateway creates a NAT gateway in the pending state.
+func (e *EC2) CreateNatGateway(ctx context.Context, params *ec2.CreateNatGatewayInput, optFns ...func(*ec2.Options)) (*ec2.CreateNatGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateNatGateway"); err != nil {
+		return nil, err
+	}
+
+	s, ok := b.subnets[stringValue(params.SubnetId)]
+	if !ok {
+		return nil, apiError("InvalidSubnetID.NotFound", "the subnet ID '%s' does not exist", stringValue(params.SubnetId))
+	}
+	allocationID := stringValue(params.AllocationId)
+	if _, ok := b.addresses[allocationID]; !ok {
+		return nil, apiError("InvalidAllocationID.NotFound", "the allocation ID '%s' does not exist", allocationID)
+	}
+	for _, n := range b.natGateways {
+		if n.allocationID == allocationID && !n.deleted() {
+			return nil, apiError("Resource.AlreadyAssociated", "elastic IP address '%s' is already associated", allocationID)
+		}
+	}
+
+	n := &natGateway{
+		id:           b.newID("nat"),
+		vpcID:        s.vpcID,
+		subnetID:     s.id,
+		allocationID: allocationID,
+		state:        types.NatGatewayStatePending,
+		polls:        b.Polls,
+		tags:         tagsFor(params.TagSpecifications, types.ResourceTypeNatgateway),
+	}
+	b.natGateways[n.id] = n
+
+	return &ec2.CreateNatGatewayOutput{NatGateway: b.natGatewayType(n)}, nil
+}
+
+// DeleteNatGateway starts the deletion of a NAT gateway.
+func (e *EC2) DeleteNatGateway(ctx context.Context, params *ec2.DeleteNatGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNatGatewayOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteNatGateway"); err != nil {
+		return nil, err
+	}
+
+	n, ok := b.natGateways[stringValue(params.NatGatewayId)]
+	if !ok || n.deleted() {
+		return nil, apiError("NatGatewayNotFound", "NAT gateway %s was not found", stringValue(params.NatGatewayId))
+	}
+	if n.state != types.NatGatewayStateDeleting {
+		n.state = types.NatGatewayStateDeleting
+		n.polls = b.Polls
+	}
+
+	return &ec2.DeleteNatGatewayOutput{NatGatewayId: stringPtr(n.id)}, nil
+}
+
+// DescribeNatGateways returns NAT gateways matching the given IDs and
+// filters.  Each call advances NAT gateways in a transitional state.
+func (e *EC2) DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeNatGateways"); err != nil {
+		return nil, err
+	}
+
+	var natGateways []types.NatGateway
+	for _, id := range sortedKeys(b.natGateways) {
+		n := b.natGateways[id]
+		if len(params.NatGatewayIds) > 0 && !contains(params.NatGatewayIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filter, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{n.vpcID}, true
+			case "subnet-id":
+				return []string{n.subnetID}, true
+			case "state":
+				return []string{string(n.state)}, true
+			case "nat-gateway-id":
+				return []string{n.id}, true
+			}
+			return tagFilterValues(name, n.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		n.advance()
+		natGateways = append(natGateways, *b.natGatewayType(n))
+	}
+
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: natGateways}, nil
+}
+
+// CreateRouteTable creates a route table with a local route for the VPC.
+func (e *EC2) CreateRouteTable(ctx context.Context, params *ec2.CreateRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.CreateRouteTableOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateRouteTable"); err != nil {
+		return nil, err
+	}
+
+	v, ok := b.vpcs[stringValue(params.VpcId)]
+	if !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", stringValue(params.VpcId))
+	}
+	rt := &routeTable{
+		id:     b.newID("rtb"),
+		vpcID:  v.id,
+		routes: v.localRoutes(),
+		tags:   tagsFor(params.TagSpecifications, types.ResourceTypeRouteTable),
+	}
+	b.routeTables[rt.id] = rt
+
+	return &ec2.CreateRouteTableOutput{RouteTable: rt.toType()}, nil
+}
+
+// CreateRoute adds a route to an internet gateway, NAT gateway, egress-only
+// internet gateway, transit gateway or VPC peering connection.
+func (e *EC2) CreateRoute(ctx context.Context, params *ec2.CreateRouteInput, optFns ...func(*ec2.Options)) (*ec2.CreateRouteOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateRoute"); err != nil {
+		return nil, err
+	}
+
+	rt, ok := b.routeTables[stringValue(params.RouteTableId)]
+	if !ok {
+		return nil, apiError("InvalidRouteTableID.NotFound", "the route table ID '%s' does not exist",
+			stringValue(params.RouteTableId))
+	}
+	route := types.Route{
+		Origin: types.RouteOriginCreateRoute,
+		State:  types.RouteStateActive,
+	}
+	destination := routeInputDestination(params.DestinationCidrBlock, params.DestinationIpv6CidrBlock)
+	if destination == "" {
+		return nil, apiError("MissingParameter", "the request must contain a destination")
+	}
+	setRouteDestination(&route, destination)
+	switch {
+	case params.GatewayId != nil:
+		igw, ok := b.internetGateways[*params.GatewayId]
+		if !ok {
+			return nil, apiError("InvalidGatewayID.NotFound", "the gateway ID '%s' does not exist", *params.GatewayId)
+		}
+		if igw.vpcID != rt.vpcID {
+			return nil, apiError("InvalidParameterValue", "route table %s and network gateway %s belong to different networks",
+				rt.id, igw.id)
+		}
+		route.GatewayId = stringPtr(igw.id)
+	case params.NatGatewayId != nil:
+		n, ok := b.natGateways[*params.NatGatewayId]
+		if !ok || n.deleted() {
+			return nil, apiError("InvalidNatGatewayID.NotFound", "the NAT gateway ID '%s' does not exist", *params.NatGatewayId)
+		}
+		route.NatGatewayId = stringPtr(n.id)
+	case params.EgressOnlyInternetGatewayId != nil:
+		eigw, ok := b.egressOnlyInternetGateways[*params.EgressOnlyInternetGatewayId]
+		if !ok || eigw.vpcID != rt.vpcID {
+			return nil, apiError("InvalidGatewayID.NotFound", "the gateway ID '%s' does not exist",
+				*params.EgressOnlyInternetGatewayId)
+		}
+		route.EgressOnlyInternetGatewayId = stringPtr(eigw.id)
+	case params.TransitGatewayId != nil:
+		if err := b.checkTransitGatewayRoute(rt, *params.TransitGatewayId); err != nil {
+			return nil, err
+		}
+		route.TransitGatewayId = stringPtr(*params.TransitGatewayId)
+	case params.VpcPeeringConnectionId != nil:
+		if err := b.checkVPCPeeringConnectionRoute(rt, *params.VpcPeeringConnectionId); err != nil {
+			return nil, err
+		}
+		route.VpcPeeringConnectionId = stringPtr(*params.VpcPeeringConnectionId)
+	default:
+		return nil, apiError("MissingParameter", "the request must contain a route target")
+	}
+	for _, r := range rt.routes {
+		if routeDestination(r) == destination {
+			return nil, apiError("RouteAlreadyExists", "the route identified by %s already exists", destination)
+		}
+	}
+	rt.routes = append(rt.routes, route)
+
+	return &ec2.CreateRouteOutput{Return: boolPtr(true)}, nil
+}
+
+// ReplaceRoute replaces the target of an existing route.
+func (e *EC2) ReplaceRoute(ctx context.Context, params *ec2.ReplaceRouteInput, optFns ...func(*ec2.Options)) (*ec2.ReplaceRouteOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ReplaceRoute"); err != nil {
+		return nil, err
+	}
+
+	rt, ok := b.routeTables[stringValue(params.RouteTableId)]
+	if !ok {
+		return nil, apiError("InvalidRouteTableID.NotFound", "the route table ID '%s' does not exist",
+			stringValue(params.RouteTableId))
+	}
+	destination := routeInputDestination(params.DestinationCidrBlock, params.DestinationIpv6CidrBlock)
+	for i, r := range rt.routes {
+		if routeDestination(r) != destination {
+			continue
+		}
+		route := types.Route{
+			Origin: types.RouteOriginCreateRoute,
+			State:  types.RouteStateActive,
+		}
+		setRouteDestination(&route, destination)
+		switch {
+		case params.GatewayId != nil:
+			igw, ok := b.internetGateways[*params.GatewayId]
+			if !ok || igw.vpcID != rt.vpcID {
+				return nil, apiError("InvalidGatewayID.NotFound", "the gateway ID '%s' does not exist", *params.GatewayId)
+			}
+			route.GatewayId = stringPtr(igw.id)
+		case params.NatGatewayId != nil:
+			n, ok := b.natGateways[*params.NatGatewayId]
+			if !ok || n.deleted() {
+				return nil, apiError("InvalidNatGatewayID.NotFound", "the NAT gateway ID '%s' does not exist", *params.NatGatewayId)
+			}
+			route.NatGatewayId = stringPtr(n.id)
+		case params.EgressOnlyInternetGatewayId != nil:
+			eigw, ok := b.egressOnlyInternetGateways[*params.EgressOnlyInternetGatewayId]
+			if !ok || eigw.vpcID != rt.vpcID {
+				return nil, apiError("InvalidGatewayID.NotFound", "the gateway ID '%s' does not exist",
+					*params.EgressOnlyInternetGatewayId)
+			}
+			route.EgressOnlyInternetGatewayId = stringPtr(eigw.id)
+		case params.TransitGatewayId != nil:
+			if err := b.checkTransitGatewayRoute(rt, *params.TransitGatewayId); err != nil {
+				return nil, err
+			}
+			route.TransitGatewayId = stringPtr(*params.TransitGatewayId)
+		case params.VpcPeeringConnectionId != nil:
+			if err := b.checkVPCPeeringConnectionRoute(rt, *params.VpcPeeringConnectionId); err != nil {
+				return nil, err
+			}
+			route.VpcPeeringConnectionId = stringPtr(*params.VpcPeeringConnectionId)
+		default:
+			return nil, apiError("MissingParameter", "the request must contain a route target")
+		}
+		rt.routes[i] = route
+
+		return &ec2.ReplaceRouteOutput{}, nil
+	}
+
+	return nil, apiError("InvalidRoute.NotFound", "no route with destination-cidr-block %s in route table %s",
+		destination, rt.id)
+}
+
+// checkTransitGatewayRoute returns an error if a route table can't have a
+// route to a transit gateway because its VPC isn't attached to it.  The caller
+// must hold the backend lock.
+func (b *Backend) checkTransitGatewayRoute(rt *routeTable, transitGatewayID string) error {
+	if _, ok := b.transitGateways[transitGatewayID]; !ok {
+		return apiError("InvalidTransitGatewayID.NotFound", "transit gateway ID '%s' does not exist", transitGatewayID)
+	}
+	for _, a := range b.transitGatewayAttachments {
+		if a.vpcID == rt.vpcID && a.transitGatewayID == transitGatewayID && !a.deleted() {
+			return nil
+		}
+	}
+
+	return apiError("InvalidTransitGatewayID.NotFound", "transit gateway %s is not attached to VPC %s",
+		transitGatewayID, rt.vpcID)
+}
+
+// checkVPCPeeringConnectionRoute returns an error if a route table can't have
+// a route to a VPC peering connection because the connection doesn't exist or
+// doesn't peer the route table's VPC.  The caller must hold the backend lock.
+func (b *Backend) checkVPCPeeringConnectionRoute(rt *routeTable, vpcPeeringConnectionID string) error {
+	p, ok := b.vpcPeeringConnections[vpcPeeringConnectionID]
+	if !ok || !p.usable() || (p.requesterVPCID != rt.vpcID && p.accepterVPCID != rt.vpcID) {
+		return apiError("InvalidVpcPeeringConnectionID.NotFound", "the vpcPeeringConnection ID '%s' does not exist",
+			vpcPeeringConnectionID)
+	}
+
+	return nil
+}
+
+// DeleteRoute removes a route from a route table.
+func (e *EC2) DeleteRoute(ctx context.Context, params *ec2.DeleteRouteInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteRoute"); err != nil {
+		return nil, err
+	}
+
+	rt, ok := b.routeTables[stringValue(params.RouteTableId)]
+	if !ok {
+		return nil, apiError("InvalidRouteTableID.NotFound", "the route table ID '%s' does not exist",
+			stringValue(params.RouteTableId))
+	}
+	destination := routeInputDestination(params.DestinationCidrBlock, params.DestinationIpv6CidrBlock)
+	for i, r := range rt.routes {
+		if routeDestination(r) != destination || r.Origin == types.RouteOriginCreateRouteTable {
+			continue
+		}
+		rt.routes = append(rt.routes[:i], rt.routes[i+1:]...)
+
+		return &ec2.DeleteRouteOutput{}, nil
+	}
+
+	return nil, apiError("InvalidRoute.NotFound", "no route with destination-cidr-block %s in route table %s",
+		destination, rt.id)
+}
+
+// AssociateRouteTable associates a route table with a subnet.
+func (e *EC2) AssociateRouteTable(ctx context.Context, params *ec2.AssociateRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.AssociateRouteTableOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AssociateRouteTable"); err != nil {
+		return nil, err
+	}
+
+	rt, ok := b.routeTables[stringValue(params.RouteTableId)]
+	if !ok {
+		return nil, apiError("InvalidRouteTableID.NotFound", "the route table ID '%s' does not exist",
+			stringValue(params.RouteTableId))
+	}
+	s, ok := b.subnets[stringValue(params.SubnetId)]
+	if !ok {
+		return nil, apiError("InvalidSubnetID.NotFound", "the subnet ID '%s' does not exist", stringValue(params.SubnetId))
+	}
+	if s.vpcID != rt.vpcID {
+		return nil, apiError("InvalidParameterValue", "route table %s and subnet %s belong to different networks", rt.id, s.id)
+	}
+	for _, other := range b.routeTables {
+		for _, a := range other.associations {
+			if stringValue(a.SubnetId) == s.id {
+				return nil, apiError("Resource.AlreadyAssociated", "the specified association for route table %s conflicts with an existing association",
+					rt.id)
+			}
+		}
+	}
+	association := types.RouteTableAssociation{
+		Main:                    boolPtr(false),
+		RouteTableAssociationId: stringPtr(b.newID("rtbassoc")),
+		RouteTableId:            stringPtr(rt.id),
+		SubnetId:                stringPtr(s.id),
+	}
+	rt.associations = append(rt.associations, association)
+
+	return &ec2.AssociateRouteTableOutput{AssociationId: association.RouteTableAssociationId}, nil
+}
+
+// DescribeRouteTables returns route tables matching the given IDs and
+// filters.
+func (e *EC2) DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeRouteTables"); err != nil {
+		return nil, err
+	}
+
+	var routeTables []types.RouteTable
+	for _, id := range sortedKeys(b.routeTables) {
+		rt := b.routeTables[id]
+		if len(params.RouteTableIds) > 0 && !contains(params.RouteTableIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{rt.vpcID}, true
+			case "route-table-id":
+				return []string{rt.id}, true
+			case "association.main":
+				return []string{fmt.Sprintf("%t", rt.main)}, true
+			case "association.subnet-id":
+				var subnetIDs []string
+				for _, a := range rt.associations {
+					subnetIDs = append(subnetIDs, stringValue(a.SubnetId))
+				}
+				return subnetIDs, true
+			}
+			return tagFilterValues(name, rt.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		routeTable := rt.toType()
+		for i := range routeTable.Routes {
+			routeTable.Routes[i].State = b.routeState(rt, routeTable.Routes[i])
+		}
+		routeTables = append(routeTables, *routeTable)
+	}
+
+	return &ec2.DescribeRouteTablesOutput{RouteTables: routeTables}, nil
+}
+
+// DisassociateRouteTable removes an association between a route table and a
+// subnet.
+func (e *EC2) DisassociateRouteTable(ctx context.Context, params *ec2.DisassociateRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateRouteTableOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DisassociateRouteTable"); err != nil {
+		return nil, err
+	}
+
+	associationID := stringValue(params.AssociationId)
+	for _, rt := range b.routeTables {
+		for i, a := range rt.associations {
+			if stringValue(a.RouteTableAssociationId) == associationID {
+				rt.associations = append(rt.associations[:i], rt.associations[i+1:]...)
+				return &ec2.DisassociateRouteTableOutput{}, nil
+			}
+		}
+	}
+
+	return nil, apiError("InvalidAssociationID.NotFound", "the association ID '%s' does not exist", associationID)
+}
+
+// DeleteRouteTable deletes a route table that is not associated with any
+// subnets.
+func (e *EC2) DeleteRouteTable(ctx context.Context, params *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteRouteTable"); err != nil {
+		return nil, err
+	}
+
+	rt, ok := b.routeTables[stringValue(params.RouteTableId)]
+	if !ok {
+		return nil, apiError("InvalidRouteTableID.NotFound", "the route table ID '%s' does not exist",
+			stringValue(params.RouteTableId))
+	}
+	if rt.main || len(rt.associations) > 0 {
+		return nil, apiError("DependencyViolation", "the routeTable '%s' has dependencies and cannot be deleted", rt.id)
+	}
+	delete(b.routeTables, rt.id)
+
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+// DescribeAvailabilityZones returns the backend's availability zones.
+func (e *EC2) DescribeAvailabilityZones(ctx context.Context, params *ec2.DescribeAvailabilityZonesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeAvailabilityZones"); err != nil {
+		return nil, err
+	}
+
+	var availabilityZones []types.AvailabilityZone
+	for _, az := range b.availabilityZones {
+		if len(params.ZoneNames) > 0 && !contains(params.ZoneNames, az.name) {
+			continue
+		}
+		if len(params.ZoneIds) > 0 && !contains(params.ZoneIds, az.id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "region-name":
+				return []string{b.Region}, true
+			case "zone-name":
+				return []string{az.name}, true
+			case "zone-id":
+				return []string{az.id}, true
+			case "zone-type":
+				return []string{az.zoneType}, true
+			case "state":
+				return []string{string(types.AvailabilityZoneStateAvailable)}, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		availabilityZones = append(availabilityZones, types.AvailabilityZone{
+			RegionName: stringPtr(b.Region),
+			State:      types.AvailabilityZoneStateAvailable,
+			ZoneId:     stringPtr(az.id),
+			ZoneName:   stringPtr(az.name),
+			ZoneType:   stringPtr(az.zoneType),
+		})
+	}
+
+	return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: availabilityZones}, nil
+}
+
+// DescribeInstanceTypeOfferings returns an offering in each of the backend's
+// zones for every instance type matching the instance-type filter, except
+// those removed with RemoveInstanceTypeOffering.  Only the
+// availability-zone-id location type is supported.
+func (e *EC2) DescribeInstanceTypeOfferings(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeInstanceTypeOfferings"); err != nil {
+		return nil, err
+	}
+	if params.LocationType != types.LocationTypeAvailabilityZoneId {
+		return nil, apiError("InvalidParameterValue", "unsupported location type %q", params.LocationType)
+	}
+
+	var instanceTypes []string
+	for _, filter := range params.Filters {
+		if stringValue(filter.Name) != "instance-type" {
+			return nil, apiError("InvalidParameterValue", "unsupported filter %q", stringValue(filter.Name))
+		}
+		instanceTypes = append(instanceTypes, filter.Values...)
+	}
+
+	var offerings []types.InstanceTypeOffering
+	for _, az := range b.availabilityZones {
+		for _, instanceType := range instanceTypes {
+			if contains(b.unofferedInstanceTypes[az.id], instanceType) {
+				continue
+			}
+			offerings = append(offerings, types.InstanceTypeOffering{
+				InstanceType: types.InstanceType(instanceType),
+				Location:     stringPtr(az.id),
+				LocationType: types.LocationTypeAvailabilityZoneId,
+			})
+		}
+	}
+
+	return &ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: offerings}, nil
+}
+
+// DescribeSecurityGroups returns security groups matching the given IDs and
+// filters.
+func (e *EC2) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeSecurityGroups"); err != nil {
+		return nil, err
+	}
+
+	var securityGroups []types.SecurityGroup
+	for _, id := range sortedKeys(b.securityGroups) {
+		sg := b.securityGroups[id]
+		if len(params.GroupIds) > 0 && !contains(params.GroupIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{sg.vpcID}, true
+			case "group-name":
+				return []string{sg.name}, true
+			case "group-id":
+				return []string{sg.id}, true
+			}
+			return tagFilterValues(name, sg.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		securityGroups = append(securityGroups, types.SecurityGroup{
+			GroupId:       stringPtr(sg.id),
+			GroupName:     stringPtr(sg.name),
+			VpcId:         stringPtr(sg.vpcID),
+			IpPermissions: append([]types.IpPermission{}, sg.ingress...),
+			Tags:          copyTags(sg.tags),
+		})
+	}
+
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: securityGroups}, nil
+}
+
+// CreateSecurityGroup creates a security group in a VPC.  Group names must be
+// unique within the VPC.
+func (e *EC2) CreateSecurityGroup(ctx context.Context, params *ec2.CreateSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateSecurityGroup"); err != nil {
+		return nil, err
+	}
+
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	name := stringValue(params.GroupName)
+	for _, sg := range b.securityGroups {
+		if sg.vpcID == vpcID && sg.name == name {
+			return nil, apiError("InvalidGroup.Duplicate", "the security group '%s' already exists for VPC '%s'", name, vpcID)
+		}
+	}
+
+	sg := &securityGroup{
+		id:    b.newID("sg"),
+		name:  name,
+		vpcID: vpcID,
+		tags:  tagsFor(params.TagSpecifications, types.ResourceTypeSecurityGroup),
+	}
+	b.securityGroups[sg.id] = sg
+
+	return &ec2.CreateSecurityGroupOutput{GroupId: stringPtr(sg.id), Tags: copyTags(sg.tags)}, nil
+}
+
+// AuthorizeSecurityGroupIngress adds ingress rules to a security group.
+func (e *EC2) AuthorizeSecurityGroupIngress(ctx context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AuthorizeSecurityGroupIngress"); err != nil {
+		return nil, err
+	}
+
+	sg, ok := b.securityGroups[stringValue(params.GroupId)]
+	if !ok {
+		return nil, apiError("InvalidGroup.NotFound", "the security group '%s' does not exist", stringValue(params.GroupId))
+	}
+	sg.ingress = append(sg.ingress, params.IpPermissions...)
+
+	return &ec2.AuthorizeSecurityGroupIngressOutput{Return: boolPtr(true)}, nil
+}
+
+// DeleteSecurityGroup deletes a security group that is not used by a VPC
+// endpoint.  Default security groups can't be deleted.
+func (e *EC2) DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteSecurityGroup"); err != nil {
+		return nil, err
+	}
+
+	sg, ok := b.securityGroups[stringValue(params.GroupId)]
+	if !ok {
+		return nil, apiError("InvalidGroup.NotFound", "the security group '%s' does not exist", stringValue(params.GroupId))
+	}
+	if sg.name == "default" {
+		return nil, apiError("CannotDelete", "the security group '%s' cannot be deleted by a user", sg.id)
+	}
+	for _, v := range b.vpcEndpoints {
+		if contains(v.securityGroupIDs, sg.id) && !v.deleted() {
+			return nil, dependencyViolation(sg.id)
+		}
+	}
+	delete(b.securityGroups, sg.id)
+
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+// CreateVpcEndpoint creates a VPC endpoint in the pending state.  A gateway
+// endpoint adds a route to the service's prefix list to each of its route
+// tables.
+func (e *EC2) CreateVpcEndpoint(ctx context.Context, params *ec2.CreateVpcEndpointInput, optFns ...func(*ec2.Options)) (*ec2.CreateVpcEndpointOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateVpcEndpoint"); err != nil {
+		return nil, err
+	}
+
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcId.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	serviceName := stringValue(params.ServiceName)
+	if !strings.HasPrefix(serviceName, fmt.Sprintf("com.amazonaws.%s.", b.Region)) {
+		return nil, apiError("InvalidServiceName", "the Vpc Endpoint Service '%s' does not exist", serviceName)
+	}
+	for _, routeTableID := range params.RouteTableIds {
+		if rt, ok := b.routeTables[routeTableID]; !ok || rt.vpcID != vpcID {
+			return nil, apiError("InvalidRouteTableId.NotFound", "the routeTable ID '%s' does not exist", routeTableID)
+		}
+	}
+	for _, subnetID := range params.SubnetIds {
+		if s, ok := b.subnets[subnetID]; !ok || s.vpcID != vpcID {
+			return nil, apiError("InvalidSubnet.NotFound", "the subnet ID '%s' does not exist", subnetID)
+		}
+	}
+	for _, securityGroupID := range params.SecurityGroupIds {
+		if sg, ok := b.securityGroups[securityGroupID]; !ok || sg.vpcID != vpcID {
+			return nil, apiError("InvalidSecurityGroupId.NotFound", "the security group '%s' does not exist", securityGroupID)
+		}
+	}
+
+	endpointType := params.VpcEndpointType
+	if endpointType == "" {
+		endpointType = types.VpcEndpointTypeGateway
+	}
+	v := &vpcEndpoint{
+		id:               b.newID("vpce"),
+		vpcID:            vpcID,
+		serviceName:      serviceName,
+		endpointType:     endpointType,
+		routeTableIDs:    append([]string{}, params.RouteTableIds...),
+		subnetIDs:        append([]string{}, params.SubnetIds...),
+		securityGroupIDs: append([]string{}, params.SecurityGroupIds...),
+		privateDNS:       params.PrivateDnsEnabled != nil && *params.PrivateDnsEnabled,
+		state:            vpcEndpointStatePending,
+		polls:            b.Polls,
+		tags:             tagsFor(params.TagSpecifications, types.ResourceTypeVpcEndpoint),
+	}
+	if endpointType == types.VpcEndpointTypeInterface && len(v.securityGroupIDs) == 0 {
+		for _, sg := range b.securityGroups {
+			if sg.vpcID == vpcID && sg.name == "default" {
+				v.securityGroupIDs = []string{sg.id}
+			}
+		}
+	}
+	for _, routeTableID := range v.routeTableIDs {
+		rt := b.routeTables[routeTableID]
+		rt.routes = append(rt.routes, types.Route{
+			DestinationPrefixListId: stringPtr("pl-" + strings.TrimPrefix(v.id, "vpce-")),
+			GatewayId:               stringPtr(v.id),
+			Origin:                  types.RouteOriginCreateRoute,
+			State:                   types.RouteStateActive,
+		})
+	}
+	b.vpcEndpoints[v.id] = v
+
+	return &ec2.CreateVpcEndpointOutput{VpcEndpoint: v.toType()}, nil
+}
+
+// DeleteVpcEndpoints starts the deletion of VPC endpoints and removes the
+// routes of gateway endpoints.  VPC endpoints that can't be deleted are
+// reported as unsuccessful items rather than as an error.
+func (e *EC2) DeleteVpcEndpoints(ctx context.Context, params *ec2.DeleteVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcEndpointsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteVpcEndpoints"); err != nil {
+		return nil, err
+	}
+
+	var unsuccessful []types.UnsuccessfulItem
+	for _, id := range params.VpcEndpointIds {
+		v, ok := b.vpcEndpoints[id]
+		if !ok || v.deleted() {
+			unsuccessful = append(unsuccessful, types.UnsuccessfulItem{
+				ResourceId: stringPtr(id),
+				Error: &types.UnsuccessfulItemError{
+					Code:    stringPtr("InvalidVpcEndpoint.NotFound"),
+					Message: stringPtr(fmt.Sprintf("the Vpc Endpoint Id '%s' does not exist", id)),
+				},
+			})
+			continue
+		}
+		if v.state != vpcEndpointStateDeleting {
+			v.state = vpcEndpointStateDeleting
+			v.polls = b.Polls
+		}
+		for _, routeTableID := range v.routeTableIDs {
+			rt, ok := b.routeTables[routeTableID]
+			if !ok {
+				continue
+			}
+			var routes []types.Route
+			for _, route := range rt.routes {
+				if stringValue(route.GatewayId) != v.id {
+					routes = append(routes, route)
+				}
+			}
+			rt.routes = routes
+		}
+	}
+
+	return &ec2.DeleteVpcEndpointsOutput{Unsuccessful: unsuccessful}, nil
+}
+
+// DescribeVpcEndpoints returns VPC endpoints matching the given IDs and
+// filters.  Each call advances VPC endpoints in a transitional state.
+func (e *EC2) DescribeVpcEndpoints(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeVpcEndpoints"); err != nil {
+		return nil, err
+	}
+
+	var vpcEndpoints []types.VpcEndpoint
+	for _, id := range sortedKeys(b.vpcEndpoints) {
+		v := b.vpcEndpoints[id]
+		if len(params.VpcEndpointIds) > 0 && !contains(params.VpcEndpointIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{v.vpcID}, true
+			case "vpc-endpoint-id":
+				return []string{v.id}, true
+			case "vpc-endpoint-state":
+				return []string{string(v.state)}, true
+			case "vpc-endpoint-type":
+				return []string{string(v.endpointType)}, true
+			case "service-name":
+				return []string{v.serviceName}, true
+			}
+			return tagFilterValues(name, v.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		v.advance()
+		vpcEndpoints = append(vpcEndpoints, *v.toType())
+	}
+
+	return &ec2.DescribeVpcEndpointsOutput{VpcEndpoints: vpcEndpoints}, nil
+}
+
+// CreateFlowLogs creates a flow log for each VPC.  VPCs that don't exist are
+// reported as unsuccessful items rather than as an error.
+func (e *EC2) CreateFlowLogs(ctx context.Context, params *ec2.CreateFlowLogsInput, optFns ...func(*ec2.Options)) (*ec2.CreateFlowLogsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateFlowLogs"); err != nil {
+		return nil, err
+	}
+
+	if params.ResourceType != types.FlowLogsResourceTypeVpc {
+		return nil, apiError("InvalidParameterValue", "unsupported resource type '%s'", params.ResourceType)
+	}
+	switch params.TrafficType {
+	case types.TrafficTypeAccept, types.TrafficTypeReject, types.TrafficTypeAll:
+	default:
+		return nil, apiError("InvalidParameterValue", "invalid traffic type '%s'", params.TrafficType)
+	}
+	destinationType := params.LogDestinationType
+	if destinationType == "" {
+		destinationType = types.LogDestinationTypeCloudWatchLogs
+	}
+	switch destinationType {
+	case types.LogDestinationTypeCloudWatchLogs:
+		if stringValue(params.LogGroupName) == "" && stringValue(params.LogDestination) == "" {
+			return nil, apiError("InvalidParameter", "a log group name or log destination is required")
+		}
+		if stringValue(params.DeliverLogsPermissionArn) == "" {
+			return nil, apiError("InvalidParameter", "DeliverLogsPermissionArn is required for CloudWatch Logs")
+		}
+	case types.LogDestinationTypeS3:
+		if !strings.HasPrefix(stringValue(params.LogDestination), "arn:aws:s3:::") {
+			return nil, apiError("InvalidParameter", "LogDestination must be an S3 bucket ARN")
+		}
+		if stringValue(params.LogGroupName) != "" || stringValue(params.DeliverLogsPermissionArn) != "" {
+			return nil, apiError("InvalidParameter", "log group name and DeliverLogsPermissionArn cannot be used with S3")
+		}
+	default:
+		return nil, apiError("InvalidParameterValue", "unsupported log destination type '%s'", destinationType)
+	}
+
+	var flowLogIDs []string
+	var unsuccessful []types.UnsuccessfulItem
+	for _, vpcID := range params.ResourceIds {
+		if _, ok := b.vpcs[vpcID]; !ok {
+			unsuccessful = append(unsuccessful, types.UnsuccessfulItem{
+				ResourceId: stringPtr(vpcID),
+				Error: &types.UnsuccessfulItemError{
+					Code:    stringPtr("InvalidVpcId.NotFound"),
+					Message: stringPtr(fmt.Sprintf("the vpc ID '%s' does not exist", vpcID)),
+				},
+			})
+			continue
+		}
+		f := &flowLog{
+			id:              b.newID("fl"),
+			resourceID:      vpcID,
+			trafficType:     params.TrafficType,
+			destinationType: destinationType,
+			destination:     stringValue(params.LogDestination),
+			logGroupName:    stringValue(params.LogGroupName),
+			deliveryRoleARN: stringValue(params.DeliverLogsPermissionArn),
+			tags:            tagsFor(params.TagSpecifications, types.ResourceTypeVpcFlowLog),
+		}
+		b.flowLogs[f.id] = f
+		flowLogIDs = append(flowLogIDs, f.id)
+	}
+
+	return &ec2.CreateFlowLogsOutput{FlowLogIds: flowLogIDs, Unsuccessful: unsuccessful}, nil
+}
+
+// DeleteFlowLogs deletes flow logs.  Flow logs that don't exist are reported
+// as unsuccessful items rather than as an error.
+func (e *EC2) DeleteFlowLogs(ctx context.Context, params *ec2.DeleteFlowLogsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteFlowLogsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteFlowLogs"); err != nil {
+		return nil, err
+	}
+
+	var unsuccessful []types.UnsuccessfulItem
+	for _, id := range params.FlowLogIds {
+		if _, ok := b.flowLogs[id]; !ok {
+			unsuccessful = append(unsuccessful, types.UnsuccessfulItem{
+				ResourceId: stringPtr(id),
+				Error: &types.UnsuccessfulItemError{
+					Code:    stringPtr("InvalidFlowLogId.NotFound"),
+					Message: stringPtr(fmt.Sprintf("flow log '%s' does not exist", id)),
+				},
+			})
+			continue
+		}
+		delete(b.flowLogs, id)
+	}
+
+	return &ec2.DeleteFlowLogsOutput{Unsuccessful: unsuccessful}, nil
+}
+
+// DescribeFlowLogs returns flow logs matching the given IDs and filters.
+func (e *EC2) DescribeFlowLogs(ctx context.Context, params *ec2.DescribeFlowLogsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeFlowLogsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeFlowLogs"); err != nil {
+		return nil, err
+	}
+
+	var flowLogs []types.FlowLog
+	for _, id := range sortedKeys(b.flowLogs) {
+		f := b.flowLogs[id]
+		if len(params.FlowLogIds) > 0 && !contains(params.FlowLogIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filter, func(name string) ([]string, bool) {
+			switch name {
+			case "flow-log-id":
+				return []string{f.id}, true
+			case "resource-id":
+				return []string{f.resourceID}, true
+			case "traffic-type":
+				return []string{string(f.trafficType)}, true
+			case "log-destination-type":
+				return []string{string(f.destinationType)}, true
+			case "log-group-name":
+				return []string{f.logGroupName}, true
+			}
+			return tagFilterValues(name, f.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		flowLogs = append(flowLogs, *f.toType())
+	}
+
+	return &ec2.DescribeFlowLogsOutput{FlowLogs: flowLogs}, nil
+}
+
+// CreateTransitGatewayVpcAttachment attaches a VPC to a transit gateway added
+// with AddTransitGateway.  The attachment becomes available once it has been
+// described a number of times.
+func (e *EC2) CreateTransitGatewayVpcAttachment(ctx context.Context, params *ec2.CreateTransitGatewayVpcAttachmentInput, optFns ...func(*ec2.Options)) (*ec2.CreateTransitGatewayVpcAttachmentOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateTransitGatewayVpcAttachment"); err != nil {
+		return nil, err
+	}
+
+	transitGatewayID := stringValue(params.TransitGatewayId)
+	if _, ok := b.transitGateways[transitGatewayID]; !ok {
+		return nil, apiError("InvalidTransitGatewayID.NotFound", "transit gateway ID '%s' does not exist", transitGatewayID)
+	}
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	if len(params.SubnetIds) == 0 {
+		return nil, apiError("MissingParameter", "the request must contain the parameter SubnetIds")
+	}
+	zones := make(map[string]bool)
+	for _, subnetID := range params.SubnetIds {
+		s, ok := b.subnets[subnetID]
+		if !ok || s.vpcID != vpcID {
+			return nil, apiError("InvalidSubnetID.NotFound", "the subnet ID '%s' does not exist", subnetID)
+		}
+		if zones[s.zone.name] {
+			return nil, apiError("DuplicateSubnetsInSameZone", "duplicate subnets for same AZ")
+		}
+		zones[s.zone.name] = true
+	}
+	for _, a := range b.transitGatewayAttachments {
+		if a.vpcID == vpcID && a.transitGatewayID == transitGatewayID && !a.deleted() {
+			return nil, apiError("DuplicateTransitGatewayAttachment",
+				"%s has non-deleted transit gateway attachment %s with VPC %s", transitGatewayID, a.id, vpcID)
+		}
+	}
+
+	a := &transitGatewayAttachment{
+		id:               b.newID("tgw-attach"),
+		transitGatewayID: transitGatewayID,
+		vpcID:            vpcID,
+		subnetIDs:        append([]string{}, params.SubnetIds...),
+		state:            types.TransitGatewayAttachmentStatePending,
+		polls:            b.Polls,
+		tags:             tagsFor(params.TagSpecifications, types.ResourceTypeTransitGatewayAttachment),
+	}
+	b.transitGatewayAttachments[a.id] = a
+
+	return &ec2.CreateTransitGatewayVpcAttachmentOutput{TransitGatewayVpcAttachment: b.transitGatewayAttachmentType(a)}, nil
+}
+
+// DeleteTransitGatewayVpcAttachment starts the deletion of a transit gateway
+// attachment.
+func (e *EC2) DeleteTransitGatewayVpcAttachment(ctx context.Context, params *ec2.DeleteTransitGatewayVpcAttachmentInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTransitGatewayVpcAttachmentOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteTransitGatewayVpcAttachment"); err != nil {
+		return nil, err
+	}
+
+	id := stringValue(params.TransitGatewayAttachmentId)
+	a, ok := b.transitGatewayAttachments[id]
+	if !ok || a.deleted() {
+		return nil, apiError("InvalidTransitGatewayAttachmentID.NotFound", "transit gateway attachment ID '%s' does not exist", id)
+	}
+	if a.state != types.TransitGatewayAttachmentStateDeleting {
+		a.state = types.TransitGatewayAttachmentStateDeleting
+		a.polls = b.Polls
+	}
+
+	return &ec2.DeleteTransitGatewayVpcAttachmentOutput{TransitGatewayVpcAttachment: b.transitGatewayAttachmentType(a)}, nil
+}
+
+// DescribeTransitGatewayVpcAttachments returns transit gateway attachments
+// matching the given IDs and filters.  Each call advances attachments in a
+// transitional state.
+func (e *EC2) DescribeTransitGatewayVpcAttachments(ctx context.Context, params *ec2.DescribeTransitGatewayVpcAttachmentsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayVpcAttachmentsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeTransitGatewayVpcAttachments"); err != nil {
+		return nil, err
+	}
+
+	for _, id := range params.TransitGatewayAttachmentIds {
+		if _, ok := b.transitGatewayAttachments[id]; !ok {
+			return nil, apiError("InvalidTransitGatewayAttachmentID.NotFound", "transit gateway attachment ID '%s' does not exist", id)
+		}
+	}
+	var attachments []types.TransitGatewayVpcAttachment
+	for _, id := range sortedKeys(b.transitGatewayAttachments) {
+		a := b.transitGatewayAttachments[id]
+		if len(params.TransitGatewayAttachmentIds) > 0 && !contains(params.TransitGatewayAttachmentIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{a.vpcID}, true
+			case "transit-gateway-id":
+				return []string{a.transitGatewayID}, true
+			case "transit-gateway-attachment-id":
+				return []string{a.id}, true
+			case "state":
+				return []string{string(a.state)}, true
+			}
+			return tagFilterValues(name, a.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		a.advance()
+		attachments = append(attachments, *b.transitGatewayAttachmentType(a))
+	}
+
+	return &ec2.DescribeTransitGatewayVpcAttachmentsOutput{TransitGatewayVpcAttachments: attachments}, nil
+}
+
+// CreateVpcPeeringConnection requests a peering connection from a VPC to a
+// peer VPC.  The request reaches the peer VPC once it has been described a
+// number of times.  A request to a VPC in the backend's account and region
+// fails if the VPC doesn't exist or its CIDR block overlaps the requester's,
+// while one to another account or region waits for acceptance forever.
+func (e *EC2) CreateVpcPeeringConnection(ctx context.Context, params *ec2.CreateVpcPeeringConnectionInput, optFns ...func(*ec2.Options)) (*ec2.CreateVpcPeeringConnectionOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateVpcPeeringConnection"); err != nil {
+		return nil, err
+	}
+
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	if params.PeerVpcId == nil {
+		return nil, apiError("MissingParameter", "the request must contain the parameter PeerVpcId")
+	}
+	p := &vpcPeeringConnection{
+		id:             b.newID("pcx"),
+		requesterVPCID: vpcID,
+		accepterVPCID:  *params.PeerVpcId,
+		accepterOwner:  b.AccountID,
+		accepterRegion: b.Region,
+		state:          types.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+		polls:          b.Polls,
+		tags:           tagsFor(params.TagSpecifications, types.ResourceTypeVpcPeeringConnection),
+	}
+	if params.PeerOwnerId != nil {
+		p.accepterOwner = *params.PeerOwnerId
+	}
+	if params.PeerRegion != nil {
+		p.accepterRegion = *params.PeerRegion
+	}
+	b.vpcPeeringConnections[p.id] = p
+
+	return &ec2.CreateVpcPeeringConnectionOutput{VpcPeeringConnection: b.vpcPeeringConnectionType(p)}, nil
+}
+
+// AcceptVpcPeeringConnection accepts a VPC peering connection request to a VPC
+// in the backend's account and region.  The connection becomes active once it
+// has been described a number of times.
+func (e *EC2) AcceptVpcPeeringConnection(ctx context.Context, params *ec2.AcceptVpcPeeringConnectionInput, optFns ...func(*ec2.Options)) (*ec2.AcceptVpcPeeringConnectionOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AcceptVpcPeeringConnection"); err != nil {
+		return nil, err
+	}
+
+	id := stringValue(params.VpcPeeringConnectionId)
+	p, ok := b.vpcPeeringConnections[id]
+	if !ok {
+		return nil, apiError("InvalidVpcPeeringConnectionID.NotFound", "the vpcPeeringConnection ID '%s' does not exist", id)
+	}
+	if p.accepterOwner != b.AccountID || p.accepterRegion != b.Region {
+		return nil, apiError("OperationNotPermitted", "the vpcPeeringConnection %s must be accepted by its accepter", id)
+	}
+	if p.state != types.VpcPeeringConnectionStateReasonCodePendingAcceptance {
+		return nil, apiError("InvalidStateTransition", "the vpcPeeringConnection %s is in state %s", id, p.state)
+	}
+	p.state = types.VpcPeeringConnectionStateReasonCodeProvisioning
+	p.polls = b.Polls
+
+	return &ec2.AcceptVpcPeeringConnectionOutput{VpcPeeringConnection: b.vpcPeeringConnectionType(p)}, nil
+}
+
+// DeleteVpcPeeringConnection deletes a VPC peering connection.
+func (e *EC2) DeleteVpcPeeringConnection(ctx context.Context, params *ec2.DeleteVpcPeeringConnectionInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcPeeringConnectionOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteVpcPeeringConnection"); err != nil {
+		return nil, err
+	}
+
+	id := stringValue(params.VpcPeeringConnectionId)
+	p, ok := b.vpcPeeringConnections[id]
+	if !ok || !p.usable() {
+		return nil, apiError("InvalidVpcPeeringConnectionID.NotFound", "the vpcPeeringConnection ID '%s' does not exist", id)
+	}
+	p.state = types.VpcPeeringConnectionStateReasonCodeDeleted
+
+	return &ec2.DeleteVpcPeeringConnectionOutput{Return: boolPtr(true)}, nil
+}
+
+// DescribeVpcPeeringConnections returns VPC peering connections matching the
+// given IDs and filters.  Each call advances connections in a transitional
+// state.
+func (e *EC2) DescribeVpcPeeringConnections(ctx context.Context, params *ec2.DescribeVpcPeeringConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeVpcPeeringConnections"); err != nil {
+		return nil, err
+	}
+
+	for _, id := range params.VpcPeeringConnectionIds {
+		if _, ok := b.vpcPeeringConnections[id]; !ok {
+			return nil, apiError("InvalidVpcPeeringConnectionID.NotFound", "the vpcPeeringConnection ID '%s' does not exist", id)
+		}
+	}
+	var vpcPeeringConnections []types.VpcPeeringConnection
+	for _, id := range sortedKeys(b.vpcPeeringConnections) {
+		p := b.vpcPeeringConnections[id]
+		if len(params.VpcPeeringConnectionIds) > 0 && !contains(params.VpcPeeringConnectionIds, id) {
+			continue
+		}
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "requester-vpc-info.vpc-id":
+				return []string{p.requesterVPCID}, true
+			case "accepter-vpc-info.vpc-id":
+				return []string{p.accepterVPCID}, true
+			case "vpc-peering-connection-id":
+				return []string{p.id}, true
+			case "status-code":
+				return []string{string(p.state)}, true
+			}
+			return tagFilterValues(name, p.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		b.advanceVPCPeeringConnection(p)
+		vpcPeeringConnections = append(vpcPeeringConnections, *b.vpcPeeringConnectionType(p))
+	}
+
+	return &ec2.DescribeVpcPeeringConnectionsOutput{VpcPeeringConnections: vpcPeeringConnections}, nil
+}
+
+// CreateNetworkAcl creates a network ACL in a VPC with only the rules that
+// deny all traffic.
+func (e *EC2) CreateNetworkAcl(ctx context.Context, params *ec2.CreateNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkAclOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateNetworkAcl"); err != nil {
+		return nil, err
+	}
+
+	vpcID := stringValue(params.VpcId)
+	if _, ok := b.vpcs[vpcID]; !ok {
+		return nil, apiError("InvalidVpcID.NotFound", "the vpc ID '%s' does not exist", vpcID)
+	}
+	n := &networkACL{
+		id:    b.newID("acl"),
+		vpcID: vpcID,
+		entries: []types.NetworkAclEntry{
+			networkACLEntry(32767, false, types.RuleActionDeny),
+			networkACLEntry(32767, true, types.RuleActionDeny),
+		},
+		tags: tagsFor(params.TagSpecifications, types.ResourceTypeNetworkAcl),
+	}
+	b.networkACLs[n.id] = n
+
+	return &ec2.CreateNetworkAclOutput{NetworkAcl: b.networkACLType(n)}, nil
+}
+
+// CreateNetworkAclEntry adds a rule to a network ACL.  TCP and UDP rules must
+// have a port range and ICMP rules an ICMP type and code.
+func (e *EC2) CreateNetworkAclEntry(ctx context.Context, params *ec2.CreateNetworkAclEntryInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkAclEntryOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateNetworkAclEntry"); err != nil {
+		return nil, err
+	}
+
+	entry := types.NetworkAclEntry{
+		RuleNumber:    params.RuleNumber,
+		Egress:        params.Egress,
+		Protocol:      params.Protocol,
+		RuleAction:    params.RuleAction,
+		CidrBlock:     params.CidrBlock,
+		Ipv6CidrBlock: params.Ipv6CidrBlock,
+		PortRange:     params.PortRange,
+		IcmpTypeCode:  params.IcmpTypeCode,
+	}
+	n, index, err := b.findNetworkACLEntry(stringValue(params.NetworkAclId), entry)
+	if err != nil {
+		return nil, err
+	}
+	if index >= 0 {
+		return nil, apiError("NetworkAclEntryAlreadyExists", "the network ACL entry identified by %d already exists",
+			*entry.RuleNumber)
+	}
+	if err := checkNetworkACLEntry(entry); err != nil {
+		return nil, err
+	}
+	n.entries = append(n.entries, entry)
+
+	return &ec2.CreateNetworkAclEntryOutput{}, nil
+}
+
+// ReplaceNetworkAclEntry replaces a rule in a network ACL.
+func (e *EC2) ReplaceNetworkAclEntry(ctx context.Context, params *ec2.ReplaceNetworkAclEntryInput, optFns ...func(*ec2.Options)) (*ec2.ReplaceNetworkAclEntryOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ReplaceNetworkAclEntry"); err != nil {
+		return nil, err
+	}
+
+	entry := types.NetworkAclEntry{
+		RuleNumber:    params.RuleNumber,
+		Egress:        params.Egress,
+		Protocol:      params.Protocol,
+		RuleAction:    params.RuleAction,
+		CidrBlock:     params.CidrBlock,
+		Ipv6CidrBlock: params.Ipv6CidrBlock,
+		PortRange:     params.PortRange,
+		IcmpTypeCode:  params.IcmpTypeCode,
+	}
+	n, index, err := b.findNetworkACLEntry(stringValue(params.NetworkAclId), entry)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, apiError("InvalidNetworkAclEntry.NotFound", "the network ACL entry identified by %d does not exist",
+			*entry.RuleNumber)
+	}
+	if err := checkNetworkACLEntry(entry); err != nil {
+		return nil, err
+	}
+	n.entries[index] = entry
+
+	return &ec2.ReplaceNetworkAclEntryOutput{}, nil
+}
+
+// DeleteNetworkAclEntry deletes a rule from a network ACL.
+func (e *EC2) DeleteNetworkAclEntry(ctx context.Context, params *ec2.DeleteNetworkAclEntryInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclEntryOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteNetworkAclEntry"); err != nil {
+		return nil, err
+	}
+
+	entry := types.NetworkAclEntry{RuleNumber: params.RuleNumber, Egress: params.Egress}
+	n, index, err := b.findNetworkACLEntry(stringValue(params.NetworkAclId), entry)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, apiError("InvalidNetworkAclEntry.NotFound", "the network ACL entry identified by %d does not exist",
+			*entry.RuleNumber)
+	}
+	n.entries = append(n.entries[:index], n.entries[index+1:]...)
+
+	return &ec2.DeleteNetworkAclEntryOutput{}, nil
+}
+
+// ReplaceNetworkAclAssociation associates the subnet of a network ACL
+// association with another network ACL in the same VPC.
+func (e *EC2) ReplaceNetworkAclAssociation(ctx context.Context, params *ec2.ReplaceNetworkAclAssociationInput, optFns ...func(*ec2.Options)) (*ec2.ReplaceNetworkAclAssociationOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ReplaceNetworkAclAssociation"); err != nil {
+		return nil, err
+	}
+
+	networkACLID := stringValue(params.NetworkAclId)
+	n, ok := b.networkACLs[networkACLID]
+	if !ok {
+		return nil, apiError("InvalidNetworkAclID.NotFound", "the network ACL ID '%s' does not exist", networkACLID)
+	}
+	associationID := stringValue(params.AssociationId)
+	for _, id := range sortedKeys(b.subnets) {
+		s := b.subnets[id]
+		if s.networkACLAssociationID != associationID {
+			continue
+		}
+		if s.vpcID != n.vpcID {
+			return nil, apiError("InvalidParameterValue", "network ACL %s and subnet %s belong to different VPCs",
+				networkACLID, s.id)
+		}
+		s.networkACLID = n.id
+		s.networkACLAssociationID = b.newID("aclassoc")
+		return &ec2.ReplaceNetworkAclAssociationOutput{NewAssociationId: stringPtr(s.networkACLAssociationID)}, nil
+	}
+
+	return nil, apiError("InvalidAssociationID.NotFound", "the association ID '%s' does not exist", associationID)
+}
+
+// DeleteNetworkAcl deletes a network ACL that no subnets are associated with.
+// The default network ACL is deleted along with its VPC.
+func (e *EC2) DeleteNetworkAcl(ctx context.Context, params *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteNetworkAcl"); err != nil {
+		return nil, err
+	}
+
+	networkACLID := stringValue(params.NetworkAclId)
+	n, ok := b.networkACLs[networkACLID]
+	if !ok {
+		return nil, apiError("InvalidNetworkAclID.NotFound", "the network ACL ID '%s' does not exist", networkACLID)
+	}
+	if n.isDefault {
+		return nil, apiError("InvalidParameterValue", "cannot delete default network ACL %s", networkACLID)
+	}
+	for _, s := range b.subnets {
+		if s.networkACLID == networkACLID {
+			return nil, dependencyViolation(networkACLID)
+		}
+	}
+	delete(b.networkACLs, networkACLID)
+
+	return &ec2.DeleteNetworkAclOutput{}, nil
+}
+
+// DescribeNetworkAcls returns network ACLs matching the given IDs and
+// filters.
+func (e *EC2) DescribeNetworkAcls(ctx context.Context, params *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeNetworkAcls"); err != nil {
+		return nil, err
+	}
+
+	for _, id := range params.NetworkAclIds {
+		if _, ok := b.networkACLs[id]; !ok {
+			return nil, apiError("InvalidNetworkAclID.NotFound", "the network ACL ID '%s' does not exist", id)
+		}
+	}
+	var networkACLs []types.NetworkAcl
+	for _, id := range sortedKeys(b.networkACLs) {
+		n := b.networkACLs[id]
+		if len(params.NetworkAclIds) > 0 && !contains(params.NetworkAclIds, id) {
+			continue
+		}
+		networkACL := b.networkACLType(n)
+		matched, err := matchFilters(params.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{n.vpcID}, true
+			case "network-acl-id":
+				return []string{n.id}, true
+			case "default":
+				return []string{fmt.Sprintf("%t", n.isDefault)}, true
+			case "association.subnet-id":
+				var subnetIDs []string
+				for _, association := range networkACL.Associations {
+					subnetIDs = append(subnetIDs, stringValue(association.SubnetId))
+				}
+				return subnetIDs, true
+			}
+			return tagFilterValues(name, n.tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		networkACLs = append(networkACLs, *networkACL)
+	}
+
+	return &ec2.DescribeNetworkAclsOutput{NetworkAcls: networkACLs}, nil
+}
+
+// findZone returns the availability zone with the given name or ID.  The
+// caller must hold the backend lock.
+func (b *Backend) findZone(name, id string) (availabilityZone, bool) {
+	for _, az := range b.availabilityZones {
+		if (name != "" && az.name == name) || (id != "" && az.id == id) {
+			return az, true
+		}
+	}
+
+	return availabilityZone{}, false
+}
+
+// natGatewayType returns the SDK representation of a NAT gateway.  The caller
+// must hold the backend lock.
+func (b *Backend) natGatewayType(n *natGateway) *types.NatGateway {
+	var publicIP string
+	if a, ok := b.addresses[n.allocationID]; ok {
+		publicIP = a.publicIP
+	}
+
+	return &types.NatGateway{
+		NatGatewayId:     stringPtr(n.id),
+		SubnetId:         stringPtr(n.subnetID),
+		VpcId:            stringPtr(n.vpcID),
+		State:            n.state,
+		ConnectivityType: types.ConnectivityTypePublic,
+		NatGatewayAddresses: []types.NatGatewayAddress{
+			{
+				AllocationId: stringPtr(n.allocationID),
+				PublicIp:     stringPtr(publicIP),
+			},
+		},
+		Tags: copyTags(n.tags),
+	}
+}
+
+// advanceVPCPeeringConnection moves a VPC peering connection in a transitional
+// state towards its next state each time it is described.  A request to a VPC
+// in another account or region stays pending acceptance.  The caller must hold
+// the backend lock.
+func (b *Backend) advanceVPCPeeringConnection(p *vpcPeeringConnection) {
+	if p.state != types.VpcPeeringConnectionStateReasonCodeInitiatingRequest &&
+		p.state != types.VpcPeeringConnectionStateReasonCodeProvisioning {
+		return
+	}
+	if p.polls > 0 {
+		p.polls--
+		return
+	}
+	if p.state == types.VpcPeeringConnectionStateReasonCodeProvisioning {
+		p.state = types.VpcPeeringConnectionStateReasonCodeActive
+		return
+	}
+	p.state = types.VpcPeeringConnectionStateReasonCodePendingAcceptance
+	if p.accepterOwner == b.AccountID && p.accepterRegion == b.Region {
+		requester, accepter := b.vpcs[p.requesterVPCID], b.vpcs[p.accepterVPCID]
+		if requester == nil || accepter == nil || requester.cidr.Overlaps(accepter.cidr) {
+			p.state = types.VpcPeeringConnectionStateReasonCodeFailed
+		}
+	}
+}
+
+// routeState returns the state of a route, which is a blackhole if it targets
+// a transit gateway the route table's VPC isn't attached to or a VPC peering
+// connection that isn't active.  The caller must hold the backend lock.
+func (b *Backend) routeState(rt *routeTable, route types.Route) types.RouteState {
+	switch {
+	case route.TransitGatewayId != nil:
+		for _, a := range b.transitGatewayAttachments {
+			if a.vpcID == rt.vpcID && a.transitGatewayID == *route.TransitGatewayId &&
+				a.state == types.TransitGatewayAttachmentStateAvailable {
+				return types.RouteStateActive
+			}
+		}
+		return types.RouteStateBlackhole
+	case route.VpcPeeringConnectionId != nil:
+		p, ok := b.vpcPeeringConnections[*route.VpcPeeringConnectionId]
+		if !ok || p.state != types.VpcPeeringConnectionStateReasonCodeActive {
+			return types.RouteStateBlackhole
+		}
+	}
+
+	return route.State
+}
+
+// transitGatewayAttachmentType returns the SDK representation of a transit
+// gateway attachment.  The caller must hold the backend lock.
+func (b *Backend) transitGatewayAttachmentType(a *transitGatewayAttachment) *types.TransitGatewayVpcAttachment {
+	return &types.TransitGatewayVpcAttachment{
+		TransitGatewayAttachmentId: stringPtr(a.id),
+		TransitGatewayId:           stringPtr(a.transitGatewayID),
+		VpcId:                      stringPtr(a.vpcID),
+		VpcOwnerId:                 stringPtr(b.AccountID),
+		SubnetIds:                  append([]string{}, a.subnetIDs...),
+		State:                      a.state,
+		Tags:                       copyTags(a.tags),
+	}
+}
+
+// findNetworkACLEntry returns a network ACL and the index of its entry with
+// the rule number and direction of the given entry, or -1 if it has none.  The
+// caller must hold the backend lock.
+func (b *Backend) findNetworkACLEntry(networkACLID string, entry types.NetworkAclEntry) (*networkACL, int, error) {
+	n, ok := b.networkACLs[networkACLID]
+	if !ok {
+		return nil, -1, apiError("InvalidNetworkAclID.NotFound", "the network ACL ID '%s' does not exist", networkACLID)
+	}
+	if entry.RuleNumber == nil || entry.Egress == nil {
+		return nil, -1, apiError("MissingParameter", "the request must contain the parameters RuleNumber and Egress")
+	}
+	if *entry.RuleNumber < 1 || *entry.RuleNumber > 32766 {
+		return nil, -1, apiError("InvalidParameterValue", "invalid rule number %d", *entry.RuleNumber)
+	}
+	for i, existing := range n.entries {
+		if *existing.RuleNumber == *entry.RuleNumber && *existing.Egress == *entry.Egress {
+			return n, i, nil
+		}
+	}
+
+	return n, -1, nil
+}
+
+// networkACLType returns the SDK representation of a network ACL along with
+// the associations of the subnets associated with it.  The caller must hold
+// the backend lock.
+func (b *Backend) networkACLType(n *networkACL) *types.NetworkAcl {
+	var associations []types.NetworkAclAssociation
+	for _, id := range sortedKeys(b.subnets) {
+		s := b.subnets[id]
+		if s.networkACLID == n.id {
+			associations = append(associations, types.NetworkAclAssociation{
+				NetworkAclAssociationId: stringPtr(s.networkACLAssociationID),
+				NetworkAclId:            stringPtr(n.id),
+				SubnetId:                stringPtr(s.id),
+			})
+		}
+	}
+
+	return &types.NetworkAcl{
+		NetworkAclId: stringPtr(n.id),
+		VpcId:        stringPtr(n.vpcID),
+		OwnerId:      stringPtr(b.AccountID),
+		IsDefault:    boolPtr(n.isDefault),
+		Entries:      append([]types.NetworkAclEntry{}, n.entries...),
+		Associations: associations,
+		Tags:         copyTags(n.tags),
+	}
+}
+
+// vpcPeeringConnectionType returns the SDK representation of a VPC peering
+// connection.  The caller must hold the backend lock.
+func (b *Backend) vpcPeeringConnectionType(p *vpcPeeringConnection) *types.VpcPeeringConnection {
+	requester := types.VpcPeeringConnectionVpcInfo{
+		VpcId:   stringPtr(p.requesterVPCID),
+		OwnerId: stringPtr(b.AccountID),
+		Region:  stringPtr(b.Region),
+	}
+	if v, ok := b.vpcs[p.requesterVPCID]; ok {
+		requester.CidrBlock = stringPtr(v.cidr.String())
+	}
+	accepter := types.VpcPeeringConnectionVpcInfo{
+		VpcId:   stringPtr(p.accepterVPCID),
+		OwnerId: stringPtr(p.accepterOwner),
+		Region:  stringPtr(p.accepterRegion),
+	}
+	if v, ok := b.vpcs[p.accepterVPCID]; ok && p.accepterOwner == b.AccountID && p.accepterRegion == b.Region {
+		accepter.CidrBlock = stringPtr(v.cidr.String())
+	}
+
+	return &types.VpcPeeringConnection{
+		VpcPeeringConnectionId: stringPtr(p.id),
+		RequesterVpcInfo:       &requester,
+		AccepterVpcInfo:        &accepter,
+		Status: &types.VpcPeeringConnectionStateReason{
+			Code:    p.state,
+			Message: stringPtr(string(p.state)),
+		},
+		Tags: copyTags(p.tags),
+	}
+}
+
+// toType returns the SDK representation of a VPC endpoint.
+func (v *vpcEndpoint) toType() *types.VpcEndpoint {
+	var groups []types.SecurityGroupIdentifier
+	for _, securityGroupID := range v.securityGroupIDs {
+		groups = append(groups, types.SecurityGroupIdentifier{GroupId: stringPtr(securityGroupID)})
+	}
+
+	return &types.VpcEndpoint{
+		VpcEndpointId:     stringPtr(v.id),
+		VpcId:             stringPtr(v.vpcID),
+		ServiceName:       stringPtr(v.serviceName),
+		VpcEndpointType:   v.endpointType,
+		RouteTableIds:     append([]string{}, v.routeTableIDs...),
+		SubnetIds:         append([]string{}, v.subnetIDs...),
+		Groups:            groups,
+		PrivateDnsEnabled: boolPtr(v.privateDNS),
+		State:             v.state,
+		Tags:              copyTags(v.tags),
+	}
+}
+
+// toType returns the SDK representation of a flow log.
+func (f *flowLog) toType() *types.FlowLog {
+	flowLog := types.FlowLog{
+		FlowLogId:          stringPtr(f.id),
+		ResourceId:         stringPtr(f.resourceID),
+		TrafficType:        f.trafficType,
+		LogDestinationType: f.destinationType,
+		FlowLogStatus:      stringPtr("ACTIVE"),
+		DeliverLogsStatus:  stringPtr("SUCCESS"),
+		Tags:               copyTags(f.tags),
+	}
+	if f.destination != "" {
+		flowLog.LogDestination = stringPtr(f.destination)
+	}
+	if f.logGroupName != "" {
+		flowLog.LogGroupName = stringPtr(f.logGroupName)
+	}
+	if f.deliveryRoleARN != "" {
+		flowLog.DeliverLogsPermissionArn = stringPtr(f.deliveryRoleARN)
+	}
+
+	return &flowLog
+}
+
+// toType returns the SDK representation of a VPC.
+func (v *vpc) toType() *types.Vpc {
+	vpc := types.Vpc{
+		VpcId:     stringPtr(v.id),
+		CidrBlock: stringPtr(v.cidr.String()),
+		State:     types.VpcStateAvailable,
+		CidrBlockAssociationSet: []types.VpcCidrBlockAssociation{
+			{
+				AssociationId:  stringPtr(strings.Replace(v.id, "vpc-", "vpc-cidr-assoc-", 1)),
+				CidrBlock:      stringPtr(v.cidr.String()),
+				CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated},
+			},
+		},
+		Tags: copyTags(v.tags),
+	}
+	for _, secondaryCIDR := range v.secondaryCIDRs {
+		vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, *secondaryCIDR.toType())
+	}
+	if v.ipv6CIDR.IsValid() {
+		vpc.Ipv6CidrBlockAssociationSet = []types.VpcIpv6CidrBlockAssociation{
+			{
+				AssociationId:      stringPtr(strings.Replace(v.id, "vpc-", "vpc-cidr-assoc-6", 1)),
+				Ipv6CidrBlock:      stringPtr(v.ipv6CIDR.String()),
+				Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated},
+				Ipv6Pool:           stringPtr("Amazon"),
+			},
+		}
+	}
+
+	return &vpc
+}
+
+// localRoutes returns the routes created in every route table in the VPC for
+// traffic within it.
+func (v *vpc) localRoutes() []types.Route {
+	routes := []types.Route{localRoute(v.cidr)}
+	for _, secondaryCIDR := range v.secondaryCIDRs {
+		if secondaryCIDR.associated() {
+			routes = append(routes, localRoute(secondaryCIDR.cidr))
+		}
+	}
+	if v.ipv6CIDR.IsValid() {
+		routes = append(routes, localRoute(v.ipv6CIDR))
+	}
+
+	return routes
+}
+
+// containsSubnetCIDR returns true if a subnet CIDR block is inside the VPC's
+// CIDR block or one of its associated secondary CIDR blocks.
+func (v *vpc) containsSubnetCIDR(cidr netip.Prefix) bool {
+	if cidr.Bits() >= v.cidr.Bits() && v.cidr.Contains(cidr.Addr()) {
+		return true
+	}
+	for _, secondaryCIDR := range v.secondaryCIDRs {
+		if secondaryCIDR.associated() && cidr.Bits() >= secondaryCIDR.cidr.Bits() &&
+			secondaryCIDR.cidr.Contains(cidr.Addr()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toType returns the SDK representation of a secondary CIDR block
+// association.
+func (c *vpcCIDRBlock) toType() *types.VpcCidrBlockAssociation {
+	return &types.VpcCidrBlockAssociation{
+		AssociationId:  stringPtr(c.associationID),
+		CidrBlock:      stringPtr(c.cidr.String()),
+		CidrBlockState: &types.VpcCidrBlockState{State: c.state},
+	}
+}
+
+// toType returns the SDK representation of a subnet.
+func (s *subnet) toType() *types.Subnet {
+	subnet := types.Subnet{
+		SubnetId:            stringPtr(s.id),
+		VpcId:               stringPtr(s.vpcID),
+		AvailabilityZone:    stringPtr(s.zone.name),
+		AvailabilityZoneId:  stringPtr(s.zone.id),
+		CidrBlock:           stringPtr(s.cidr.String()),
+		MapPublicIpOnLaunch: boolPtr(s.mapPublicIP),
+		State:               types.SubnetStateAvailable,
+		Tags:                copyTags(s.tags),
+	}
+	if s.ipv6CIDR.IsValid() {
+		subnet.Ipv6CidrBlockAssociationSet = []types.SubnetIpv6CidrBlockAssociation{
+			{
+				AssociationId:      stringPtr(strings.Replace(s.id, "subnet-", "subnet-cidr-assoc-", 1)),
+				Ipv6CidrBlock:      stringPtr(s.ipv6CIDR.String()),
+				Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated},
+			},
+		}
+	}
+
+	return &subnet
+}
+
+// toType returns the SDK representation of an internet gateway.
+func (igw *internetGateway) toType() *types.InternetGateway {
+	internetGateway := types.InternetGateway{
+		InternetGatewayId: stringPtr(igw.id),
+		Tags:              copyTags(igw.tags),
+	}
+	if igw.vpcID != "" {
+		internetGateway.Attachments = []types.InternetGatewayAttachment{
+			{
+				State: types.AttachmentStatus("available"),
+				VpcId: stringPtr(igw.vpcID),
+			},
+		}
+	}
+
+	return &internetGateway
+}
+
+// toType returns the SDK representation of a route table.
+func (rt *routeTable) toType() *types.RouteTable {
+	return &types.RouteTable{
+		RouteTableId: stringPtr(rt.id),
+		VpcId:        stringPtr(rt.vpcID),
+		Routes:       append([]types.Route{}, rt.routes...),
+		Associations: append([]types.RouteTableAssociation{}, rt.associations...),
+		Tags:         copyTags(rt.tags),
+	}
+}
+
+// toType returns the SDK representation of an egress-only internet gateway.
+func (eigw *egressOnlyInternetGateway) toType() *types.EgressOnlyInternetGateway {
+	return &types.EgressOnlyInternetGateway{
+		EgressOnlyInternetGatewayId: stringPtr(eigw.id),
+		Attachments: []types.InternetGatewayAttachment{
+			{
+				State: types.AttachmentStatusAttached,
+				VpcId: stringPtr(eigw.vpcID),
+			},
+		},
+		Tags: copyTags(eigw.tags),
+	}
+}
+
+// localRoute returns the route created in every route table for traffic
+// within one of the VPC's CIDR blocks.
+func localRoute(cidr netip.Prefix) types.Route {
+	route := types.Route{
+		GatewayId: stringPtr("local"),
+		Origin:    types.RouteOriginCreateRouteTable,
+		State:     types.RouteStateActive,
+	}
+	setRouteDestination(&route, cidr.String())
+
+	return route
+}
+
+// routeInputDestination returns the destination of a route request, which is
+// either an IPv4 or an IPv6 CIDR block.
+func routeInputDestination(cidrBlock, ipv6CidrBlock *string) string {
+	if ipv6CidrBlock != nil {
+		return *ipv6CidrBlock
+	}
+
+	return stringValue(cidrBlock)
+}
+
+// routeDestination returns the IPv4 or IPv6 CIDR block a route is for.
+func routeDestination(route types.Route) string {
+	if route.DestinationIpv6CidrBlock != nil {
+		return *route.DestinationIpv6CidrBlock
+	}
+
+	return stringValue(route.DestinationCidrBlock)
+}
+
+// setRouteDestination sets the IPv4 or IPv6 destination of a route.
+func setRouteDestination(route *types.Route, destination string) {
+	if strings.Contains(destination, ":") {
+		route.DestinationIpv6CidrBlock = stringPtr(destination)
+	} else {
+		route.DestinationCidrBlock = stringPtr(destination)
+	}
+}
+
+// networkACLEntry returns a network ACL entry for all traffic to or from
+// anywhere.
+func networkACLEntry(ruleNumber int32, egress bool, action types.RuleAction) types.NetworkAclEntry {
+	return types.NetworkAclEntry{
+		RuleNumber: &ruleNumber,
+		Egress:     boolPtr(egress),
+		Protocol:   stringPtr("-1"),
+		RuleAction: action,
+		CidrBlock:  stringPtr("0.0.0.0/0"),
+	}
+}
+
+// checkNetworkACLEntry returns the error EC2 returns for a network ACL entry
+// without a protocol, action or a single CIDR block, a TCP or UDP entry
+// without a port range or an ICMP entry without an ICMP type and code.
+func checkNetworkACLEntry(entry types.NetworkAclEntry) error {
+	if entry.Protocol == nil || entry.RuleAction == "" {
+		return apiError("MissingParameter", "the request must contain the parameters Protocol and RuleAction")
+	}
+	if (entry.CidrBlock == nil) == (entry.Ipv6CidrBlock == nil) {
+		return apiError("InvalidParameterCombination", "exactly one of CidrBlock and Ipv6CidrBlock must be specified")
+	}
+	cidr := stringValue(entry.CidrBlock) + stringValue(entry.Ipv6CidrBlock)
+	if _, err := netip.ParsePrefix(cidr); err != nil {
+		return apiError("InvalidParameterValue", "invalid CIDR block %q", cidr)
+	}
+	switch *entry.Protocol {
+	case "6", "17":
+		if entry.PortRange == nil || entry.PortRange.From == nil || entry.PortRange.To == nil {
+			return apiError("InvalidParameterValue", "a port range is required for protocol %s", *entry.Protocol)
+		}
+	case "1", "58":
+		if entry.IcmpTypeCode == nil || entry.IcmpTypeCode.Type == nil || entry.IcmpTypeCode.Code == nil {
+			return apiError("InvalidParameterValue", "an ICMP type and code are required for protocol %s", *entry.Protocol)
+		}
+	}
+
+	return nil
+}
+
+// dependencyViolation returns the error EC2 returns when deleting a resource
+// that other resources depend on.
+func dependencyViolation(id string) error {
+	return apiError("DependencyViolation", "the resource '%s' has dependencies and cannot be deleted", id)
+}
+
+// matchFilters returns true if a resource matches all filters.  The values
+// function returns the resource's values for a filter name, and false if the
+// filter name is not supported.
+func matchFilters(filters []types.Filter, values func(name string) ([]string, bool)) (bool, error) {
+	for _, filter := range filters {
+		name := stringValue(filter.Name)
+		resourceValues, ok := values(name)
+		if !ok {
+			return false, apiError("InvalidParameterValue", "the filter '%s' is invalid", name)
+		}
+		matched := false
+		for _, v := range resourceValues {
+			if contains(filter.Values, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// tagFilterValues returns the values of a resource's tags for "tag:<key>"
+// and "tag-key" filters.
+func tagFilterValues(name string, tags []types.Tag) ([]string, bool) {
+	switch {
+	case strings.HasPrefix(name, "tag:"):
+		key := strings.TrimPrefix(name, "tag:")
+		var values []string
+		for _, tag := range tags {
+			if stringValue(tag.Key) == key {
+				values = append(values, stringValue(tag.Value))
+			}
+		}
+		return values, true
+	case name == "tag-key":
+		var keys []string
+		for _, tag := range tags {
+			keys = append(keys, stringValue(tag.Key))
+		}
+		return keys, true
+	}
+
+	return nil, false
+}
+
+// tagsFor returns a copy of the tags in the tag specification for the given
+// resource type.
+func tagsFor(specs []types.TagSpecification, resourceType types.ResourceType) []types.Tag {
+	for _, spec := range specs {
+		if spec.ResourceType == resourceType {
+			return copyTags(spec.Tags)
+		}
+	}
+
+	return nil
+}
+
+// copyTags returns a deep copy of EC2 tags.
+func copyTags(tags []types.Tag) []types.Tag {
+	var copied []types.Tag
+	for _, tag := range tags {
+		copied = append(copied, types.Tag{
+			Key:   stringPtr(stringValue(tag.Key)),
+			Value: stringPtr(stringValue(tag.Value)),
+		})
+	}
+
+	return copied
+}
+
+// ec2Tags returns EC2 tags for a tag map in key order.
+func ec2Tags(m map[string]string) []types.Tag {
+	var tags []types.Tag
+	for _, k := range sortedKeys(m) {
+		tags = append(tags, types.Tag{
+			Key:   stringPtr(k),
+			Value: stringPtr(m[k]),
+		})
+	}
+
+	return tags
+}
+
+// contains returns true if the slice contains the value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringValue returns the value of a string pointer or an empty string.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+// stringPtr returns a pointer to the string.
+func stringPtr(s string) *string {
+	return &s
+}
+
+// boolPtr returns a pointer to the bool.
+func boolPtr(b bool) *bool {
+	return &b
+}
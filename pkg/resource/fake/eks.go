@@ -0,0 +1,529 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var _ resource.EKSAPI = (*EKS)(nil)
+
+// EKS implements the resource package's EKSAPI against the backend state.
+type EKS struct {
+	b *Backend
+}
+
+type cluster struct {
+	name            string
+	arn             string
+	roleARN         string
+	version         string
+	subnetIDs       []string
+	vpcID           string
+	securityGroupID string
+	issuer          string
+	ipFamily        types.IpFamily
+	status          types.ClusterStatus
+	polls           int
+	tags            map[string]string
+	nodegroups      map[string]*nodegroup
+	addons          map[string]*addon
+}
+
+type nodegroup struct {
+	name          string
+	arn           string
+	nodeRole      string
+	subnetIDs     []string
+	instanceTypes []string
+	scalingConfig *types.NodegroupScalingConfig
+	failure       string
+	status        types.NodegroupStatus
+	polls         int
+	tags          map[string]string
+}
+
+type addon struct {
+	name                  string
+	arn                   string
+	serviceAccountRoleARN string
+	tags                  map[string]string
+}
+
+// CreateCluster creates a cluster in the CREATING status along with the
+// cluster security group EKS manages in the cluster's VPC.
+func (e *EKS) CreateCluster(ctx context.Context, params *eks.CreateClusterInput, optFns ...func(*eks.Options)) (*eks.CreateClusterOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateCluster"); err != nil {
+		return nil, err
+	}
+
+	name := stringValue(params.Name)
+	if _, ok := b.clusters[name]; ok {
+		return nil, &types.ResourceInUseException{Message: stringPtr(fmt.Sprintf("Cluster already exists with name: %s", name))}
+	}
+	if params.ResourcesVpcConfig == nil || len(params.ResourcesVpcConfig.SubnetIds) < 2 {
+		return nil, &types.InvalidParameterException{Message: stringPtr("Subnets specified must be in at least two different AZs")}
+	}
+	var vpcID string
+	for _, subnetID := range params.ResourcesVpcConfig.SubnetIds {
+		s, ok := b.subnets[subnetID]
+		if !ok {
+			return nil, &types.InvalidParameterException{Message: stringPtr(fmt.Sprintf("The subnet ID '%s' does not exist", subnetID))}
+		}
+		vpcID = s.vpcID
+	}
+	if !b.roleExists(stringValue(params.RoleArn)) {
+		return nil, &types.InvalidParameterException{Message: stringPtr(fmt.Sprintf("Role %s does not exist", stringValue(params.RoleArn)))}
+	}
+
+	securityGroup := &securityGroup{
+		id:    b.newID("sg"),
+		name:  fmt.Sprintf("eks-cluster-sg-%s", name),
+		vpcID: vpcID,
+	}
+	securityGroup.tags = ec2Tags(map[string]string{"aws:eks:cluster-name": name})
+	b.securityGroups[securityGroup.id] = securityGroup
+
+	ipFamily := types.IpFamilyIpv4
+	if params.KubernetesNetworkConfig != nil && params.KubernetesNetworkConfig.IpFamily != "" {
+		ipFamily = params.KubernetesNetworkConfig.IpFamily
+	}
+	c := &cluster{
+		name:            name,
+		arn:             fmt.Sprintf("arn:aws:eks:%s:%s:cluster/%s", b.Region, b.AccountID, name),
+		roleARN:         stringValue(params.RoleArn),
+		version:         stringValue(params.Version),
+		subnetIDs:       append([]string{}, params.ResourcesVpcConfig.SubnetIds...),
+		vpcID:           vpcID,
+		securityGroupID: securityGroup.id,
+		ipFamily:        ipFamily,
+		status:          types.ClusterStatusCreating,
+		polls:           b.Polls,
+		tags:            copyMap(params.Tags),
+		nodegroups:      make(map[string]*nodegroup),
+		addons:          make(map[string]*addon),
+	}
+	c.issuer = fmt.Sprintf("https://oidc.eks.%s.amazonaws.com/id/%s", b.Region,
+		strings.ToUpper(strings.TrimPrefix(b.newID("oidc"), "oidc-")))
+	b.clusters[name] = c
+
+	return &eks.CreateClusterOutput{Cluster: c.toType()}, nil
+}
+
+// DeleteCluster starts the deletion of a cluster that has no node groups.
+func (e *EKS) DeleteCluster(ctx context.Context, params *eks.DeleteClusterInput, optFns ...func(*eks.Options)) (*eks.DeleteClusterOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteCluster"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.Name)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.Name))
+	}
+	if len(c.nodegroups) > 0 {
+		return nil, &types.ResourceInUseException{
+			Message: stringPtr(fmt.Sprintf("Cluster has nodegroups attached: %s", strings.Join(sortedKeys(c.nodegroups), ", "))),
+		}
+	}
+	if c.status != types.ClusterStatusDeleting {
+		c.status = types.ClusterStatusDeleting
+		c.polls = b.Polls
+	}
+
+	return &eks.DeleteClusterOutput{Cluster: c.toType()}, nil
+}
+
+// DescribeCluster returns a cluster.  Each call advances a cluster in a
+// transitional status.
+func (e *EKS) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeCluster"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.Name)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.Name))
+	}
+	b.advanceCluster(c)
+	if _, ok := b.clusters[c.name]; !ok {
+		return nil, clusterNotFound(c.name)
+	}
+
+	return &eks.DescribeClusterOutput{Cluster: c.toType()}, nil
+}
+
+// CreateNodegroup creates a node group in the CREATING status.  If node group
+// failures were requested with FailNodegroups, the node group reaches the
+// CREATE_FAILED status instead of ACTIVE.
+func (e *EKS) CreateNodegroup(ctx context.Context, params *eks.CreateNodegroupInput, optFns ...func(*eks.Options)) (*eks.CreateNodegroupOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateNodegroup"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	if c.status != types.ClusterStatusActive {
+		return nil, &types.InvalidRequestException{
+			Message: stringPtr(fmt.Sprintf("Cluster '%s' is not in ACTIVE status", c.name)),
+		}
+	}
+	name := stringValue(params.NodegroupName)
+	if _, ok := c.nodegroups[name]; ok {
+		return nil, &types.ResourceInUseException{Message: stringPtr(fmt.Sprintf("NodeGroup already exists with name %s", name))}
+	}
+	for _, subnetID := range params.Subnets {
+		if _, ok := b.subnets[subnetID]; !ok {
+			return nil, &types.InvalidParameterException{Message: stringPtr(fmt.Sprintf("The subnet ID '%s' does not exist", subnetID))}
+		}
+	}
+	if !b.roleExists(stringValue(params.NodeRole)) {
+		return nil, &types.InvalidParameterException{Message: stringPtr(fmt.Sprintf("Role %s does not exist", stringValue(params.NodeRole)))}
+	}
+
+	ng := &nodegroup{
+		name:          name,
+		arn:           fmt.Sprintf("arn:aws:eks:%s:%s:nodegroup/%s/%s", b.Region, b.AccountID, c.name, name),
+		nodeRole:      stringValue(params.NodeRole),
+		subnetIDs:     append([]string{}, params.Subnets...),
+		instanceTypes: append([]string{}, params.InstanceTypes...),
+		scalingConfig: params.ScalingConfig,
+		failure:       b.nodegroupFailure,
+		status:        types.NodegroupStatusCreating,
+		polls:         b.Polls,
+		tags:          copyMap(params.Tags),
+	}
+	c.nodegroups[name] = ng
+
+	return &eks.CreateNodegroupOutput{Nodegroup: ng.toType(c.name)}, nil
+}
+
+// DeleteNodegroup starts the deletion of a node group.
+func (e *EKS) DeleteNodegroup(ctx context.Context, params *eks.DeleteNodegroupInput, optFns ...func(*eks.Options)) (*eks.DeleteNodegroupOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteNodegroup"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	ng, ok := c.nodegroups[stringValue(params.NodegroupName)]
+	if !ok {
+		return nil, nodegroupNotFound(c.name, stringValue(params.NodegroupName))
+	}
+	if ng.status != types.NodegroupStatusDeleting {
+		ng.status = types.NodegroupStatusDeleting
+		ng.polls = b.Polls
+	}
+
+	return &eks.DeleteNodegroupOutput{Nodegroup: ng.toType(c.name)}, nil
+}
+
+// DescribeNodegroup returns a node group.  Each call advances a node group in
+// a transitional status.
+func (e *EKS) DescribeNodegroup(ctx context.Context, params *eks.DescribeNodegroupInput, optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeNodegroup"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	ng, ok := c.nodegroups[stringValue(params.NodegroupName)]
+	if !ok {
+		return nil, nodegroupNotFound(c.name, stringValue(params.NodegroupName))
+	}
+	ng.advance()
+	if ng.status == types.NodegroupStatusDeleting && ng.polls < 0 {
+		delete(c.nodegroups, ng.name)
+		return nil, nodegroupNotFound(c.name, ng.name)
+	}
+
+	return &eks.DescribeNodegroupOutput{Nodegroup: ng.toType(c.name)}, nil
+}
+
+// ListNodegroups returns the names of the node groups in a cluster.
+func (e *EKS) ListNodegroups(ctx context.Context, params *eks.ListNodegroupsInput, optFns ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListNodegroups"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+
+	return &eks.ListNodegroupsOutput{Nodegroups: sortedKeys(c.nodegroups)}, nil
+}
+
+// CreateAddon installs an addon on an active cluster.  Addons become active
+// immediately.
+func (e *EKS) CreateAddon(ctx context.Context, params *eks.CreateAddonInput, optFns ...func(*eks.Options)) (*eks.CreateAddonOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateAddon"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	name := stringValue(params.AddonName)
+	if _, ok := c.addons[name]; ok {
+		return nil, &types.ResourceInUseException{Message: stringPtr(fmt.Sprintf("Addon already exists with name %s", name))}
+	}
+
+	a := &addon{
+		name:                  name,
+		arn:                   fmt.Sprintf("arn:aws:eks:%s:%s:addon/%s/%s", b.Region, b.AccountID, c.name, name),
+		serviceAccountRoleARN: stringValue(params.ServiceAccountRoleArn),
+		tags:                  copyMap(params.Tags),
+	}
+	c.addons[name] = a
+
+	return &eks.CreateAddonOutput{Addon: a.toType(c.name)}, nil
+}
+
+// DescribeAddon returns an addon installed on a cluster.
+func (e *EKS) DescribeAddon(ctx context.Context, params *eks.DescribeAddonInput, optFns ...func(*eks.Options)) (*eks.DescribeAddonOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeAddon"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	a, ok := c.addons[stringValue(params.AddonName)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: stringPtr(fmt.Sprintf("No addon: %s found in cluster: %s", stringValue(params.AddonName), c.name)),
+		}
+	}
+
+	return &eks.DescribeAddonOutput{Addon: a.toType(c.name)}, nil
+}
+
+// ListAddons returns the names of the addons installed on a cluster.
+func (e *EKS) ListAddons(ctx context.Context, params *eks.ListAddonsInput, optFns ...func(*eks.Options)) (*eks.ListAddonsOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListAddons"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+
+	return &eks.ListAddonsOutput{Addons: sortedKeys(c.addons)}, nil
+}
+
+// DeleteAddon removes an addon from a cluster.  Addons are removed
+// immediately.
+func (e *EKS) DeleteAddon(ctx context.Context, params *eks.DeleteAddonInput, optFns ...func(*eks.Options)) (*eks.DeleteAddonOutput, error) {
+	b := e.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteAddon"); err != nil {
+		return nil, err
+	}
+
+	c, ok := b.clusters[stringValue(params.ClusterName)]
+	if !ok {
+		return nil, clusterNotFound(stringValue(params.ClusterName))
+	}
+	a, ok := c.addons[stringValue(params.AddonName)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: stringPtr(fmt.Sprintf("No addon: %s found in cluster: %s", stringValue(params.AddonName), c.name)),
+		}
+	}
+	delete(c.addons, a.name)
+
+	addon := a.toType(c.name)
+	addon.Status = types.AddonStatusDeleting
+
+	return &eks.DeleteAddonOutput{Addon: addon}, nil
+}
+
+// advanceCluster moves a cluster in a transitional status towards its final
+// status, removing the cluster, its addons and its security group once
+// deletion completes.  The caller must hold the backend lock.
+func (b *Backend) advanceCluster(c *cluster) {
+	if c.status != types.ClusterStatusCreating && c.status != types.ClusterStatusDeleting {
+		return
+	}
+	if c.polls > 0 {
+		c.polls--
+		return
+	}
+	if c.status == types.ClusterStatusCreating {
+		c.status = types.ClusterStatusActive
+		return
+	}
+	delete(b.securityGroups, c.securityGroupID)
+	delete(b.clusters, c.name)
+}
+
+// advance moves a node group in a transitional status towards its final
+// status.  A deleted node group is marked with negative polls for the caller
+// to remove.
+func (ng *nodegroup) advance() {
+	if ng.status != types.NodegroupStatusCreating && ng.status != types.NodegroupStatusDeleting {
+		return
+	}
+	if ng.polls > 0 {
+		ng.polls--
+		return
+	}
+	switch {
+	case ng.status == types.NodegroupStatusDeleting:
+		ng.polls = -1
+	case ng.failure != "":
+		ng.status = types.NodegroupStatusCreateFailed
+	default:
+		ng.status = types.NodegroupStatusActive
+	}
+}
+
+// roleExists returns true if the role ARN refers to a role in the backend.
+// The caller must hold the backend lock.
+func (b *Backend) roleExists(roleARN string) bool {
+	for _, r := range b.roles {
+		if r.arn == roleARN {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toType returns the SDK representation of a cluster.
+func (c *cluster) toType() *types.Cluster {
+	return &types.Cluster{
+		Arn:      stringPtr(c.arn),
+		Endpoint: stringPtr(fmt.Sprintf("https://%s.eks.amazonaws.com", strings.ToLower(c.name))),
+		Identity: &types.Identity{
+			Oidc: &types.OIDC{Issuer: stringPtr(c.issuer)},
+		},
+		KubernetesNetworkConfig: &types.KubernetesNetworkConfigResponse{IpFamily: c.ipFamily},
+		Name:                    stringPtr(c.name),
+		ResourcesVpcConfig: &types.VpcConfigResponse{
+			ClusterSecurityGroupId: stringPtr(c.securityGroupID),
+			EndpointPrivateAccess:  true,
+			EndpointPublicAccess:   true,
+			SubnetIds:              append([]string{}, c.subnetIDs...),
+			VpcId:                  stringPtr(c.vpcID),
+		},
+		RoleArn: stringPtr(c.roleARN),
+		Status:  c.status,
+		Tags:    copyMap(c.tags),
+		Version: stringPtr(c.version),
+	}
+}
+
+// toType returns the SDK representation of a node group.
+func (ng *nodegroup) toType(clusterName string) *types.Nodegroup {
+	nodegroup := types.Nodegroup{
+		ClusterName:   stringPtr(clusterName),
+		Health:        &types.NodegroupHealth{},
+		InstanceTypes: append([]string{}, ng.instanceTypes...),
+		NodeRole:      stringPtr(ng.nodeRole),
+		NodegroupArn:  stringPtr(ng.arn),
+		NodegroupName: stringPtr(ng.name),
+		ScalingConfig: ng.scalingConfig,
+		Status:        ng.status,
+		Subnets:       append([]string{}, ng.subnetIDs...),
+		Tags:          copyMap(ng.tags),
+	}
+	if ng.status == types.NodegroupStatusCreateFailed {
+		nodegroup.Health.Issues = []types.Issue{
+			{
+				Code:        types.NodegroupIssueCode("NodeCreationFailure"),
+				Message:     stringPtr(ng.failure),
+				ResourceIds: append([]string{}, ng.subnetIDs...),
+			},
+		}
+	}
+
+	return &nodegroup
+}
+
+// toType returns the SDK representation of an addon.
+func (a *addon) toType(clusterName string) *types.Addon {
+	return &types.Addon{
+		AddonArn:              stringPtr(a.arn),
+		AddonName:             stringPtr(a.name),
+		ClusterName:           stringPtr(clusterName),
+		ServiceAccountRoleArn: stringPtr(a.serviceAccountRoleARN),
+		Status:                types.AddonStatusActive,
+		Tags:                  copyMap(a.tags),
+	}
+}
+
+// clusterNotFound returns the error EKS returns for a missing cluster.
+func clusterNotFound(name string) error {
+	return &types.ResourceNotFoundException{
+		ClusterName: stringPtr(name),
+		Message:     stringPtr(fmt.Sprintf("No cluster found for name: %s.", name)),
+	}
+}
+
+// nodegroupNotFound returns the error EKS returns for a missing node group.
+func nodegroupNotFound(clusterName, name string) error {
+	return &types.ResourceNotFoundException{
+		ClusterName:   stringPtr(clusterName),
+		NodegroupName: stringPtr(name),
+		Message:       stringPtr(fmt.Sprintf("No node group found for name: %s.", name)),
+	}
+}
+
+// copyMap returns a copy of a tag map.
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+
+	return copied
+}
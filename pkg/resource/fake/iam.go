@@ -0,0 +1,461 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var _ resource.IAMAPI = (*IAM)(nil)
+
+// IAM implements the resource package's IAMAPI against the backend state.
+type IAM struct {
+	b *Backend
+}
+
+type role struct {
+	name                string
+	arn                 string
+	assumeRolePolicy    string
+	permissionsBoundary string
+	attachedPolicyARNs  []string
+	tags                []types.Tag
+}
+
+type policy struct {
+	name     string
+	arn      string
+	document string
+	tags     []types.Tag
+}
+
+// CreateRole creates a role.
+func (i *IAM) CreateRole(ctx context.Context, params *iam.CreateRoleInput, optFns ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateRole"); err != nil {
+		return nil, err
+	}
+
+	name := stringValue(params.RoleName)
+	if _, ok := b.roles[name]; ok {
+		return nil, &types.EntityAlreadyExistsException{
+			Message: stringPtr(fmt.Sprintf("Role with name %s already exists.", name)),
+		}
+	}
+	if stringValue(params.AssumeRolePolicyDocument) == "" {
+		return nil, &types.MalformedPolicyDocumentException{Message: stringPtr("assume role policy document is required")}
+	}
+
+	r := &role{
+		name:                name,
+		arn:                 fmt.Sprintf("arn:aws:iam::%s:role/%s", b.AccountID, name),
+		assumeRolePolicy:    stringValue(params.AssumeRolePolicyDocument),
+		permissionsBoundary: stringValue(params.PermissionsBoundary),
+		tags:                copyIAMTags(params.Tags),
+	}
+	b.roles[name] = r
+
+	return &iam.CreateRoleOutput{Role: r.toType()}, nil
+}
+
+// GetRole returns a role.
+func (i *IAM) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "GetRole"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+
+	return &iam.GetRoleOutput{Role: r.toType()}, nil
+}
+
+// ListRoleTags returns the tags on a role.  All tags are returned in a single
+// page.
+func (i *IAM) ListRoleTags(ctx context.Context, params *iam.ListRoleTagsInput, optFns ...func(*iam.Options)) (*iam.ListRoleTagsOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListRoleTags"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+
+	return &iam.ListRoleTagsOutput{Tags: copyIAMTags(r.tags)}, nil
+}
+
+// DeleteRole deletes a role that has no attached policies.
+func (i *IAM) DeleteRole(ctx context.Context, params *iam.DeleteRoleInput, optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteRole"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+	if len(r.attachedPolicyARNs) > 0 {
+		return nil, &types.DeleteConflictException{
+			Message: stringPtr("Cannot delete entity, must detach all policies first."),
+		}
+	}
+	delete(b.roles, r.name)
+
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+// AttachRolePolicy attaches a managed policy to a role.  AWS managed policies
+// are accepted without being created first.
+func (i *IAM) AttachRolePolicy(ctx context.Context, params *iam.AttachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "AttachRolePolicy"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+	policyARN := stringValue(params.PolicyArn)
+	if _, ok := b.policies[policyARN]; !ok && !awsManagedPolicy(policyARN) {
+		return nil, policyNotFound(policyARN)
+	}
+	if !contains(r.attachedPolicyARNs, policyARN) {
+		r.attachedPolicyARNs = append(r.attachedPolicyARNs, policyARN)
+	}
+
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+// DetachRolePolicy detaches a managed policy from a role.
+func (i *IAM) DetachRolePolicy(ctx context.Context, params *iam.DetachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DetachRolePolicy"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+	policyARN := stringValue(params.PolicyArn)
+	var attached []string
+	for _, arn := range r.attachedPolicyARNs {
+		if arn != policyARN {
+			attached = append(attached, arn)
+		}
+	}
+	if len(attached) == len(r.attachedPolicyARNs) {
+		return nil, &types.NoSuchEntityException{
+			Message: stringPtr(fmt.Sprintf("Policy %s was not found.", policyARN)),
+		}
+	}
+	r.attachedPolicyARNs = attached
+
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+// ListAttachedRolePolicies returns the managed policies attached to a role.
+// All policies are returned in a single page.
+func (i *IAM) ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListAttachedRolePolicies"); err != nil {
+		return nil, err
+	}
+
+	r, ok := b.roles[stringValue(params.RoleName)]
+	if !ok {
+		return nil, roleNotFound(stringValue(params.RoleName))
+	}
+	var attachedPolicies []types.AttachedPolicy
+	for _, policyARN := range r.attachedPolicyARNs {
+		attachedPolicies = append(attachedPolicies, types.AttachedPolicy{
+			PolicyArn:  stringPtr(policyARN),
+			PolicyName: stringPtr(policyARN[strings.LastIndex(policyARN, "/")+1:]),
+		})
+	}
+
+	return &iam.ListAttachedRolePoliciesOutput{AttachedPolicies: attachedPolicies}, nil
+}
+
+// CreatePolicy creates a customer managed policy.
+func (i *IAM) CreatePolicy(ctx context.Context, params *iam.CreatePolicyInput, optFns ...func(*iam.Options)) (*iam.CreatePolicyOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreatePolicy"); err != nil {
+		return nil, err
+	}
+
+	name := stringValue(params.PolicyName)
+	arn := fmt.Sprintf("arn:aws:iam::%s:policy/%s", b.AccountID, name)
+	if _, ok := b.policies[arn]; ok {
+		return nil, &types.EntityAlreadyExistsException{
+			Message: stringPtr(fmt.Sprintf("A policy called %s already exists.", name)),
+		}
+	}
+	if stringValue(params.PolicyDocument) == "" {
+		return nil, &types.MalformedPolicyDocumentException{Message: stringPtr("policy document is required")}
+	}
+
+	p := &policy{
+		name:     name,
+		arn:      arn,
+		document: stringValue(params.PolicyDocument),
+		tags:     copyIAMTags(params.Tags),
+	}
+	b.policies[arn] = p
+
+	return &iam.CreatePolicyOutput{Policy: p.toType()}, nil
+}
+
+// GetPolicy returns a customer managed policy, or a minimal representation of
+// an AWS managed policy.
+func (i *IAM) GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "GetPolicy"); err != nil {
+		return nil, err
+	}
+
+	policyARN := stringValue(params.PolicyArn)
+	if awsManagedPolicy(policyARN) {
+		return &iam.GetPolicyOutput{Policy: &types.Policy{
+			Arn:          stringPtr(policyARN),
+			IsAttachable: true,
+			PolicyName:   stringPtr(policyARN[strings.LastIndex(policyARN, "/")+1:]),
+		}}, nil
+	}
+	p, ok := b.policies[policyARN]
+	if !ok {
+		return nil, policyNotFound(policyARN)
+	}
+
+	return &iam.GetPolicyOutput{Policy: p.toType()}, nil
+}
+
+// ListPolicies returns the customer managed policies.  AWS managed policies
+// are not tracked so they are never returned.  All policies are returned in a
+// single page.
+func (i *IAM) ListPolicies(ctx context.Context, params *iam.ListPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListPoliciesOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListPolicies"); err != nil {
+		return nil, err
+	}
+
+	var policies []types.Policy
+	if params.Scope != types.PolicyScopeTypeAws {
+		for _, arn := range sortedKeys(b.policies) {
+			policies = append(policies, *b.policies[arn].toType())
+		}
+	}
+
+	return &iam.ListPoliciesOutput{Policies: policies}, nil
+}
+
+// ListPolicyTags returns the tags on a customer managed policy.  All tags are
+// returned in a single page.
+func (i *IAM) ListPolicyTags(ctx context.Context, params *iam.ListPolicyTagsInput, optFns ...func(*iam.Options)) (*iam.ListPolicyTagsOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "ListPolicyTags"); err != nil {
+		return nil, err
+	}
+
+	p, ok := b.policies[stringValue(params.PolicyArn)]
+	if !ok {
+		return nil, policyNotFound(stringValue(params.PolicyArn))
+	}
+
+	return &iam.ListPolicyTagsOutput{Tags: copyIAMTags(p.tags)}, nil
+}
+
+// DeletePolicy deletes a policy that is not attached to any role.
+func (i *IAM) DeletePolicy(ctx context.Context, params *iam.DeletePolicyInput, optFns ...func(*iam.Options)) (*iam.DeletePolicyOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeletePolicy"); err != nil {
+		return nil, err
+	}
+
+	policyARN := stringValue(params.PolicyArn)
+	if _, ok := b.policies[policyARN]; !ok {
+		return nil, policyNotFound(policyARN)
+	}
+	for _, r := range b.roles {
+		if contains(r.attachedPolicyARNs, policyARN) {
+			return nil, &types.DeleteConflictException{
+				Message: stringPtr("Cannot delete a policy attached to entities."),
+			}
+		}
+	}
+	delete(b.policies, policyARN)
+
+	return &iam.DeletePolicyOutput{}, nil
+}
+
+// CreateOpenIDConnectProvider creates an OIDC identity provider.
+func (i *IAM) CreateOpenIDConnectProvider(ctx context.Context, params *iam.CreateOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.CreateOpenIDConnectProviderOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateOpenIDConnectProvider"); err != nil {
+		return nil, err
+	}
+
+	url := stringValue(params.Url)
+	if !strings.HasPrefix(url, "https://") {
+		return nil, &types.InvalidInputException{Message: stringPtr(fmt.Sprintf("invalid provider URL %s", url))}
+	}
+	if len(params.ThumbprintList) == 0 {
+		return nil, &types.InvalidInputException{Message: stringPtr("a thumbprint is required")}
+	}
+	arn := fmt.Sprintf("arn:aws:iam::%s:oidc-provider/%s", b.AccountID, strings.TrimPrefix(url, "https://"))
+	if _, ok := b.oidcProviders[arn]; ok {
+		return nil, &types.EntityAlreadyExistsException{
+			Message: stringPtr(fmt.Sprintf("Provider with url %s already exists.", url)),
+		}
+	}
+	b.oidcProviders[arn] = url
+
+	return &iam.CreateOpenIDConnectProviderOutput{
+		OpenIDConnectProviderArn: stringPtr(arn),
+		Tags:                     copyIAMTags(params.Tags),
+	}, nil
+}
+
+// GetOpenIDConnectProvider returns an OIDC identity provider.
+func (i *IAM) GetOpenIDConnectProvider(ctx context.Context, params *iam.GetOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.GetOpenIDConnectProviderOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "GetOpenIDConnectProvider"); err != nil {
+		return nil, err
+	}
+
+	arn := stringValue(params.OpenIDConnectProviderArn)
+	url, ok := b.oidcProviders[arn]
+	if !ok {
+		return nil, &types.NoSuchEntityException{
+			Message: stringPtr(fmt.Sprintf("OpenIDConnect Provider not found for arn %s", arn)),
+		}
+	}
+
+	return &iam.GetOpenIDConnectProviderOutput{
+		ClientIDList: []string{"sts.amazonaws.com"},
+		Url:          stringPtr(strings.TrimPrefix(url, "https://")),
+	}, nil
+}
+
+// DeleteOpenIDConnectProvider deletes an OIDC identity provider.
+func (i *IAM) DeleteOpenIDConnectProvider(ctx context.Context, params *iam.DeleteOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.DeleteOpenIDConnectProviderOutput, error) {
+	b := i.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteOpenIDConnectProvider"); err != nil {
+		return nil, err
+	}
+
+	arn := stringValue(params.OpenIDConnectProviderArn)
+	if _, ok := b.oidcProviders[arn]; !ok {
+		return nil, &types.NoSuchEntityException{
+			Message: stringPtr(fmt.Sprintf("OpenIDConnect Provider not found for arn %s", arn)),
+		}
+	}
+	delete(b.oidcProviders, arn)
+
+	return &iam.DeleteOpenIDConnectProviderOutput{}, nil
+}
+
+// toType returns the SDK representation of a role.
+func (r *role) toType() *types.Role {
+	iamRole := types.Role{
+		Arn:                      stringPtr(r.arn),
+		AssumeRolePolicyDocument: stringPtr(r.assumeRolePolicy),
+		Path:                     stringPtr("/"),
+		RoleId:                   stringPtr(strings.ToUpper(strings.ReplaceAll(r.name, "-", ""))),
+		RoleName:                 stringPtr(r.name),
+		Tags:                     copyIAMTags(r.tags),
+	}
+	if r.permissionsBoundary != "" {
+		iamRole.PermissionsBoundary = &types.AttachedPermissionsBoundary{
+			PermissionsBoundaryArn:  stringPtr(r.permissionsBoundary),
+			PermissionsBoundaryType: types.PermissionsBoundaryAttachmentTypePolicy,
+		}
+	}
+
+	return &iamRole
+}
+
+// toType returns the SDK representation of a policy.
+func (p *policy) toType() *types.Policy {
+	return &types.Policy{
+		Arn:          stringPtr(p.arn),
+		IsAttachable: true,
+		Path:         stringPtr("/"),
+		PolicyName:   stringPtr(p.name),
+		Tags:         copyIAMTags(p.tags),
+	}
+}
+
+// awsManagedPolicy returns true if the ARN refers to a policy managed by AWS.
+func awsManagedPolicy(arn string) bool {
+	return strings.HasPrefix(arn, "arn:aws:iam::aws:policy/")
+}
+
+// roleNotFound returns the error IAM returns for a missing role.
+func roleNotFound(name string) error {
+	return &types.NoSuchEntityException{
+		Message: stringPtr(fmt.Sprintf("The role with name %s cannot be found.", name)),
+	}
+}
+
+// policyNotFound returns the error IAM returns for a missing policy.
+func policyNotFound(arn string) error {
+	return &types.NoSuchEntityException{
+		Message: stringPtr(fmt.Sprintf("Policy %s does not exist or is not attachable.", arn)),
+	}
+}
+
+// copyIAMTags returns a deep copy of IAM tags.
+func copyIAMTags(tags []types.Tag) []types.Tag {
+	var copied []types.Tag
+	for _, tag := range tags {
+		copied = append(copied, types.Tag{
+			Key:   stringPtr(stringValue(tag.Key)),
+			Value: stringPtr(stringValue(tag.Value)),
+		})
+	}
+
+	return copied
+}
@@ -0,0 +1,148 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var _ resource.CloudWatchLogsAPI = (*CloudWatchLogs)(nil)
+
+// CloudWatchLogs implements the resource package's CloudWatchLogsAPI against
+// the backend state.
+type CloudWatchLogs struct {
+	b *Backend
+}
+
+type logGroup struct {
+	name          string
+	arn           string
+	retentionDays int32
+	tags          map[string]string
+}
+
+func (g *logGroup) toType() types.LogGroup {
+	logGroup := types.LogGroup{
+		LogGroupName: stringPtr(g.name),
+		Arn:          stringPtr(g.arn),
+	}
+	if g.retentionDays != 0 {
+		retentionDays := g.retentionDays
+		logGroup.RetentionInDays = &retentionDays
+	}
+
+	return logGroup
+}
+
+// describeLogGroupsPageSize is the number of log groups returned by each call
+// to DescribeLogGroups so that paging is exercised.
+const describeLogGroupsPageSize = 2
+
+// CreateLogGroup creates a log group whose events never expire.
+func (l *CloudWatchLogs) CreateLogGroup(ctx context.Context, params *cloudwatchlogs.CreateLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	b := l.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "CreateLogGroup"); err != nil {
+		return nil, err
+	}
+
+	name := stringValue(params.LogGroupName)
+	if name == "" {
+		return nil, &types.InvalidParameterException{Message: stringPtr("log group name is required")}
+	}
+	if _, ok := b.logGroups[name]; ok {
+		return nil, &types.ResourceAlreadyExistsException{Message: stringPtr("The specified log group already exists")}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range params.Tags {
+		tags[k] = v
+	}
+	b.logGroups[name] = &logGroup{
+		name: name,
+		arn:  fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s:*", b.Region, b.AccountID, name),
+		tags: tags,
+	}
+
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+// PutRetentionPolicy sets the number of days a log group's events are kept.
+func (l *CloudWatchLogs) PutRetentionPolicy(ctx context.Context, params *cloudwatchlogs.PutRetentionPolicyInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutRetentionPolicyOutput, error) {
+	b := l.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "PutRetentionPolicy"); err != nil {
+		return nil, err
+	}
+
+	g, ok := b.logGroups[stringValue(params.LogGroupName)]
+	if !ok {
+		return nil, logGroupNotFound()
+	}
+	if params.RetentionInDays == nil || *params.RetentionInDays <= 0 {
+		return nil, &types.InvalidParameterException{Message: stringPtr("invalid retention in days")}
+	}
+	g.retentionDays = *params.RetentionInDays
+
+	return &cloudwatchlogs.PutRetentionPolicyOutput{}, nil
+}
+
+// DescribeLogGroups returns the log groups with names that start with the
+// given prefix, a page at a time.
+func (l *CloudWatchLogs) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	b := l.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DescribeLogGroups"); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range sortedKeys(b.logGroups) {
+		if strings.HasPrefix(name, stringValue(params.LogGroupNamePrefix)) && name > stringValue(params.NextToken) {
+			names = append(names, name)
+		}
+	}
+
+	var output cloudwatchlogs.DescribeLogGroupsOutput
+	for i, name := range names {
+		if i == describeLogGroupsPageSize {
+			output.NextToken = stringPtr(names[i-1])
+			break
+		}
+		output.LogGroups = append(output.LogGroups, b.logGroups[name].toType())
+	}
+
+	return &output, nil
+}
+
+// DeleteLogGroup deletes a log group.
+func (l *CloudWatchLogs) DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	b := l.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteLogGroup"); err != nil {
+		return nil, err
+	}
+
+	name := stringValue(params.LogGroupName)
+	if _, ok := b.logGroups[name]; !ok {
+		return nil, logGroupNotFound()
+	}
+	delete(b.logGroups, name)
+
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+// logGroupNotFound returns the error CloudWatch Logs returns for a missing log
+// group.
+func logGroupNotFound() error {
+	return &types.ResourceNotFoundException{Message: stringPtr("The specified log group does not exist.")}
+}
@@ -0,0 +1,70 @@
+package fake
+
+import (
+	"sync"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+// Recorder drains a resource client's message and inventory channels so that
+// resource operations do not block, keeping every message and the latest
+// inventory.
+type Recorder struct {
+	mu            sync.Mutex
+	wg            sync.WaitGroup
+	messageChan   chan string
+	inventoryChan chan resource.ResourceInventory
+	messages      []string
+	inventory     resource.ResourceInventory
+}
+
+// Record sets new message and inventory channels on the resource client and
+// starts draining them.  Call Stop once the resource operations are complete.
+func (r *Recorder) Record(c *resource.ResourceClient) {
+	r.messageChan = make(chan string)
+	r.inventoryChan = make(chan resource.ResourceInventory)
+	c.MessageChan = &r.messageChan
+	c.InventoryChan = &r.inventoryChan
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		for msg := range r.messageChan {
+			r.mu.Lock()
+			r.messages = append(r.messages, msg)
+			r.mu.Unlock()
+		}
+	}()
+	go func() {
+		defer r.wg.Done()
+		for inventory := range r.inventoryChan {
+			r.mu.Lock()
+			r.inventory = inventory
+			r.mu.Unlock()
+		}
+	}()
+}
+
+// Stop closes the channels set by Record and waits until everything sent on
+// them has been recorded.  Record may be called again afterwards.
+func (r *Recorder) Stop() {
+	close(r.messageChan)
+	close(r.inventoryChan)
+	r.wg.Wait()
+}
+
+// Messages returns the messages recorded so far.
+func (r *Recorder) Messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.messages...)
+}
+
+// Inventory returns the latest inventory recorded.
+func (r *Recorder) Inventory() resource.ResourceInventory {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.inventory
+}
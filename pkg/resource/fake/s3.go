@@ -0,0 +1,109 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+var _ resource.S3API = (*S3)(nil)
+
+// S3 implements the resource package's S3API against the backend state.
+// Every bucket exists and holds the objects written to it.
+type S3 struct {
+	b *Backend
+}
+
+// GetObject returns the contents of an object.
+func (s *S3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	b := s.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "GetObject"); err != nil {
+		return nil, err
+	}
+
+	data, ok := b.objects[objectKey(params.Bucket, params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{Message: stringPtr("The specified key does not exist.")}
+	}
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+// PutObject writes an object.  With IfNoneMatch set to "*" the write fails if
+// the object already exists, as S3 conditional writes do.
+func (s *S3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	b := s.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "PutObject"); err != nil {
+		return nil, err
+	}
+
+	key := objectKey(params.Bucket, params.Key)
+	switch stringValue(params.IfNoneMatch) {
+	case "":
+	case "*":
+		if _, ok := b.objects[key]; ok {
+			return nil, apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		}
+	default:
+		return nil, apiError("NotImplemented", "If-None-Match only supports *")
+	}
+
+	var data []byte
+	if params.Body != nil {
+		var err error
+		data, err = io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b.objects[key] = data
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+// DeleteObject deletes an object.  Deleting an object that doesn't exist
+// succeeds, as it does in S3.
+func (s *S3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	b := s.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, "DeleteObject"); err != nil {
+		return nil, err
+	}
+
+	delete(b.objects, objectKey(params.Bucket, params.Key))
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// Objects returns the keys of the objects in a bucket in sorted order.
+func (s *S3) Objects(bucket string) []string {
+	b := s.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var keys []string
+	prefix := bucket + "/"
+	for _, key := range sortedKeys(b.objects) {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, strings.TrimPrefix(key, prefix))
+		}
+	}
+
+	return keys
+}
+
+// objectKey returns the key objects are stored under in the backend.
+func objectKey(bucket, key *string) string {
+	return stringValue(bucket) + "/" + stringValue(key)
+}
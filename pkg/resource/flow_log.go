@@ -0,0 +1,237 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// FlowLogDestination is where the VPC's flow logs are published.
+type FlowLogDestination string
+
+const (
+	// FlowLogDestinationCloudWatchLogs publishes flow logs to a CloudWatch
+	// Logs log group created for the cluster, along with an IAM role that
+	// allows the flow logs service to deliver to it.  This is the default.
+	FlowLogDestinationCloudWatchLogs FlowLogDestination = "cloud-watch-logs"
+
+	// FlowLogDestinationS3 publishes flow logs to an existing S3 bucket.  Its
+	// bucket policy must allow the flow logs service to deliver to it - AWS
+	// adds the statement when the flow log is created if it can.
+	FlowLogDestinationS3 FlowLogDestination = "s3"
+)
+
+const (
+	// DefaultFlowLogGroupPrefix is the prefix of the name of the log group
+	// created for flow logs if no name is configured.  The cluster name is
+	// appended to it.
+	DefaultFlowLogGroupPrefix = "/aws/vpc/flow-logs/"
+
+	// DefaultFlowLogTrafficType is the type of traffic logged if none is
+	// configured.
+	DefaultFlowLogTrafficType = types.TrafficTypeAll
+)
+
+// flowLogRetentionDays are the numbers of days CloudWatch Logs allows a log
+// group's events to be kept for.
+var flowLogRetentionDays = []int32{
+	1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653,
+}
+
+// destination returns the flow log destination, or the default destination if
+// it is not set.
+func (f *FlowLogsConfig) destination() FlowLogDestination {
+	if f.Destination == "" {
+		return FlowLogDestinationCloudWatchLogs
+	}
+
+	return f.Destination
+}
+
+// trafficType returns the type of traffic to log, or the default if it is not
+// set.
+func (f *FlowLogsConfig) trafficType() types.TrafficType {
+	if f.TrafficType == "" {
+		return DefaultFlowLogTrafficType
+	}
+
+	return types.TrafficType(f.TrafficType)
+}
+
+// logGroupName returns the name of the log group to create for flow logs
+// published to CloudWatch Logs, or the default name for the cluster if it is
+// not set.
+func (f *FlowLogsConfig) logGroupName(clusterName string) string {
+	if f.LogGroupName == "" {
+		return DefaultFlowLogGroupPrefix + clusterName
+	}
+
+	return f.LogGroupName
+}
+
+// checkFlowLogs checks the flow logs config in the resource config.  Flow
+// logs can't be created when using an existing VPC as they are deleted along
+// with the cluster.  The log group options only apply to CloudWatch Logs and
+// the S3 bucket only to S3.
+func (r *ResourceConfig) checkFlowLogs() error {
+	if r.FlowLogs == nil {
+		return nil
+	}
+	if r.UsesExistingVPC() {
+		return errors.New("flow logs cannot be created when using an existing VPC")
+	}
+
+	flowLogs := r.FlowLogs
+	switch flowLogs.trafficType() {
+	case types.TrafficTypeAccept, types.TrafficTypeReject, types.TrafficTypeAll:
+	default:
+		return fmt.Errorf("flow log traffic type %q in resource config must be one of %s, %s or %s",
+			flowLogs.TrafficType, types.TrafficTypeAccept, types.TrafficTypeReject, types.TrafficTypeAll)
+	}
+
+	switch flowLogs.destination() {
+	case FlowLogDestinationCloudWatchLogs:
+		if flowLogs.S3BucketARN != "" {
+			return fmt.Errorf("flow log S3 bucket ARN cannot be set in resource config with destination %s",
+				FlowLogDestinationCloudWatchLogs)
+		}
+		if flowLogs.RetentionDays != 0 && !validFlowLogRetention(flowLogs.RetentionDays) {
+			return fmt.Errorf("flow log retention of %d days in resource config is not supported by CloudWatch Logs, must be one of %v",
+				flowLogs.RetentionDays, flowLogRetentionDays)
+		}
+	case FlowLogDestinationS3:
+		if flowLogs.S3BucketARN == "" {
+			return fmt.Errorf("flow log S3 bucket ARN must be set in resource config with destination %s",
+				FlowLogDestinationS3)
+		}
+		if !strings.HasPrefix(flowLogs.S3BucketARN, "arn:") || !strings.Contains(flowLogs.S3BucketARN, ":s3:::") {
+			return fmt.Errorf("flow log S3 bucket ARN %q in resource config is not a valid S3 bucket ARN",
+				flowLogs.S3BucketARN)
+		}
+		if flowLogs.LogGroupName != "" || flowLogs.RetentionDays != 0 {
+			return fmt.Errorf("flow log group name and retention cannot be set in resource config with destination %s",
+				FlowLogDestinationS3)
+		}
+	default:
+		return fmt.Errorf("flow log destination %q in resource config must be one of %s or %s",
+			flowLogs.Destination, FlowLogDestinationCloudWatchLogs, FlowLogDestinationS3)
+	}
+
+	return nil
+}
+
+// validFlowLogRetention returns true if CloudWatch Logs allows a log group's
+// events to be kept for the given number of days.
+func validFlowLogRetention(retentionDays int32) bool {
+	for _, days := range flowLogRetentionDays {
+		if days == retentionDays {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateFlowLog creates a flow log that captures the traffic for a VPC.  Flow
+// logs published to CloudWatch Logs are delivered to the log group using the
+// delivery role.  Those published to S3 are delivered to the bucket.
+func (c *ResourceClient) CreateFlowLog(
+	ctx context.Context,
+	tags *[]types.Tag,
+	vpcID string,
+	flowLogs *FlowLogsConfig,
+	logGroupName string,
+	deliveryRoleARN string,
+) (string, error) {
+	svc := c.ec2Client()
+
+	createFlowLogsInput := ec2.CreateFlowLogsInput{
+		ResourceIds:  []string{vpcID},
+		ResourceType: types.FlowLogsResourceTypeVpc,
+		TrafficType:  flowLogs.trafficType(),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeVpcFlowLog,
+				Tags:         *tags,
+			},
+		},
+	}
+	switch flowLogs.destination() {
+	case FlowLogDestinationS3:
+		createFlowLogsInput.LogDestinationType = types.LogDestinationTypeS3
+		createFlowLogsInput.LogDestination = &flowLogs.S3BucketARN
+	default:
+		createFlowLogsInput.LogDestinationType = types.LogDestinationTypeCloudWatchLogs
+		createFlowLogsInput.LogGroupName = &logGroupName
+		createFlowLogsInput.DeliverLogsPermissionArn = &deliveryRoleARN
+	}
+	resp, err := svc.CreateFlowLogs(ctx, &createFlowLogsInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to create flow log for VPC with ID %s: %w", vpcID, err)
+	}
+
+	// a flow log that couldn't be created for the VPC is reported as an
+	// unsuccessful item rather than an error
+	for _, item := range resp.Unsuccessful {
+		if item.Error == nil {
+			continue
+		}
+		return "", fmt.Errorf("failed to create flow log for VPC with ID %s: %s %s", vpcID,
+			aws.ToString(item.Error.Code), aws.ToString(item.Error.Message))
+	}
+	if len(resp.FlowLogIds) == 0 {
+		return "", fmt.Errorf("no flow log created for VPC with ID %s", vpcID)
+	}
+
+	return resp.FlowLogIds[0], nil
+}
+
+// DeleteFlowLog deletes a flow log.  If an empty flow log ID is supplied, or
+// if the flow log is not found, it returns without error.
+func (c *ResourceClient) DeleteFlowLog(ctx context.Context, flowLogID string) error {
+	// if flowLogID is empty, there's nothing to delete
+	if flowLogID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteFlowLogsInput := ec2.DeleteFlowLogsInput{FlowLogIds: []string{flowLogID}}
+	resp, err := svc.DeleteFlowLogs(ctx, &deleteFlowLogsInput)
+	if err != nil {
+		return fmt.Errorf("failed to delete flow log with ID %s: %w", flowLogID, err)
+	}
+	for _, item := range resp.Unsuccessful {
+		if item.Error == nil || aws.ToString(item.Error.Code) == "InvalidFlowLogId.NotFound" {
+			// attempting to delete a flow log that doesn't exist so return
+			// without error
+			continue
+		}
+		return fmt.Errorf("failed to delete flow log with ID %s: %s %s", flowLogID,
+			aws.ToString(item.Error.Code), aws.ToString(item.Error.Message))
+	}
+
+	return nil
+}
+
+// getFlowLog retrieves the flow log with the given ID.  If the flow log is not
+// found it returns ErrResourceNotFound.
+func (c *ResourceClient) getFlowLog(ctx context.Context, flowLogID string) (*types.FlowLog, error) {
+	svc := c.ec2Client()
+
+	describeFlowLogsInput := ec2.DescribeFlowLogsInput{FlowLogIds: []string{flowLogID}}
+	resp, err := svc.DescribeFlowLogs(ctx, &describeFlowLogsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe flow log with ID %s: %w", flowLogID, err)
+	}
+	if len(resp.FlowLogs) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.FlowLogs[0], nil
+}
@@ -0,0 +1,251 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// s3FlowLogsConfig returns a resource config with flow logs published to an
+// S3 bucket.
+func s3FlowLogsConfig() *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.FlowLogs = &resource.FlowLogsConfig{
+		Destination: resource.FlowLogDestinationS3,
+		S3BucketARN: "arn:aws:s3:::logs",
+	}
+
+	return resourceConfig
+}
+
+func TestFlowLogsCloudWatchLogs(t *testing.T) {
+	ctx := context.Background()
+	resourceConfig := func() *resource.ResourceConfig {
+		resourceConfig := flowLogsConfig()
+		resourceConfig.FlowLogs.TrafficType = "REJECT"
+		return resourceConfig
+	}
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, resourceConfig())
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if plan.FlowLog == nil || plan.FlowLog.LogGroupName != "/aws/vpc/flow-logs/eks-cluster" || plan.FlowLog.RoleName == "" {
+		t.Errorf("expected flow log with log group and role in plan, got %+v", plan.FlowLog)
+	}
+
+	backend, c, inventory := createResourceStack(t, resourceConfig())
+	if inventory.FlowLogID == "" || inventory.FlowLogGroupName == "" || inventory.FlowLogsRole.RoleName == "" {
+		t.Errorf("expected flow log, log group and role in inventory, got %q, %q and %+v",
+			inventory.FlowLogID, inventory.FlowLogGroupName, inventory.FlowLogsRole)
+	}
+
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+	verified := make(map[resource.ResourceKind]bool)
+	for _, drift := range report.Resources {
+		verified[drift.Kind] = true
+	}
+	if !verified[resource.ResourceKindFlowLog] || !verified[resource.ResourceKindLogGroup] {
+		t.Error("expected flow log and log group to be verified")
+	}
+
+	// resuming with another log group is rejected
+	otherConfig := resourceConfig()
+	otherConfig.FlowLogs.LogGroupName = "other"
+	c.FailurePolicy = resource.FailurePolicyKeep
+	var r fake.Recorder
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, otherConfig, &inventory)
+	r.Stop()
+	c.FailurePolicy = ""
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected log group mismatch, got %v", err)
+	}
+
+	// the flow log, log group and role are recovered
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if recovered.FlowLogsRole.RoleName != inventory.FlowLogsRole.RoleName {
+		t.Errorf("expected flow logs role %s to be recovered, got %s",
+			inventory.FlowLogsRole.RoleName, recovered.FlowLogsRole.RoleName)
+	}
+
+	// a flow log deleted out of band is reported and recreated on resume
+	// without recreating the log group
+	if err := c.DeleteFlowLog(ctx, inventory.FlowLogID); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if !report.Drifted {
+		t.Error("expected deleted flow log to be reported")
+	}
+	calls := len(backend.Calls())
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, resourceConfig(), &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to resume resource stack: %v", err)
+	}
+	var recreated bool
+	for _, call := range backend.Calls()[calls:] {
+		recreated = recreated || call == "CreateFlowLogs"
+		if call == "CreateLogGroup" {
+			t.Error("expected log group not to be recreated")
+		}
+	}
+	if !recreated {
+		t.Error("expected flow log to be recreated")
+	}
+
+	deleteResourceStack(t, backend, c, r.Inventory())
+}
+
+func TestFlowLogsS3(t *testing.T) {
+	ctx := context.Background()
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, s3FlowLogsConfig())
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if plan.FlowLog == nil || plan.FlowLog.RoleName != "" || plan.FlowLog.LogGroupName != "" {
+		t.Errorf("expected flow log without log group or role in plan, got %+v", plan.FlowLog)
+	}
+	for _, policy := range plan.Policies {
+		if strings.HasPrefix(policy.PolicyName, "FlowLogs") {
+			t.Errorf("expected no flow logs policy in plan, got %s", policy.PolicyName)
+		}
+	}
+
+	backend, c, inventory := createResourceStack(t, s3FlowLogsConfig())
+	if inventory.FlowLogID == "" || inventory.FlowLogGroupName != "" || inventory.FlowLogsRole.RoleName != "" {
+		t.Errorf("expected only a flow log in inventory, got %q, %q and %+v",
+			inventory.FlowLogID, inventory.FlowLogGroupName, inventory.FlowLogsRole)
+	}
+	if snapshot := backend.Snapshot(); len(snapshot.LogGroupNames) != 0 {
+		t.Errorf("expected no log groups, got %v", snapshot.LogGroupNames)
+	}
+
+	var r fake.Recorder
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if recovered.FlowLogID != inventory.FlowLogID {
+		t.Errorf("expected flow log %s to be recovered, got %s", inventory.FlowLogID, recovered.FlowLogID)
+	}
+
+	deleteResourceStack(t, backend, c, inventory)
+}
+
+func TestFlowLogsCreateFailure(t *testing.T) {
+	for _, operation := range []string{"CreateCluster", "PutRetentionPolicy"} {
+		t.Run(operation, func(t *testing.T) {
+			backend := fake.NewBackend(testRegion)
+			c := backend.ResourceClient()
+			c.FailurePolicy = resource.FailurePolicyDelete
+			backend.Fail(operation, errors.New("injected failure"))
+
+			var r fake.Recorder
+			r.Record(c)
+			err := c.CreateResourceStack(context.Background(), flowLogsConfig())
+			r.Stop()
+			var failedErr *resource.CreateFailedError
+			if !errors.As(err, &failedErr) || !failedErr.Deleted {
+				t.Fatalf("expected resources to be deleted after failure, got %v", err)
+			}
+			if snapshot := backend.Snapshot(); !snapshot.Empty() {
+				t.Errorf("expected no resources, got %+v", snapshot)
+			}
+		})
+	}
+}
+
+func TestFlowLogsInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*resource.ResourceConfig)
+		wantErr string
+	}{
+		{
+			name:    "unknown traffic type",
+			mutate:  func(r *resource.ResourceConfig) { r.FlowLogs.TrafficType = "SOME" },
+			wantErr: "flow log traffic type \"SOME\"",
+		},
+		{
+			name:    "unsupported retention",
+			mutate:  func(r *resource.ResourceConfig) { r.FlowLogs.RetentionDays = 2 },
+			wantErr: "flow log retention of 2 days",
+		},
+		{
+			name:    "unknown destination",
+			mutate:  func(r *resource.ResourceConfig) { r.FlowLogs.Destination = "local-file" },
+			wantErr: "flow log destination \"local-file\"",
+		},
+		{
+			name: "S3 without bucket ARN",
+			mutate: func(r *resource.ResourceConfig) {
+				r.FlowLogs.Destination = resource.FlowLogDestinationS3
+				r.FlowLogs.RetentionDays = 0
+			},
+			wantErr: "flow log S3 bucket ARN must be set",
+		},
+		{
+			name: "S3 with invalid bucket ARN",
+			mutate: func(r *resource.ResourceConfig) {
+				r.FlowLogs.Destination = resource.FlowLogDestinationS3
+				r.FlowLogs.RetentionDays = 0
+				r.FlowLogs.S3BucketARN = "logs"
+			},
+			wantErr: "is not a valid S3 bucket ARN",
+		},
+		{
+			name: "S3 with retention",
+			mutate: func(r *resource.ResourceConfig) {
+				r.FlowLogs.Destination = resource.FlowLogDestinationS3
+				r.FlowLogs.S3BucketARN = "arn:aws:s3:::logs"
+			},
+			wantErr: "flow log group name and retention cannot be set",
+		},
+		{
+			name:    "CloudWatch Logs with bucket ARN",
+			mutate:  func(r *resource.ResourceConfig) { r.FlowLogs.S3BucketARN = "arn:aws:s3:::logs" },
+			wantErr: "flow log S3 bucket ARN cannot be set",
+		},
+		{
+			name: "existing VPC",
+			mutate: func(r *resource.ResourceConfig) {
+				r.VPCID = "vpc-123"
+				r.PrivateSubnetIDs = []string{"subnet-1"}
+			},
+			wantErr: "flow logs cannot be created when using an existing VPC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := flowLogsConfig()
+			tc.mutate(resourceConfig)
+			_, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// resourceNode is a resource, or a group of related resources that are
+// recorded together in the inventory, along with the names of the nodes it
+// depends on.  The create and delete functions record the resources they
+// manage in the stack's inventory, send events for them and may run
+// concurrently with the functions of other nodes.  A failed event for the
+// node's kind of resource is sent if they return an error.  A nil function is
+// skipped.
+type resourceNode struct {
+	name      string
+	kind      ResourceKind
+	dependsOn []string
+	create    func(ctx context.Context, stack *resourceStack) error
+	delete    func(ctx context.Context, stack *resourceStack) error
+}
+
+// resourceGraph is a set of resource nodes and the dependencies between them.
+// Resources are created in dependency order and deleted in the reverse order.
+type resourceGraph struct {
+	nodes []resourceNode
+}
+
+// add adds a node to the resource graph.  Nodes with no dependency between
+// them are started in the order they are added.
+func (g *resourceGraph) add(node resourceNode) {
+	g.nodes = append(g.nodes, node)
+}
+
+// createOrder returns the nodes in the order they must be created so that
+// each node comes after all the nodes it depends on.  It returns an error if
+// node names are duplicated, a dependency is unknown or dependencies form a
+// cycle.
+func (g *resourceGraph) createOrder() ([]resourceNode, error) {
+	names := make(map[string]bool)
+	for _, node := range g.nodes {
+		if names[node.name] {
+			return nil, fmt.Errorf("duplicate resource %s in resource graph", node.name)
+		}
+		names[node.name] = true
+	}
+	for _, node := range g.nodes {
+		for _, dependency := range node.dependsOn {
+			if !names[dependency] {
+				return nil, fmt.Errorf("resource %s depends on unknown resource %s", node.name, dependency)
+			}
+		}
+	}
+
+	var order []resourceNode
+	ordered := make(map[string]bool)
+	for len(order) < len(g.nodes) {
+		// add the first node, in the order they were added, whose
+		// dependencies have all been ordered
+		progressed := false
+		for _, node := range g.nodes {
+			if ordered[node.name] || !containsAll(ordered, node.dependsOn) {
+				continue
+			}
+			order = append(order, node)
+			ordered[node.name] = true
+			progressed = true
+			break
+		}
+		if !progressed {
+			var remaining []string
+			for _, node := range g.nodes {
+				if !ordered[node.name] {
+					remaining = append(remaining, node.name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between resources: %s", strings.Join(remaining, ", "))
+		}
+	}
+
+	return order, nil
+}
+
+// deleteOrder returns the nodes in the order they must be deleted so that
+// each node comes before all the nodes it depends on.
+func (g *resourceGraph) deleteOrder() ([]resourceNode, error) {
+	order, err := g.createOrder()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+
+	return order, nil
+}
+
+// createResourceGraph creates the resources for each node in a resource graph.
+// A node is created once all the nodes it depends on have been created, and
+// independent nodes are created concurrently up to the resource client's
+// concurrency limit.
+func (c *ResourceClient) createResourceGraph(ctx context.Context, graph *resourceGraph, stack *resourceStack) error {
+	nodes, err := graph.createOrder()
+	if err != nil {
+		return err
+	}
+
+	waitFor := make(map[string][]string)
+	for _, node := range nodes {
+		waitFor[node.name] = node.dependsOn
+	}
+
+	return c.runResourceNodes(ctx, nodes, waitFor, func(node resourceNode) error {
+		if node.create == nil {
+			return nil
+		}
+		err := node.create(ctx, stack)
+		if err != nil {
+			c.sendEvent(Event{Kind: node.kind, Action: EventActionCreate, Phase: EventPhaseFailed, Err: err})
+		}
+		return err
+	})
+}
+
+// deleteResourceGraph deletes the resources for each node in a resource graph.
+// A node is deleted once all the nodes that depend on it have been deleted,
+// and independent nodes are deleted concurrently up to the resource client's
+// concurrency limit.
+func (c *ResourceClient) deleteResourceGraph(ctx context.Context, graph *resourceGraph, stack *resourceStack) error {
+	nodes, err := graph.deleteOrder()
+	if err != nil {
+		return err
+	}
+
+	waitFor := make(map[string][]string)
+	for _, node := range nodes {
+		for _, dependency := range node.dependsOn {
+			waitFor[dependency] = append(waitFor[dependency], node.name)
+		}
+	}
+
+	return c.runResourceNodes(ctx, nodes, waitFor, func(node resourceNode) error {
+		if node.delete == nil {
+			return nil
+		}
+		err := node.delete(ctx, stack)
+		if err != nil {
+			c.sendEvent(Event{Kind: node.kind, Action: EventActionDelete, Phase: EventPhaseFailed, Err: err})
+		}
+		return err
+	})
+}
+
+// nodeResult is the outcome of running a resource node.
+type nodeResult struct {
+	name string
+	err  error
+}
+
+// runResourceNodes runs a function for each resource node once all the nodes
+// it waits for have completed.  Nodes that are ready are started in the order
+// given, with no more than the resource client's concurrency limit running at
+// once.  If a node fails or the context is cancelled, no more nodes are
+// started and the error is returned once the running nodes have completed.
+// Errors from any other nodes that fail in the meantime are included in the
+// error message.
+func (c *ResourceClient) runResourceNodes(
+	ctx context.Context,
+	nodes []resourceNode,
+	waitFor map[string][]string,
+	run func(node resourceNode) error,
+) error {
+	concurrency := c.concurrency()
+	results := make(chan nodeResult)
+	started := make(map[string]bool)
+	completed := make(map[string]bool)
+	running := 0
+
+	var nodeErr error
+	var otherErrs []string
+	for {
+		if nodeErr == nil && ctx.Err() != nil {
+			nodeErr = ctx.Err()
+		}
+		if nodeErr == nil {
+			for _, node := range nodes {
+				if running >= concurrency {
+					break
+				}
+				if started[node.name] || !containsAll(completed, waitFor[node.name]) {
+					continue
+				}
+				started[node.name] = true
+				running++
+				go func(node resourceNode) {
+					results <- nodeResult{name: node.name, err: run(node)}
+				}(node)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		completed[result.name] = true
+		if result.err != nil {
+			if nodeErr == nil {
+				nodeErr = result.err
+			} else {
+				otherErrs = append(otherErrs, result.err.Error())
+			}
+		}
+	}
+
+	if len(otherErrs) > 0 {
+		return fmt.Errorf("%w; %s", nodeErr, strings.Join(otherErrs, "; "))
+	}
+
+	return nodeErr
+}
+
+// containsAll returns true if a set of node names contains all of the given
+// names.
+func containsAll(set map[string]bool, names []string) bool {
+	for _, name := range names {
+		if !set[name] {
+			return false
+		}
+	}
+
+	return true
+}
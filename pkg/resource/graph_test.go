@@ -0,0 +1,252 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNode is the name of a resource node and the nodes it depends on.
+type testNode struct {
+	name      string
+	dependsOn []string
+}
+
+// testGraph returns a resource graph with the given nodes added in order.
+func testGraph(nodes []testNode) *resourceGraph {
+	var graph resourceGraph
+	for _, node := range nodes {
+		graph.add(resourceNode{name: node.name, dependsOn: node.dependsOn})
+	}
+
+	return &graph
+}
+
+// nodeNames returns the names of resource nodes in order.
+func nodeNames(nodes []resourceNode) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.name)
+	}
+
+	return names
+}
+
+func TestResourceGraphOrder(t *testing.T) {
+	testCases := []struct {
+		name      string
+		nodes     []testNode
+		wantOrder []string
+		wantErr   string
+	}{
+		{
+			name: "independent nodes in the order added",
+			nodes: []testNode{
+				{name: "vpc"},
+				{name: "role"},
+				{name: "policy"},
+			},
+			wantOrder: []string{"vpc", "role", "policy"},
+		},
+		{
+			name: "dependencies before dependents",
+			nodes: []testNode{
+				{name: "cluster", dependsOn: []string{"subnets", "role"}},
+				{name: "subnets", dependsOn: []string{"vpc"}},
+				{name: "role", dependsOn: []string{"policy"}},
+				{name: "vpc"},
+				{name: "policy"},
+			},
+			wantOrder: []string{"vpc", "subnets", "policy", "role", "cluster"},
+		},
+		{
+			name: "duplicate node",
+			nodes: []testNode{
+				{name: "vpc"},
+				{name: "vpc"},
+			},
+			wantErr: "duplicate resource vpc",
+		},
+		{
+			name: "unknown dependency",
+			nodes: []testNode{
+				{name: "subnets", dependsOn: []string{"vpc"}},
+			},
+			wantErr: "resource subnets depends on unknown resource vpc",
+		},
+		{
+			name: "cycle",
+			nodes: []testNode{
+				{name: "vpc"},
+				{name: "subnets", dependsOn: []string{"vpc", "route tables"}},
+				{name: "route tables", dependsOn: []string{"nat gateways"}},
+				{name: "nat gateways", dependsOn: []string{"subnets"}},
+			},
+			wantErr: "dependency cycle between resources: subnets, route tables, nat gateways",
+		},
+		{
+			name: "self dependency",
+			nodes: []testNode{
+				{name: "vpc", dependsOn: []string{"vpc"}},
+			},
+			wantErr: "dependency cycle between resources: vpc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			graph := testGraph(tc.nodes)
+			createOrder, createErr := graph.createOrder()
+			deleteOrder, deleteErr := graph.deleteOrder()
+			if tc.wantErr != "" {
+				for _, err := range []error{createErr, deleteErr} {
+					if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+					}
+				}
+				return
+			}
+			if createErr != nil || deleteErr != nil {
+				t.Fatalf("failed to order resource graph: %v, %v", createErr, deleteErr)
+			}
+
+			if names := nodeNames(createOrder); !reflect.DeepEqual(names, tc.wantOrder) {
+				t.Errorf("expected create order %v, got %v", tc.wantOrder, names)
+			}
+			var wantDeleteOrder []string
+			for i := len(tc.wantOrder) - 1; i >= 0; i-- {
+				wantDeleteOrder = append(wantDeleteOrder, tc.wantOrder[i])
+			}
+			if names := nodeNames(deleteOrder); !reflect.DeepEqual(names, wantDeleteOrder) {
+				t.Errorf("expected delete order %v, got %v", wantDeleteOrder, names)
+			}
+		})
+	}
+}
+
+func TestRunResourceGraph(t *testing.T) {
+	nodes := []testNode{
+		{name: "vpc"},
+		{name: "policy"},
+		{name: "role", dependsOn: []string{"policy"}},
+		{name: "internet gateway", dependsOn: []string{"vpc"}},
+		{name: "subnets", dependsOn: []string{"vpc"}},
+		{name: "nat gateways", dependsOn: []string{"subnets", "internet gateway"}},
+		{name: "cluster", dependsOn: []string{"subnets", "role"}},
+		{name: "node group", dependsOn: []string{"cluster", "nat gateways"}},
+	}
+
+	testCases := []struct {
+		name        string
+		concurrency int
+		fail        string
+		wantSkipped []string
+	}{
+		{
+			name:        "sequential",
+			concurrency: 1,
+		},
+		{
+			name:        "concurrent",
+			concurrency: 3,
+		},
+		{
+			name:        "failed node",
+			concurrency: 2,
+			fail:        "subnets",
+			wantSkipped: []string{"nat gateways", "cluster", "node group"},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, deleting := range []bool{false, true} {
+			name := tc.name + " create"
+			if deleting {
+				name = tc.name + " delete"
+			}
+			t.Run(name, func(t *testing.T) {
+				var mu sync.Mutex
+				var running, maxRunning int
+				completed := make(map[string]bool)
+				failErr := errors.New("injected failure")
+
+				// each node checks that the nodes it waits for have
+				// completed before it started
+				run := func(node testNode) func(ctx context.Context, stack *resourceStack) error {
+					return func(ctx context.Context, stack *resourceStack) error {
+						mu.Lock()
+						for _, other := range nodes {
+							waitsFor := containsString(node.dependsOn, other.name)
+							if deleting {
+								waitsFor = containsString(other.dependsOn, node.name)
+							}
+							if waitsFor && !completed[other.name] {
+								t.Errorf("%s started before %s completed", node.name, other.name)
+							}
+						}
+						running++
+						if running > maxRunning {
+							maxRunning = running
+						}
+						mu.Unlock()
+
+						time.Sleep(time.Millisecond)
+
+						mu.Lock()
+						defer mu.Unlock()
+						running--
+						completed[node.name] = true
+						if node.name == tc.fail {
+							return failErr
+						}
+						return nil
+					}
+				}
+				var graph resourceGraph
+				for _, node := range nodes {
+					graph.add(resourceNode{
+						name:      node.name,
+						dependsOn: node.dependsOn,
+						create:    run(node),
+						delete:    run(node),
+					})
+				}
+
+				c := ResourceClient{Concurrency: tc.concurrency}
+				var err error
+				if deleting {
+					err = c.deleteResourceGraph(context.Background(), &graph, nil)
+				} else {
+					err = c.createResourceGraph(context.Background(), &graph, nil)
+				}
+
+				if maxRunning > tc.concurrency {
+					t.Errorf("expected at most %d nodes running at once, got %d", tc.concurrency, maxRunning)
+				}
+				if tc.fail == "" {
+					if err != nil {
+						t.Fatalf("failed to run resource graph: %v", err)
+					}
+					if len(completed) != len(nodes) {
+						t.Errorf("expected all %d nodes to complete, got %v", len(nodes), completed)
+					}
+					return
+				}
+				if !errors.Is(err, failErr) {
+					t.Fatalf("expected injected failure, got %v", err)
+				}
+				if !deleting {
+					for _, skipped := range tc.wantSkipped {
+						if completed[skipped] {
+							t.Errorf("expected %s not to run after %s failed", skipped, tc.fail)
+						}
+					}
+				}
+			})
+		}
+	}
+}
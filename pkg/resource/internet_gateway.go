@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,11 +13,12 @@ import (
 // CreateInternetGateway creates an internet gateway for the VPC in which an EKS
 // cluster is provisioned.
 func (c *ResourceClient) CreateInternetGateway(
+	ctx context.Context,
 	tags *[]types.Tag,
 	vpcID string,
 	clusterName string,
 ) (*types.InternetGateway, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	createIGWInput := ec2.CreateInternetGatewayInput{
 		TagSpecifications: []types.TagSpecification{
@@ -26,40 +28,51 @@ func (c *ResourceClient) CreateInternetGateway(
 			},
 		},
 	}
-	resp, err := svc.CreateInternetGateway(c.Context, &createIGWInput)
+	resp, err := svc.CreateInternetGateway(ctx, &createIGWInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create internet gateway: %w", err)
 	}
 
+	if err := c.AttachInternetGateway(ctx, *resp.InternetGateway.InternetGatewayId, vpcID); err != nil {
+		return resp.InternetGateway, err
+	}
+
+	return resp.InternetGateway, nil
+}
+
+// AttachInternetGateway attaches an existing internet gateway to a VPC.
+func (c *ResourceClient) AttachInternetGateway(ctx context.Context, internetGatewayID, vpcID string) error {
+	svc := c.ec2Client()
+
 	attachIGWInput := ec2.AttachInternetGatewayInput{
-		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
+		InternetGatewayId: &internetGatewayID,
 		VpcId:             &vpcID,
 	}
-	_, err = svc.AttachInternetGateway(c.Context, &attachIGWInput)
+	_, err := svc.AttachInternetGateway(ctx, &attachIGWInput)
 	if err != nil {
-		return resp.InternetGateway, fmt.Errorf(
+		return fmt.Errorf(
 			"failed to attach internet gateway with ID %s to VPC with ID %s: %w",
-			*resp.InternetGateway.InternetGatewayId, vpcID, err)
+			internetGatewayID, vpcID, err)
 	}
 
-	return resp.InternetGateway, nil
+	return nil
 }
 
 // DeleteInternetGateway deletes an internet gateway.  If an empty ID is
 // supplied, or if the internet gateway is not found, it returns without error.
-func (c *ResourceClient) DeleteInternetGateway(internetGatewayID, vpcID string) error {
+func (c *ResourceClient) DeleteInternetGateway(ctx context.Context, internetGatewayID, vpcID string) error {
 	// if internetGatewayID is empty, there's nothing to delete
 	if internetGatewayID == "" {
 		return nil
 	}
 
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	detachInternetGatewayInput := ec2.DetachInternetGatewayInput{
 		InternetGatewayId: &internetGatewayID,
 		VpcId:             &vpcID,
 	}
-	_, err := svc.DetachInternetGateway(c.Context, &detachInternetGatewayInput)
+	_, err := svc.DetachInternetGateway(ctx, &detachInternetGatewayInput)
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
@@ -76,7 +89,7 @@ func (c *ResourceClient) DeleteInternetGateway(internetGatewayID, vpcID string)
 	}
 
 	deleteInternetGatewayInput := ec2.DeleteInternetGatewayInput{InternetGatewayId: &internetGatewayID}
-	_, err = svc.DeleteInternetGateway(c.Context, &deleteInternetGatewayInput)
+	_, err = svc.DeleteInternetGateway(ctx, &deleteInternetGatewayInput)
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
@@ -94,3 +107,40 @@ func (c *ResourceClient) DeleteInternetGateway(internetGatewayID, vpcID string)
 
 	return nil
 }
+
+// getInternetGateway retrieves the internet gateway with the given ID.  If the
+// internet gateway is not found it returns ErrResourceNotFound.
+func (c *ResourceClient) getInternetGateway(ctx context.Context, internetGatewayID string) (*types.InternetGateway, error) {
+	svc := c.ec2Client()
+
+	filterName := "internet-gateway-id"
+	describeIGWsInput := ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{
+			{
+				Name:   &filterName,
+				Values: []string{internetGatewayID},
+			},
+		},
+	}
+	resp, err := svc.DescribeInternetGateways(ctx, &describeIGWsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe internet gateway with ID %s: %w", internetGatewayID, err)
+	}
+	if len(resp.InternetGateways) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.InternetGateways[0], nil
+}
+
+// internetGatewayAttached returns true if the internet gateway is attached to
+// the VPC.
+func internetGatewayAttached(internetGateway *types.InternetGateway, vpcID string) bool {
+	for _, attachment := range internetGateway.Attachments {
+		if attachment.VpcId != nil && *attachment.VpcId == vpcID {
+			return true
+		}
+	}
+
+	return false
+}
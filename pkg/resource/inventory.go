@@ -2,30 +2,137 @@ package resource
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 )
 
+// InventorySchemaVersion is the version of the inventory JSON written by this
+// package.  It is incremented, and a migration added to inventoryMigrations,
+// whenever a change to ResourceInventory means older inventory files would be
+// misread, or older versions of eks-cluster would misread newer files.  New
+// fields that older versions can safely ignore don't need a new version.
+const InventorySchemaVersion = 3
+
+// ErrInventoryVersionUnsupported is returned when reading an inventory written
+// with a newer schema version than this package supports.
+var ErrInventoryVersionUnsupported = errors.New("inventory schema version not supported")
+
 // ResourceInventory contains a record of all resources created so they can be
 // referenced and cleaned up.
 type ResourceInventory struct {
-	Region                 string           `json:"region"`
-	VPCID                  string           `json:"vpcID"`
-	SubnetIDs              []string         `json:"subnetIDs"`
-	InternetGatewayID      string           `json:"internetGatewayID"`
-	ElasticIPIDs           []string         `json:"elasticIPIDs"`
-	PrivateRouteTableIDs   []string         `json:"privateRouteTableIDs"`
-	PublicRouteTableID     string           `json:"publicRouteTableID"`
-	ClusterRole            RoleInventory    `json:"clusterRole"`
-	WorkerRole             RoleInventory    `json:"workerRole"`
-	DNSManagementRole      RoleInventory    `json:"dnsManagementRole"`
-	DNS01ChallengeRole     RoleInventory    `json:"dns01ChallengeRole"`
-	StorageManagementRole  RoleInventory    `json:"storageManagementRole"`
-	ClusterAutoscalingRole RoleInventory    `json:"clusterAutoscalingRole"`
-	PolicyARNs             []string         `json:"policyARNs"`
-	Cluster                ClusterInventory `json:"cluster"`
-	NodeGroupNames         []string         `json:"nodeGroupNames"`
-	OIDCProviderARN        string           `json:"oidcProviderARN"`
-	SecurityGroupID        string           `json:"securityGroupID"`
+	SchemaVersion          int                `json:"schemaVersion"`
+	Region                 string             `json:"region"`
+	VPCID                  string             `json:"vpcID"`
+	SubnetIDs              []string           `json:"subnetIDs"`
+	AvailabilityZones      []AvailabilityZone `json:"availabilityZones"`
+	InternetGatewayID      string             `json:"internetGatewayID"`
+	ElasticIPIDs           []string           `json:"elasticIPIDs"`
+	NATGatewayIDs          []string           `json:"natGatewayIDs"`
+	PrivateRouteTableIDs   []string           `json:"privateRouteTableIDs"`
+	PublicRouteTableID     string             `json:"publicRouteTableID"`
+	Routes                 []RouteInventory   `json:"routes"`
+	ClusterRole            RoleInventory      `json:"clusterRole"`
+	WorkerRole             RoleInventory      `json:"workerRole"`
+	DNSManagementRole      RoleInventory      `json:"dnsManagementRole"`
+	DNS01ChallengeRole     RoleInventory      `json:"dns01ChallengeRole"`
+	StorageManagementRole  RoleInventory      `json:"storageManagementRole"`
+	ClusterAutoscalingRole RoleInventory      `json:"clusterAutoscalingRole"`
+	PolicyARNs             []string           `json:"policyARNs"`
+	Cluster                ClusterInventory   `json:"cluster"`
+	NodeGroupNames         []string           `json:"nodeGroupNames"`
+	OIDCProviderARN        string             `json:"oidcProviderARN"`
+	AddonNames             []string           `json:"addonNames"`
+
+	// The cluster security group is created by EKS and deleted by EKS along
+	// with the cluster.  It is recorded but never deleted directly.
+	SecurityGroupID string `json:"securityGroupID"`
+
+	// The tags applied to EC2 resources, including the kubernetes.io/cluster
+	// tags added for the cluster.
+	Tags map[string]string `json:"tags"`
+
+	// The existing VPC and subnets the cluster was created in, if any.  They
+	// are recorded but never deleted as they weren't created for the cluster.
+	ExistingVPCID     string   `json:"existingVPCID,omitempty"`
+	ExistingSubnetIDs []string `json:"existingSubnetIDs,omitempty"`
+
+	// The NAT gateway mode the VPC's NAT gateways were created with.  Not set
+	// for an existing VPC.  Inventories that record NAT gateways but not the
+	// mode used the default of a NAT gateway per availability zone.
+	NATGatewayMode NATGatewayMode `json:"natGatewayMode,omitempty"`
+
+	// Set for inventories migrated from a schema version that didn't record
+	// NAT gateway IDs.  The NAT gateways in the VPC are deleted instead.
+	NATGatewaysUntracked bool `json:"natGatewaysUntracked,omitempty"`
+
+	// The VPC endpoints created for AWS services and the security group for
+	// the interface endpoints.
+	VPCEndpointSecurityGroupID string                 `json:"vpcEndpointSecurityGroupID,omitempty"`
+	VPCEndpoints               []VPCEndpointInventory `json:"vpcEndpoints,omitempty"`
+
+	// The IP family the VPC and cluster were created with and, for IPv6, the
+	// egress-only internet gateway for the private subnets.  Inventories that
+	// don't record the IP family used IPv4.  The egress-only internet gateway
+	// and its routes must be deleted before the VPC, so they are only recorded
+	// from schema version 3, which versions that would ignore them reject.
+	IPFamily                    IPFamily `json:"ipFamily,omitempty"`
+	EgressOnlyInternetGatewayID string   `json:"egressOnlyInternetGatewayID,omitempty"`
+
+	// The secondary CIDR block associated with the VPC for the pod subnets
+	// used by VPC CNI custom networking.  The pod subnets are recorded with
+	// the other subnets and in the availability zones.
+	SecondaryCIDR              string `json:"secondaryCIDR,omitempty"`
+	SecondaryCIDRAssociationID string `json:"secondaryCIDRAssociationID,omitempty"`
+
+	// The flow log created for the VPC and, for flow logs published to
+	// CloudWatch Logs, the log group and the IAM role that delivers to it.
+	// The log group is deleted along with the flow logs in it.
+	FlowLogID        string        `json:"flowLogID,omitempty"`
+	FlowLogGroupName string        `json:"flowLogGroupName,omitempty"`
+	FlowLogsRole     RoleInventory `json:"flowLogsRole"`
+
+	// The attachment of the VPC to an existing transit gateway and the
+	// peering connection from the VPC to another VPC.  The routes to them are
+	// recorded with the other routes.
+	TransitGatewayAttachmentID string `json:"transitGatewayAttachmentID,omitempty"`
+	VPCPeeringConnectionID     string `json:"vpcPeeringConnectionID,omitempty"`
+
+	// The network ACLs created for tiers of subnets.  The subnets are
+	// associated with the VPC's default network ACL again before they are
+	// deleted.
+	NetworkACLs []NetworkACLInventory `json:"networkACLs,omitempty"`
+}
+
+// RouteInventory contains the details for a route added to a route table.
+// The route targets an internet gateway, a NAT gateway, an egress-only
+// internet gateway, a transit gateway or a VPC peering connection.  The
+// destination is an IPv4 or IPv6 CIDR block.  Routes to the last three are
+// only recorded from schema version 3.
+type RouteInventory struct {
+	RouteTableID                string `json:"routeTableID"`
+	DestinationCIDR             string `json:"destinationCIDR"`
+	GatewayID                   string `json:"gatewayID,omitempty"`
+	NATGatewayID                string `json:"natGatewayID,omitempty"`
+	EgressOnlyInternetGatewayID string `json:"egressOnlyInternetGatewayID,omitempty"`
+	TransitGatewayID            string `json:"transitGatewayID,omitempty"`
+	VPCPeeringConnectionID      string `json:"vpcPeeringConnectionID,omitempty"`
+}
+
+// VPCEndpointInventory contains the details for a VPC endpoint created for an
+// AWS service.
+type VPCEndpointInventory struct {
+	VPCEndpointID string `json:"vpcEndpointID"`
+	ServiceName   string `json:"serviceName"`
+}
+
+// NetworkACLInventory contains the details for a network ACL created for a
+// tier of subnets - public, private or pod.
+type NetworkACLInventory struct {
+	Tier         string `json:"tier"`
+	NetworkACLID string `json:"networkACLID"`
 }
 
 // RoleInventory contains the details for each role created.
@@ -42,22 +149,125 @@ type ClusterInventory struct {
 	OIDCProviderURL string `json:"oidcProviderURL"`
 }
 
-// WriteInventory writes the inventory to a file.
+// clusterVPCID returns the ID of the VPC the cluster in an inventory is in -
+// either the VPC created for it or the existing VPC it uses.
+func clusterVPCID(inventory *ResourceInventory) string {
+	if inventory.VPCID != "" {
+		return inventory.VPCID
+	}
+
+	return inventory.ExistingVPCID
+}
+
+// inventoryFileMutex serializes inventory file writes so that concurrent
+// writes cannot interleave.
+var inventoryFileMutex sync.Mutex
+
+// InventoryBackupFile returns the name of the backup file kept alongside an
+// inventory file.
+func InventoryBackupFile(inventoryFile string) string {
+	return inventoryFile + ".bak"
+}
+
+// WriteInventory writes the inventory to a file.  The file is replaced
+// atomically so a crash cannot leave a partially written inventory, and the
+// previous inventory is kept in the backup file.
 func WriteInventory(inventoryFile string, inventory *ResourceInventory) error {
 	inventoryJSON, err := MarshalInventory(inventory)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(inventoryFile, inventoryJSON, 0644); err != nil {
-		return err
+	inventoryFileMutex.Lock()
+	defer inventoryFileMutex.Unlock()
+
+	// keep the previous inventory as a backup as long as it is readable so a
+	// corrupt file never replaces a good backup
+	previousBytes, err := os.ReadFile(inventoryFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read previous inventory file %s: %w", inventoryFile, err)
+	}
+	if err == nil {
+		var previousInventory ResourceInventory
+		if err := UnmarshalInventory(previousBytes, &previousInventory); err == nil {
+			if err := writeFileAtomic(InventoryBackupFile(inventoryFile), previousBytes, 0644); err != nil {
+				return fmt.Errorf("failed to back up inventory file %s: %w", inventoryFile, err)
+			}
+		}
+	}
+
+	if err := writeFileAtomic(inventoryFile, inventoryJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write inventory file %s: %w", inventoryFile, err)
 	}
 
 	return nil
 }
 
-// ReadInventory reads the inventory from the inventory file.
+// InventoryBackupUsedError is returned along with the backup of the previous
+// inventory when the inventory file can't be read or parsed.  The backup may
+// be missing the last resource recorded, so the user should be told it was
+// used.
+type InventoryBackupUsedError struct {
+	// The inventory file that couldn't be read.
+	File string
+
+	// The backup file that was read instead.
+	BackupFile string
+
+	// The error reading the inventory file.
+	Err error
+}
+
+// Error returns why the inventory file couldn't be read and the backup file
+// that was read instead.
+func (e *InventoryBackupUsedError) Error() string {
+	return fmt.Sprintf(
+		"failed to read inventory file %s so its backup %s was used - the backup may be missing the last resource recorded: %s",
+		e.File, e.BackupFile, e.Err,
+	)
+}
+
+// Unwrap returns the error reading the inventory file.
+func (e *InventoryBackupUsedError) Unwrap() error {
+	return e.Err
+}
+
+// ReadInventory reads the inventory from the inventory file.  If the inventory
+// file can't be read or parsed, the backup of the previous inventory is
+// returned instead along with an InventoryBackupUsedError.  If neither can be
+// read, both errors are returned.
 func ReadInventory(inventoryFile string) (*ResourceInventory, error) {
+	inventory, err := readInventoryFile(inventoryFile)
+	if err == nil || errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrInventoryVersionUnsupported) {
+		return inventory, err
+	}
+
+	backupFile := InventoryBackupFile(inventoryFile)
+	backupInventory, backupErr := readInventoryFile(backupFile)
+	if backupErr != nil {
+		return nil, fmt.Errorf("failed to read inventory file %s: %w, and its backup %s: %s", inventoryFile, err, backupFile, backupErr)
+	}
+
+	return backupInventory, &InventoryBackupUsedError{File: inventoryFile, BackupFile: backupFile, Err: err}
+}
+
+// RemoveInventory removes an inventory file and its backup.  It returns
+// without error if they don't exist.
+func RemoveInventory(inventoryFile string) error {
+	inventoryFileMutex.Lock()
+	defer inventoryFileMutex.Unlock()
+
+	for _, file := range []string{inventoryFile, InventoryBackupFile(inventoryFile)} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove inventory file %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// readInventoryFile reads and unmarshals a single inventory file.
+func readInventoryFile(inventoryFile string) (*ResourceInventory, error) {
 	// read inventory file
 	inventoryBytes, err := os.ReadFile(inventoryFile)
 	if err != nil {
@@ -73,11 +283,57 @@ func ReadInventory(inventoryFile string) (*ResourceInventory, error) {
 	return &inventory, nil
 }
 
+// writeFileAtomic writes data to a temporary file in the same directory,
+// syncs it to disk and renames it over the file so that readers only ever
+// see the old or the new contents.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(file)
+	tempFile, err := os.CreateTemp(dir, filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tempName := tempFile.Name()
+	defer os.Remove(tempName)
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Chmod(perm); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tempName, file); err != nil {
+		return err
+	}
+
+	// sync the directory so the rename survives a crash - not all platforms
+	// support syncing a directory so errors are ignored
+	if dirFile, err := os.Open(dir); err == nil {
+		dirFile.Sync()
+		dirFile.Close()
+	}
+
+	return nil
+}
+
 // MarshalInventory returns a json representation of inventory from a
-// ResourceInventory object.
+// ResourceInventory object.  The inventory is always written with the current
+// schema version.
 func MarshalInventory(inventory *ResourceInventory) ([]byte, error) {
+	versionedInventory := *inventory
+	versionedInventory.SchemaVersion = InventorySchemaVersion
+
 	var inventoryJSON []byte
-	inventoryJSON, err := json.MarshalIndent(inventory, "", "  ")
+	inventoryJSON, err := json.MarshalIndent(&versionedInventory, "", "  ")
 	if err != nil {
 		return inventoryJSON, err
 	}
@@ -86,11 +342,135 @@ func MarshalInventory(inventory *ResourceInventory) ([]byte, error) {
 }
 
 // UnmarshalInventory unmarshalls an inventory as a JSON byte array into a
-// ResourceInventory object.
+// ResourceInventory object.  Inventories written with an older schema version
+// are migrated to the current version.  An inventory written with a newer
+// schema version returns ErrInventoryVersionUnsupported.
 func UnmarshalInventory(inventoryBytes []byte, inventory *ResourceInventory) error {
-	if err := json.Unmarshal(inventoryBytes, &inventory); err != nil {
+	migratedBytes, err := migrateInventory(inventoryBytes)
+	if err != nil {
 		return err
 	}
 
+	if err := json.Unmarshal(migratedBytes, &inventory); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// inventoryMigration upgrades the JSON fields of an inventory by one schema
+// version.
+type inventoryMigration func(inventory map[string]interface{}) error
+
+// inventoryMigrations are the migrations between inventory schema versions.
+// The migration at index i upgrades an inventory from version i to i+1, so
+// there is always one migration for each version below
+// InventorySchemaVersion.
+var inventoryMigrations = []inventoryMigration{
+	migrateInventoryV0,
+	migrateInventoryV1,
+	migrateInventoryV2,
+}
+
+// migrateInventory upgrades inventory JSON to the current schema version by
+// applying each migration from the inventory's version onward.  Inventories
+// without a schema version are version 0.  JSON other than an object, such as
+// null, is rejected.
+func migrateInventory(inventoryBytes []byte) ([]byte, error) {
+	var inventory map[string]interface{}
+	if err := json.Unmarshal(inventoryBytes, &inventory); err != nil {
+		return nil, err
+	}
+	if inventory == nil {
+		return nil, errors.New("invalid inventory: not a JSON object")
+	}
+
+	version := 0
+	if versionValue, ok := inventory["schemaVersion"]; ok && versionValue != nil {
+		versionNumber, ok := versionValue.(float64)
+		if !ok || versionNumber < 0 || versionNumber != float64(int(versionNumber)) {
+			return nil, fmt.Errorf("invalid inventory schema version: %v", versionValue)
+		}
+		version = int(versionNumber)
+	}
+
+	if version > InventorySchemaVersion {
+		return nil, fmt.Errorf(
+			"%w: inventory has schema version %d but the latest supported version is %d - use a newer version of eks-cluster to read it",
+			ErrInventoryVersionUnsupported, version, InventorySchemaVersion,
+		)
+	}
+	if version == InventorySchemaVersion {
+		return inventoryBytes, nil
+	}
+
+	for ; version < InventorySchemaVersion; version++ {
+		if err := inventoryMigrations[version](inventory); err != nil {
+			return nil, fmt.Errorf("failed to migrate inventory from schema version %d to %d: %w", version, version+1, err)
+		}
+		inventory["schemaVersion"] = version + 1
+	}
+
+	return json.Marshal(inventory)
+}
+
+// migrateInventoryV0 migrates inventories written before the schema version
+// was recorded.  Their fields are unchanged in version 1 - the availability
+// zones may be missing, in which case the subnet, route table and elastic IP
+// lists are used as before.
+func migrateInventoryV0(inventory map[string]interface{}) error {
+	return nil
+}
+
+// migrateInventoryV1 migrates inventories that recorded NAT gateway IDs only
+// on the availability zones.  The IDs are copied to the natGatewayIDs list.
+// If there are none but a VPC was created, the NAT gateways are marked as
+// untracked so those in the VPC are deleted as before.  The EBS storage addon
+// was always installed along with the storage management role so it is
+// recorded if the role was created.  Routes and route table associations
+// weren't recorded - they are removed along with the route tables.
+func migrateInventoryV1(inventory map[string]interface{}) error {
+	var natGatewayIDs []interface{}
+	if availabilityZones, ok := inventory["availabilityZones"].([]interface{}); ok {
+		for _, availabilityZone := range availabilityZones {
+			az, ok := availabilityZone.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid availability zone in inventory: %v", availabilityZone)
+			}
+			if natGatewayID, ok := az["natGatewayID"].(string); ok && natGatewayID != "" {
+				natGatewayIDs = append(natGatewayIDs, natGatewayID)
+			}
+		}
+	}
+	inventory["natGatewayIDs"] = natGatewayIDs
+	if vpcID, ok := inventory["vpcID"].(string); ok && vpcID != "" && len(natGatewayIDs) == 0 {
+		inventory["natGatewaysUntracked"] = true
+	}
+
+	var addonNames []interface{}
+	cluster, _ := inventory["cluster"].(map[string]interface{})
+	storageManagementRole, _ := inventory["storageManagementRole"].(map[string]interface{})
+	if cluster != nil && storageManagementRole != nil {
+		clusterName, _ := cluster["clusterName"].(string)
+		roleName, _ := storageManagementRole["roleName"].(string)
+		if clusterName != "" && roleName != "" {
+			addonNames = append(addonNames, EBSStorageAddonName)
+		}
+	}
+	inventory["addonNames"] = addonNames
+
+	return nil
+}
+
+// migrateInventoryV2 migrates inventories written before resources that must
+// be deleted before the VPC were recorded: VPC endpoints and their security
+// group, the egress-only internet gateway and its routes, the flow log along
+// with its log group and role, the transit gateway attachment, the VPC peering
+// connection and custom network ACLs.  eks-cluster versions that only read
+// version 2 would ignore those fields, leave the resources behind on delete and
+// then fail to delete the VPC, so version 3 makes them reject the inventory
+// instead.  Version 2 inventories have none of those resources, so their
+// fields are unchanged.
+func migrateInventoryV2(inventory map[string]interface{}) error {
 	return nil
 }
@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// ErrInventoryNotFound is returned when an inventory backend has no inventory
+// stored.
+var ErrInventoryNotFound = errors.New("inventory not found")
+
+// InventoryBackend stores a resource inventory so that resources created on
+// one machine can be managed from another.  A lock prevents two operations
+// from using the same inventory at the same time.
+type InventoryBackend interface {
+	// ReadInventory returns the stored inventory.  If no inventory is stored
+	// ErrInventoryNotFound is returned.  If a backup of the previous inventory
+	// was read instead, it is returned along with an
+	// InventoryBackupUsedError.
+	ReadInventory(ctx context.Context) (*ResourceInventory, error)
+
+	// WriteInventory stores the inventory, replacing any stored inventory.
+	WriteInventory(ctx context.Context, inventory *ResourceInventory) error
+
+	// DeleteInventory removes the stored inventory.  It returns without error
+	// if no inventory is stored.
+	DeleteInventory(ctx context.Context) error
+
+	// Lock acquires the lock on the inventory.  If another lock is held an
+	// InventoryLockedError is returned.
+	Lock(ctx context.Context, lock *InventoryLock) error
+
+	// Unlock releases the lock on the inventory.  It returns without error if
+	// the lock is not held.
+	Unlock(ctx context.Context, lock *InventoryLock) error
+}
+
+// InventoryLock records who holds the lock on an inventory.
+type InventoryLock struct {
+	// A unique ID for the lock.
+	ID string `json:"id"`
+
+	// The operation the lock was acquired for, e.g. create or delete.
+	Operation string `json:"operation"`
+
+	// The user and host that acquired the lock.
+	Who string `json:"who"`
+
+	// The time the lock was acquired.
+	Created time.Time `json:"created"`
+}
+
+// NewInventoryLock returns a lock with a new ID for the given operation.
+func NewInventoryLock(operation string) (*InventoryLock, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate inventory lock ID: %w", err)
+	}
+
+	who := "unknown"
+	if currentUser, err := user.Current(); err == nil {
+		who = currentUser.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		who = fmt.Sprintf("%s@%s", who, hostname)
+	}
+
+	return &InventoryLock{
+		ID:        hex.EncodeToString(idBytes),
+		Operation: operation,
+		Who:       who,
+		Created:   time.Now().UTC(),
+	}, nil
+}
+
+// InventoryLockedError is returned when acquiring the lock on an inventory
+// that is already locked.
+type InventoryLockedError struct {
+	// The lock that is held.  Nil if the backend could not report it.
+	Lock *InventoryLock
+
+	// Where the lock is held, e.g. the lock file or object, so that a stale
+	// lock can be found and removed.  Empty if the backend could not report
+	// it.
+	Location string
+}
+
+// Error returns the details of the lock that is held and where it is held.
+func (e *InventoryLockedError) Error() string {
+	var location string
+	if e.Location != "" {
+		location = fmt.Sprintf(", held in %s", e.Location)
+	}
+	if e.Lock == nil {
+		return fmt.Sprintf("inventory is locked by another operation (lock ID unknown%s)", location)
+	}
+
+	return fmt.Sprintf(
+		"inventory is locked by %s for %s since %s (lock ID %s%s)",
+		e.Lock.Who, e.Lock.Operation, e.Lock.Created.Format(time.RFC3339), e.Lock.ID, location,
+	)
+}
+
+// NewInventoryBackend returns the inventory backend for a location.  One of:
+// * s3://bucket/key for an S3 or S3-compatible object store
+// * http://host/path or https://host/path for an HTTP backend
+// * a local file path
+// The AWS config is only used by the S3 backend and may be nil otherwise.
+func NewInventoryBackend(location string, awsConfig *aws.Config) (InventoryBackend, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		if awsConfig == nil {
+			return nil, fmt.Errorf("AWS config required for S3 inventory %s", location)
+		}
+		return NewS3InventoryBackend(location, awsConfig)
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return NewHTTPInventoryBackend(location)
+	}
+
+	return &FileInventoryBackend{Path: location}, nil
+}
+
+// RedactInventoryLocation returns an inventory location that is safe to
+// print.  The password in an HTTP backend's URL is redacted.
+func RedactInventoryLocation(location string) string {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return location
+	}
+
+	return (&HTTPInventoryBackend{Address: location}).location()
+}
+
+// IsS3InventoryLocation returns true if the inventory location refers to an
+// S3 backend, which needs AWS credentials to use.
+func IsS3InventoryLocation(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// FileInventoryBackend stores the inventory in a local file.  The lock is a
+// second file alongside it with a .lock extension.
+type FileInventoryBackend struct {
+	// The path to the inventory file.
+	Path string
+}
+
+// ReadInventory reads the inventory from the inventory file, or its backup if
+// the inventory file can't be read.
+func (b *FileInventoryBackend) ReadInventory(ctx context.Context) (*ResourceInventory, error) {
+	inventory, err := ReadInventory(b.Path)
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrInventoryNotFound, b.Path)
+	}
+
+	return inventory, err
+}
+
+// WriteInventory writes the inventory to the inventory file.
+func (b *FileInventoryBackend) WriteInventory(ctx context.Context, inventory *ResourceInventory) error {
+	return WriteInventory(b.Path, inventory)
+}
+
+// DeleteInventory removes the inventory file and its backup.
+func (b *FileInventoryBackend) DeleteInventory(ctx context.Context) error {
+	return RemoveInventory(b.Path)
+}
+
+// Lock creates the lock file.  It fails if the lock file already exists.  If
+// the lock can't be written the lock file is removed again.
+func (b *FileInventoryBackend) Lock(ctx context.Context, lock *InventoryLock) error {
+	lockJSON, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := os.OpenFile(b.lockPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			existingLock, _ := b.readLock()
+			return &InventoryLockedError{Lock: existingLock, Location: b.lockPath()}
+		}
+		return fmt.Errorf("failed to create inventory lock file %s: %w", b.lockPath(), err)
+	}
+
+	// remove the lock file if the lock can't be written - an empty lock file
+	// would otherwise block every later operation with an unknown lock ID
+	_, err = lockFile.Write(lockJSON)
+	if closeErr := lockFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(b.lockPath())
+		return fmt.Errorf("failed to write inventory lock file %s: %w", b.lockPath(), err)
+	}
+
+	return nil
+}
+
+// Unlock removes the lock file if it holds the given lock.
+func (b *FileInventoryBackend) Unlock(ctx context.Context, lock *InventoryLock) error {
+	existingLock, err := b.readLock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if existingLock.ID != lock.ID {
+		return &InventoryLockedError{Lock: existingLock, Location: b.lockPath()}
+	}
+
+	if err := os.Remove(b.lockPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove inventory lock file %s: %w", b.lockPath(), err)
+	}
+
+	return nil
+}
+
+// lockPath returns the path to the lock file.
+func (b *FileInventoryBackend) lockPath() string {
+	return b.Path + ".lock"
+}
+
+// readLock reads the lock from the lock file.
+func (b *FileInventoryBackend) readLock() (*InventoryLock, error) {
+	lockBytes, err := os.ReadFile(b.lockPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var lock InventoryLock
+	if err := json.Unmarshal(lockBytes, &lock); err != nil {
+		return nil, fmt.Errorf("failed to read inventory lock file %s: %w", b.lockPath(), err)
+	}
+
+	return &lock, nil
+}
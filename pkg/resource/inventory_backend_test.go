@@ -0,0 +1,46 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+func TestFileInventoryBackendLock(t *testing.T) {
+	ctx := context.Background()
+	b := resource.FileInventoryBackend{Path: filepath.Join(t.TempDir(), "inventory.json")}
+	lockPath := b.Path + ".lock"
+
+	if err := b.Lock(ctx, testLock("mine", "create")); err != nil {
+		t.Fatalf("expected lock to be acquired, got %v", err)
+	}
+
+	// the lock is held until it is released by the same lock ID
+	var lockedErr *resource.InventoryLockedError
+	if err := b.Lock(ctx, testLock("theirs", "delete")); !errors.As(err, &lockedErr) {
+		t.Fatalf("expected InventoryLockedError, got %v", err)
+	}
+	if lockedErr.Location != lockPath || lockedErr.Lock == nil || lockedErr.Lock.ID != "mine" {
+		t.Errorf("expected lock mine in %s, got %+v in %s", lockPath, lockedErr.Lock, lockedErr.Location)
+	}
+	if err := b.Unlock(ctx, testLock("theirs", "delete")); !errors.As(err, &lockedErr) {
+		t.Errorf("expected InventoryLockedError unlocking another lock, got %v", err)
+	}
+
+	if err := b.Unlock(ctx, testLock("mine", "create")); err != nil {
+		t.Fatalf("failed to unlock: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected lock file to be removed, got %v", err)
+	}
+	if err := b.Unlock(ctx, testLock("mine", "create")); err != nil {
+		t.Errorf("expected unlocking twice to succeed, got %v", err)
+	}
+	if err := b.Lock(ctx, testLock("theirs", "delete")); err != nil {
+		t.Errorf("expected lock to be acquired after unlock, got %v", err)
+	}
+}
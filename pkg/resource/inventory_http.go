@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// HTTPInventoryLockMethod is the HTTP method used to lock an inventory.
+	HTTPInventoryLockMethod = "LOCK"
+
+	// HTTPInventoryUnlockMethod is the HTTP method used to unlock an
+	// inventory.
+	HTTPInventoryUnlockMethod = "UNLOCK"
+)
+
+// HTTPInventoryBackend stores the inventory with a generic HTTP server.  The
+// inventory is read with GET, written with PUT and deleted with DELETE on the
+// address.  The lock is acquired with a LOCK request and released with an
+// UNLOCK request on the address, each with the lock as a JSON body.  The
+// server responds to a LOCK request for an inventory that is already locked
+// with 423 Locked or 409 Conflict and the lock that is held as the body.
+// Basic auth credentials may be included in the address - the password is
+// redacted from errors.
+type HTTPInventoryBackend struct {
+	// The URL of the inventory.
+	Address string
+
+	// The HTTP client used to send requests.  If nil, http.DefaultClient is
+	// used.
+	HTTPClient *http.Client
+
+	// The parsed address.  Set by NewHTTPInventoryBackend, otherwise the
+	// address is parsed for each request.
+	addressURL *url.URL
+}
+
+// NewHTTPInventoryBackend returns an HTTP backend for the inventory at an
+// http:// or https:// address.
+func NewHTTPInventoryBackend(address string) (*HTTPInventoryBackend, error) {
+	addressURL, err := parseInventoryURL(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPInventoryBackend{
+		Address:    address,
+		addressURL: addressURL,
+	}, nil
+}
+
+// ReadInventory reads the inventory from the server.
+func (b *HTTPInventoryBackend) ReadInventory(ctx context.Context) (*ResourceInventory, error) {
+	inventoryBytes, status, err := b.do(ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory from %s: %w", b.location(), err)
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusNoContent:
+		return nil, fmt.Errorf("%w: %s", ErrInventoryNotFound, b.location())
+	default:
+		return nil, fmt.Errorf("failed to read inventory from %s: %s", b.location(), httpErrorMessage(status, inventoryBytes))
+	}
+
+	var inventory ResourceInventory
+	if err := UnmarshalInventory(inventoryBytes, &inventory); err != nil {
+		return nil, err
+	}
+
+	return &inventory, nil
+}
+
+// WriteInventory writes the inventory to the server.
+func (b *HTTPInventoryBackend) WriteInventory(ctx context.Context, inventory *ResourceInventory) error {
+	inventoryJSON, err := MarshalInventory(inventory)
+	if err != nil {
+		return err
+	}
+
+	respBytes, status, err := b.do(ctx, http.MethodPut, inventoryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write inventory to %s: %w", b.location(), err)
+	}
+	if !httpSuccess(status) {
+		return fmt.Errorf("failed to write inventory to %s: %s", b.location(), httpErrorMessage(status, respBytes))
+	}
+
+	return nil
+}
+
+// DeleteInventory deletes the inventory from the server.
+func (b *HTTPInventoryBackend) DeleteInventory(ctx context.Context) error {
+	respBytes, status, err := b.do(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete inventory from %s: %w", b.location(), err)
+	}
+	if !httpSuccess(status) && status != http.StatusNotFound {
+		return fmt.Errorf("failed to delete inventory from %s: %s", b.location(), httpErrorMessage(status, respBytes))
+	}
+
+	return nil
+}
+
+// Lock sends a LOCK request to the server.
+func (b *HTTPInventoryBackend) Lock(ctx context.Context, lock *InventoryLock) error {
+	lockJSON, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	respBytes, status, err := b.do(ctx, HTTPInventoryLockMethod, lockJSON)
+	if err != nil {
+		return fmt.Errorf("failed to lock inventory at %s: %w", b.location(), err)
+	}
+	switch {
+	case httpSuccess(status):
+		return nil
+	case status == http.StatusLocked || status == http.StatusConflict:
+		var existingLock InventoryLock
+		if err := json.Unmarshal(respBytes, &existingLock); err != nil || existingLock.ID == "" {
+			return &InventoryLockedError{Location: b.location()}
+		}
+		return &InventoryLockedError{Lock: &existingLock, Location: b.location()}
+	}
+
+	return fmt.Errorf("failed to lock inventory at %s: %s", b.location(), httpErrorMessage(status, respBytes))
+}
+
+// Unlock sends an UNLOCK request to the server.
+func (b *HTTPInventoryBackend) Unlock(ctx context.Context, lock *InventoryLock) error {
+	lockJSON, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	respBytes, status, err := b.do(ctx, HTTPInventoryUnlockMethod, lockJSON)
+	if err != nil {
+		return fmt.Errorf("failed to unlock inventory at %s: %w", b.location(), err)
+	}
+	if !httpSuccess(status) && status != http.StatusNotFound {
+		return fmt.Errorf("failed to unlock inventory at %s: %s", b.location(), httpErrorMessage(status, respBytes))
+	}
+
+	return nil
+}
+
+// do sends a request to the inventory address and returns the response body
+// and status code.
+func (b *HTTPInventoryBackend) do(ctx context.Context, method string, body []byte) ([]byte, int, error) {
+	addressURL, err := b.parsedAddress()
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, addressURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := b.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	return respBytes, resp.StatusCode, nil
+}
+
+// parsedAddress returns the parsed address.
+func (b *HTTPInventoryBackend) parsedAddress() (*url.URL, error) {
+	if b.addressURL != nil {
+		return b.addressURL, nil
+	}
+
+	return parseInventoryURL(b.Address)
+}
+
+// location returns the address with the password redacted for use in errors
+// and messages.
+func (b *HTTPInventoryBackend) location() string {
+	addressURL, err := b.parsedAddress()
+	if err != nil {
+		return "invalid inventory URL"
+	}
+
+	return addressURL.Redacted()
+}
+
+// parseInventoryURL parses the address of an HTTP inventory.  The error
+// doesn't include the address as it may contain a password.
+func parseInventoryURL(address string) (*url.URL, error) {
+	addressURL, err := url.Parse(address)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid inventory URL: %w", err)
+	}
+
+	return addressURL, nil
+}
+
+// httpSuccess returns true for a 2xx status code.
+func httpSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+// httpErrorMessage returns a message for an unexpected HTTP response.
+func httpErrorMessage(status int, body []byte) string {
+	message := strings.TrimSpace(string(body))
+	if len(message) > 512 {
+		message = message[:512]
+	}
+
+	return fmt.Sprintf("unexpected response status %d: %s", status, message)
+}
@@ -0,0 +1,248 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// S3EndpointEnv is the environment variable that sets the endpoint for an
+// S3-compatible object store, e.g. http://localhost:9000 for a local MinIO
+// server.  If set, requests use path-style addressing.
+const S3EndpointEnv = "AWS_ENDPOINT_URL_S3"
+
+// S3InventoryBackend stores the inventory as an object in an S3 bucket or an
+// S3-compatible object store.  The lock is a second object alongside it with a
+// .lock suffix that is created with a conditional write so only one lock can
+// be held.
+type S3InventoryBackend struct {
+	// The bucket the inventory is stored in.
+	Bucket string
+
+	// The object key for the inventory.
+	Key string
+
+	// The S3 API used to read and write objects.
+	Client S3API
+}
+
+// NewS3InventoryBackend returns an S3 backend for a location of the form
+// s3://bucket/key.  The region can be set with a region query parameter,
+// e.g. s3://bucket/key?region=us-west-2, and otherwise defaults to the AWS
+// config region.  The endpoint is read from S3EndpointEnv.
+func NewS3InventoryBackend(location string, awsConfig *aws.Config) (*S3InventoryBackend, error) {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 inventory location %s: %w", location, err)
+	}
+	key := strings.TrimPrefix(locationURL.Path, "/")
+	if locationURL.Host == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 inventory location %s, must be of the form s3://bucket/key", location)
+	}
+
+	region := locationURL.Query().Get("region")
+	if region == "" {
+		region = awsConfig.Region
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv(S3EndpointEnv)
+
+	client := s3.NewFromConfig(*awsConfig, func(options *s3.Options) {
+		options.Region = region
+		if endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+			options.UsePathStyle = true
+		}
+	})
+
+	return &S3InventoryBackend{
+		Bucket: locationURL.Host,
+		Key:    key,
+		Client: client,
+	}, nil
+}
+
+// ReadInventory reads the inventory object.
+func (b *S3InventoryBackend) ReadInventory(ctx context.Context) (*ResourceInventory, error) {
+	inventoryBytes, err := b.getObject(ctx, b.Key)
+	if err != nil {
+		if s3ObjectNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrInventoryNotFound, b.location(b.Key))
+		}
+		return nil, fmt.Errorf("failed to read inventory from %s: %w", b.location(b.Key), err)
+	}
+
+	var inventory ResourceInventory
+	if err := UnmarshalInventory(inventoryBytes, &inventory); err != nil {
+		return nil, err
+	}
+
+	return &inventory, nil
+}
+
+// WriteInventory writes the inventory object.
+func (b *S3InventoryBackend) WriteInventory(ctx context.Context, inventory *ResourceInventory) error {
+	inventoryJSON, err := MarshalInventory(inventory)
+	if err != nil {
+		return err
+	}
+
+	putObjectInput := s3.PutObjectInput{
+		Bucket:      aws.String(b.Bucket),
+		Key:         aws.String(b.Key),
+		Body:        bytes.NewReader(inventoryJSON),
+		ContentType: aws.String("application/json"),
+	}
+	if _, err := b.Client.PutObject(ctx, &putObjectInput); err != nil {
+		return fmt.Errorf("failed to write inventory to %s: %w", b.location(b.Key), err)
+	}
+
+	return nil
+}
+
+// DeleteInventory deletes the inventory object.
+func (b *S3InventoryBackend) DeleteInventory(ctx context.Context) error {
+	return b.deleteObject(ctx, b.Key)
+}
+
+// Lock creates the lock object if it doesn't already exist.  The object is
+// written with If-None-Match so that S3 rejects the write if another lock
+// object exists.
+func (b *S3InventoryBackend) Lock(ctx context.Context, lock *InventoryLock) error {
+	lockJSON, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	putObjectInput := s3.PutObjectInput{
+		Bucket:      aws.String(b.Bucket),
+		Key:         aws.String(b.lockKey()),
+		Body:        bytes.NewReader(lockJSON),
+		ContentType: aws.String("application/json"),
+		IfNoneMatch: aws.String("*"),
+	}
+	if _, err := b.Client.PutObject(ctx, &putObjectInput); err != nil {
+		if !s3PreconditionFailed(err) {
+			return fmt.Errorf("failed to lock inventory at %s: %w", b.location(b.Key), err)
+		}
+
+		// a write that succeeded but was retried fails its precondition on
+		// its own lock, which is then already held
+		existingLock, _ := b.readLock(ctx)
+		if existingLock != nil && existingLock.ID == lock.ID {
+			return nil
+		}
+		return &InventoryLockedError{Lock: existingLock, Location: b.location(b.lockKey())}
+	}
+
+	return nil
+}
+
+// Unlock deletes the lock object if it holds the given lock.
+func (b *S3InventoryBackend) Unlock(ctx context.Context, lock *InventoryLock) error {
+	existingLock, err := b.readLock(ctx)
+	if err != nil {
+		return err
+	}
+	if existingLock == nil {
+		return nil
+	}
+	if existingLock.ID != lock.ID {
+		return &InventoryLockedError{Lock: existingLock, Location: b.location(b.lockKey())}
+	}
+
+	return b.deleteObject(ctx, b.lockKey())
+}
+
+// lockKey returns the object key for the lock.
+func (b *S3InventoryBackend) lockKey() string {
+	return b.Key + ".lock"
+}
+
+// location returns the s3:// location of an object in the bucket.
+func (b *S3InventoryBackend) location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", b.Bucket, key)
+}
+
+// readLock reads the lock object.  If there is no lock object it returns nil
+// without error.
+func (b *S3InventoryBackend) readLock(ctx context.Context) (*InventoryLock, error) {
+	lockBytes, err := b.getObject(ctx, b.lockKey())
+	if err != nil {
+		if s3ObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read inventory lock from %s: %w", b.location(b.lockKey()), err)
+	}
+
+	var lock InventoryLock
+	if err := json.Unmarshal(lockBytes, &lock); err != nil {
+		return nil, fmt.Errorf("failed to read inventory lock from %s: %w", b.location(b.lockKey()), err)
+	}
+
+	return &lock, nil
+}
+
+// getObject returns the contents of an object in the bucket.
+func (b *S3InventoryBackend) getObject(ctx context.Context, key string) ([]byte, error) {
+	getObjectInput := s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(key),
+	}
+	resp, err := b.Client.GetObject(ctx, &getObjectInput)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// deleteObject deletes an object from the bucket.  S3 returns success for
+// objects that don't exist.
+func (b *S3InventoryBackend) deleteObject(ctx context.Context, key string) error {
+	deleteObjectInput := s3.DeleteObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(key),
+	}
+	if _, err := b.Client.DeleteObject(ctx, &deleteObjectInput); err != nil && !s3ObjectNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %w", b.location(key), err)
+	}
+
+	return nil
+}
+
+// s3ObjectNotFound returns true if an S3 error is for an object that doesn't
+// exist.  S3-compatible object stores don't all use the same error code.
+func s3ObjectNotFound(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+
+	return ae.ErrorCode() == "NoSuchKey" || ae.ErrorCode() == "NotFound"
+}
+
+// s3PreconditionFailed returns true if an S3 error is for a conditional write
+// that was rejected because the object exists.  S3 returns a conflict instead
+// if another conditional write to the object is in progress.
+func s3PreconditionFailed(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+
+	return ae.ErrorCode() == "PreconditionFailed" || ae.ErrorCode() == "ConditionalRequestConflict"
+}
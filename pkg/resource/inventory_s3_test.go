@@ -0,0 +1,286 @@
+package resource_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+const (
+	testBucket  = "inventories"
+	testKey     = "clusters/dev.json"
+	testLockKey = testKey + ".lock"
+)
+
+// newS3InventoryBackend returns an S3 inventory backend that stores objects
+// in a fake backend.
+func newS3InventoryBackend() (*resource.S3InventoryBackend, *fake.Backend) {
+	backend := fake.NewBackend("us-east-1")
+
+	return &resource.S3InventoryBackend{
+		Bucket: testBucket,
+		Key:    testKey,
+		Client: backend.S3,
+	}, backend
+}
+
+// putLock writes a lock object directly, as another operation would.
+func putLock(t *testing.T, backend *fake.Backend, lock *resource.InventoryLock) {
+	t.Helper()
+
+	lockJSON, err := json.Marshal(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = backend.S3.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(testBucket),
+		Key:    aws.String(testLockKey),
+		Body:   bytes.NewReader(lockJSON),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getLock reads the lock object directly.  It returns nil if there is none.
+func getLock(t *testing.T, backend *fake.Backend) *resource.InventoryLock {
+	t.Helper()
+
+	resp, err := backend.S3.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(testBucket),
+		Key:    aws.String(testLockKey),
+	})
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	var lock resource.InventoryLock
+	if err := json.NewDecoder(resp.Body).Decode(&lock); err != nil {
+		t.Fatal(err)
+	}
+
+	return &lock
+}
+
+func testLock(id, operation string) *resource.InventoryLock {
+	return &resource.InventoryLock{
+		ID:        id,
+		Operation: operation,
+		Who:       "tester@host",
+		Created:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestS3InventoryBackendLock(t *testing.T) {
+	testCases := []struct {
+		name         string
+		existingLock *resource.InventoryLock
+		putErr       error
+		wantLocked   bool
+		wantHeldBy   string
+		wantErr      bool
+		wantLockID   string
+	}{
+		{
+			name:       "unlocked",
+			wantLockID: "mine",
+		},
+		{
+			name:         "locked by another operation",
+			existingLock: testLock("theirs", "delete"),
+			wantLocked:   true,
+			wantHeldBy:   "theirs",
+			wantLockID:   "theirs",
+		},
+		{
+			name:         "retried write of the same lock",
+			existingLock: testLock("mine", "create"),
+			wantLockID:   "mine",
+		},
+		{
+			name:       "conflicting conditional write in progress",
+			putErr:     &smithy.GenericAPIError{Code: "ConditionalRequestConflict", Message: "conflict"},
+			wantLocked: true,
+		},
+		{
+			name:    "write fails",
+			putErr:  &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, backend := newS3InventoryBackend()
+			if tc.existingLock != nil {
+				putLock(t, backend, tc.existingLock)
+			}
+			if tc.putErr != nil {
+				backend.Fail("PutObject", tc.putErr)
+			}
+
+			err := b.Lock(context.Background(), testLock("mine", "create"))
+
+			var lockedErr *resource.InventoryLockedError
+			switch {
+			case tc.wantLocked:
+				if !errors.As(err, &lockedErr) {
+					t.Fatalf("expected InventoryLockedError, got %v", err)
+				}
+				if lockedErr.Location != "s3://"+testBucket+"/"+testLockKey {
+					t.Errorf("expected lock location of lock object, got %q", lockedErr.Location)
+				}
+				if tc.wantHeldBy != "" && (lockedErr.Lock == nil || lockedErr.Lock.ID != tc.wantHeldBy) {
+					t.Errorf("expected lock held by %s, got %+v", tc.wantHeldBy, lockedErr.Lock)
+				}
+			case tc.wantErr:
+				if err == nil || errors.As(err, &lockedErr) {
+					t.Fatalf("expected write error, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("expected lock to be acquired, got %v", err)
+				}
+			}
+
+			lock := getLock(t, backend)
+			switch {
+			case tc.wantLockID == "" && lock != nil:
+				t.Errorf("expected no lock object, got lock %s", lock.ID)
+			case tc.wantLockID != "" && (lock == nil || lock.ID != tc.wantLockID):
+				t.Errorf("expected lock object for lock %s, got %+v", tc.wantLockID, lock)
+			}
+		})
+	}
+}
+
+func TestS3InventoryBackendLockConditionalWrite(t *testing.T) {
+	b, backend := newS3InventoryBackend()
+
+	// only one of many concurrent locks may be acquired
+	const lockers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, lockers)
+	for i := 0; i < lockers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lock, err := resource.NewInventoryLock("create")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = b.Lock(context.Background(), lock)
+		}(i)
+	}
+	wg.Wait()
+
+	var acquired int
+	for _, err := range errs {
+		var lockedErr *resource.InventoryLockedError
+		switch {
+		case err == nil:
+			acquired++
+		case !errors.As(err, &lockedErr):
+			t.Errorf("expected InventoryLockedError, got %v", err)
+		}
+	}
+	if acquired != 1 {
+		t.Errorf("expected exactly one lock to be acquired, got %d", acquired)
+	}
+	if keys := backend.S3.Objects(testBucket); len(keys) != 1 || keys[0] != testLockKey {
+		t.Errorf("expected only the lock object, got %v", keys)
+	}
+}
+
+func TestS3InventoryBackendUnlock(t *testing.T) {
+	testCases := []struct {
+		name         string
+		existingLock *resource.InventoryLock
+		wantLocked   bool
+		wantLockID   string
+	}{
+		{
+			name: "not locked",
+		},
+		{
+			name:         "locked by this operation",
+			existingLock: testLock("mine", "create"),
+		},
+		{
+			name:         "locked by another operation",
+			existingLock: testLock("theirs", "delete"),
+			wantLocked:   true,
+			wantLockID:   "theirs",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, backend := newS3InventoryBackend()
+			if tc.existingLock != nil {
+				putLock(t, backend, tc.existingLock)
+			}
+
+			err := b.Unlock(context.Background(), testLock("mine", "create"))
+
+			var lockedErr *resource.InventoryLockedError
+			if tc.wantLocked != errors.As(err, &lockedErr) {
+				t.Fatalf("expected locked error %t, got %v", tc.wantLocked, err)
+			}
+			if !tc.wantLocked && err != nil {
+				t.Fatalf("expected unlock to succeed, got %v", err)
+			}
+
+			lock := getLock(t, backend)
+			switch {
+			case tc.wantLockID == "" && lock != nil:
+				t.Errorf("expected lock object to be removed, got lock %s", lock.ID)
+			case tc.wantLockID != "" && (lock == nil || lock.ID != tc.wantLockID):
+				t.Errorf("expected lock object for lock %s to remain, got %+v", tc.wantLockID, lock)
+			}
+		})
+	}
+}
+
+func TestS3InventoryBackendInventory(t *testing.T) {
+	b, _ := newS3InventoryBackend()
+	ctx := context.Background()
+
+	if _, err := b.ReadInventory(ctx); !errors.Is(err, resource.ErrInventoryNotFound) {
+		t.Fatalf("expected ErrInventoryNotFound before write, got %v", err)
+	}
+
+	inventory := resource.ResourceInventory{Region: "us-east-2", VPCID: "vpc-0123"}
+	if err := b.WriteInventory(ctx, &inventory); err != nil {
+		t.Fatal(err)
+	}
+	readInventory, err := b.ReadInventory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readInventory.VPCID != inventory.VPCID || readInventory.SchemaVersion != resource.InventorySchemaVersion {
+		t.Errorf("expected written inventory at the current schema version, got %+v", readInventory)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := b.DeleteInventory(ctx); err != nil {
+			t.Fatalf("delete %d: %v", i+1, err)
+		}
+	}
+	if _, err := b.ReadInventory(ctx); !errors.Is(err, resource.ErrInventoryNotFound) {
+		t.Fatalf("expected ErrInventoryNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package resource_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+func TestUnmarshalInventory(t *testing.T) {
+	testCases := []struct {
+		name          string
+		inventoryJSON string
+		want          resource.ResourceInventory
+		wantErr       string
+		wantErrIs     error
+	}{
+		{
+			name: "version 0",
+			inventoryJSON: `{
+				"vpcID": "vpc-1",
+				"availabilityZones": [{"zone": "us-east-2a", "natGatewayID": "nat-1"}],
+				"cluster": {"clusterName": "test"},
+				"storageManagementRole": {"roleName": "test-storage"}
+			}`,
+			want: resource.ResourceInventory{
+				VPCID:                 "vpc-1",
+				AvailabilityZones:     []resource.AvailabilityZone{{Zone: "us-east-2a", NATGatewayID: "nat-1"}},
+				NATGatewayIDs:         []string{"nat-1"},
+				Cluster:               resource.ClusterInventory{ClusterName: "test"},
+				StorageManagementRole: resource.RoleInventory{RoleName: "test-storage"},
+				AddonNames:            []string{resource.EBSStorageAddonName},
+			},
+		},
+		{
+			name:          "version 0 without NAT gateway IDs",
+			inventoryJSON: `{"vpcID": "vpc-1"}`,
+			want: resource.ResourceInventory{
+				VPCID:                "vpc-1",
+				NATGatewaysUntracked: true,
+			},
+		},
+		{
+			name:          "version 2",
+			inventoryJSON: `{"schemaVersion": 2, "vpcID": "vpc-1", "natGatewayIDs": ["nat-1"]}`,
+			want: resource.ResourceInventory{
+				VPCID:         "vpc-1",
+				NATGatewayIDs: []string{"nat-1"},
+			},
+		},
+		{
+			name:          "null schema version",
+			inventoryJSON: `{"schemaVersion": null}`,
+		},
+		{
+			name:          "current version",
+			inventoryJSON: fmt.Sprintf(`{"schemaVersion": %d, "vpcID": "vpc-1", "natGatewayIDs": ["nat-1"]}`, resource.InventorySchemaVersion),
+			want: resource.ResourceInventory{
+				VPCID:         "vpc-1",
+				NATGatewayIDs: []string{"nat-1"},
+			},
+		},
+		{
+			name:          "newer version",
+			inventoryJSON: fmt.Sprintf(`{"schemaVersion": %d}`, resource.InventorySchemaVersion+1),
+			wantErr:       "use a newer version of eks-cluster",
+			wantErrIs:     resource.ErrInventoryVersionUnsupported,
+		},
+		{
+			name:          "negative version",
+			inventoryJSON: `{"schemaVersion": -1}`,
+			wantErr:       "invalid inventory schema version: -1",
+		},
+		{
+			name:          "fractional version",
+			inventoryJSON: `{"schemaVersion": 1.5}`,
+			wantErr:       "invalid inventory schema version: 1.5",
+		},
+		{
+			name:          "string version",
+			inventoryJSON: `{"schemaVersion": "1"}`,
+			wantErr:       "invalid inventory schema version: 1",
+		},
+		{
+			name:          "null",
+			inventoryJSON: `null`,
+			wantErr:       "invalid inventory: not a JSON object",
+		},
+		{
+			name:          "array",
+			inventoryJSON: `[]`,
+			wantErr:       "cannot unmarshal array",
+		},
+		{
+			name:          "string",
+			inventoryJSON: `"inventory"`,
+			wantErr:       "cannot unmarshal string",
+		},
+		{
+			name:          "invalid JSON",
+			inventoryJSON: `{"vpcID":`,
+			wantErr:       "unexpected end of JSON input",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var inventory resource.ResourceInventory
+			err := resource.UnmarshalInventory([]byte(tc.inventoryJSON), &inventory)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				if tc.wantErrIs != nil && !errors.Is(err, tc.wantErrIs) {
+					t.Errorf("expected %v, got %v", tc.wantErrIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to unmarshal inventory: %v", err)
+			}
+
+			// migrated inventories are read at the current version
+			tc.want.SchemaVersion = resource.InventorySchemaVersion
+			if !reflect.DeepEqual(inventory, tc.want) {
+				t.Errorf("expected inventory %+v, got %+v", tc.want, inventory)
+			}
+		})
+	}
+}
+
+func TestMarshalInventory(t *testing.T) {
+	inventory := resource.ResourceInventory{VPCID: "vpc-1", NATGatewayIDs: []string{"nat-1"}}
+	inventoryJSON, err := resource.MarshalInventory(&inventory)
+	if err != nil {
+		t.Fatalf("failed to marshal inventory: %v", err)
+	}
+
+	var unmarshalled resource.ResourceInventory
+	if err := resource.UnmarshalInventory(inventoryJSON, &unmarshalled); err != nil {
+		t.Fatalf("failed to unmarshal inventory: %v", err)
+	}
+	inventory.SchemaVersion = resource.InventorySchemaVersion
+	if !reflect.DeepEqual(unmarshalled, inventory) {
+		t.Errorf("expected inventory %+v, got %+v", inventory, unmarshalled)
+	}
+}
+
+func TestReadInventory(t *testing.T) {
+	testCases := []struct {
+		name          string
+		corrupt       bool
+		corruptBackup bool
+		wantVPCID     string
+		wantBackup    bool
+		wantErr       string
+	}{
+		{
+			name:      "inventory file",
+			wantVPCID: "vpc-2",
+		},
+		{
+			name:       "corrupt inventory file",
+			corrupt:    true,
+			wantVPCID:  "vpc-1",
+			wantBackup: true,
+		},
+		{
+			name:          "corrupt inventory file and backup",
+			corrupt:       true,
+			corruptBackup: true,
+			wantErr:       "inventory.json.bak",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inventoryFile := filepath.Join(t.TempDir(), "inventory.json")
+
+			// the first inventory is kept as a backup when the second is
+			// written
+			for _, vpcID := range []string{"vpc-1", "vpc-2"} {
+				if err := resource.WriteInventory(inventoryFile, &resource.ResourceInventory{VPCID: vpcID}); err != nil {
+					t.Fatalf("failed to write inventory: %v", err)
+				}
+			}
+			if tc.corrupt {
+				if err := os.WriteFile(inventoryFile, []byte("{"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.corruptBackup {
+				if err := os.WriteFile(resource.InventoryBackupFile(inventoryFile), []byte("null"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			inventory, err := resource.ReadInventory(inventoryFile)
+			var backupErr *resource.InventoryBackupUsedError
+			switch {
+			case tc.wantErr != "":
+				if inventory != nil || err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %+v, %v", tc.wantErr, inventory, err)
+				}
+				return
+			case tc.wantBackup:
+				if !errors.As(err, &backupErr) {
+					t.Fatalf("expected InventoryBackupUsedError, got %v", err)
+				}
+				if backupErr.BackupFile != resource.InventoryBackupFile(inventoryFile) {
+					t.Errorf("expected backup file %s, got %s", resource.InventoryBackupFile(inventoryFile), backupErr.BackupFile)
+				}
+			case err != nil:
+				t.Fatalf("failed to read inventory: %v", err)
+			}
+			if inventory == nil || inventory.VPCID != tc.wantVPCID {
+				t.Errorf("expected inventory with VPC %s, got %+v", tc.wantVPCID, inventory)
+			}
+		})
+	}
+}
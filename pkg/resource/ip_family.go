@@ -0,0 +1,156 @@
+package resource
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// IPFamily is the IP address family the cluster's pods and services are
+// assigned addresses from.
+type IPFamily string
+
+const (
+	// IPFamilyIPv4 gives pods and services IPv4 addresses.  This is the
+	// default.
+	IPFamilyIPv4 IPFamily = "ipv4"
+
+	// IPFamilyIPv6 gives pods and services IPv6 addresses.  The VPC and
+	// subnets are dual-stack, with an Amazon-provided IPv6 CIDR block, and
+	// the private subnets reach the internet over IPv6 through an egress-only
+	// internet gateway.
+	IPFamilyIPv6 IPFamily = "ipv6"
+)
+
+const (
+	// subnetIPv6PrefixLength is the prefix length of the IPv6 CIDR blocks
+	// assigned to subnets - AWS only allows /64s.
+	subnetIPv6PrefixLength = 64
+
+	// the destinations of the default routes for each IP family
+	ipv4DefaultRouteCIDR = "0.0.0.0/0"
+	ipv6DefaultRouteCIDR = "::/0"
+)
+
+// ipFamilyOrDefault returns the IP family, or the default IP family if it is
+// not set.
+func ipFamilyOrDefault(ipFamily IPFamily) IPFamily {
+	if ipFamily == "" {
+		return IPFamilyIPv4
+	}
+
+	return ipFamily
+}
+
+// checkIPFamily checks that the IP family in the resource config is valid.
+// IPv6 can't be used with an existing VPC as its subnets would need IPv6 CIDR
+// blocks and routes that eks-cluster doesn't manage.
+func (r *ResourceConfig) checkIPFamily() error {
+	switch r.IPFamily {
+	case "", IPFamilyIPv4, IPFamilyIPv6:
+	default:
+		return fmt.Errorf("IP family %q in resource config must be one of %s or %s",
+			r.IPFamily, IPFamilyIPv4, IPFamilyIPv6)
+	}
+	if r.IPFamily == IPFamilyIPv6 && r.UsesExistingVPC() {
+		return errors.New("IP family ipv6 cannot be used in resource config with an existing VPC")
+	}
+
+	return nil
+}
+
+// vpcIPv6CIDR returns the Amazon-provided IPv6 CIDR block associated with a
+// VPC, or an empty string if it doesn't have one yet.
+func vpcIPv6CIDR(vpc *types.Vpc) string {
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlock == nil || association.Ipv6CidrBlockState == nil ||
+			association.Ipv6CidrBlockState.State != types.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		return *association.Ipv6CidrBlock
+	}
+
+	return ""
+}
+
+// subnetIPv6CIDR returns the IPv6 CIDR block associated with a subnet, or an
+// empty string if it doesn't have one.
+func subnetIPv6CIDR(subnet types.Subnet) string {
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlock != nil {
+			return *association.Ipv6CidrBlock
+		}
+	}
+
+	return ""
+}
+
+// setSubnetIPv6CIDRs sets the IPv6 CIDR blocks for the private and public
+// subnets in each availability zone that doesn't already have them.  Each
+// subnet gets the lowest /64 in the VPC's IPv6 CIDR block that isn't already
+// used.
+func setSubnetIPv6CIDRs(vpcCIDR string, availabilityZones []AvailabilityZone) error {
+	prefix, err := netip.ParsePrefix(vpcCIDR)
+	if err != nil || !prefix.Addr().Is6() || prefix.Bits() > subnetIPv6PrefixLength {
+		return fmt.Errorf("VPC IPv6 CIDR %q is not a valid IPv6 CIDR block", vpcCIDR)
+	}
+	prefix = prefix.Masked()
+
+	used := make(map[netip.Prefix]bool)
+	for _, az := range availabilityZones {
+		for _, cidr := range []string{az.PrivateSubnetIPv6CIDR, az.PublicSubnetIPv6CIDR} {
+			if cidr == "" {
+				continue
+			}
+			subnetCIDR, err := netip.ParsePrefix(cidr)
+			if err != nil || subnetCIDR.Bits() != subnetIPv6PrefixLength || !prefix.Contains(subnetCIDR.Addr()) {
+				return fmt.Errorf("subnet IPv6 CIDR %q for availability zone %s is not a /%d in VPC IPv6 CIDR %s",
+					cidr, az.Zone, subnetIPv6PrefixLength, vpcCIDR)
+			}
+			used[subnetCIDR] = true
+		}
+	}
+
+	next := uint64(0)
+	nextCIDR := func() (string, error) {
+		for ; next < uint64(1)<<(subnetIPv6PrefixLength-prefix.Bits()); next++ {
+			cidr := ipv6SubnetCIDR(prefix, next)
+			if !used[cidr] {
+				used[cidr] = true
+				return cidr.String(), nil
+			}
+		}
+		return "", fmt.Errorf("no room left for a /%d subnet in VPC IPv6 CIDR %s", subnetIPv6PrefixLength, vpcCIDR)
+	}
+	for i := range availabilityZones {
+		if availabilityZones[i].PrivateSubnetIPv6CIDR == "" {
+			if availabilityZones[i].PrivateSubnetIPv6CIDR, err = nextCIDR(); err != nil {
+				return err
+			}
+		}
+		if availabilityZones[i].PublicSubnetIPv6CIDR == "" {
+			if availabilityZones[i].PublicSubnetIPv6CIDR, err = nextCIDR(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ipv6SubnetCIDR returns the index'th /64 in an IPv6 CIDR block.
+func ipv6SubnetCIDR(prefix netip.Prefix, index uint64) netip.Prefix {
+	a := prefix.Addr().As16()
+	binary.BigEndian.PutUint64(a[:8], binary.BigEndian.Uint64(a[:8])+index)
+
+	return netip.PrefixFrom(netip.AddrFrom16(a), subnetIPv6PrefixLength)
+}
+
+// isIPv6CIDR returns true if a CIDR block is an IPv6 CIDR block.
+func isIPv6CIDR(cidr string) bool {
+	return strings.Contains(cidr, ":")
+}
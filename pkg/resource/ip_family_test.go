@@ -0,0 +1,188 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// ipv6Config returns a resource config with the IPv6 IP family.
+func ipv6Config() *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.DesiredAZCount = 3
+	resourceConfig.IPFamily = resource.IPFamilyIPv6
+
+	return resourceConfig
+}
+
+func TestIPFamilyIPv6(t *testing.T) {
+	ctx := context.Background()
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, ipv6Config())
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if !plan.EgressOnlyInternetGateway || !plan.VPC.AmazonProvidedIPv6CIDR || plan.Cluster.IPFamily != resource.IPFamilyIPv6 {
+		t.Errorf("expected dual-stack VPC with egress-only internet gateway and IPv6 cluster in plan, got %+v", plan)
+	}
+
+	backend, c, inventory := createResourceStack(t, ipv6Config())
+	if inventory.IPFamily != resource.IPFamilyIPv6 || inventory.EgressOnlyInternetGatewayID == "" {
+		t.Errorf("expected IP family and egress-only internet gateway in inventory, got %s and %q",
+			inventory.IPFamily, inventory.EgressOnlyInternetGatewayID)
+	}
+
+	// each subnet gets its own /64 from the VPC's IPv6 CIDR block
+	subnetCIDRs := make(map[string]bool)
+	for _, az := range inventory.AvailabilityZones {
+		for _, cidr := range []string{az.PrivateSubnetIPv6CIDR, az.PublicSubnetIPv6CIDR} {
+			if !strings.HasSuffix(cidr, "/64") || subnetCIDRs[cidr] {
+				t.Errorf("expected a distinct /64 for each subnet in availability zone %s, got %q", az.Zone, cidr)
+			}
+			subnetCIDRs[cidr] = true
+		}
+	}
+	describeSubnetsOutput, err := backend.EC2.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subnet := range describeSubnetsOutput.Subnets {
+		if len(subnet.Ipv6CidrBlockAssociationSet) != 1 {
+			t.Errorf("expected subnet %s to have an IPv6 CIDR block, got %+v", *subnet.SubnetId,
+				subnet.Ipv6CidrBlockAssociationSet)
+		}
+	}
+
+	// the public route table sends IPv6 traffic to the internet gateway and
+	// the private route tables to the egress-only internet gateway
+	ipv6Routes := 0
+	for _, route := range inventory.Routes {
+		if route.DestinationCIDR != "::/0" {
+			continue
+		}
+		ipv6Routes++
+		if route.GatewayID != inventory.InternetGatewayID &&
+			route.EgressOnlyInternetGatewayID != inventory.EgressOnlyInternetGatewayID {
+			t.Errorf("expected IPv6 default route to an internet gateway, got %+v", route)
+		}
+	}
+	if want := len(inventory.PrivateRouteTableIDs) + 1; ipv6Routes != want {
+		t.Errorf("expected %d IPv6 default routes, got %d", want, ipv6Routes)
+	}
+
+	describeClusterInput := eks.DescribeClusterInput{Name: &inventory.Cluster.ClusterName}
+	describeClusterOutput, err := backend.EKS.DescribeCluster(ctx, &describeClusterInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipFamily := describeClusterOutput.Cluster.KubernetesNetworkConfig.IpFamily; string(ipFamily) != string(resource.IPFamilyIPv6) {
+		t.Errorf("expected cluster IP family %s, got %s", resource.IPFamilyIPv6, ipFamily)
+	}
+	var cniPolicy bool
+	for _, policyARN := range inventory.WorkerRole.RolePolicyARNs {
+		cniPolicy = cniPolicy || strings.Contains(policyARN, "CNIIPv6")
+	}
+	if !cniPolicy {
+		t.Errorf("expected IPv6 CNI policy on worker role, got %v", inventory.WorkerRole.RolePolicyARNs)
+	}
+
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+
+	// resuming with the IPv4 IP family is rejected
+	ipv4Config := ipv6Config()
+	ipv4Config.IPFamily = resource.IPFamilyIPv4
+	c.FailurePolicy = resource.FailurePolicyKeep
+	var r fake.Recorder
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, ipv4Config, &inventory)
+	r.Stop()
+	c.FailurePolicy = ""
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected IP family mismatch, got %v", err)
+	}
+
+	// the IP family, egress-only internet gateway and IPv6 routes are
+	// recovered
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if recovered.IPFamily != resource.IPFamilyIPv6 ||
+		recovered.EgressOnlyInternetGatewayID != inventory.EgressOnlyInternetGatewayID ||
+		len(recovered.Routes) != len(inventory.Routes) {
+		t.Errorf("expected IPv6 resources to be recovered, got %s, %q and %+v",
+			recovered.IPFamily, recovered.EgressOnlyInternetGatewayID, recovered.Routes)
+	}
+
+	deleteResourceStack(t, backend, c, inventory)
+}
+
+func TestIPFamilyIPv6CreateFailure(t *testing.T) {
+	backend := fake.NewBackend(testRegion)
+	c := backend.ResourceClient()
+	c.FailurePolicy = resource.FailurePolicyDelete
+	backend.Fail("CreateCluster", errors.New("injected failure"))
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.CreateResourceStack(context.Background(), ipv6Config())
+	r.Stop()
+	var failedErr *resource.CreateFailedError
+	if !errors.As(err, &failedErr) || !failedErr.Deleted {
+		t.Fatalf("expected resources to be deleted after failure, got %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+}
+
+func TestIPFamilyInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ipFamily resource.IPFamily
+		vpcID    string
+		wantErr  string
+	}{
+		{
+			name:     "unknown IP family",
+			ipFamily: "ipv5",
+			wantErr:  "must be one of ipv4 or ipv6",
+		},
+		{
+			name:     "IPv6 with existing VPC",
+			ipFamily: resource.IPFamilyIPv6,
+			vpcID:    "vpc-123",
+			wantErr:  "existing VPC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := testConfig()
+			resourceConfig.IPFamily = tc.ipFamily
+			if tc.vpcID != "" {
+				resourceConfig.VPCID = tc.vpcID
+				resourceConfig.PrivateSubnetIDs = []string{"subnet-1", "subnet-2"}
+			}
+
+			_, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// CloudWatchLogsEndpointEnv is the environment variable that sets the
+// endpoint for the CloudWatch Logs API, e.g. http://localhost:4566 for a local
+// emulator.
+const CloudWatchLogsEndpointEnv = "AWS_ENDPOINT_URL_CLOUDWATCH_LOGS"
+
+// CreateLogGroup creates a CloudWatch Logs log group and sets the number of
+// days its events are kept.  If the retention is zero the events never
+// expire.  The log group name is returned, even if setting the retention
+// fails, so it can be recorded in the inventory.
+func (c *ResourceClient) CreateLogGroup(
+	ctx context.Context,
+	tags map[string]string,
+	logGroupName string,
+	retentionDays int32,
+) (string, error) {
+	svc := c.cloudWatchLogsClient()
+
+	createLogGroupInput := cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: &logGroupName,
+		Tags:         tags,
+	}
+	if _, err := svc.CreateLogGroup(ctx, &createLogGroupInput); err != nil {
+		return "", fmt.Errorf("failed to create log group %s: %w", logGroupName, err)
+	}
+
+	if retentionDays != 0 {
+		putRetentionPolicyInput := cloudwatchlogs.PutRetentionPolicyInput{
+			LogGroupName:    &logGroupName,
+			RetentionInDays: &retentionDays,
+		}
+		if _, err := svc.PutRetentionPolicy(ctx, &putRetentionPolicyInput); err != nil {
+			return logGroupName, fmt.Errorf("failed to set retention for log group %s: %w", logGroupName, err)
+		}
+	}
+
+	return logGroupName, nil
+}
+
+// DeleteLogGroup deletes a CloudWatch Logs log group along with its events.
+// If an empty log group name is supplied, or if the log group is not found, it
+// returns without error.
+func (c *ResourceClient) DeleteLogGroup(ctx context.Context, logGroupName string) error {
+	// if logGroupName is empty, there's nothing to delete
+	if logGroupName == "" {
+		return nil
+	}
+
+	svc := c.cloudWatchLogsClient()
+
+	deleteLogGroupInput := cloudwatchlogs.DeleteLogGroupInput{LogGroupName: &logGroupName}
+	if _, err := svc.DeleteLogGroup(ctx, &deleteLogGroupInput); err != nil {
+		var notFoundErr *types.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete log group %s: %w", logGroupName, err)
+	}
+
+	return nil
+}
+
+// getLogGroup retrieves the CloudWatch Logs log group with the given name.  If
+// the log group is not found it returns ErrResourceNotFound.
+func (c *ResourceClient) getLogGroup(ctx context.Context, logGroupName string) (*types.LogGroup, error) {
+	svc := c.cloudWatchLogsClient()
+
+	// log groups can only be listed by name prefix
+	describeLogGroupsInput := cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: &logGroupName}
+	for {
+		resp, err := svc.DescribeLogGroups(ctx, &describeLogGroupsInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe log group %s: %w", logGroupName, err)
+		}
+		for i, logGroup := range resp.LogGroups {
+			if logGroup.LogGroupName != nil && *logGroup.LogGroupName == logGroupName {
+				return &resp.LogGroups[i], nil
+			}
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		describeLogGroupsInput.NextToken = resp.NextToken
+	}
+
+	return nil, ErrResourceNotFound
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -12,6 +13,24 @@ import (
 
 type NATGatewayCondition string
 
+// NATGatewayMode is how many NAT gateways are created for the private subnets
+// to reach the public internet through.
+type NATGatewayMode string
+
+const (
+	// NATGatewayModePerAZ creates a NAT gateway in each availability zone.
+	// This is the default.
+	NATGatewayModePerAZ NATGatewayMode = "perAZ"
+
+	// NATGatewayModeSingle creates one NAT gateway in the first availability
+	// zone that all the private subnets route through.
+	NATGatewayModeSingle NATGatewayMode = "single"
+
+	// NATGatewayModeNone creates no NAT gateways, leaving the private subnets
+	// without a route to the public internet.
+	NATGatewayModeNone NATGatewayMode = "none"
+)
+
 const (
 	NATGatewayConditionCreated = "NATGatewayCreated"
 	NATGatewayConditionDeleted = "NATGatewayDeleted"
@@ -19,20 +38,76 @@ const (
 	NATGatewayCheckMaxCount    = 20 // check 20 times before giving up (5 minutes)
 )
 
+// natGatewayModeOrDefault returns the NAT gateway mode, or the default mode
+// if it is not set.
+func natGatewayModeOrDefault(mode NATGatewayMode) NATGatewayMode {
+	if mode == "" {
+		return NATGatewayModePerAZ
+	}
+
+	return mode
+}
+
+// checkNATGatewayMode checks that the NAT gateway mode in the resource config
+// is valid.  It can't be set when using an existing VPC as no NAT gateways are
+// created.  Without NAT gateways the private subnets only reach AWS services
+// through VPC endpoints or over IPv6, so one of those is required.
+func (r *ResourceConfig) checkNATGatewayMode() error {
+	switch r.NATGatewayMode {
+	case "", NATGatewayModePerAZ, NATGatewayModeSingle, NATGatewayModeNone:
+	default:
+		return fmt.Errorf("NAT gateway mode %q in resource config must be one of %s, %s or %s",
+			r.NATGatewayMode, NATGatewayModePerAZ, NATGatewayModeSingle, NATGatewayModeNone)
+	}
+	if r.NATGatewayMode != "" && r.UsesExistingVPC() {
+		return errors.New("NAT gateway mode cannot be set in resource config when using an existing VPC")
+	}
+	if r.NATGatewayMode == NATGatewayModeNone && !r.VPCEndpoints && r.IPFamily != IPFamilyIPv6 {
+		return fmt.Errorf("NAT gateway mode %s in resource config requires vpcEndpoints to be enabled or the %s IP family - "+
+			"without a NAT gateway the private subnets have no route to ECR, STS or EKS so nodes can't pull images or join the cluster",
+			NATGatewayModeNone, IPFamilyIPv6)
+	}
+
+	return nil
+}
+
+// natGatewayCount returns the number of availability zones that get a NAT
+// gateway for a NAT gateway mode.  They are always the first availability
+// zones.
+func natGatewayCount(mode NATGatewayMode, azCount int) int {
+	switch natGatewayModeOrDefault(mode) {
+	case NATGatewayModeSingle:
+		if azCount > 0 {
+			return 1
+		}
+		return 0
+	case NATGatewayModeNone:
+		return 0
+	default:
+		return azCount
+	}
+}
+
 // CreateNATGateways creates a NAT gateway for each private subnet so that it
-// may reach the public internet.
+// may reach the public internet.  Each NAT gateway is placed in the public
+// subnet of its availability zone and uses the availability zone's elastic IP.
+// NAT gateways whose IDs are already set on the availability zone are not
+// created again.
 func (c *ResourceClient) CreateNATGateways(
+	ctx context.Context,
 	tags *[]types.Tag,
-	availabilityZones []AvailabilityZone,
-	elasticIPIDs []string,
+	availabilityZones *[]AvailabilityZone,
 ) error {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
-	for i, az := range availabilityZones {
-		eip := elasticIPIDs[i]
+	azs := *availabilityZones
+	for i, az := range azs {
+		if az.NATGatewayID != "" {
+			continue
+		}
 		createNATGatewayInput := ec2.CreateNatGatewayInput{
 			SubnetId:     &az.PublicSubnetID,
-			AllocationId: &eip,
+			AllocationId: &az.ElasticIPID,
 			TagSpecifications: []types.TagSpecification{
 				{
 					ResourceType: types.ResourceTypeNatgateway,
@@ -40,42 +115,33 @@ func (c *ResourceClient) CreateNATGateways(
 				},
 			},
 		}
-		_, err := svc.CreateNatGateway(c.Context, &createNATGatewayInput)
+		resp, err := svc.CreateNatGateway(ctx, &createNATGatewayInput)
 		if err != nil {
 			return fmt.Errorf("failed to create NAT gateway in subnet with ID %s: %w", az.PublicSubnetID, err)
 		}
-
+		if resp.NatGateway != nil && resp.NatGateway.NatGatewayId != nil {
+			azs[i].NATGatewayID = *resp.NatGateway.NatGatewayId
+		}
 	}
 
 	return nil
 }
 
-// DeleteNATGateways deletes all the NAT gateways for the VPC in which the EKS
-// cluster was provisioned.  If an empty VPC ID is supplied, or if there are no
-// NAT gateways found in the VPC it returns without error.
-func (c *ResourceClient) DeleteNATGateways(vpcID string) error {
-	// if no VPC ID, there's nothing to delete
-	if vpcID == "" {
-		return nil
-	}
-
-	svc := ec2.NewFromConfig(*c.AWSConfig)
-
-	_, natGatewayIDs, err := c.getNATGatewayStatuses(vpcID, nil)
-	if err != nil {
-		return fmt.Errorf("failed to get NAT gateway statuses for VPC with ID %s during deletion: %w", vpcID, err)
-	}
+// DeleteNATGateways deletes the NAT gateways with the given IDs.  NAT
+// gateways that are not found are skipped.
+func (c *ResourceClient) DeleteNATGateways(ctx context.Context, natGatewayIDs []string) error {
+	svc := c.ec2Client()
 
 	for _, natGatewayID := range natGatewayIDs {
 		deleteNATGatewayInput := ec2.DeleteNatGatewayInput{NatGatewayId: &natGatewayID}
-		_, err := svc.DeleteNatGateway(c.Context, &deleteNATGatewayInput)
+		_, err := svc.DeleteNatGateway(ctx, &deleteNATGatewayInput)
 		if err != nil {
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
-				if ae.ErrorCode() == "InvalidNATGatewayID.NotFound" {
-					// attempting to delete a NAT gateway that doesn't exist so return
-					// without error
-					return nil
+				if ae.ErrorCode() == "NatGatewayNotFound" || ae.ErrorCode() == "InvalidNATGatewayID.NotFound" {
+					// attempting to delete a NAT gateway that doesn't exist so
+					// move on to the next one
+					continue
 				} else {
 					return fmt.Errorf("failed to delete NAT gateway with ID %s: %w", natGatewayID, err)
 				}
@@ -88,14 +154,22 @@ func (c *ResourceClient) DeleteNATGateways(vpcID string) error {
 	return nil
 }
 
-// WaitForNATGateways waits for a NAT gateway to reach a given condition.  One of:
+// WaitForNATGateways waits for the NAT gateways with the given IDs to reach a
+// given condition.  One of:
 // * NATGatewayConditionCreated
 // * NATGatewayConditionDeleted
+// If no NAT gateway IDs are supplied it returns without error.
 func (c *ResourceClient) WaitForNATGateways(
+	ctx context.Context,
 	vpcID string,
-	availabilityZones *[]AvailabilityZone,
+	natGatewayIDs []string,
 	natGatewayCondition NATGatewayCondition,
 ) error {
+	// if no NAT gateway IDs, there's nothing to wait for
+	if len(natGatewayIDs) == 0 {
+		return nil
+	}
+
 	natGatewayCheckCount := 0
 
 	for {
@@ -104,14 +178,14 @@ func (c *ResourceClient) WaitForNATGateways(
 			return errors.New("NAT gateway condition check timed out")
 		}
 
-		natGatewayStates, _, err := c.getNATGatewayStatuses(vpcID, availabilityZones)
+		natGatewayStates, err := c.getNATGatewayStatuses(ctx, vpcID, natGatewayIDs)
 		if err != nil {
 			return fmt.Errorf("failed to get NAT gateway statuses for VPC with ID %s: %w", vpcID, err)
 		}
 
 		if len(*natGatewayStates) == 0 && natGatewayCondition == NATGatewayConditionDeleted {
-			// no NAT gateway resources found for this VPC while waiting for
-			// deletion so condition is met
+			// none of the NAT gateways were found while waiting for deletion
+			// so condition is met
 			break
 		}
 
@@ -134,65 +208,85 @@ func (c *ResourceClient) WaitForNATGateways(
 			break
 		}
 
-		time.Sleep(time.Second * 15)
+		if err := c.waitCheckInterval(ctx, time.Second*NATGatewayCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for NAT gateways in VPC with ID %s: %w", vpcID, err)
+		}
 	}
 
 	return nil
 }
 
-// getNATGatewayStatuses returns the state of each NAT gateway for a given VPC
-// as well as the public subnet with which the NAT gateway is associated.  This
-// mapping is stored in the AvailabilityZone field of the ResourceConfig (during
-// resource creation) or in a map[string]string (for deletion).
+// getNATGatewayStatuses returns the state of each NAT gateway in a VPC with
+// one of the given IDs.
 func (c *ResourceClient) getNATGatewayStatuses(
+	ctx context.Context,
 	vpcID string,
-	availabilityZones *[]AvailabilityZone,
-) (*[]types.NatGatewayState, map[string]string, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	natGatewayIDs []string,
+) (*[]types.NatGatewayState, error) {
+	svc := c.ec2Client()
 
 	var natGatewayStates []types.NatGatewayState
-	subnetMap := make(map[string]string)
 
-	filterName := "vpc-id"
+	vpcFilterName := "vpc-id"
+	natGatewayFilterName := "nat-gateway-id"
 	describeNATGatewaysInput := ec2.DescribeNatGatewaysInput{
 		Filter: []types.Filter{
 			{
-				Name:   &filterName,
+				Name:   &vpcFilterName,
 				Values: []string{vpcID},
 			},
+			{
+				Name:   &natGatewayFilterName,
+				Values: natGatewayIDs,
+			},
 		},
 	}
-	resp, err := svc.DescribeNatGateways(c.Context, &describeNATGatewaysInput)
+	resp, err := svc.DescribeNatGateways(ctx, &describeNATGatewaysInput)
 	if err != nil {
-		return nil, subnetMap, fmt.Errorf("failed to describe NAT gateways for VPC with ID %s: %w", vpcID, err)
+		return nil, fmt.Errorf("failed to describe NAT gateways for VPC with ID %s: %w", vpcID, err)
 	}
 
-	// map the NAT gateways to the public subnets in which they reside and put
-	// it in a map[availabiltiy- zone]public-subnet-id (used for deletions) and
-	// in the []AvailabilityZone slice (for creations) where applicable
 	for _, natGateway := range resp.NatGateways {
 		natGatewayStates = append(natGatewayStates, natGateway.State)
-		if natGateway.SubnetId != nil && natGateway.NatGatewayId != nil {
-			subnetMap[*natGateway.SubnetId] = *natGateway.NatGatewayId
-			if availabilityZones != nil {
-				azs := *availabilityZones
-				subnetMapped := false
-				for i, az := range azs {
-					if *natGateway.SubnetId == az.PublicSubnetID {
-						azs[i].NATGatewayID = *natGateway.NatGatewayId
-						availabilityZones = &azs
-						subnetMapped = true
-						break
-					}
-				}
-				if !subnetMapped {
-					return nil, subnetMap, errors.New(fmt.Sprintf(
-						"failed to map NAT gateway with ID %s in subnet with ID %s to an availability zone",
-						*natGateway.NatGatewayId, *natGateway.SubnetId))
-				}
-			}
-		}
 	}
 
-	return &natGatewayStates, subnetMap, nil
+	return &natGatewayStates, nil
+}
+
+// getNATGateways retrieves the NAT gateways in a VPC that are pending or
+// available.  If NAT gateway IDs are supplied only those NAT gateways are
+// included.
+func (c *ResourceClient) getNATGateways(ctx context.Context, vpcID string, natGatewayIDs []string) ([]types.NatGateway, error) {
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	stateFilterName := "state"
+	describeNATGatewaysInput := ec2.DescribeNatGatewaysInput{
+		Filter: []types.Filter{
+			{
+				Name:   &vpcFilterName,
+				Values: []string{vpcID},
+			},
+			{
+				Name: &stateFilterName,
+				Values: []string{
+					string(types.NatGatewayStatePending),
+					string(types.NatGatewayStateAvailable),
+				},
+			},
+		},
+	}
+	if len(natGatewayIDs) > 0 {
+		natGatewayFilterName := "nat-gateway-id"
+		describeNATGatewaysInput.Filter = append(describeNATGatewaysInput.Filter, types.Filter{
+			Name:   &natGatewayFilterName,
+			Values: natGatewayIDs,
+		})
+	}
+	resp, err := svc.DescribeNatGateways(ctx, &describeNATGatewaysInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe NAT gateways for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return resp.NatGateways, nil
 }
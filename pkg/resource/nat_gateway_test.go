@@ -0,0 +1,156 @@
+package resource_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+func TestNATGatewayMode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		mode            resource.NATGatewayMode
+		wantMode        resource.NATGatewayMode
+		wantNATGateways int
+	}{
+		{
+			name:            "default",
+			wantMode:        resource.NATGatewayModePerAZ,
+			wantNATGateways: 3,
+		},
+		{
+			name:            "per availability zone",
+			mode:            resource.NATGatewayModePerAZ,
+			wantMode:        resource.NATGatewayModePerAZ,
+			wantNATGateways: 3,
+		},
+		{
+			name:            "single",
+			mode:            resource.NATGatewayModeSingle,
+			wantMode:        resource.NATGatewayModeSingle,
+			wantNATGateways: 1,
+		},
+		{
+			name:     "none",
+			mode:     resource.NATGatewayModeNone,
+			wantMode: resource.NATGatewayModeNone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := func() *resource.ResourceConfig {
+				resourceConfig := testConfig()
+				resourceConfig.DesiredAZCount = 3
+				resourceConfig.NATGatewayMode = tc.mode
+				resourceConfig.VPCEndpoints = tc.mode == resource.NATGatewayModeNone
+				return resourceConfig
+			}
+			backend, c, inventory := createResourceStack(t, resourceConfig())
+
+			snapshot := backend.Snapshot()
+			if len(snapshot.NATGatewayIDs) != tc.wantNATGateways || len(snapshot.AllocationIDs) != tc.wantNATGateways {
+				t.Errorf("expected %d NAT gateways and elastic IPs, got %v and %v",
+					tc.wantNATGateways, snapshot.NATGatewayIDs, snapshot.AllocationIDs)
+			}
+			if inventory.NATGatewayMode != tc.wantMode {
+				t.Errorf("expected NAT gateway mode %s in inventory, got %s", tc.wantMode, inventory.NATGatewayMode)
+			}
+
+			// every private route table has a default route to one of the NAT
+			// gateways, unless there are none
+			natGatewayRoutes := 0
+			for _, route := range inventory.Routes {
+				if route.NATGatewayID != "" {
+					natGatewayRoutes++
+				}
+			}
+			wantNATGatewayRoutes := len(inventory.PrivateRouteTableIDs)
+			if tc.wantNATGateways == 0 {
+				wantNATGatewayRoutes = 0
+			}
+			if natGatewayRoutes != wantNATGatewayRoutes {
+				t.Errorf("expected %d routes to NAT gateways, got %d", wantNATGatewayRoutes, natGatewayRoutes)
+			}
+
+			// resuming with another mode is rejected
+			otherConfig := resourceConfig()
+			otherConfig.NATGatewayMode = resource.NATGatewayModeSingle
+			if tc.mode == resource.NATGatewayModeSingle {
+				otherConfig.NATGatewayMode = resource.NATGatewayModePerAZ
+			}
+			var r fake.Recorder
+			r.Record(c)
+			err := c.ResumeResourceStack(context.Background(), otherConfig, &inventory)
+			r.Stop()
+			if err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Errorf("expected NAT gateway mode mismatch, got %v", err)
+			}
+
+			deleteResourceStack(t, backend, c, inventory)
+		})
+	}
+}
+
+func TestNATGatewayModeInvalid(t *testing.T) {
+	testCases := []struct {
+		name         string
+		mode         resource.NATGatewayMode
+		vpcEndpoints bool
+		ipFamily     resource.IPFamily
+		vpcID        string
+		wantErr      string
+	}{
+		{
+			name:    "unknown mode",
+			mode:    "bogus",
+			wantErr: "must be one of perAZ, single or none",
+		},
+		{
+			name:    "none without VPC endpoints",
+			mode:    resource.NATGatewayModeNone,
+			wantErr: "no route to ECR",
+		},
+		{
+			name:         "none with VPC endpoints",
+			mode:         resource.NATGatewayModeNone,
+			vpcEndpoints: true,
+		},
+		{
+			name:     "none with IPv6",
+			mode:     resource.NATGatewayModeNone,
+			ipFamily: resource.IPFamilyIPv6,
+		},
+		{
+			name:    "existing VPC",
+			mode:    resource.NATGatewayModeSingle,
+			vpcID:   "vpc-123",
+			wantErr: "existing VPC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := testConfig()
+			resourceConfig.NATGatewayMode = tc.mode
+			resourceConfig.VPCEndpoints = tc.vpcEndpoints
+			resourceConfig.IPFamily = tc.ipFamily
+			resourceConfig.VPCID = tc.vpcID
+
+			backend := fake.NewBackend(testRegion)
+			_, err := backend.ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected NAT gateway mode to be accepted, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
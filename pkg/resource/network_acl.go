@@ -0,0 +1,526 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// NetworkACLRuleNumberMax is the highest rule number that can be used in
+	// a network ACL.  Rule number 32767 is the rule that denies all traffic
+	// not matched by another rule.
+	NetworkACLRuleNumberMax = 32766
+
+	// networkACLDefaultRuleNumber is the rule number of the rule AWS adds to
+	// every network ACL that denies all traffic not matched by another rule.
+	networkACLDefaultRuleNumber = 32767
+)
+
+// networkACLProtocols are the IP protocol numbers for the protocol names that
+// can be used in network ACL rules.  -1 is all protocols.
+var networkACLProtocols = map[string]string{
+	"all":    "-1",
+	"tcp":    "6",
+	"udp":    "17",
+	"icmp":   "1",
+	"icmpv6": "58",
+}
+
+// networkACLTier is a tier of subnets along with the rules for its network
+// ACL.
+type networkACLTier struct {
+	tier  string
+	rules []NetworkACLRule
+}
+
+// tiers returns the subnet tiers with network ACL rules.  Tiers without rules
+// keep the VPC's default network ACL.
+func (n *NetworkACLsConfig) tiers() []networkACLTier {
+	var tiers []networkACLTier
+	for _, tier := range []networkACLTier{
+		{tier: "public", rules: n.Public},
+		{tier: "private", rules: n.Private},
+		{tier: "pod", rules: n.Pod},
+	} {
+		if len(tier.rules) > 0 {
+			tiers = append(tiers, tier)
+		}
+	}
+
+	return tiers
+}
+
+// checkNetworkACLs checks the network ACL rules in the resource config.
+// Network ACLs can't be created when using an existing VPC as its subnets are
+// not changed.  Pod rules need the pod subnets created with a secondary CIDR.
+// Rule numbers must be unique within the inbound and outbound rules of a tier.
+func (r *ResourceConfig) checkNetworkACLs() error {
+	if r.NetworkACLs == nil {
+		return nil
+	}
+	if r.UsesExistingVPC() {
+		return errors.New("network ACLs cannot be created when using an existing VPC")
+	}
+	if len(r.NetworkACLs.Pod) > 0 && r.SecondaryCIDR == "" {
+		return errors.New("pod network ACL rules in resource config require a secondary CIDR for the pod subnets")
+	}
+
+	for _, tier := range r.NetworkACLs.tiers() {
+		ruleNumbers := make(map[string]bool)
+		for _, rule := range tier.rules {
+			if err := checkNetworkACLRule(tier.tier, &rule); err != nil {
+				return err
+			}
+			key := fmt.Sprintf("%s/%d", networkACLRuleDirection(rule.Egress), rule.RuleNumber)
+			if ruleNumbers[key] {
+				return fmt.Errorf("%s network ACL in resource config has more than one %s rule with rule number %d",
+					tier.tier, networkACLRuleDirection(rule.Egress), rule.RuleNumber)
+			}
+			ruleNumbers[key] = true
+		}
+	}
+
+	return nil
+}
+
+// checkNetworkACLRule checks a rule for the network ACL of a subnet tier.
+// Ports can only be set for TCP and UDP.
+func checkNetworkACLRule(tier string, rule *NetworkACLRule) error {
+	if rule.RuleNumber < 1 || rule.RuleNumber > NetworkACLRuleNumberMax {
+		return fmt.Errorf("%s network ACL rule number %d in resource config must be between 1 and %d",
+			tier, rule.RuleNumber, NetworkACLRuleNumberMax)
+	}
+	switch types.RuleAction(rule.Action) {
+	case types.RuleActionAllow, types.RuleActionDeny:
+	default:
+		return fmt.Errorf("%s network ACL rule %d action %q in resource config must be one of %s or %s",
+			tier, rule.RuleNumber, rule.Action, types.RuleActionAllow, types.RuleActionDeny)
+	}
+	protocol, ok := networkACLProtocol(rule.Protocol)
+	if !ok {
+		return fmt.Errorf("%s network ACL rule %d protocol %q in resource config must be tcp, udp, icmp, icmpv6, all or a protocol number",
+			tier, rule.RuleNumber, rule.Protocol)
+	}
+	prefix, err := netip.ParsePrefix(rule.CIDR)
+	if err != nil || prefix != prefix.Masked() {
+		return fmt.Errorf("%s network ACL rule %d CIDR %q in resource config is not a valid CIDR block",
+			tier, rule.RuleNumber, rule.CIDR)
+	}
+	if !networkACLProtocolHasPorts(protocol) {
+		if rule.FromPort != 0 || rule.ToPort != 0 {
+			return fmt.Errorf("%s network ACL rule %d in resource config can only have ports for tcp and udp",
+				tier, rule.RuleNumber)
+		}
+		return nil
+	}
+	fromPort, toPort := rule.portRange()
+	if fromPort < 0 || toPort > 65535 || fromPort > toPort {
+		return fmt.Errorf("%s network ACL rule %d port range %d-%d in resource config is not valid",
+			tier, rule.RuleNumber, rule.FromPort, rule.ToPort)
+	}
+
+	return nil
+}
+
+// networkACLProtocol returns the IP protocol number for a protocol in a
+// network ACL rule.  It returns false if the protocol is not a known protocol
+// name or protocol number.
+func networkACLProtocol(protocol string) (string, bool) {
+	if number, ok := networkACLProtocols[strings.ToLower(protocol)]; ok {
+		return number, true
+	}
+	number, err := strconv.Atoi(protocol)
+	if err != nil || number < 0 || number > 255 {
+		return "", false
+	}
+
+	return strconv.Itoa(number), true
+}
+
+// networkACLProtocolHasPorts returns true if network ACL rules for the IP
+// protocol number have a port range.
+func networkACLProtocolHasPorts(protocol string) bool {
+	return protocol == networkACLProtocols["tcp"] || protocol == networkACLProtocols["udp"]
+}
+
+// networkACLRuleDirection returns the direction of traffic a network ACL rule
+// applies to.
+func networkACLRuleDirection(egress bool) string {
+	if egress {
+		return "outbound"
+	}
+
+	return "inbound"
+}
+
+// portRange returns the port range of a TCP or UDP network ACL rule.  If no
+// ports are set the rule applies to all ports.
+func (r *NetworkACLRule) portRange() (int32, int32) {
+	if r.FromPort == 0 && r.ToPort == 0 {
+		return 0, 65535
+	}
+
+	return r.FromPort, r.ToPort
+}
+
+// networkACLEntry returns the network ACL entry for a rule.  ICMP rules apply
+// to all ICMP types and codes.
+func (r *NetworkACLRule) networkACLEntry() types.NetworkAclEntry {
+	protocol, _ := networkACLProtocol(r.Protocol)
+	ruleNumber := r.RuleNumber
+	egress := r.Egress
+	entry := types.NetworkAclEntry{
+		RuleNumber: &ruleNumber,
+		Egress:     &egress,
+		Protocol:   &protocol,
+		RuleAction: types.RuleAction(r.Action),
+	}
+
+	cidr := r.CIDR
+	if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Addr().Is6() {
+		entry.Ipv6CidrBlock = &cidr
+	} else {
+		entry.CidrBlock = &cidr
+	}
+
+	switch {
+	case networkACLProtocolHasPorts(protocol):
+		fromPort, toPort := r.portRange()
+		entry.PortRange = &types.PortRange{From: &fromPort, To: &toPort}
+	case protocol == networkACLProtocols["icmp"] || protocol == networkACLProtocols["icmpv6"]:
+		allICMP := int32(-1)
+		entry.IcmpTypeCode = &types.IcmpTypeCode{Type: &allICMP, Code: &allICMP}
+	}
+
+	return entry
+}
+
+// CreateNetworkACL creates a network ACL in the VPC.  It has no rules until
+// they are set so denies all traffic.
+func (c *ResourceClient) CreateNetworkACL(ctx context.Context, tags *[]types.Tag, vpcID string) (string, error) {
+	svc := c.ec2Client()
+
+	createNetworkACLInput := ec2.CreateNetworkAclInput{
+		VpcId: &vpcID,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeNetworkAcl,
+				Tags:         *tags,
+			},
+		},
+	}
+	resp, err := svc.CreateNetworkAcl(ctx, &createNetworkACLInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network ACL for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return *resp.NetworkAcl.NetworkAclId, nil
+}
+
+// SetNetworkACLRules makes the rules in a network ACL match the given rules.
+// Rules with the rule number of an existing rule replace it and existing rules
+// that are not given are deleted, so the network ACL's rules can be updated
+// in place.
+func (c *ResourceClient) SetNetworkACLRules(ctx context.Context, networkACLID string, rules []NetworkACLRule) error {
+	svc := c.ec2Client()
+
+	networkACL, err := c.getNetworkACL(ctx, networkACLID)
+	if err != nil {
+		return err
+	}
+	existingEntries := make(map[string]bool)
+	for _, entry := range networkACL.Entries {
+		if entry.RuleNumber == nil || *entry.RuleNumber == networkACLDefaultRuleNumber {
+			continue
+		}
+		existingEntries[networkACLEntryKey(entry)] = true
+	}
+
+	for _, rule := range rules {
+		entry := rule.networkACLEntry()
+		key := networkACLEntryKey(entry)
+		if existingEntries[key] {
+			replaceNetworkACLEntryInput := ec2.ReplaceNetworkAclEntryInput{
+				NetworkAclId:  &networkACLID,
+				RuleNumber:    entry.RuleNumber,
+				Egress:        entry.Egress,
+				Protocol:      entry.Protocol,
+				RuleAction:    entry.RuleAction,
+				CidrBlock:     entry.CidrBlock,
+				Ipv6CidrBlock: entry.Ipv6CidrBlock,
+				PortRange:     entry.PortRange,
+				IcmpTypeCode:  entry.IcmpTypeCode,
+			}
+			if _, err := svc.ReplaceNetworkAclEntry(ctx, &replaceNetworkACLEntryInput); err != nil {
+				return fmt.Errorf("failed to replace %s rule %d in network ACL with ID %s: %w",
+					networkACLRuleDirection(rule.Egress), rule.RuleNumber, networkACLID, err)
+			}
+			delete(existingEntries, key)
+			continue
+		}
+		createNetworkACLEntryInput := ec2.CreateNetworkAclEntryInput{
+			NetworkAclId:  &networkACLID,
+			RuleNumber:    entry.RuleNumber,
+			Egress:        entry.Egress,
+			Protocol:      entry.Protocol,
+			RuleAction:    entry.RuleAction,
+			CidrBlock:     entry.CidrBlock,
+			Ipv6CidrBlock: entry.Ipv6CidrBlock,
+			PortRange:     entry.PortRange,
+			IcmpTypeCode:  entry.IcmpTypeCode,
+		}
+		if _, err := svc.CreateNetworkAclEntry(ctx, &createNetworkACLEntryInput); err != nil {
+			return fmt.Errorf("failed to create %s rule %d in network ACL with ID %s: %w",
+				networkACLRuleDirection(rule.Egress), rule.RuleNumber, networkACLID, err)
+		}
+	}
+
+	// remove the rules no longer in the config
+	for _, entry := range networkACL.Entries {
+		if !existingEntries[networkACLEntryKey(entry)] {
+			continue
+		}
+		deleteNetworkACLEntryInput := ec2.DeleteNetworkAclEntryInput{
+			NetworkAclId: &networkACLID,
+			RuleNumber:   entry.RuleNumber,
+			Egress:       entry.Egress,
+		}
+		if _, err := svc.DeleteNetworkAclEntry(ctx, &deleteNetworkACLEntryInput); err != nil {
+			return fmt.Errorf("failed to delete %s rule %d in network ACL with ID %s: %w",
+				networkACLRuleDirection(*entry.Egress), *entry.RuleNumber, networkACLID, err)
+		}
+	}
+
+	return nil
+}
+
+// AssociateNetworkACL associates subnets with a network ACL in place of the
+// network ACL they are currently associated with.  Subnets already
+// associated with it are left as they are.
+func (c *ResourceClient) AssociateNetworkACL(
+	ctx context.Context,
+	vpcID string,
+	networkACLID string,
+	subnetIDs []string,
+) error {
+	if len(subnetIDs) == 0 {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	subnetFilterName := "association.subnet-id"
+	describeNetworkACLsInput := ec2.DescribeNetworkAclsInput{
+		Filters: []types.Filter{
+			{Name: &vpcFilterName, Values: []string{vpcID}},
+			{Name: &subnetFilterName, Values: subnetIDs},
+		},
+	}
+	resp, err := svc.DescribeNetworkAcls(ctx, &describeNetworkACLsInput)
+	if err != nil {
+		return fmt.Errorf("failed to describe network ACLs for VPC with ID %s: %w", vpcID, err)
+	}
+	associations := make(map[string]types.NetworkAclAssociation)
+	for _, networkACL := range resp.NetworkAcls {
+		for _, association := range networkACL.Associations {
+			if association.SubnetId != nil {
+				associations[*association.SubnetId] = association
+			}
+		}
+	}
+
+	for _, subnetID := range subnetIDs {
+		association, ok := associations[subnetID]
+		if !ok || association.NetworkAclAssociationId == nil {
+			return fmt.Errorf("no network ACL association found for subnet with ID %s", subnetID)
+		}
+		if association.NetworkAclId != nil && *association.NetworkAclId == networkACLID {
+			continue
+		}
+		replaceNetworkACLAssociationInput := ec2.ReplaceNetworkAclAssociationInput{
+			AssociationId: association.NetworkAclAssociationId,
+			NetworkAclId:  &networkACLID,
+		}
+		if _, err := svc.ReplaceNetworkAclAssociation(ctx, &replaceNetworkACLAssociationInput); err != nil {
+			return fmt.Errorf("failed to associate subnet with ID %s with network ACL with ID %s: %w",
+				subnetID, networkACLID, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteNetworkACL associates the subnets associated with a network ACL with
+// the VPC's default network ACL and deletes it.  If an empty network ACL ID
+// is supplied, or if the network ACL is not found, it returns without error.
+func (c *ResourceClient) DeleteNetworkACL(ctx context.Context, networkACLID string) error {
+	// if networkACLID is empty, there's nothing to delete
+	if networkACLID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	networkACL, err := c.getNetworkACL(ctx, networkACLID)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil
+		}
+		return err
+	}
+	if subnetIDs := networkACLSubnetIDs(networkACL); len(subnetIDs) > 0 {
+		defaultNetworkACL, err := c.getDefaultNetworkACL(ctx, *networkACL.VpcId)
+		if err != nil {
+			return err
+		}
+		if err := c.AssociateNetworkACL(ctx, *networkACL.VpcId, *defaultNetworkACL.NetworkAclId, subnetIDs); err != nil {
+			return err
+		}
+	}
+
+	deleteNetworkACLInput := ec2.DeleteNetworkAclInput{
+		NetworkAclId: &networkACLID,
+	}
+	_, err = svc.DeleteNetworkAcl(ctx, &deleteNetworkACLInput)
+	if err != nil {
+		if networkACLNotFound(err) {
+			// attempting to delete a network ACL that doesn't exist so
+			// return without error
+			return nil
+		}
+		return fmt.Errorf("failed to delete network ACL with ID %s: %w", networkACLID, err)
+	}
+
+	return nil
+}
+
+// getNetworkACL retrieves the network ACL with the given ID.  If the network
+// ACL is not found it returns ErrResourceNotFound.
+func (c *ResourceClient) getNetworkACL(ctx context.Context, networkACLID string) (*types.NetworkAcl, error) {
+	svc := c.ec2Client()
+
+	describeNetworkACLsInput := ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: []string{networkACLID},
+	}
+	resp, err := svc.DescribeNetworkAcls(ctx, &describeNetworkACLsInput)
+	if err != nil {
+		if networkACLNotFound(err) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to describe network ACL with ID %s: %w", networkACLID, err)
+	}
+	if len(resp.NetworkAcls) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.NetworkAcls[0], nil
+}
+
+// getDefaultNetworkACL retrieves the default network ACL that AWS creates
+// with a VPC.
+func (c *ResourceClient) getDefaultNetworkACL(ctx context.Context, vpcID string) (*types.NetworkAcl, error) {
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	defaultFilterName := "default"
+	describeNetworkACLsInput := ec2.DescribeNetworkAclsInput{
+		Filters: []types.Filter{
+			{Name: &vpcFilterName, Values: []string{vpcID}},
+			{Name: &defaultFilterName, Values: []string{"true"}},
+		},
+	}
+	resp, err := svc.DescribeNetworkAcls(ctx, &describeNetworkACLsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe default network ACL for VPC with ID %s: %w", vpcID, err)
+	}
+	if len(resp.NetworkAcls) == 0 {
+		return nil, fmt.Errorf("no default network ACL found for VPC with ID %s", vpcID)
+	}
+
+	return &resp.NetworkAcls[0], nil
+}
+
+// networkACLNotFound returns true if an error is the one returned by the EC2
+// API for a network ACL that doesn't exist.
+func networkACLNotFound(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && ae.ErrorCode() == "InvalidNetworkAclID.NotFound"
+}
+
+// networkACLEntryKey returns the direction and rule number that identify an
+// entry in a network ACL.
+func networkACLEntryKey(entry types.NetworkAclEntry) string {
+	egress := entry.Egress != nil && *entry.Egress
+	var ruleNumber int32
+	if entry.RuleNumber != nil {
+		ruleNumber = *entry.RuleNumber
+	}
+
+	return fmt.Sprintf("%s/%d", networkACLRuleDirection(egress), ruleNumber)
+}
+
+// networkACLSubnetIDs returns the IDs of the subnets associated with a network
+// ACL.
+func networkACLSubnetIDs(networkACL *types.NetworkAcl) []string {
+	var subnetIDs []string
+	for _, association := range networkACL.Associations {
+		if association.SubnetId != nil {
+			subnetIDs = append(subnetIDs, *association.SubnetId)
+		}
+	}
+
+	return subnetIDs
+}
+
+// tierSubnetIDs returns the IDs of the subnets in a tier - public, private or
+// pod - of the availability zones.
+func tierSubnetIDs(tier string, availabilityZones []AvailabilityZone) []string {
+	var subnetIDs []string
+	for _, az := range availabilityZones {
+		var subnetID string
+		switch tier {
+		case "public":
+			subnetID = az.PublicSubnetID
+		case "private":
+			subnetID = az.PrivateSubnetID
+		case "pod":
+			subnetID = az.PodSubnetID
+		}
+		if subnetID != "" {
+			subnetIDs = append(subnetIDs, subnetID)
+		}
+	}
+
+	return subnetIDs
+}
+
+// findNetworkACLID returns the ID of the network ACL for a subnet tier in the
+// inventory.  If there is none it returns an empty string.
+func findNetworkACLID(networkACLs []NetworkACLInventory, tier string) string {
+	for _, networkACL := range networkACLs {
+		if networkACL.Tier == tier {
+			return networkACL.NetworkACLID
+		}
+	}
+
+	return ""
+}
+
+// getNetworkACLIDs returns the IDs of the network ACLs in the inventory.
+func getNetworkACLIDs(networkACLs []NetworkACLInventory) []string {
+	var networkACLIDs []string
+	for _, networkACL := range networkACLs {
+		networkACLIDs = append(networkACLIDs, networkACL.NetworkACLID)
+	}
+
+	return networkACLIDs
+}
@@ -0,0 +1,344 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// networkACLsConfig returns a resource config with network ACLs for the
+// private and public subnets.
+func networkACLsConfig() *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.SecondaryCIDR = "100.64.0.0/16"
+	resourceConfig.NetworkACLs = &resource.NetworkACLsConfig{
+		Private: []resource.NetworkACLRule{
+			{RuleNumber: 100, Action: "allow", Protocol: "all", CIDR: "10.0.0.0/16"},
+			{RuleNumber: 110, Action: "allow", Protocol: "tcp", CIDR: "0.0.0.0/0", FromPort: 1024, ToPort: 65535},
+			{RuleNumber: 100, Egress: true, Action: "allow", Protocol: "all", CIDR: "0.0.0.0/0"},
+		},
+		Public: []resource.NetworkACLRule{
+			{RuleNumber: 100, Action: "allow", Protocol: "tcp", CIDR: "0.0.0.0/0", FromPort: 443, ToPort: 443},
+			{RuleNumber: 110, Action: "allow", Protocol: "icmp", CIDR: "0.0.0.0/0"},
+			{RuleNumber: 120, Action: "allow", Protocol: "udp", CIDR: "::/0"},
+			{RuleNumber: 100, Egress: true, Action: "allow", Protocol: "all", CIDR: "0.0.0.0/0"},
+		},
+	}
+
+	return resourceConfig
+}
+
+// networkACLDrift returns the drift reported for network ACLs with the given
+// status.
+func networkACLDrift(report *resource.DriftReport, status resource.DriftStatus) []resource.ResourceDrift {
+	var drifts []resource.ResourceDrift
+	for _, drift := range report.Resources {
+		if drift.Kind == resource.ResourceKindNetworkACL && drift.Status == status {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	return drifts
+}
+
+func TestNetworkACLs(t *testing.T) {
+	ctx := context.Background()
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, networkACLsConfig())
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if len(plan.NetworkACLs) != 2 || plan.NetworkACLs[0].Tier != "public" || plan.NetworkACLs[1].Tier != "private" {
+		t.Fatalf("expected public and private network ACLs in plan, got %+v", plan.NetworkACLs)
+	}
+	// only tcp and udp rules have port ranges, which default to all ports
+	publicRules := plan.NetworkACLs[0].Rules
+	for i, want := range []string{"443-443", "", "0-65535"} {
+		if publicRules[i].PortRange != want {
+			t.Errorf("expected port range %q for public rule %d, got %q", want, publicRules[i].RuleNumber, publicRules[i].PortRange)
+		}
+	}
+
+	backend, c, inventory := createResourceStack(t, networkACLsConfig())
+	if snapshot := backend.Snapshot(); len(inventory.NetworkACLs) != 2 || len(snapshot.NetworkACLIDs) != 2 {
+		t.Errorf("expected 2 network ACLs, got %+v and %v", inventory.NetworkACLs, snapshot.NetworkACLIDs)
+	}
+
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+	if verified := networkACLDrift(report, resource.DriftStatusInSync); len(verified) != 2 {
+		t.Errorf("expected 2 network ACLs to be verified, got %+v", verified)
+	}
+
+	// resuming with changed rules updates the entries in place
+	changedConfig := networkACLsConfig()
+	changedConfig.NetworkACLs.Private = changedConfig.NetworkACLs.Private[:2]
+	changedConfig.NetworkACLs.Private[0].Action = "deny"
+	calls := len(backend.Calls())
+	var r fake.Recorder
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, changedConfig, &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to resume resource stack: %v", err)
+	}
+	var deletedEntries, replacedEntries int
+	for _, call := range backend.Calls()[calls:] {
+		switch call {
+		case "CreateNetworkAcl":
+			t.Error("expected network ACLs not to be recreated")
+		case "DeleteNetworkAclEntry":
+			deletedEntries++
+		case "ReplaceNetworkAclEntry":
+			replacedEntries++
+		}
+	}
+	if deletedEntries != 1 || replacedEntries == 0 {
+		t.Errorf("expected 1 entry to be deleted and the rest replaced, got %d deleted and %d replaced",
+			deletedEntries, replacedEntries)
+	}
+	inventory = r.Inventory()
+
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if len(recovered.NetworkACLs) != len(inventory.NetworkACLs) {
+		t.Errorf("expected network ACLs %+v to be recovered, got %+v", inventory.NetworkACLs, recovered.NetworkACLs)
+	}
+	for _, networkACL := range recovered.NetworkACLs {
+		var found bool
+		for _, want := range inventory.NetworkACLs {
+			found = found || networkACL == want
+		}
+		if !found {
+			t.Errorf("expected recovered network ACL %+v in inventory %+v", networkACL, inventory.NetworkACLs)
+		}
+	}
+
+	// an extra network ACL and a subnet moved back to the default network ACL
+	// are reported
+	createNetworkAclInput := ec2.CreateNetworkAclInput{VpcId: aws.String(inventory.VPCID)}
+	extra, err := backend.EC2.CreateNetworkAcl(ctx, &createNetworkAclInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	describeNetworkAclsInput := ec2.DescribeNetworkAclsInput{
+		Filters: []types.Filter{
+			{Name: aws.String("default"), Values: []string{"true"}},
+			{Name: aws.String("vpc-id"), Values: []string{inventory.VPCID}},
+		},
+	}
+	describeNetworkAclsOutput, err := backend.EC2.DescribeNetworkAcls(ctx, &describeNetworkAclsInput)
+	if err != nil || len(describeNetworkAclsOutput.NetworkAcls) != 1 {
+		t.Fatalf("expected default network ACL, got %+v and %v", describeNetworkAclsOutput, err)
+	}
+	movedSubnetID := inventory.AvailabilityZones[0].PublicSubnetID
+	defaultNetworkACLID := *describeNetworkAclsOutput.NetworkAcls[0].NetworkAclId
+	if err := c.AssociateNetworkACL(ctx, inventory.VPCID, defaultNetworkACLID, []string{movedSubnetID}); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if extraDrift := networkACLDrift(report, resource.DriftStatusExtra); len(extraDrift) != 1 ||
+		extraDrift[0].ID != *extra.NetworkAcl.NetworkAclId {
+		t.Errorf("expected extra network ACL %s to be reported, got %+v", *extra.NetworkAcl.NetworkAclId, extraDrift)
+	}
+	if modifiedDrift := networkACLDrift(report, resource.DriftStatusModified); len(modifiedDrift) != 1 ||
+		!strings.Contains(strings.Join(modifiedDrift[0].Details, "; "), movedSubnetID) {
+		t.Errorf("expected moved subnet %s to be reported, got %+v", movedSubnetID, modifiedDrift)
+	}
+	if err := c.DeleteNetworkACL(ctx, *extra.NetworkAcl.NetworkAclId); err != nil {
+		t.Fatal(err)
+	}
+
+	// a network ACL deleted out of band is reported and recreated on resume
+	if err := c.DeleteNetworkACL(ctx, inventory.NetworkACLs[0].NetworkACLID); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if missingDrift := networkACLDrift(report, resource.DriftStatusMissing); len(missingDrift) != 1 {
+		t.Errorf("expected deleted network ACL to be reported, got %+v", missingDrift)
+	}
+	calls = len(backend.Calls())
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, networkACLsConfig(), &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to resume resource stack: %v", err)
+	}
+	var created int
+	for _, call := range backend.Calls()[calls:] {
+		if call == "CreateNetworkAcl" {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("expected 1 network ACL to be recreated, got %d", created)
+	}
+	inventory = r.Inventory()
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift after resume, got %+v", report.Resources)
+	}
+
+	deleteResourceStack(t, backend, c, inventory)
+}
+
+func TestNetworkACLsPodTier(t *testing.T) {
+	resourceConfig := networkACLsConfig()
+	resourceConfig.NetworkACLs = &resource.NetworkACLsConfig{
+		Pod: []resource.NetworkACLRule{
+			{RuleNumber: 100, Action: "allow", Protocol: "6", CIDR: "10.0.0.0/16", FromPort: 10250, ToPort: 10250},
+		},
+	}
+	backend, c, inventory := createResourceStack(t, resourceConfig)
+	if len(inventory.NetworkACLs) != 1 || inventory.NetworkACLs[0].Tier != "pod" {
+		t.Fatalf("expected pod network ACL in inventory, got %+v", inventory.NetworkACLs)
+	}
+
+	var r fake.Recorder
+	r.Record(c)
+	recovered, err := c.RecoverInventory(context.Background(), inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if len(recovered.NetworkACLs) != 1 || recovered.NetworkACLs[0] != inventory.NetworkACLs[0] {
+		t.Errorf("expected network ACLs %+v to be recovered, got %+v", inventory.NetworkACLs, recovered.NetworkACLs)
+	}
+
+	deleteResourceStack(t, backend, c, inventory)
+}
+
+func TestNetworkACLsCreateFailure(t *testing.T) {
+	for _, operation := range []string{"CreateCluster", "CreateNetworkAclEntry"} {
+		t.Run(operation, func(t *testing.T) {
+			backend := fake.NewBackend(testRegion)
+			c := backend.ResourceClient()
+			c.FailurePolicy = resource.FailurePolicyDelete
+			backend.Fail(operation, errors.New("injected failure"))
+
+			var r fake.Recorder
+			r.Record(c)
+			err := c.CreateResourceStack(context.Background(), networkACLsConfig())
+			r.Stop()
+			var failedErr *resource.CreateFailedError
+			if !errors.As(err, &failedErr) || !failedErr.Deleted {
+				t.Fatalf("expected resources to be deleted after failure, got %v", err)
+			}
+			if snapshot := backend.Snapshot(); !snapshot.Empty() {
+				t.Errorf("expected no resources, got %+v", snapshot)
+			}
+		})
+	}
+}
+
+func TestNetworkACLsInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*resource.ResourceConfig)
+		wantErr string
+	}{
+		{
+			name:    "rule number too large",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].RuleNumber = 32767 },
+			wantErr: "public network ACL rule number 32767",
+		},
+		{
+			name:    "rule number zero",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].RuleNumber = 0 },
+			wantErr: "public network ACL rule number 0",
+		},
+		{
+			name:    "duplicate rule number",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[1].RuleNumber = 100 },
+			wantErr: "has more than one inbound rule with rule number 100",
+		},
+		{
+			name:    "unknown action",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].Action = "permit" },
+			wantErr: "action \"permit\"",
+		},
+		{
+			name:    "unknown protocol",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].Protocol = "sctp" },
+			wantErr: "protocol \"sctp\"",
+		},
+		{
+			name:    "protocol number out of range",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].Protocol = "256" },
+			wantErr: "protocol \"256\"",
+		},
+		{
+			name:    "unmasked CIDR",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].CIDR = "10.0.0.1/16" },
+			wantErr: "is not a valid CIDR block",
+		},
+		{
+			name:    "reversed port range",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].FromPort = 500 },
+			wantErr: "port range 500-443",
+		},
+		{
+			name:    "port out of range",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[0].ToPort = 70000 },
+			wantErr: "port range 443-70000",
+		},
+		{
+			name:    "ports for icmp",
+			mutate:  func(r *resource.ResourceConfig) { r.NetworkACLs.Public[1].ToPort = 8 },
+			wantErr: "can only have ports for tcp and udp",
+		},
+		{
+			name: "pod rules without secondary CIDR",
+			mutate: func(r *resource.ResourceConfig) {
+				r.SecondaryCIDR = ""
+				r.NetworkACLs.Pod = r.NetworkACLs.Public
+			},
+			wantErr: "require a secondary CIDR",
+		},
+		{
+			name: "existing VPC",
+			mutate: func(r *resource.ResourceConfig) {
+				r.VPCID = "vpc-123"
+				r.PrivateSubnetIDs = []string{"subnet-1"}
+				r.SecondaryCIDR = ""
+			},
+			wantErr: "network ACLs cannot be created when using an existing VPC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := networkACLsConfig()
+			tc.mutate(resourceConfig)
+			_, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
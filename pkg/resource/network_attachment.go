@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// awsAccountIDPattern matches a 12 digit AWS account ID.
+var awsAccountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// checkNetworkAttachments checks the transit gateway and VPC peering config in
+// the resource config.  They can't be used with an existing VPC as their
+// routes are added to the route tables created for the cluster.  Each needs at
+// least one destination CIDR.  Destination CIDRs must not be default routes,
+// overlap the cluster or secondary CIDR, or overlap each other, as a route
+// table can only have one route for a destination.
+func (r *ResourceConfig) checkNetworkAttachments() error {
+	if r.TransitGateway == nil && r.VPCPeering == nil {
+		return nil
+	}
+	if r.UsesExistingVPC() {
+		return errors.New("transit gateway and VPC peering connection cannot be used in resource config with an existing VPC")
+	}
+
+	var vpcCIDRs []netip.Prefix
+	for _, cidr := range []string{r.ClusterCIDR, r.SecondaryCIDR} {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			vpcCIDRs = append(vpcCIDRs, prefix.Masked())
+		}
+	}
+	var destinationCIDRs []netip.Prefix
+
+	if transitGateway := r.TransitGateway; transitGateway != nil {
+		if !strings.HasPrefix(transitGateway.TransitGatewayID, "tgw-") {
+			return fmt.Errorf("transit gateway ID %q in resource config is not a valid transit gateway ID",
+				transitGateway.TransitGatewayID)
+		}
+		if err := checkDestinationCIDRs("transit gateway", transitGateway.DestinationCIDRs, vpcCIDRs, &destinationCIDRs); err != nil {
+			return err
+		}
+	}
+
+	if vpcPeering := r.VPCPeering; vpcPeering != nil {
+		if !strings.HasPrefix(vpcPeering.PeerVPCID, "vpc-") {
+			return fmt.Errorf("peer VPC ID %q in resource config is not a valid VPC ID", vpcPeering.PeerVPCID)
+		}
+		if vpcPeering.PeerOwnerID != "" && !awsAccountIDPattern.MatchString(vpcPeering.PeerOwnerID) {
+			return fmt.Errorf("peer owner ID %q in resource config is not a valid AWS account ID", vpcPeering.PeerOwnerID)
+		}
+		if err := checkDestinationCIDRs("VPC peering connection", vpcPeering.DestinationCIDRs, vpcCIDRs, &destinationCIDRs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDestinationCIDRs checks the destination CIDRs routed to a transit
+// gateway or VPC peering connection.  Valid CIDRs are added to those already
+// checked so that overlaps between them are found.
+func checkDestinationCIDRs(target string, cidrs []string, vpcCIDRs []netip.Prefix, checked *[]netip.Prefix) error {
+	if len(cidrs) == 0 {
+		return fmt.Errorf("%s in resource config must have at least one destination CIDR", target)
+	}
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || prefix != prefix.Masked() {
+			return fmt.Errorf("%s destination CIDR %q in resource config is not a valid CIDR block", target, cidr)
+		}
+		if prefix.Bits() == 0 {
+			return fmt.Errorf("%s destination CIDR %s in resource config cannot be a default route", target, cidr)
+		}
+		for _, vpcCIDR := range vpcCIDRs {
+			if prefix.Overlaps(vpcCIDR) {
+				return fmt.Errorf("%s destination CIDR %s in resource config overlaps VPC CIDR %s", target, cidr, vpcCIDR)
+			}
+		}
+		for _, checkedCIDR := range *checked {
+			if prefix.Overlaps(checkedCIDR) {
+				return fmt.Errorf("%s destination CIDR %s in resource config overlaps destination CIDR %s",
+					target, cidr, checkedCIDR)
+			}
+		}
+		*checked = append(*checked, prefix)
+	}
+
+	return nil
+}
+
+// networkAttachmentRoutes returns the routes to the transit gateway and VPC
+// peering connection in a resource config that are added to every route
+// table.  The route table IDs are not set.
+func networkAttachmentRoutes(resourceConfig *ResourceConfig, vpcPeeringConnectionID string) []RouteInventory {
+	var routes []RouteInventory
+	if transitGateway := resourceConfig.TransitGateway; transitGateway != nil {
+		for _, cidr := range transitGateway.DestinationCIDRs {
+			routes = append(routes, RouteInventory{
+				DestinationCIDR:  cidr,
+				TransitGatewayID: transitGateway.TransitGatewayID,
+			})
+		}
+	}
+	if vpcPeering := resourceConfig.VPCPeering; vpcPeering != nil {
+		for _, cidr := range vpcPeering.DestinationCIDRs {
+			routes = append(routes, RouteInventory{
+				DestinationCIDR:        cidr,
+				VPCPeeringConnectionID: vpcPeeringConnectionID,
+			})
+		}
+	}
+
+	return routes
+}
@@ -0,0 +1,339 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// networkAttachmentBackend returns a new fake backend with a transit gateway
+// and a peer VPC to attach the cluster's VPC to, along with their IDs.
+func networkAttachmentBackend(t *testing.T) (backend *fake.Backend, transitGatewayID, peerVPCID string) {
+	t.Helper()
+
+	backend = fake.NewBackend(testRegion)
+	createVpcInput := ec2.CreateVpcInput{CidrBlock: aws.String("172.31.0.0/16")}
+	createVpcOutput, err := backend.EC2.CreateVpc(context.Background(), &createVpcInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return backend, backend.AddTransitGateway(), *createVpcOutput.Vpc.VpcId
+}
+
+// networkAttachmentConfig returns a resource config that attaches the VPC to
+// a transit gateway and peers it with another VPC.
+func networkAttachmentConfig(transitGatewayID, peerVPCID string) *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.AWSAccountID = fake.DefaultAccountID
+	resourceConfig.TransitGateway = &resource.TransitGatewayConfig{
+		TransitGatewayID: transitGatewayID,
+		DestinationCIDRs: []string{"10.100.0.0/16", "192.168.0.0/24"},
+	}
+	resourceConfig.VPCPeering = &resource.VPCPeeringConfig{
+		PeerVPCID:        peerVPCID,
+		DestinationCIDRs: []string{"172.31.0.0/16"},
+	}
+
+	return resourceConfig
+}
+
+// deleteNetworkAttachmentStack deletes the resources in the inventory and the
+// peer VPC and checks that none remain.
+func deleteNetworkAttachmentStack(
+	t *testing.T,
+	backend *fake.Backend,
+	c *resource.ResourceClient,
+	inventory resource.ResourceInventory,
+	peerVPCID string,
+) {
+	t.Helper()
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.DeleteResourceStack(context.Background(), &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to delete resource stack: %v", err)
+	}
+	if _, err := backend.EC2.DeleteVpc(context.Background(), &ec2.DeleteVpcInput{VpcId: aws.String(peerVPCID)}); err != nil {
+		t.Fatalf("failed to delete peer VPC: %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources after delete, got %+v", snapshot)
+	}
+}
+
+// networkAttachmentRoutes returns the number of routes to transit gateways
+// and VPC peering connections in an inventory.
+func networkAttachmentRoutes(inventory *resource.ResourceInventory) (transitGatewayRoutes, vpcPeeringRoutes int) {
+	for _, route := range inventory.Routes {
+		if route.TransitGatewayID != "" {
+			transitGatewayRoutes++
+		}
+		if route.VPCPeeringConnectionID != "" {
+			vpcPeeringRoutes++
+		}
+	}
+
+	return transitGatewayRoutes, vpcPeeringRoutes
+}
+
+func TestNetworkAttachment(t *testing.T) {
+	ctx := context.Background()
+	backend, transitGatewayID, peerVPCID := networkAttachmentBackend(t)
+	c := backend.ResourceClient()
+
+	plan, err := c.PlanResourceStack(ctx, networkAttachmentConfig(transitGatewayID, peerVPCID))
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if plan.TransitGatewayAttachment == nil || plan.VPCPeeringConnection == nil || !plan.VPCPeeringConnection.AutoAccept {
+		t.Errorf("expected transit gateway attachment and auto-accepted VPC peering connection in plan, got %+v and %+v",
+			plan.TransitGatewayAttachment, plan.VPCPeeringConnection)
+	}
+
+	var r fake.Recorder
+	r.Record(c)
+	err = c.CreateResourceStack(ctx, networkAttachmentConfig(transitGatewayID, peerVPCID))
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to create resource stack: %v", err)
+	}
+	inventory := r.Inventory()
+	if inventory.TransitGatewayAttachmentID == "" || inventory.VPCPeeringConnectionID == "" {
+		t.Errorf("expected transit gateway attachment and VPC peering connection in inventory, got %q and %q",
+			inventory.TransitGatewayAttachmentID, inventory.VPCPeeringConnectionID)
+	}
+
+	// the public and private route tables have a route for each destination
+	// CIDR
+	transitGatewayRoutes, vpcPeeringRoutes := networkAttachmentRoutes(&inventory)
+	routeTables := len(inventory.PrivateRouteTableIDs) + 1
+	if transitGatewayRoutes != 2*routeTables || vpcPeeringRoutes != routeTables {
+		t.Errorf("expected %d transit gateway routes and %d VPC peering routes, got %d and %d",
+			2*routeTables, routeTables, transitGatewayRoutes, vpcPeeringRoutes)
+	}
+
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+	verified := make(map[resource.ResourceKind]bool)
+	for _, drift := range report.Resources {
+		verified[drift.Kind] = true
+	}
+	if !verified[resource.ResourceKindTransitGatewayAttachment] || !verified[resource.ResourceKindVPCPeeringConnection] {
+		t.Error("expected transit gateway attachment and VPC peering connection to be verified")
+	}
+
+	// resuming with another transit gateway is rejected
+	otherConfig := networkAttachmentConfig(backend.AddTransitGateway(), peerVPCID)
+	c.FailurePolicy = resource.FailurePolicyKeep
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, otherConfig, &inventory)
+	r.Stop()
+	c.FailurePolicy = ""
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected transit gateway mismatch, got %v", err)
+	}
+
+	// the attachment, peering connection and their routes are recovered
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	recoveredTransitGatewayRoutes, recoveredVPCPeeringRoutes := networkAttachmentRoutes(recovered)
+	if recovered.TransitGatewayAttachmentID != inventory.TransitGatewayAttachmentID ||
+		recovered.VPCPeeringConnectionID != inventory.VPCPeeringConnectionID ||
+		recoveredTransitGatewayRoutes != transitGatewayRoutes || recoveredVPCPeeringRoutes != vpcPeeringRoutes {
+		t.Errorf("expected transit gateway attachment and VPC peering connection to be recovered, got %q, %q and %+v",
+			recovered.TransitGatewayAttachmentID, recovered.VPCPeeringConnectionID, recovered.Routes)
+	}
+
+	// a peering connection deleted out of band is reported and recreated on
+	// resume without recreating the transit gateway attachment
+	if err := c.DeleteVPCPeeringConnection(ctx, inventory.VPCPeeringConnectionID); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if !report.Drifted {
+		t.Error("expected deleted VPC peering connection to be reported")
+	}
+	calls := len(backend.Calls())
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, networkAttachmentConfig(transitGatewayID, peerVPCID), &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to resume resource stack: %v", err)
+	}
+	var recreated bool
+	for _, call := range backend.Calls()[calls:] {
+		recreated = recreated || call == "CreateVpcPeeringConnection"
+		if call == "CreateTransitGatewayVpcAttachment" {
+			t.Error("expected transit gateway attachment not to be recreated")
+		}
+	}
+	if !recreated {
+		t.Error("expected VPC peering connection to be recreated")
+	}
+	inventory = r.Inventory()
+	report, err = c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift after resume, got %+v", report.Resources)
+	}
+
+	deleteNetworkAttachmentStack(t, backend, c, inventory, peerVPCID)
+}
+
+func TestVPCPeeringCrossAccount(t *testing.T) {
+	ctx := context.Background()
+	backend, transitGatewayID, peerVPCID := networkAttachmentBackend(t)
+	c := backend.ResourceClient()
+	resourceConfig := networkAttachmentConfig(transitGatewayID, peerVPCID)
+	resourceConfig.TransitGateway = nil
+	resourceConfig.VPCPeering.PeerOwnerID = "210987654321"
+
+	plan, err := c.PlanResourceStack(ctx, resourceConfig)
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if plan.VPCPeeringConnection == nil || plan.VPCPeeringConnection.AutoAccept {
+		t.Errorf("expected VPC peering connection that isn't auto-accepted in plan, got %+v", plan.VPCPeeringConnection)
+	}
+
+	// the peering connection stays pending acceptance by the other account
+	// and isn't reported as drift
+	var r fake.Recorder
+	r.Record(c)
+	err = c.CreateResourceStack(ctx, resourceConfig)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to create resource stack: %v", err)
+	}
+	for _, call := range backend.Calls() {
+		if call == "AcceptVpcPeeringConnection" {
+			t.Error("expected cross-account VPC peering connection not to be accepted")
+		}
+	}
+	inventory := r.Inventory()
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+
+	deleteNetworkAttachmentStack(t, backend, c, inventory, peerVPCID)
+}
+
+func TestNetworkAttachmentCreateFailure(t *testing.T) {
+	ctx := context.Background()
+	backend, transitGatewayID, peerVPCID := networkAttachmentBackend(t)
+	c := backend.ResourceClient()
+	c.FailurePolicy = resource.FailurePolicyDelete
+	backend.Fail("CreateCluster", errors.New("injected failure"))
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.CreateResourceStack(ctx, networkAttachmentConfig(transitGatewayID, peerVPCID))
+	r.Stop()
+	var failedErr *resource.CreateFailedError
+	if !errors.As(err, &failedErr) || !failedErr.Deleted {
+		t.Fatalf("expected resources to be deleted after failure, got %v", err)
+	}
+	if _, err := backend.EC2.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(peerVPCID)}); err != nil {
+		t.Fatalf("failed to delete peer VPC: %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+}
+
+func TestNetworkAttachmentInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*resource.ResourceConfig)
+		wantErr string
+	}{
+		{
+			name:    "invalid transit gateway ID",
+			mutate:  func(r *resource.ResourceConfig) { r.TransitGateway.TransitGatewayID = "bogus" },
+			wantErr: "is not a valid transit gateway ID",
+		},
+		{
+			name:    "no destination CIDRs",
+			mutate:  func(r *resource.ResourceConfig) { r.TransitGateway.DestinationCIDRs = nil },
+			wantErr: "must have at least one destination CIDR",
+		},
+		{
+			name:    "unmasked destination CIDR",
+			mutate:  func(r *resource.ResourceConfig) { r.TransitGateway.DestinationCIDRs = []string{"10.100.0.1/16"} },
+			wantErr: "is not a valid CIDR block",
+		},
+		{
+			name:    "default route",
+			mutate:  func(r *resource.ResourceConfig) { r.TransitGateway.DestinationCIDRs = []string{"0.0.0.0/0"} },
+			wantErr: "cannot be a default route",
+		},
+		{
+			name:    "overlaps cluster CIDR",
+			mutate:  func(r *resource.ResourceConfig) { r.TransitGateway.DestinationCIDRs = []string{"10.0.0.0/8"} },
+			wantErr: "overlaps VPC CIDR",
+		},
+		{
+			name:    "overlapping destination CIDRs",
+			mutate:  func(r *resource.ResourceConfig) { r.VPCPeering.DestinationCIDRs = []string{"10.100.1.0/24"} },
+			wantErr: "overlaps destination CIDR",
+		},
+		{
+			name:    "invalid peer VPC ID",
+			mutate:  func(r *resource.ResourceConfig) { r.VPCPeering.PeerVPCID = "pcx-1" },
+			wantErr: "is not a valid VPC ID",
+		},
+		{
+			name:    "invalid peer owner ID",
+			mutate:  func(r *resource.ResourceConfig) { r.VPCPeering.PeerOwnerID = "123" },
+			wantErr: "is not a valid AWS account ID",
+		},
+		{
+			name: "existing VPC",
+			mutate: func(r *resource.ResourceConfig) {
+				r.VPCID = "vpc-123"
+				r.PrivateSubnetIDs = []string{"subnet-1", "subnet-2"}
+			},
+			wantErr: "with an existing VPC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend, transitGatewayID, peerVPCID := networkAttachmentBackend(t)
+			resourceConfig := networkAttachmentConfig(transitGatewayID, peerVPCID)
+			tc.mutate(resourceConfig)
+			_, err := backend.ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -20,6 +21,7 @@ const (
 
 // CreateNodeGroups creates a private node group for an EKS cluster.
 func (c *ResourceClient) CreateNodeGroups(
+	ctx context.Context,
 	tags *map[string]string,
 	clusterName string,
 	kubernetesVersion string,
@@ -31,7 +33,7 @@ func (c *ResourceClient) CreateNodeGroups(
 	maxNodes int32,
 	keyPair string,
 ) (*[]types.Nodegroup, error) {
-	svc := eks.NewFromConfig(*c.AWSConfig)
+	svc := c.eksClient()
 
 	var nodeGroups []types.Nodegroup
 
@@ -67,7 +69,7 @@ func (c *ResourceClient) CreateNodeGroups(
 			Tags:          *tags,
 		}
 	}
-	privateNodeGroupResp, err := svc.CreateNodegroup(c.Context, &createPrivateNodeGroupInput)
+	privateNodeGroupResp, err := svc.CreateNodegroup(ctx, &createPrivateNodeGroupInput)
 	if err != nil {
 		return &nodeGroups, fmt.Errorf("failed to create node group %s: %w", privateNodeGroupName, err)
 	}
@@ -79,20 +81,20 @@ func (c *ResourceClient) CreateNodeGroups(
 // DeleteNodeGroups deletes the EKS cluster node groups.  If an empty cluster
 // name or node group name is supplied, or if it does not find a node group
 // matching the given name it returns without error.
-func (c *ResourceClient) DeleteNodeGroups(clusterName string, nodeGroupNames []string) error {
+func (c *ResourceClient) DeleteNodeGroups(ctx context.Context, clusterName string, nodeGroupNames []string) error {
 	// if clusterName or nodeGroupName are empty, there's nothing to delete
 	if clusterName == "" || len(nodeGroupNames) == 0 {
 		return nil
 	}
 
-	svc := eks.NewFromConfig(*c.AWSConfig)
+	svc := c.eksClient()
 
 	for _, nodeGroupName := range nodeGroupNames {
 		deleteNodeGroupInput := eks.DeleteNodegroupInput{
 			ClusterName:   &clusterName,
 			NodegroupName: &nodeGroupName,
 		}
-		_, err := svc.DeleteNodegroup(c.Context, &deleteNodeGroupInput)
+		_, err := svc.DeleteNodegroup(ctx, &deleteNodeGroupInput)
 		if err != nil {
 			var notFoundErr *types.ResourceNotFoundException
 			if errors.As(err, &notFoundErr) {
@@ -111,6 +113,7 @@ func (c *ResourceClient) DeleteNodeGroups(clusterName string, nodeGroupNames []s
 // * NodeGroupConditionCreated
 // * NodeGroupConditionDeleted
 func (c *ResourceClient) WaitForNodeGroups(
+	ctx context.Context,
 	clusterName string,
 	nodeGroupNames []string,
 	nodeGroupCondition NodeGroupCondition,
@@ -131,7 +134,7 @@ func (c *ResourceClient) WaitForNodeGroups(
 
 		allConditionsMet := true
 		for _, nodeGroupName := range nodeGroupNames {
-			nodeGroup, err := c.getNodeGroup(clusterName, nodeGroupName)
+			nodeGroup, err := c.getNodeGroup(ctx, clusterName, nodeGroupName)
 			if nodeGroup != nil && nodeGroup.Health != nil {
 				nodeGroupHealth = *nodeGroup.Health
 			}
@@ -160,21 +163,23 @@ func (c *ResourceClient) WaitForNodeGroups(
 		if allConditionsMet {
 			break
 		}
-		time.Sleep(time.Second * NodeGroupCheckInterval)
+		if err := c.waitCheckInterval(ctx, time.Second*NodeGroupCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for node groups %s: %w", nodeGroupNames, err)
+		}
 	}
 
 	return nil
 }
 
 // getNodeGroup retrieves the status of a node group.
-func (c *ResourceClient) getNodeGroup(clusterName, nodeGroupName string) (*types.Nodegroup, error) {
-	svc := eks.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) getNodeGroup(ctx context.Context, clusterName, nodeGroupName string) (*types.Nodegroup, error) {
+	svc := c.eksClient()
 
 	describeNodeGroupInput := eks.DescribeNodegroupInput{
 		ClusterName:   &clusterName,
 		NodegroupName: &nodeGroupName,
 	}
-	resp, err := svc.DescribeNodegroup(c.Context, &describeNodeGroupInput)
+	resp, err := svc.DescribeNodegroup(ctx, &describeNodeGroupInput)
 	if err != nil {
 		var notFoundErr *types.ResourceNotFoundException
 		if errors.As(err, &notFoundErr) {
@@ -2,6 +2,7 @@ package resource
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"crypto/tls"
 	"errors"
@@ -16,36 +17,26 @@ import (
 // CreateOIDCProvider creates a new identity provider in IAM for the EKS cluster.
 // This enables IAM roles for Kubernetes service accounts (IRSA).
 func (c *ResourceClient) CreateOIDCProvider(
+	ctx context.Context,
 	tags *[]types.Tag,
 	providerURL string,
 ) (string, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	var oidcProviderARN string
 	// get the OIDC provider server certificate thumbprint
-	u, err := url.Parse(providerURL)
-	if err != nil {
-		return oidcProviderARN, fmt.Errorf("failed to parse OIDC provider URL: %w", err)
-	}
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%d", u.Hostname(), 443), &tls.Config{})
+	thumbprintString, err := c.getThumbprint(ctx, providerURL)
 	if err != nil {
-		return oidcProviderARN, fmt.Errorf("failed to connect to OIDC provider: %w", err)
-	}
-	cert := conn.ConnectionState().PeerCertificates[len(conn.ConnectionState().PeerCertificates)-1]
-	thumbprint := sha1.Sum(cert.Raw)
-	var thumbprintString string
-	for _, t := range thumbprint {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, "%02X", t)
-		thumbprintString = thumbprintString + strings.ToLower(buf.String())
+		return oidcProviderARN, err
 	}
 
 	createOIDCProviderInput := iam.CreateOpenIDConnectProviderInput{
 		ClientIDList:   []string{"sts.amazonaws.com"},
 		ThumbprintList: []string{thumbprintString},
 		Url:            &providerURL,
+		Tags:           *tags,
 	}
-	resp, err := svc.CreateOpenIDConnectProvider(c.Context, &createOIDCProviderInput)
+	resp, err := svc.CreateOpenIDConnectProvider(ctx, &createOIDCProviderInput)
 	if err != nil {
 		return oidcProviderARN, fmt.Errorf("failed to create IAM identity provider: %w", err)
 	}
@@ -56,18 +47,18 @@ func (c *ResourceClient) CreateOIDCProvider(
 
 // DeleteOIDCProvider deletes an OIDC identity cluster in IAM.  If  an empty ARN
 // is provided or if not found it returns without error.
-func (c *ResourceClient) DeleteOIDCProvider(oidcProviderARN string) error {
+func (c *ResourceClient) DeleteOIDCProvider(ctx context.Context, oidcProviderARN string) error {
 	// if clusterName is empty, there's nothing to delete
 	if oidcProviderARN == "" {
 		return nil
 	}
 
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	deleteOIDCProviderInput := iam.DeleteOpenIDConnectProviderInput{
 		OpenIDConnectProviderArn: &oidcProviderARN,
 	}
-	_, err := svc.DeleteOpenIDConnectProvider(c.Context, &deleteOIDCProviderInput)
+	_, err := svc.DeleteOpenIDConnectProvider(ctx, &deleteOIDCProviderInput)
 	if err != nil {
 		var noSuchEntityErr *types.NoSuchEntityException
 		if errors.As(err, &noSuchEntityErr) {
@@ -79,3 +70,59 @@ func (c *ResourceClient) DeleteOIDCProvider(oidcProviderARN string) error {
 
 	return nil
 }
+
+// getOIDCProvider checks that the OIDC identity provider with the given ARN
+// exists.  If it is not found it returns ErrResourceNotFound.
+func (c *ResourceClient) getOIDCProvider(ctx context.Context, oidcProviderARN string) error {
+	svc := c.iamClient()
+
+	getOIDCProviderInput := iam.GetOpenIDConnectProviderInput{
+		OpenIDConnectProviderArn: &oidcProviderARN,
+	}
+	_, err := svc.GetOpenIDConnectProvider(ctx, &getOIDCProviderInput)
+	if err != nil {
+		var noSuchEntityErr *types.NoSuchEntityException
+		if errors.As(err, &noSuchEntityErr) {
+			return ErrResourceNotFound
+		} else {
+			return fmt.Errorf("failed to get IAM identity provider %s: %w", oidcProviderARN, err)
+		}
+	}
+
+	return nil
+}
+
+// GetOIDCThumbprint returns the SHA-1 thumbprint of the root certificate
+// presented by the OIDC provider's server.
+func GetOIDCThumbprint(ctx context.Context, providerURL string) (string, error) {
+	u, err := url.Parse(providerURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse OIDC provider URL: %w", err)
+	}
+	dialer := tls.Dialer{Config: &tls.Config{}}
+	netConn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", u.Hostname(), 443))
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to OIDC provider: %w", err)
+	}
+	defer netConn.Close()
+	conn := netConn.(*tls.Conn)
+	cert := conn.ConnectionState().PeerCertificates[len(conn.ConnectionState().PeerCertificates)-1]
+	thumbprint := sha1.Sum(cert.Raw)
+	var thumbprintString string
+	for _, t := range thumbprint {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "%02X", t)
+		thumbprintString = thumbprintString + strings.ToLower(buf.String())
+	}
+
+	return thumbprintString, nil
+}
+
+// getThumbprint returns the OIDC provider thumbprint using the resource
+// client's ThumbprintFunc if set, or GetOIDCThumbprint otherwise.
+func (c *ResourceClient) getThumbprint(ctx context.Context, providerURL string) (string, error) {
+	if c.ThumbprintFunc != nil {
+		return c.ThumbprintFunc(ctx, providerURL)
+	}
+	return GetOIDCThumbprint(ctx, providerURL)
+}
@@ -0,0 +1,517 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ResourcePlan describes the resources that CreateResourceStack would create
+// for a resource config.
+type ResourcePlan struct {
+	Region                    string                           `json:"region"`
+	ClusterName               string                           `json:"clusterName"`
+	KubernetesVersion         string                           `json:"kubernetesVersion"`
+	Tags                      map[string]string                `json:"tags"`
+	VPC                       PlannedVPC                       `json:"vpc"`
+	InternetGateway           bool                             `json:"internetGateway"`
+	IPFamily                  IPFamily                         `json:"ipFamily"`
+	EgressOnlyInternetGateway bool                             `json:"egressOnlyInternetGateway,omitempty"`
+	AvailabilityZones         []PlannedAvailabilityZone        `json:"availabilityZones"`
+	NATGatewayMode            NATGatewayMode                   `json:"natGatewayMode,omitempty"`
+	ElasticIPCount            int                              `json:"elasticIPCount"`
+	NATGateways               []PlannedNATGateway              `json:"natGateways"`
+	RouteTables               []PlannedRouteTable              `json:"routeTables"`
+	VPCEndpoints              []PlannedVPCEndpoint             `json:"vpcEndpoints,omitempty"`
+	FlowLog                   *PlannedFlowLog                  `json:"flowLog,omitempty"`
+	TransitGatewayAttachment  *PlannedTransitGatewayAttachment `json:"transitGatewayAttachment,omitempty"`
+	VPCPeeringConnection      *PlannedVPCPeeringConnection     `json:"vpcPeeringConnection,omitempty"`
+	NetworkACLs               []PlannedNetworkACL              `json:"networkACLs,omitempty"`
+	Policies                  []PlannedPolicy                  `json:"policies"`
+	Roles                     []PlannedRole                    `json:"roles"`
+	Cluster                   PlannedCluster                   `json:"cluster"`
+	NodeGroups                []PlannedNodeGroup               `json:"nodeGroups"`
+	OIDCProvider              bool                             `json:"oidcProvider"`
+	Addons                    []string                         `json:"addons"`
+}
+
+// PlannedVPC describes the VPC to be created.  The ID is only set for an
+// existing VPC.  An Amazon-provided IPv6 CIDR block is requested for the IPv6
+// IP family - the subnets' IPv6 CIDR blocks are carved out of it once it is
+// known.  The secondary CIDR, if any, is associated with the VPC for the pod
+// subnets.
+type PlannedVPC struct {
+	ID                     string `json:"id,omitempty"`
+	CIDR                   string `json:"cidr"`
+	AmazonProvidedIPv6CIDR bool   `json:"amazonProvidedIPv6CIDR,omitempty"`
+	SecondaryCIDR          string `json:"secondaryCIDR,omitempty"`
+}
+
+// PlannedAvailabilityZone describes the subnets to be created in an
+// availability zone.  The subnet IDs are only set for existing subnets.  The
+// pod subnet is only created with a secondary CIDR.
+type PlannedAvailabilityZone struct {
+	Zone              string `json:"zone"`
+	ZoneID            string `json:"zoneID,omitempty"`
+	PrivateSubnetID   string `json:"privateSubnetID,omitempty"`
+	PrivateSubnetCIDR string `json:"privateSubnetCIDR"`
+	PublicSubnetID    string `json:"publicSubnetID,omitempty"`
+	PublicSubnetCIDR  string `json:"publicSubnetCIDR"`
+	PodSubnetCIDR     string `json:"podSubnetCIDR,omitempty"`
+}
+
+// PlannedNATGateway describes a NAT gateway to be created in the public subnet
+// of an availability zone.
+type PlannedNATGateway struct {
+	Zone             string `json:"zone"`
+	PublicSubnetCIDR string `json:"publicSubnetCIDR"`
+}
+
+// PlannedRouteTable describes a route table to be created along with the
+// subnets it is associated with and its default routes.  Private route tables
+// have no IPv4 default route when no NAT gateways are created.  The IPv6
+// default route is only added for the IPv6 IP family.  Routes to a transit
+// gateway or VPC peering connection are added to every route table.
+type PlannedRouteTable struct {
+	Tier                   string         `json:"tier"`
+	Zones                  []string       `json:"zones"`
+	SubnetCIDRs            []string       `json:"subnetCIDRs"`
+	DefaultRouteTarget     string         `json:"defaultRouteTarget,omitempty"`
+	IPv6DefaultRouteTarget string         `json:"ipv6DefaultRouteTarget,omitempty"`
+	Routes                 []PlannedRoute `json:"routes,omitempty"`
+}
+
+// PlannedRoute describes a route to be added to a route table other than its
+// default routes.
+type PlannedRoute struct {
+	DestinationCIDR string `json:"destinationCIDR"`
+	Target          string `json:"target"`
+}
+
+// PlannedVPCEndpoint describes a VPC endpoint to be created for an AWS service.
+// Gateway endpoints are added to the private route tables and interface
+// endpoints are placed in the private subnets with the VPC endpoint security
+// group.
+type PlannedVPCEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	Type        string `json:"type"`
+}
+
+// PlannedFlowLog describes the flow log to be created for the VPC and where it
+// is published.  The log group and the role that delivers to it are only
+// created for CloudWatch Logs.
+type PlannedFlowLog struct {
+	Destination   FlowLogDestination `json:"destination"`
+	TrafficType   string             `json:"trafficType"`
+	LogGroupName  string             `json:"logGroupName,omitempty"`
+	RetentionDays int32              `json:"retentionDays,omitempty"`
+	RoleName      string             `json:"roleName,omitempty"`
+	S3BucketARN   string             `json:"s3BucketARN,omitempty"`
+}
+
+// PlannedTransitGatewayAttachment describes the attachment of the VPC to an
+// existing transit gateway.  It is given a network interface in each private
+// subnet.
+type PlannedTransitGatewayAttachment struct {
+	TransitGatewayID string   `json:"transitGatewayID"`
+	DestinationCIDRs []string `json:"destinationCIDRs"`
+}
+
+// PlannedVPCPeeringConnection describes the peering connection to be requested
+// from the VPC to the peer VPC.  It is only accepted automatically when the
+// peer VPC is in the same account and region.
+type PlannedVPCPeeringConnection struct {
+	PeerVPCID        string   `json:"peerVPCID"`
+	PeerOwnerID      string   `json:"peerOwnerID,omitempty"`
+	PeerRegion       string   `json:"peerRegion,omitempty"`
+	DestinationCIDRs []string `json:"destinationCIDRs"`
+	AutoAccept       bool     `json:"autoAccept"`
+}
+
+// PlannedNetworkACL describes a network ACL to be created for a tier of
+// subnets along with the subnets it is associated with and its rules.
+type PlannedNetworkACL struct {
+	Tier        string                  `json:"tier"`
+	Zones       []string                `json:"zones"`
+	SubnetCIDRs []string                `json:"subnetCIDRs"`
+	Rules       []PlannedNetworkACLRule `json:"rules"`
+}
+
+// PlannedNetworkACLRule describes an inbound or outbound rule in a network
+// ACL.  The port range is only set for TCP and UDP.
+type PlannedNetworkACLRule struct {
+	RuleNumber int32  `json:"ruleNumber"`
+	Direction  string `json:"direction"`
+	Action     string `json:"action"`
+	Protocol   string `json:"protocol"`
+	CIDR       string `json:"cidr"`
+	PortRange  string `json:"portRange,omitempty"`
+}
+
+// PlannedPolicy describes an IAM policy to be created.
+type PlannedPolicy struct {
+	PolicyName string `json:"policyName"`
+}
+
+// PlannedRole describes an IAM role to be created along with the policies
+// attached to it.  Policies that are created as part of the stack are
+// referenced by name, AWS managed policies by ARN.
+type PlannedRole struct {
+	RoleName            string   `json:"roleName"`
+	Policies            []string `json:"policies"`
+	PermissionsBoundary string   `json:"permissionsBoundary,omitempty"`
+}
+
+// PlannedCluster describes the EKS cluster to be created.
+type PlannedCluster struct {
+	ClusterName       string   `json:"clusterName"`
+	KubernetesVersion string   `json:"kubernetesVersion"`
+	RoleName          string   `json:"roleName"`
+	SubnetTier        string   `json:"subnetTier"`
+	IPFamily          IPFamily `json:"ipFamily"`
+}
+
+// PlannedNodeGroup describes an EKS node group to be created.
+type PlannedNodeGroup struct {
+	NodeGroupName string   `json:"nodeGroupName"`
+	RoleName      string   `json:"roleName"`
+	SubnetTier    string   `json:"subnetTier"`
+	InstanceTypes []string `json:"instanceTypes"`
+	InitialNodes  int32    `json:"initialNodes"`
+	MinNodes      int32    `json:"minNodes"`
+	MaxNodes      int32    `json:"maxNodes"`
+	KeyPair       string   `json:"keyPair,omitempty"`
+}
+
+// PlanResourceStack resolves the defaults in a resource config, including the
+// availability zones, and returns the resources CreateResourceStack would
+// create.  When using an existing VPC, its subnets are checked and no
+// networking resources are planned.  Other than looking up availability zones
+// and the existing VPC, no AWS API calls are made and nothing is created.
+func (c *ResourceClient) PlanResourceStack(ctx context.Context, resourceConfig *ResourceConfig) (*ResourcePlan, error) {
+	var plan ResourcePlan
+	if resourceConfig.Region != "" {
+		c.AWSConfig.Region = resourceConfig.Region
+	} else {
+		resourceConfig.Region = c.AWSConfig.Region
+	}
+	plan.Region = resourceConfig.Region
+	plan.ClusterName = resourceConfig.Name
+	plan.KubernetesVersion = resourceConfig.KubernetesVersion
+	plan.Tags = CreateMapTags(resourceConfig.Name, resourceConfig.Tags)
+
+	// set availability zones as needed
+	if err := resourceConfig.checkNATGatewayMode(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkVPCEndpoints(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkIPFamily(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkSecondaryCIDR(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkFlowLogs(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkNetworkAttachments(); err != nil {
+		return nil, err
+	}
+	if err := resourceConfig.checkNetworkACLs(); err != nil {
+		return nil, err
+	}
+	plan.IPFamily = ipFamilyOrDefault(resourceConfig.IPFamily)
+	if err := resourceConfig.SetAvailabilityZones(ctx, c); err != nil {
+		return nil, err
+	}
+
+	// an existing VPC and subnets are used as they are
+	if resourceConfig.UsesExistingVPC() {
+		plan.VPC = PlannedVPC{ID: resourceConfig.VPCID, CIDR: resourceConfig.ClusterCIDR}
+		for _, az := range resourceConfig.AvailabilityZones {
+			plan.AvailabilityZones = append(plan.AvailabilityZones, PlannedAvailabilityZone{
+				Zone:              az.Zone,
+				ZoneID:            az.ZoneID,
+				PrivateSubnetID:   az.PrivateSubnetID,
+				PrivateSubnetCIDR: az.PrivateSubnetCIDR,
+				PublicSubnetID:    az.PublicSubnetID,
+				PublicSubnetCIDR:  az.PublicSubnetCIDR,
+			})
+		}
+	} else {
+		plan.planNetwork(resourceConfig)
+	}
+
+	// IAM policies
+	dnsPolicyName := fmt.Sprintf("%s-%s", DNSPolicyName, resourceConfig.Name)
+	dns01ChallengePolicyName := fmt.Sprintf("%s-%s", DNS01ChallengePolicyName, resourceConfig.Name)
+	autoscalingPolicyName := fmt.Sprintf("%s-%s", AutoscalingPolicyName, resourceConfig.Name)
+	if resourceConfig.DNSManagement {
+		plan.Policies = append(plan.Policies, PlannedPolicy{PolicyName: dnsPolicyName})
+	}
+	if resourceConfig.DNS01Challenge {
+		plan.Policies = append(plan.Policies, PlannedPolicy{PolicyName: dns01ChallengePolicyName})
+	}
+	if resourceConfig.ClusterAutoscaling {
+		plan.Policies = append(plan.Policies, PlannedPolicy{PolicyName: autoscalingPolicyName})
+	}
+	workerPolicies := getWorkerPolicyARNs()
+	if plan.IPFamily == IPFamilyIPv6 {
+		cniIPv6PolicyName := fmt.Sprintf("%s-%s", CNIIPv6PolicyName, resourceConfig.Name)
+		plan.Policies = append(plan.Policies, PlannedPolicy{PolicyName: cniIPv6PolicyName})
+		workerPolicies = append(workerPolicies, cniIPv6PolicyName)
+	}
+	flowLogsPolicyName := fmt.Sprintf("%s-%s", FlowLogsPolicyName, resourceConfig.Name)
+	if plan.FlowLog != nil && plan.FlowLog.RoleName != "" {
+		plan.Policies = append(plan.Policies, PlannedPolicy{PolicyName: flowLogsPolicyName})
+	}
+
+	// IAM roles
+	clusterRoleName := fmt.Sprintf("%s-%s", ClusterRoleName, resourceConfig.Name)
+	workerRoleName := fmt.Sprintf("%s-%s", WorkerRoleName, resourceConfig.Name)
+	plan.Roles = append(plan.Roles,
+		PlannedRole{RoleName: clusterRoleName, Policies: []string{ClusterPolicyARN}},
+		PlannedRole{RoleName: workerRoleName, Policies: workerPolicies},
+	)
+	if resourceConfig.DNSManagement {
+		plan.Roles = append(plan.Roles, PlannedRole{
+			RoleName: fmt.Sprintf("%s-%s", DNSManagementRoleName, resourceConfig.Name),
+			Policies: []string{dnsPolicyName},
+		})
+	}
+	if resourceConfig.DNS01Challenge {
+		plan.Roles = append(plan.Roles, PlannedRole{
+			RoleName: fmt.Sprintf("%s-%s", DNS01ChallengeRoleName, resourceConfig.Name),
+			Policies: []string{dns01ChallengePolicyName},
+		})
+	}
+	if resourceConfig.ClusterAutoscaling {
+		plan.Roles = append(plan.Roles, PlannedRole{
+			RoleName:            fmt.Sprintf("%s-%s", ClusterAutoscalingRoleName, resourceConfig.Name),
+			Policies:            []string{autoscalingPolicyName},
+			PermissionsBoundary: autoscalingPolicyName,
+		})
+	}
+	plan.Roles = append(plan.Roles, PlannedRole{
+		RoleName:            fmt.Sprintf("%s-%s", StorageManagementRoleName, resourceConfig.Name),
+		Policies:            []string{CSIDriverPolicyARN},
+		PermissionsBoundary: CSIDriverPolicyARN,
+	})
+	if plan.FlowLog != nil && plan.FlowLog.RoleName != "" {
+		plan.Roles = append(plan.Roles, PlannedRole{
+			RoleName: plan.FlowLog.RoleName,
+			Policies: []string{flowLogsPolicyName},
+		})
+	}
+	for _, role := range plan.Roles {
+		if err := CheckRoleName(role.RoleName); err != nil {
+			return nil, err
+		}
+	}
+
+	// EKS cluster, node group, OIDC provider and addons
+	plan.Cluster = PlannedCluster{
+		ClusterName:       resourceConfig.Name,
+		KubernetesVersion: resourceConfig.KubernetesVersion,
+		RoleName:          clusterRoleName,
+		SubnetTier:        "private",
+		IPFamily:          plan.IPFamily,
+	}
+	plan.NodeGroups = []PlannedNodeGroup{
+		{
+			NodeGroupName: fmt.Sprintf("%s-private-node-group", resourceConfig.Name),
+			RoleName:      workerRoleName,
+			SubnetTier:    "private",
+			InstanceTypes: resourceConfig.InstanceTypes,
+			InitialNodes:  resourceConfig.InitialNodes,
+			MinNodes:      resourceConfig.MinNodes,
+			MaxNodes:      resourceConfig.MaxNodes,
+			KeyPair:       resourceConfig.KeyPair,
+		},
+	}
+	plan.OIDCProvider = true
+	plan.Addons = []string{EBSStorageAddonName}
+
+	return &plan, nil
+}
+
+// planNetwork adds the VPC, internet gateways, subnets, elastic IPs, NAT
+// gateways, route tables, VPC endpoints, flow log, transit gateway attachment,
+// VPC peering connection and network ACLs to be created for the availability
+// zones in a resource config to the plan.
+func (p *ResourcePlan) planNetwork(resourceConfig *ResourceConfig) {
+	ipv6 := p.IPFamily == IPFamilyIPv6
+	p.VPC = PlannedVPC{
+		CIDR:                   resourceConfig.ClusterCIDR,
+		AmazonProvidedIPv6CIDR: ipv6,
+		SecondaryCIDR:          resourceConfig.SecondaryCIDR,
+	}
+	p.InternetGateway = true
+	p.EgressOnlyInternetGateway = ipv6
+	p.NATGatewayMode = natGatewayModeOrDefault(resourceConfig.NATGatewayMode)
+	natCount := natGatewayCount(p.NATGatewayMode, len(resourceConfig.AvailabilityZones))
+	routes := planNetworkAttachmentRoutes(resourceConfig)
+	publicRouteTable := PlannedRouteTable{
+		Tier:               "public",
+		DefaultRouteTarget: "internet-gateway",
+		Routes:             routes,
+	}
+	var privateRouteTables []PlannedRouteTable
+	for i, az := range resourceConfig.AvailabilityZones {
+		p.AvailabilityZones = append(p.AvailabilityZones, PlannedAvailabilityZone{
+			Zone:              az.Zone,
+			ZoneID:            az.ZoneID,
+			PrivateSubnetCIDR: az.PrivateSubnetCIDR,
+			PublicSubnetCIDR:  az.PublicSubnetCIDR,
+			PodSubnetCIDR:     az.PodSubnetCIDR,
+		})
+
+		// the public subnets of the first availability zones get an elastic
+		// IP and NAT gateway
+		if i < natCount {
+			p.ElasticIPCount += 1
+			p.NATGateways = append(p.NATGateways, PlannedNATGateway{
+				Zone:             az.Zone,
+				PublicSubnetCIDR: az.PublicSubnetCIDR,
+			})
+		}
+
+		// public subnets share a route table, each private subnet has its own
+		// with a default route to its own NAT gateway, the single NAT gateway
+		// or nothing - the pod subnet shares the private subnet's
+		publicRouteTable.Zones = append(publicRouteTable.Zones, az.Zone)
+		publicRouteTable.SubnetCIDRs = append(publicRouteTable.SubnetCIDRs, az.PublicSubnetCIDR)
+		privateRouteTable := PlannedRouteTable{
+			Tier:        "private",
+			Zones:       []string{az.Zone},
+			SubnetCIDRs: []string{az.PrivateSubnetCIDR},
+			Routes:      routes,
+		}
+		if az.PodSubnetCIDR != "" {
+			privateRouteTable.SubnetCIDRs = append(privateRouteTable.SubnetCIDRs, az.PodSubnetCIDR)
+		}
+		switch {
+		case i < natCount:
+			privateRouteTable.DefaultRouteTarget = fmt.Sprintf("nat-gateway/%s", az.Zone)
+		case natCount > 0:
+			privateRouteTable.DefaultRouteTarget = fmt.Sprintf("nat-gateway/%s", resourceConfig.AvailabilityZones[0].Zone)
+		}
+		if ipv6 {
+			privateRouteTable.IPv6DefaultRouteTarget = "egress-only-internet-gateway"
+		}
+		privateRouteTables = append(privateRouteTables, privateRouteTable)
+	}
+	if ipv6 {
+		publicRouteTable.IPv6DefaultRouteTarget = "internet-gateway"
+	}
+	p.RouteTables = append(privateRouteTables, publicRouteTable)
+
+	if resourceConfig.VPCEndpoints {
+		for _, service := range vpcEndpointServices {
+			p.VPCEndpoints = append(p.VPCEndpoints, PlannedVPCEndpoint{
+				ServiceName: vpcEndpointServiceName(resourceConfig.Region, service.name),
+				Type:        string(service.endpointType),
+			})
+		}
+	}
+
+	if flowLogs := resourceConfig.FlowLogs; flowLogs != nil {
+		p.FlowLog = &PlannedFlowLog{
+			Destination: flowLogs.destination(),
+			TrafficType: string(flowLogs.trafficType()),
+		}
+		if flowLogs.destination() == FlowLogDestinationCloudWatchLogs {
+			p.FlowLog.LogGroupName = flowLogs.logGroupName(resourceConfig.Name)
+			p.FlowLog.RetentionDays = flowLogs.RetentionDays
+			p.FlowLog.RoleName = fmt.Sprintf("%s-%s", FlowLogsRoleName, resourceConfig.Name)
+		} else {
+			p.FlowLog.S3BucketARN = flowLogs.S3BucketARN
+		}
+	}
+
+	if transitGateway := resourceConfig.TransitGateway; transitGateway != nil {
+		p.TransitGatewayAttachment = &PlannedTransitGatewayAttachment{
+			TransitGatewayID: transitGateway.TransitGatewayID,
+			DestinationCIDRs: transitGateway.DestinationCIDRs,
+		}
+	}
+	if vpcPeering := resourceConfig.VPCPeering; vpcPeering != nil {
+		p.VPCPeeringConnection = &PlannedVPCPeeringConnection{
+			PeerVPCID:        vpcPeering.PeerVPCID,
+			PeerOwnerID:      vpcPeering.PeerOwnerID,
+			PeerRegion:       vpcPeering.PeerRegion,
+			DestinationCIDRs: vpcPeering.DestinationCIDRs,
+			AutoAccept:       vpcPeering.autoAccept(resourceConfig.AWSAccountID, resourceConfig.Region),
+		}
+	}
+
+	if networkACLs := resourceConfig.NetworkACLs; networkACLs != nil {
+		for _, tier := range networkACLs.tiers() {
+			p.NetworkACLs = append(p.NetworkACLs, planNetworkACL(tier, resourceConfig.AvailabilityZones))
+		}
+	}
+}
+
+// planNetworkACL returns the network ACL to be created for a tier of subnets
+// in the availability zones.
+func planNetworkACL(tier networkACLTier, availabilityZones []AvailabilityZone) PlannedNetworkACL {
+	networkACL := PlannedNetworkACL{Tier: tier.tier}
+	for _, az := range availabilityZones {
+		var subnetCIDR string
+		switch tier.tier {
+		case "public":
+			subnetCIDR = az.PublicSubnetCIDR
+		case "private":
+			subnetCIDR = az.PrivateSubnetCIDR
+		case "pod":
+			subnetCIDR = az.PodSubnetCIDR
+		}
+		networkACL.Zones = append(networkACL.Zones, az.Zone)
+		networkACL.SubnetCIDRs = append(networkACL.SubnetCIDRs, subnetCIDR)
+	}
+	for _, rule := range tier.rules {
+		plannedRule := PlannedNetworkACLRule{
+			RuleNumber: rule.RuleNumber,
+			Direction:  networkACLRuleDirection(rule.Egress),
+			Action:     rule.Action,
+			Protocol:   rule.Protocol,
+			CIDR:       rule.CIDR,
+		}
+		if protocol, _ := networkACLProtocol(rule.Protocol); networkACLProtocolHasPorts(protocol) {
+			fromPort, toPort := rule.portRange()
+			plannedRule.PortRange = fmt.Sprintf("%d-%d", fromPort, toPort)
+		}
+		networkACL.Rules = append(networkACL.Rules, plannedRule)
+	}
+
+	return networkACL
+}
+
+// planNetworkAttachmentRoutes returns the routes to the transit gateway and
+// VPC peering connection in a resource config that are added to every route
+// table.  The peering connection is named by its peer VPC as its ID is not
+// known until it is requested.
+func planNetworkAttachmentRoutes(resourceConfig *ResourceConfig) []PlannedRoute {
+	var routes []PlannedRoute
+	for _, route := range networkAttachmentRoutes(resourceConfig, "") {
+		target := fmt.Sprintf("transit-gateway/%s", route.TransitGatewayID)
+		if route.TransitGatewayID == "" {
+			target = fmt.Sprintf("vpc-peering-connection/%s", resourceConfig.VPCPeering.PeerVPCID)
+		}
+		routes = append(routes, PlannedRoute{DestinationCIDR: route.DestinationCIDR, Target: target})
+	}
+
+	return routes
+}
+
+// MarshalPlan returns the JSON representation of a resource plan.
+func MarshalPlan(plan *ResourcePlan) ([]byte, error) {
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource plan to JSON: %w", err)
+	}
+
+	return planJSON, nil
+}
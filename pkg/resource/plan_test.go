@@ -0,0 +1,80 @@
+package resource_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+func TestPlanResourceStack(t *testing.T) {
+	testCases := []struct {
+		name           string
+		resourceConfig func() *resource.ResourceConfig
+	}{
+		{
+			name:           "defaults",
+			resourceConfig: testConfig,
+		},
+		{
+			name: "all roles and policies",
+			resourceConfig: func() *resource.ResourceConfig {
+				resourceConfig := testConfig()
+				resourceConfig.DNSManagement = true
+				resourceConfig.DNS01Challenge = true
+				resourceConfig.ClusterAutoscaling = true
+				return resourceConfig
+			},
+		},
+		{
+			name:           "flow logs",
+			resourceConfig: flowLogsConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := fake.NewBackend(testRegion)
+			plan, err := backend.ResourceClient().PlanResourceStack(context.Background(), tc.resourceConfig())
+			if err != nil {
+				t.Fatalf("failed to plan resource stack: %v", err)
+			}
+
+			// planning only looks up availability zones
+			for _, call := range backend.Calls() {
+				if !strings.HasPrefix(call, "Describe") {
+					t.Errorf("expected only describe calls while planning, got %s", call)
+				}
+			}
+			if snapshot := backend.Snapshot(); !snapshot.Empty() {
+				t.Fatalf("expected planning to create nothing, got %+v", snapshot)
+			}
+
+			// the plan matches what is created for the same config
+			createdBackend, _, inventory := createResourceStack(t, tc.resourceConfig())
+			created := createdBackend.Snapshot()
+			for name, counts := range map[string][2]int{
+				"subnets":      {len(created.SubnetIDs), 2 * len(plan.AvailabilityZones)},
+				"NAT gateways": {len(created.NATGatewayIDs), len(plan.NATGateways)},
+				"elastic IPs":  {len(created.AllocationIDs), plan.ElasticIPCount},
+				"route tables": {len(created.RouteTableIDs), len(plan.RouteTables)},
+				"policies":     {len(created.PolicyARNs), len(plan.Policies)},
+				"roles":        {len(created.RoleNames), len(plan.Roles)},
+				"node groups":  {len(created.NodegroupNames), len(plan.NodeGroups)},
+				"addons":       {len(created.AddonNames), len(plan.Addons)},
+			} {
+				if counts[0] != counts[1] {
+					t.Errorf("expected %d %s from plan, got %d", counts[1], name, counts[0])
+				}
+			}
+			if plan.Cluster.ClusterName != inventory.Cluster.ClusterName {
+				t.Errorf("expected planned cluster %s, got %s", inventory.Cluster.ClusterName, plan.Cluster.ClusterName)
+			}
+			if (plan.FlowLog != nil) != (inventory.FlowLogID != "") {
+				t.Errorf("expected planned flow log %t, got %+v", inventory.FlowLogID != "", plan.FlowLog)
+			}
+		})
+	}
+}
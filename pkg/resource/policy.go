@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,12 +13,14 @@ const (
 	DNSPolicyName            = "DNSUpdates"
 	DNS01ChallengePolicyName = "DNS01Challenge"
 	AutoscalingPolicyName    = "ClusterAutoscaler"
+	CNIIPv6PolicyName        = "CNIIPv6"
+	FlowLogsPolicyName       = "FlowLogsDelivery"
 )
 
 // CreateDNSManagementPolicy creates the IAM policy to be used for managing
 // Route53 DNS records.
-func (c *ResourceClient) CreateDNSManagementPolicy(tags *[]types.Tag, clusterName string) (*types.Policy, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) CreateDNSManagementPolicy(ctx context.Context, tags *[]types.Tag, clusterName string) (*types.Policy, error) {
+	svc := c.iamClient()
 
 	dnsPolicyName := fmt.Sprintf("%s-%s", DNSPolicyName, clusterName)
 	dnsPolicyDescription := "Allow cluster services to update Route53 records"
@@ -49,8 +52,9 @@ func (c *ResourceClient) CreateDNSManagementPolicy(tags *[]types.Tag, clusterNam
 		PolicyName:     &dnsPolicyName,
 		Description:    &dnsPolicyDescription,
 		PolicyDocument: &dnsPolicyDocument,
+		Tags:           *tags,
 	}
-	r53PolicyResp, err := svc.CreatePolicy(c.Context, &createR53PolicyInput)
+	r53PolicyResp, err := svc.CreatePolicy(ctx, &createR53PolicyInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create DNS management policy %s: %w", dnsPolicyName, err)
 	}
@@ -60,8 +64,8 @@ func (c *ResourceClient) CreateDNSManagementPolicy(tags *[]types.Tag, clusterNam
 
 // CreateDNS01ChallengePolicy creates the IAM policy to be used for completing
 // DNS01 challenges.
-func (c *ResourceClient) CreateDNS01ChallengePolicy(tags *[]types.Tag, clusterName string) (*types.Policy, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) CreateDNS01ChallengePolicy(ctx context.Context, tags *[]types.Tag, clusterName string) (*types.Policy, error) {
+	svc := c.iamClient()
 
 	dnsPolicyName := fmt.Sprintf("%s-%s", DNS01ChallengePolicyName, clusterName)
 	dnsPolicyDescription := "Allow cluster services to complete DNS01 challenges"
@@ -100,8 +104,9 @@ func (c *ResourceClient) CreateDNS01ChallengePolicy(tags *[]types.Tag, clusterNa
 		PolicyName:     &dnsPolicyName,
 		Description:    &dnsPolicyDescription,
 		PolicyDocument: &dnsPolicyDocument,
+		Tags:           *tags,
 	}
-	r53PolicyResp, err := svc.CreatePolicy(c.Context, &createR53PolicyInput)
+	r53PolicyResp, err := svc.CreatePolicy(ctx, &createR53PolicyInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create DNS01 challenge policy %s: %w", dnsPolicyName, err)
 	}
@@ -112,10 +117,11 @@ func (c *ResourceClient) CreateDNS01ChallengePolicy(tags *[]types.Tag, clusterNa
 // CreateClusterAutoscalingPolicy creates the IAM policy to be used for cluster
 // autoscaling to manage node pool sizes.
 func (c *ResourceClient) CreateClusterAutoscalingPolicy(
+	ctx context.Context,
 	tags *[]types.Tag,
 	clusterName string,
 ) (*types.Policy, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	autoscalingPolicyName := fmt.Sprintf("%s-%s", AutoscalingPolicyName, clusterName)
 	autoscalingPolicyDescription := "Allow cluster autoscaler to manage node pool sizes"
@@ -153,8 +159,9 @@ func (c *ResourceClient) CreateClusterAutoscalingPolicy(
 		PolicyName:     &autoscalingPolicyName,
 		Description:    &autoscalingPolicyDescription,
 		PolicyDocument: &autoscalingPolicyDocument,
+		Tags:           *tags,
 	}
-	autoscalingPolicyResp, err := svc.CreatePolicy(c.Context, &createAutoscalingPolicyInput)
+	autoscalingPolicyResp, err := svc.CreatePolicy(ctx, &createAutoscalingPolicyInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cluster autoscaler management policy %s: %w", autoscalingPolicyName, err)
 	}
@@ -162,21 +169,114 @@ func (c *ResourceClient) CreateClusterAutoscalingPolicy(
 	return autoscalingPolicyResp.Policy, nil
 }
 
+// CreateCNIIPv6Policy creates the IAM policy the Amazon VPC CNI plugin needs to
+// assign IPv6 addresses to pods on worker nodes.  AWS doesn't provide a managed
+// policy for it.
+func (c *ResourceClient) CreateCNIIPv6Policy(ctx context.Context, tags *[]types.Tag, clusterName string) (*types.Policy, error) {
+	svc := c.iamClient()
+
+	cniIPv6PolicyName := fmt.Sprintf("%s-%s", CNIIPv6PolicyName, clusterName)
+	cniIPv6PolicyDescription := "Allow the VPC CNI plugin to assign IPv6 addresses to pods"
+	cniIPv6PolicyDocument := `{
+"Version": "2012-10-17",
+"Statement": [
+{
+  "Effect": "Allow",
+  "Action": [
+	"ec2:AssignIpv6Addresses",
+	"ec2:DescribeInstances",
+	"ec2:DescribeTags",
+	"ec2:DescribeNetworkInterfaces",
+	"ec2:DescribeInstanceTypes"
+  ],
+  "Resource": [
+	"*"
+  ]
+},
+{
+  "Effect": "Allow",
+  "Action": [
+	"ec2:CreateTags"
+  ],
+  "Resource": [
+	"arn:aws:ec2:*:*:network-interface/*"
+  ]
+}
+]
+}`
+	createCNIIPv6PolicyInput := iam.CreatePolicyInput{
+		PolicyName:     &cniIPv6PolicyName,
+		Description:    &cniIPv6PolicyDescription,
+		PolicyDocument: &cniIPv6PolicyDocument,
+		Tags:           *tags,
+	}
+	cniIPv6PolicyResp, err := svc.CreatePolicy(ctx, &createCNIIPv6PolicyInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CNI IPv6 policy %s: %w", cniIPv6PolicyName, err)
+	}
+
+	return cniIPv6PolicyResp.Policy, nil
+}
+
+// CreateFlowLogsPolicy creates the IAM policy the VPC flow logs service needs
+// to deliver flow logs to a CloudWatch Logs log group.
+func (c *ResourceClient) CreateFlowLogsPolicy(
+	ctx context.Context,
+	tags *[]types.Tag,
+	logGroupName string,
+	clusterName string,
+) (*types.Policy, error) {
+	svc := c.iamClient()
+
+	flowLogsPolicyName := fmt.Sprintf("%s-%s", FlowLogsPolicyName, clusterName)
+	flowLogsPolicyDescription := "Allow VPC flow logs to be delivered to CloudWatch Logs"
+	flowLogsPolicyDocument := fmt.Sprintf(`{
+"Version": "2012-10-17",
+"Statement": [
+{
+  "Effect": "Allow",
+  "Action": [
+	"logs:CreateLogStream",
+	"logs:PutLogEvents",
+	"logs:DescribeLogGroups",
+	"logs:DescribeLogStreams"
+  ],
+  "Resource": [
+	"arn:aws:logs:*:*:log-group:%[1]s",
+	"arn:aws:logs:*:*:log-group:%[1]s:*"
+  ]
+}
+]
+}`, logGroupName)
+	createFlowLogsPolicyInput := iam.CreatePolicyInput{
+		PolicyName:     &flowLogsPolicyName,
+		Description:    &flowLogsPolicyDescription,
+		PolicyDocument: &flowLogsPolicyDocument,
+		Tags:           *tags,
+	}
+	flowLogsPolicyResp, err := svc.CreatePolicy(ctx, &createFlowLogsPolicyInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create flow logs delivery policy %s: %w", flowLogsPolicyName, err)
+	}
+
+	return flowLogsPolicyResp.Policy, nil
+}
+
 // DeletePolicies deletes the IAM policies.  If the policyARNs slice is empty it
 // returns without error.
-func (c *ResourceClient) DeletePolicies(policyARNs []string) error {
+func (c *ResourceClient) DeletePolicies(ctx context.Context, policyARNs []string) error {
 	// if roleARN is empty, there's nothing to delete
 	if len(policyARNs) == 0 {
 		return nil
 	}
 
 	for _, policyARN := range policyARNs {
-		svc := iam.NewFromConfig(*c.AWSConfig)
+		svc := c.iamClient()
 
 		deletePolicyInput := iam.DeletePolicyInput{
 			PolicyArn: &policyARN,
 		}
-		_, err := svc.DeletePolicy(c.Context, &deletePolicyInput)
+		_, err := svc.DeletePolicy(ctx, &deletePolicyInput)
 		if err != nil {
 			var noSuchEntityErr *types.NoSuchEntityException
 			if errors.As(err, &noSuchEntityErr) {
@@ -189,3 +289,22 @@ func (c *ResourceClient) DeletePolicies(policyARNs []string) error {
 
 	return nil
 }
+
+// getPolicy retrieves the IAM policy with the given ARN.  If the policy is not
+// found it returns ErrResourceNotFound.
+func (c *ResourceClient) getPolicy(ctx context.Context, policyARN string) (*types.Policy, error) {
+	svc := c.iamClient()
+
+	getPolicyInput := iam.GetPolicyInput{PolicyArn: &policyARN}
+	resp, err := svc.GetPolicy(ctx, &getPolicyInput)
+	if err != nil {
+		var noSuchEntityErr *types.NoSuchEntityException
+		if errors.As(err, &noSuchEntityErr) {
+			return nil, ErrResourceNotFound
+		} else {
+			return nil, fmt.Errorf("failed to get policy %s: %w", policyARN, err)
+		}
+	}
+
+	return resp.Policy, nil
+}
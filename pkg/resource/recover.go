@@ -0,0 +1,696 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// RecoverInventory rebuilds the inventory for an EKS cluster from the
+// resources that exist in AWS so that a lost inventory can be replaced.  EC2
+// resources are found by the kubernetes.io/cluster/cluster-name tag applied
+// to all of them, including the transit gateway attachment and VPC peering
+// connection.  The flow log group is found from the VPC's flow log.  IAM
+// roles, IAM policies, the EKS cluster and its node groups and addons are found
+// by the names they are created with.  IAM roles, IAM policies and the
+// cluster are skipped if their Name tag doesn't match the cluster name, and a
+// message is sent for each IAM role and policy skipped.  The
+// OIDC provider is found from the cluster's issuer URL, so it can't be
+// recovered once the cluster is deleted.  Resources are only read, never
+// changed.
+func (c *ResourceClient) RecoverInventory(ctx context.Context, clusterName string) (*ResourceInventory, error) {
+	inventory := ResourceInventory{
+		SchemaVersion: InventorySchemaVersion,
+		Region:        c.AWSConfig.Region,
+	}
+
+	availabilityZones, err := c.recoverNetwork(ctx, &inventory, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.recoverRoles(ctx, &inventory, clusterName); err != nil {
+		return nil, err
+	}
+	if err := c.recoverPolicies(ctx, &inventory, clusterName); err != nil {
+		return nil, err
+	}
+	if err := c.recoverCluster(ctx, &inventory, clusterName); err != nil {
+		return nil, err
+	}
+
+	// set the subnet, NAT gateway and route table IDs on each availability
+	// zone
+	if err := c.reconcileInventory(ctx, &inventory, &availabilityZones); err != nil {
+		return nil, err
+	}
+	inventory.AvailabilityZones = availabilityZones
+
+	if inventoryEmpty(&inventory) {
+		return nil, fmt.Errorf("%w: no resources found for cluster %s in region %s", ErrResourceNotFound, clusterName, inventory.Region)
+	}
+
+	return &inventory, nil
+}
+
+// recoverNetwork adds the EC2 resources tagged for the cluster to the
+// inventory.  It returns the availability zones the cluster's subnets are in
+// with their subnet CIDR blocks.
+func (c *ResourceClient) recoverNetwork(
+	ctx context.Context,
+	inventory *ResourceInventory,
+	clusterName string,
+) ([]AvailabilityZone, error) {
+	svc := c.ec2Client()
+
+	// VPC
+	describeVPCsInput := ec2.DescribeVpcsInput{
+		Filters: []ec2types.Filter{clusterTagFilter(clusterName)},
+	}
+	vpcsResp, err := svc.DescribeVpcs(ctx, &describeVPCsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPCs for cluster %s: %w", clusterName, err)
+	}
+	if len(vpcsResp.Vpcs) > 1 {
+		var vpcIDs []string
+		for _, vpc := range vpcsResp.Vpcs {
+			vpcIDs = append(vpcIDs, *vpc.VpcId)
+		}
+		return nil, fmt.Errorf("found multiple VPCs tagged for cluster %s: %s", clusterName, strings.Join(vpcIDs, ", "))
+	}
+	if len(vpcsResp.Vpcs) == 1 {
+		inventory.VPCID = *vpcsResp.Vpcs[0].VpcId
+		inventory.Tags = ec2TagMap(vpcsResp.Vpcs[0].Tags)
+		// only VPCs created for the IPv6 IP family have an IPv6 CIDR block
+		if len(vpcsResp.Vpcs[0].Ipv6CidrBlockAssociationSet) > 0 {
+			inventory.IPFamily = IPFamilyIPv6
+		} else {
+			inventory.IPFamily = IPFamilyIPv4
+		}
+		// the secondary CIDR is the only other IPv4 CIDR block associated
+		// with the VPC
+		vpc := vpcsResp.Vpcs[0]
+		for _, association := range vpc.CidrBlockAssociationSet {
+			if aws.ToString(association.CidrBlock) == aws.ToString(vpc.CidrBlock) ||
+				vpcCIDRBlockAssociation(&vpc, aws.ToString(association.AssociationId)) == nil {
+				continue
+			}
+			inventory.SecondaryCIDR = aws.ToString(association.CidrBlock)
+			inventory.SecondaryCIDRAssociationID = aws.ToString(association.AssociationId)
+			break
+		}
+	}
+
+	// Internet Gateway
+	describeIGWsInput := ec2.DescribeInternetGatewaysInput{
+		Filters: []ec2types.Filter{clusterTagFilter(clusterName)},
+	}
+	igwsResp, err := svc.DescribeInternetGateways(ctx, &describeIGWsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe internet gateways for cluster %s: %w", clusterName, err)
+	}
+	if len(igwsResp.InternetGateways) > 1 {
+		return nil, fmt.Errorf("found multiple internet gateways tagged for cluster %s", clusterName)
+	}
+	if len(igwsResp.InternetGateways) == 1 {
+		inventory.InternetGatewayID = *igwsResp.InternetGateways[0].InternetGatewayId
+	}
+
+	// Egress-only Internet Gateway
+	describeEIGWsInput := ec2.DescribeEgressOnlyInternetGatewaysInput{
+		Filters: []ec2types.Filter{clusterTagFilter(clusterName)},
+	}
+	eigwsResp, err := svc.DescribeEgressOnlyInternetGateways(ctx, &describeEIGWsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe egress-only internet gateways for cluster %s: %w", clusterName, err)
+	}
+	if len(eigwsResp.EgressOnlyInternetGateways) > 1 {
+		return nil, fmt.Errorf("found multiple egress-only internet gateways tagged for cluster %s", clusterName)
+	}
+	if len(eigwsResp.EgressOnlyInternetGateways) == 1 {
+		inventory.EgressOnlyInternetGatewayID = *eigwsResp.EgressOnlyInternetGateways[0].EgressOnlyInternetGatewayId
+	}
+
+	// Elastic IPs
+	describeAddressesInput := ec2.DescribeAddressesInput{
+		Filters: []ec2types.Filter{clusterTagFilter(clusterName)},
+	}
+	addressesResp, err := svc.DescribeAddresses(ctx, &describeAddressesInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe elastic IPs for cluster %s: %w", clusterName, err)
+	}
+	for _, address := range addressesResp.Addresses {
+		inventory.ElasticIPIDs = append(inventory.ElasticIPIDs, *address.AllocationId)
+	}
+
+	// everything else is inside the VPC
+	if inventory.VPCID == "" {
+		return []AvailabilityZone{}, nil
+	}
+	vpcFilterName := "vpc-id"
+	vpcFilter := ec2types.Filter{
+		Name:   &vpcFilterName,
+		Values: []string{inventory.VPCID},
+	}
+
+	// Subnets - public and private subnets are told apart by the load
+	// balancer role tags added when they are created, pod subnets by being in
+	// the secondary CIDR
+	describeSubnetsInput := ec2.DescribeSubnetsInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	subnetsResp, err := svc.DescribeSubnets(ctx, &describeSubnetsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnets for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	secondaryCIDR, _ := netip.ParsePrefix(inventory.SecondaryCIDR)
+	azMap := make(map[string]*AvailabilityZone)
+	subnetTiers := make(map[string]string)
+	for _, subnet := range subnetsResp.Subnets {
+		inventory.SubnetIDs = append(inventory.SubnetIDs, *subnet.SubnetId)
+		if subnet.AvailabilityZone == nil || subnet.CidrBlock == nil {
+			continue
+		}
+		az, ok := azMap[*subnet.AvailabilityZone]
+		if !ok {
+			az = &AvailabilityZone{Zone: *subnet.AvailabilityZone, ZoneID: aws.ToString(subnet.AvailabilityZoneId)}
+			azMap[*subnet.AvailabilityZone] = az
+		}
+		subnetTags := ec2TagMap(subnet.Tags)
+		subnetCIDR, _ := netip.ParsePrefix(*subnet.CidrBlock)
+		if _, ok := subnetTags["kubernetes.io/role/elb"]; ok {
+			az.PublicSubnetCIDR = *subnet.CidrBlock
+			az.PublicSubnetIPv6CIDR = subnetIPv6CIDR(subnet)
+			subnetTiers[*subnet.SubnetId] = "public"
+		} else if secondaryCIDR.IsValid() && subnetCIDR.IsValid() && secondaryCIDR.Contains(subnetCIDR.Addr()) {
+			az.PodSubnetCIDR = *subnet.CidrBlock
+			subnetTiers[*subnet.SubnetId] = "pod"
+		} else {
+			az.PrivateSubnetCIDR = *subnet.CidrBlock
+			az.PrivateSubnetIPv6CIDR = subnetIPv6CIDR(subnet)
+			subnetTiers[*subnet.SubnetId] = "private"
+		}
+	}
+	var zones []string
+	for zone := range azMap {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	availabilityZones := []AvailabilityZone{}
+	for _, zone := range zones {
+		availabilityZones = append(availabilityZones, *azMap[zone])
+	}
+
+	// NAT Gateways
+	stateFilterName := "state"
+	describeNATGatewaysInput := ec2.DescribeNatGatewaysInput{
+		Filter: []ec2types.Filter{
+			vpcFilter,
+			clusterTagFilter(clusterName),
+			{
+				Name: &stateFilterName,
+				Values: []string{
+					string(ec2types.NatGatewayStatePending),
+					string(ec2types.NatGatewayStateAvailable),
+				},
+			},
+		},
+	}
+	natGatewaysResp, err := svc.DescribeNatGateways(ctx, &describeNATGatewaysInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe NAT gateways for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, natGateway := range natGatewaysResp.NatGateways {
+		inventory.NATGatewayIDs = append(inventory.NATGatewayIDs, *natGateway.NatGatewayId)
+	}
+
+	// the NAT gateway mode is worked out from the number of NAT gateways.
+	// With none it can't be told apart from NAT gateways that were never
+	// created so it is left unset.
+	switch {
+	case len(inventory.NATGatewayIDs) == 0:
+	case len(inventory.NATGatewayIDs) == 1 && len(availabilityZones) > 1:
+		inventory.NATGatewayMode = NATGatewayModeSingle
+	default:
+		inventory.NATGatewayMode = NATGatewayModePerAZ
+	}
+
+	// Route Tables - the public route table is the one with a route to the
+	// internet gateway.  The VPC's main route table is not tagged so it is
+	// never included.
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	routeTablesResp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, routeTable := range routeTablesResp.RouteTables {
+		routeTableID := *routeTable.RouteTableId
+		public := false
+		for _, route := range routeTable.Routes {
+			if route.Origin != ec2types.RouteOriginCreateRoute || routeDestination(route) == "" {
+				continue
+			}
+			routeInventory := RouteInventory{
+				RouteTableID:    routeTableID,
+				DestinationCIDR: routeDestination(route),
+			}
+			switch {
+			case route.GatewayId != nil && strings.HasPrefix(*route.GatewayId, "igw-"):
+				routeInventory.GatewayID = *route.GatewayId
+				public = true
+			case route.NatGatewayId != nil:
+				routeInventory.NATGatewayID = *route.NatGatewayId
+			case route.EgressOnlyInternetGatewayId != nil:
+				routeInventory.EgressOnlyInternetGatewayID = *route.EgressOnlyInternetGatewayId
+			case route.TransitGatewayId != nil:
+				routeInventory.TransitGatewayID = *route.TransitGatewayId
+			case route.VpcPeeringConnectionId != nil:
+				routeInventory.VPCPeeringConnectionID = *route.VpcPeeringConnectionId
+			default:
+				continue
+			}
+			inventory.Routes = append(inventory.Routes, routeInventory)
+		}
+		if public && inventory.PublicRouteTableID == "" {
+			inventory.PublicRouteTableID = routeTableID
+		} else {
+			inventory.PrivateRouteTableIDs = append(inventory.PrivateRouteTableIDs, routeTableID)
+		}
+	}
+
+	// VPC Endpoints and their security group - the security group is told
+	// apart from any others tagged for the cluster by the name it is created
+	// with
+	describeSecurityGroupsInput := ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	securityGroupsResp, err := svc.DescribeSecurityGroups(ctx, &describeSecurityGroupsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe security groups for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, securityGroup := range securityGroupsResp.SecurityGroups {
+		if securityGroup.GroupName != nil && *securityGroup.GroupName == fmt.Sprintf("%s-%s", VPCEndpointSecurityGroupName, clusterName) {
+			inventory.VPCEndpointSecurityGroupID = *securityGroup.GroupId
+		}
+	}
+	describeVPCEndpointsInput := ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	vpcEndpointsResp, err := svc.DescribeVpcEndpoints(ctx, &describeVPCEndpointsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPC endpoints for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, vpcEndpoint := range vpcEndpointsResp.VpcEndpoints {
+		if vpcEndpointStateIs(vpcEndpoint.State, ec2types.StateDeleted) ||
+			vpcEndpointStateIs(vpcEndpoint.State, ec2types.StateDeleting) {
+			continue
+		}
+		inventory.VPCEndpoints = append(inventory.VPCEndpoints, VPCEndpointInventory{
+			VPCEndpointID: *vpcEndpoint.VpcEndpointId,
+			ServiceName:   aws.ToString(vpcEndpoint.ServiceName),
+		})
+	}
+
+	// Flow Log and the log group it publishes to, if any
+	resourceIDFilterName := "resource-id"
+	describeFlowLogsInput := ec2.DescribeFlowLogsInput{
+		Filter: []ec2types.Filter{
+			{
+				Name:   &resourceIDFilterName,
+				Values: []string{inventory.VPCID},
+			},
+			clusterTagFilter(clusterName),
+		},
+	}
+	flowLogsResp, err := svc.DescribeFlowLogs(ctx, &describeFlowLogsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe flow logs for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	if len(flowLogsResp.FlowLogs) > 1 {
+		return nil, fmt.Errorf("found multiple flow logs tagged for cluster %s", clusterName)
+	}
+	if len(flowLogsResp.FlowLogs) == 1 {
+		flowLog := flowLogsResp.FlowLogs[0]
+		inventory.FlowLogID = *flowLog.FlowLogId
+		if flowLog.LogDestinationType == ec2types.LogDestinationTypeCloudWatchLogs {
+			inventory.FlowLogGroupName = aws.ToString(flowLog.LogGroupName)
+		}
+	}
+
+	// Transit Gateway Attachment
+	describeTransitGatewayVpcAttachmentsInput := ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	attachmentsResp, err := svc.DescribeTransitGatewayVpcAttachments(ctx, &describeTransitGatewayVpcAttachmentsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe transit gateway attachments for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, attachment := range attachmentsResp.TransitGatewayVpcAttachments {
+		if attachment.State == ec2types.TransitGatewayAttachmentStateDeleted {
+			continue
+		}
+		if inventory.TransitGatewayAttachmentID != "" {
+			return nil, fmt.Errorf("found multiple transit gateway attachments tagged for cluster %s", clusterName)
+		}
+		inventory.TransitGatewayAttachmentID = *attachment.TransitGatewayAttachmentId
+	}
+
+	// VPC Peering Connection requested from the VPC
+	requesterVPCFilterName := "requester-vpc-info.vpc-id"
+	describeVPCPeeringConnectionsInput := ec2.DescribeVpcPeeringConnectionsInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   &requesterVPCFilterName,
+				Values: []string{inventory.VPCID},
+			},
+			clusterTagFilter(clusterName),
+		},
+	}
+	vpcPeeringConnectionsResp, err := svc.DescribeVpcPeeringConnections(ctx, &describeVPCPeeringConnectionsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPC peering connections for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, vpcPeeringConnection := range vpcPeeringConnectionsResp.VpcPeeringConnections {
+		if !vpcPeeringConnectionUsable(&vpcPeeringConnection) {
+			continue
+		}
+		if inventory.VPCPeeringConnectionID != "" {
+			return nil, fmt.Errorf("found multiple VPC peering connections tagged for cluster %s", clusterName)
+		}
+		inventory.VPCPeeringConnectionID = *vpcPeeringConnection.VpcPeeringConnectionId
+	}
+
+	// Network ACLs - the tier of each is that of the subnets associated with
+	// it, so one whose subnets are no longer associated is recorded without
+	// a tier
+	describeNetworkACLsInput := ec2.DescribeNetworkAclsInput{
+		Filters: []ec2types.Filter{vpcFilter, clusterTagFilter(clusterName)},
+	}
+	networkACLsResp, err := svc.DescribeNetworkAcls(ctx, &describeNetworkACLsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe network ACLs for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	for _, networkACL := range networkACLsResp.NetworkAcls {
+		if aws.ToBool(networkACL.IsDefault) {
+			continue
+		}
+		var tier string
+		for _, subnetID := range networkACLSubnetIDs(&networkACL) {
+			if subnetTier, ok := subnetTiers[subnetID]; ok {
+				tier = subnetTier
+				break
+			}
+		}
+		inventory.NetworkACLs = append(inventory.NetworkACLs, NetworkACLInventory{
+			Tier:         tier,
+			NetworkACLID: *networkACL.NetworkAclId,
+		})
+	}
+
+	return availabilityZones, nil
+}
+
+// recoverRoles adds the IAM roles created for the cluster, and the policies
+// attached to them, to the inventory.
+func (c *ResourceClient) recoverRoles(ctx context.Context, inventory *ResourceInventory, clusterName string) error {
+	roles := []struct {
+		name string
+		role *RoleInventory
+	}{
+		{ClusterRoleName, &inventory.ClusterRole},
+		{WorkerRoleName, &inventory.WorkerRole},
+		{DNSManagementRoleName, &inventory.DNSManagementRole},
+		{DNS01ChallengeRoleName, &inventory.DNS01ChallengeRole},
+		{ClusterAutoscalingRoleName, &inventory.ClusterAutoscalingRole},
+		{StorageManagementRoleName, &inventory.StorageManagementRole},
+		{FlowLogsRoleName, &inventory.FlowLogsRole},
+	}
+	for _, r := range roles {
+		roleName := fmt.Sprintf("%s-%s", r.name, clusterName)
+		role, err := c.getRole(ctx, roleName)
+		if err != nil {
+			if errors.Is(err, ErrResourceNotFound) {
+				continue
+			}
+			return err
+		}
+		roleTags, err := c.listRoleTags(ctx, roleName)
+		if err != nil {
+			return err
+		}
+		if iamTagValue(roleTags, "Name") != clusterName {
+			// not created for this cluster, or not tagged by this version
+			c.sendMessage(fmt.Sprintf("Skipping IAM role %s as it isn't tagged Name=%s\n", roleName, clusterName))
+			continue
+		}
+		rolePolicyARNs, err := c.getAttachedRolePolicyARNs(ctx, roleName)
+		if err != nil {
+			return err
+		}
+		*r.role = RoleInventory{
+			RoleName:       roleName,
+			RoleARN:        *role.Arn,
+			RolePolicyARNs: rolePolicyARNs,
+		}
+	}
+
+	return nil
+}
+
+// recoverPolicies adds the customer managed IAM policies created for the
+// cluster to the inventory.
+func (c *ResourceClient) recoverPolicies(ctx context.Context, inventory *ResourceInventory, clusterName string) error {
+	svc := c.iamClient()
+
+	policyNames := []string{
+		fmt.Sprintf("%s-%s", DNSPolicyName, clusterName),
+		fmt.Sprintf("%s-%s", DNS01ChallengePolicyName, clusterName),
+		fmt.Sprintf("%s-%s", AutoscalingPolicyName, clusterName),
+		fmt.Sprintf("%s-%s", CNIIPv6PolicyName, clusterName),
+		fmt.Sprintf("%s-%s", FlowLogsPolicyName, clusterName),
+	}
+
+	listPoliciesInput := iam.ListPoliciesInput{Scope: iamtypes.PolicyScopeTypeLocal}
+	for {
+		resp, err := svc.ListPolicies(ctx, &listPoliciesInput)
+		if err != nil {
+			return fmt.Errorf("failed to list IAM policies: %w", err)
+		}
+		for _, policy := range resp.Policies {
+			if policy.PolicyName == nil || !containsString(policyNames, *policy.PolicyName) {
+				continue
+			}
+			policyTags, err := c.listPolicyTags(ctx, *policy.Arn)
+			if err != nil {
+				return err
+			}
+			if iamTagValue(policyTags, "Name") != clusterName {
+				c.sendMessage(fmt.Sprintf("Skipping IAM policy %s as it isn't tagged Name=%s\n", *policy.PolicyName, clusterName))
+				continue
+			}
+			inventory.PolicyARNs = append(inventory.PolicyARNs, *policy.Arn)
+		}
+		if !resp.IsTruncated || resp.Marker == nil {
+			break
+		}
+		listPoliciesInput.Marker = resp.Marker
+	}
+
+	return nil
+}
+
+// recoverCluster adds the EKS cluster, its node groups, addons and security
+// group and the OIDC provider for its issuer to the inventory.
+func (c *ResourceClient) recoverCluster(ctx context.Context, inventory *ResourceInventory, clusterName string) error {
+	svc := c.eksClient()
+
+	cluster, err := c.getCluster(ctx, clusterName)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil
+		}
+		return err
+	}
+	if cluster.Tags["Name"] != clusterName {
+		// not created for this cluster
+		return nil
+	}
+	inventory.Cluster.ClusterName = clusterName
+	inventory.Cluster.ClusterARN = *cluster.Arn
+	if cluster.Identity != nil && cluster.Identity.Oidc != nil && cluster.Identity.Oidc.Issuer != nil {
+		inventory.Cluster.OIDCProviderURL = *cluster.Identity.Oidc.Issuer
+	}
+
+	// Node Groups
+	listNodegroupsInput := eks.ListNodegroupsInput{ClusterName: &clusterName}
+	for {
+		resp, err := svc.ListNodegroups(ctx, &listNodegroupsInput)
+		if err != nil {
+			return fmt.Errorf("failed to list node groups for cluster %s: %w", clusterName, err)
+		}
+		inventory.NodeGroupNames = append(inventory.NodeGroupNames, resp.Nodegroups...)
+		if resp.NextToken == nil {
+			break
+		}
+		listNodegroupsInput.NextToken = resp.NextToken
+	}
+
+	// Addons
+	listAddonsInput := eks.ListAddonsInput{ClusterName: &clusterName}
+	for {
+		resp, err := svc.ListAddons(ctx, &listAddonsInput)
+		if err != nil {
+			return fmt.Errorf("failed to list addons for cluster %s: %w", clusterName, err)
+		}
+		inventory.AddonNames = append(inventory.AddonNames, resp.Addons...)
+		if resp.NextToken == nil {
+			break
+		}
+		listAddonsInput.NextToken = resp.NextToken
+	}
+
+	// Security Group
+	securityGroupID, err := c.GetClusterSecurityGroup(ctx, clusterName)
+	if err == nil {
+		inventory.SecurityGroupID = securityGroupID
+	}
+
+	// OIDC Provider - its ARN is made up of the account ID from the cluster
+	// ARN and the issuer URL without the scheme
+	clusterARNParts := strings.Split(*cluster.Arn, ":")
+	if inventory.Cluster.OIDCProviderURL != "" && len(clusterARNParts) > 4 {
+		oidcProviderARN := fmt.Sprintf(
+			"arn:%s:iam::%s:oidc-provider/%s",
+			clusterARNParts[1],
+			clusterARNParts[4],
+			strings.TrimPrefix(inventory.Cluster.OIDCProviderURL, "https://"),
+		)
+		if err := c.getOIDCProvider(ctx, oidcProviderARN); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+		} else {
+			inventory.OIDCProviderARN = oidcProviderARN
+		}
+	}
+
+	return nil
+}
+
+// getAttachedRolePolicyARNs retrieves the ARNs of the managed policies attached
+// to a role.
+func (c *ResourceClient) getAttachedRolePolicyARNs(ctx context.Context, roleName string) ([]string, error) {
+	svc := c.iamClient()
+
+	var policyARNs []string
+	listAttachedRolePoliciesInput := iam.ListAttachedRolePoliciesInput{RoleName: &roleName}
+	for {
+		resp, err := svc.ListAttachedRolePolicies(ctx, &listAttachedRolePoliciesInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list policies attached to role %s: %w", roleName, err)
+		}
+		for _, attachedPolicy := range resp.AttachedPolicies {
+			policyARNs = append(policyARNs, *attachedPolicy.PolicyArn)
+		}
+		if !resp.IsTruncated || resp.Marker == nil {
+			break
+		}
+		listAttachedRolePoliciesInput.Marker = resp.Marker
+	}
+
+	return policyARNs, nil
+}
+
+// inventoryEmpty returns true if an inventory records no resources.
+func inventoryEmpty(inventory *ResourceInventory) bool {
+	return inventory.VPCID == "" &&
+		inventory.InternetGatewayID == "" &&
+		len(inventory.ElasticIPIDs) == 0 &&
+		inventory.ClusterRole.RoleName == "" &&
+		inventory.WorkerRole.RoleName == "" &&
+		inventory.DNSManagementRole.RoleName == "" &&
+		inventory.DNS01ChallengeRole.RoleName == "" &&
+		inventory.ClusterAutoscalingRole.RoleName == "" &&
+		inventory.StorageManagementRole.RoleName == "" &&
+		inventory.FlowLogsRole.RoleName == "" &&
+		len(inventory.PolicyARNs) == 0 &&
+		inventory.Cluster.ClusterName == "" &&
+		inventory.OIDCProviderARN == ""
+}
+
+// clusterTagFilter returns the EC2 filter for resources tagged with the
+// kubernetes.io/cluster/cluster-name tag added to all EC2 resources created
+// for a cluster.  The kubernetes.io/cluster/<cluster name> tag is not used as
+// it is also added to existing VPCs and subnets that the cluster uses.
+func clusterTagFilter(clusterName string) ec2types.Filter {
+	filterName := "tag:kubernetes.io/cluster/cluster-name"
+	return ec2types.Filter{
+		Name:   &filterName,
+		Values: []string{clusterName},
+	}
+}
+
+// listRoleTags returns all the tags on an IAM role.
+func (c *ResourceClient) listRoleTags(ctx context.Context, roleName string) ([]iamtypes.Tag, error) {
+	svc := c.iamClient()
+
+	var tags []iamtypes.Tag
+	listRoleTagsInput := iam.ListRoleTagsInput{RoleName: &roleName}
+	for {
+		resp, err := svc.ListRoleTags(ctx, &listRoleTagsInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for role %s: %w", roleName, err)
+		}
+		tags = append(tags, resp.Tags...)
+		if !resp.IsTruncated || resp.Marker == nil {
+			break
+		}
+		listRoleTagsInput.Marker = resp.Marker
+	}
+
+	return tags, nil
+}
+
+// listPolicyTags returns all the tags on a customer managed IAM policy.
+func (c *ResourceClient) listPolicyTags(ctx context.Context, policyARN string) ([]iamtypes.Tag, error) {
+	svc := c.iamClient()
+
+	var tags []iamtypes.Tag
+	listPolicyTagsInput := iam.ListPolicyTagsInput{PolicyArn: &policyARN}
+	for {
+		resp, err := svc.ListPolicyTags(ctx, &listPolicyTagsInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for policy %s: %w", policyARN, err)
+		}
+		tags = append(tags, resp.Tags...)
+		if !resp.IsTruncated || resp.Marker == nil {
+			break
+		}
+		listPolicyTagsInput.Marker = resp.Marker
+	}
+
+	return tags, nil
+}
+
+// iamTagValue returns the value of the IAM tag with the given key.  If the tag
+// is not present it returns an empty string.
+func iamTagValue(tags []iamtypes.Tag, key string) string {
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil && *tag.Key == key {
+			return *tag.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,159 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// sorted returns a sorted copy of a list of IDs or names.
+func sorted(values []string) []string {
+	values = append([]string{}, values...)
+	sort.Strings(values)
+
+	return values
+}
+
+func TestRecoverInventory(t *testing.T) {
+	testCases := []struct {
+		name           string
+		resourceConfig func() *resource.ResourceConfig
+	}{
+		{
+			name:           "defaults",
+			resourceConfig: testConfig,
+		},
+		{
+			name: "all roles and policies",
+			resourceConfig: func() *resource.ResourceConfig {
+				resourceConfig := testConfig()
+				resourceConfig.DNSManagement = true
+				resourceConfig.DNS01Challenge = true
+				resourceConfig.ClusterAutoscaling = true
+				return resourceConfig
+			},
+		},
+		{
+			name:           "flow logs",
+			resourceConfig: flowLogsConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend, c, inventory := createResourceStack(t, tc.resourceConfig())
+
+			var r fake.Recorder
+			r.Record(c)
+			recovered, err := c.RecoverInventory(context.Background(), inventory.Cluster.ClusterName)
+			r.Stop()
+			if err != nil {
+				t.Fatalf("failed to recover inventory: %v", err)
+			}
+
+			// the recovered inventory records the same resources, though
+			// not necessarily in the same order
+			for name, ids := range map[string][2][]string{
+				"subnets":      {recovered.SubnetIDs, inventory.SubnetIDs},
+				"elastic IPs":  {recovered.ElasticIPIDs, inventory.ElasticIPIDs},
+				"NAT gateways": {recovered.NATGatewayIDs, inventory.NATGatewayIDs},
+				"route tables": {recovered.PrivateRouteTableIDs, inventory.PrivateRouteTableIDs},
+				"policies":     {recovered.PolicyARNs, inventory.PolicyARNs},
+				"node groups":  {recovered.NodeGroupNames, inventory.NodeGroupNames},
+				"addons":       {recovered.AddonNames, inventory.AddonNames},
+			} {
+				if got, want := sorted(ids[0]), sorted(ids[1]); !reflect.DeepEqual(got, want) {
+					t.Errorf("expected recovered %s %v, got %v", name, want, got)
+				}
+			}
+			for name, ids := range map[string][2]string{
+				"VPC":              {recovered.VPCID, inventory.VPCID},
+				"internet gateway": {recovered.InternetGatewayID, inventory.InternetGatewayID},
+				"cluster":          {recovered.Cluster.ClusterName, inventory.Cluster.ClusterName},
+				"OIDC provider":    {recovered.OIDCProviderARN, inventory.OIDCProviderARN},
+				"cluster role":     {recovered.ClusterRole.RoleName, inventory.ClusterRole.RoleName},
+				"worker role":      {recovered.WorkerRole.RoleName, inventory.WorkerRole.RoleName},
+				"DNS role":         {recovered.DNSManagementRole.RoleName, inventory.DNSManagementRole.RoleName},
+				"flow log":         {recovered.FlowLogID, inventory.FlowLogID},
+				"flow log group":   {recovered.FlowLogGroupName, inventory.FlowLogGroupName},
+			} {
+				if ids[0] != ids[1] {
+					t.Errorf("expected recovered %s %q, got %q", name, ids[1], ids[0])
+				}
+			}
+
+			deleteResourceStack(t, backend, c, *recovered)
+		})
+	}
+}
+
+func TestRecoverInventoryUntagged(t *testing.T) {
+	ctx := context.Background()
+	backend, c, inventory := createResourceStack(t, testConfig())
+	clusterName := inventory.Cluster.ClusterName
+
+	// a role and policy with the names the cluster would use but without its
+	// Name tag weren't created by eks-cluster
+	roleName := fmt.Sprintf("%s-%s", resource.DNSManagementRoleName, clusterName)
+	createRoleInput := iam.CreateRoleInput{
+		RoleName:                 aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(`{"Version":"2012-10-17","Statement":[]}`),
+	}
+	if _, err := backend.IAM.CreateRole(ctx, &createRoleInput); err != nil {
+		t.Fatal(err)
+	}
+	policyName := fmt.Sprintf("%s-%s", resource.DNSPolicyName, clusterName)
+	createPolicyInput := iam.CreatePolicyInput{
+		PolicyName:     aws.String(policyName),
+		PolicyDocument: aws.String(`{"Version":"2012-10-17","Statement":[]}`),
+	}
+	createPolicyOutput, err := backend.IAM.CreatePolicy(ctx, &createPolicyInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r fake.Recorder
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, clusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+
+	if recovered.DNSManagementRole.RoleName != "" {
+		t.Errorf("expected untagged role not to be recovered, got %s", recovered.DNSManagementRole.RoleName)
+	}
+	for _, policyARN := range recovered.PolicyARNs {
+		if policyARN == *createPolicyOutput.Policy.Arn {
+			t.Errorf("expected untagged policy not to be recovered, got %s", policyARN)
+		}
+	}
+	messages := strings.Join(r.Messages(), "")
+	for _, skipped := range []string{"Skipping IAM role " + roleName, "Skipping IAM policy " + policyName} {
+		if !strings.Contains(messages, skipped) {
+			t.Errorf("expected message %q, got %q", skipped, messages)
+		}
+	}
+}
+
+func TestRecoverInventoryNotFound(t *testing.T) {
+	_, c, _ := createResourceStack(t, testConfig())
+
+	var r fake.Recorder
+	r.Record(c)
+	_, err := c.RecoverInventory(context.Background(), "other")
+	r.Stop()
+	if !errors.Is(err, resource.ErrResourceNotFound) {
+		t.Errorf("expected ErrResourceNotFound, got %v", err)
+	}
+}
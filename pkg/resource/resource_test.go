@@ -0,0 +1,119 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+const testRegion = "us-east-2"
+
+// testConfig returns a resource config for the test region.
+func testConfig() *resource.ResourceConfig {
+	resourceConfig := resource.NewResourceConfig()
+	resourceConfig.Region = testRegion
+
+	return resourceConfig
+}
+
+// flowLogsConfig returns a resource config with flow logs published to
+// CloudWatch Logs.
+func flowLogsConfig() *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.FlowLogs = &resource.FlowLogsConfig{RetentionDays: 30}
+
+	return resourceConfig
+}
+
+// createResourceStack creates a resource stack against a new fake backend and
+// returns the backend, the resource client and the inventory that was
+// recorded.  The create must succeed.
+func createResourceStack(
+	t *testing.T,
+	resourceConfig *resource.ResourceConfig,
+) (*fake.Backend, *resource.ResourceClient, resource.ResourceInventory) {
+	t.Helper()
+
+	backend := fake.NewBackend(testRegion)
+	c := backend.ResourceClient()
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.CreateResourceStack(context.Background(), resourceConfig)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to create resource stack: %v", err)
+	}
+
+	return backend, c, r.Inventory()
+}
+
+// deleteResourceStack deletes the resources in the inventory and checks that
+// none remain.
+func deleteResourceStack(
+	t *testing.T,
+	backend *fake.Backend,
+	c *resource.ResourceClient,
+	inventory resource.ResourceInventory,
+) {
+	t.Helper()
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.DeleteResourceStack(context.Background(), &inventory)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to delete resource stack: %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources after delete, got %+v", snapshot)
+	}
+}
+
+func TestCreateDeleteResourceStack(t *testing.T) {
+	testCases := []struct {
+		name           string
+		resourceConfig func() *resource.ResourceConfig
+	}{
+		{
+			name:           "defaults",
+			resourceConfig: testConfig,
+		},
+		{
+			name: "all roles and policies",
+			resourceConfig: func() *resource.ResourceConfig {
+				resourceConfig := testConfig()
+				resourceConfig.DNSManagement = true
+				resourceConfig.DNS01Challenge = true
+				resourceConfig.ClusterAutoscaling = true
+				return resourceConfig
+			},
+		},
+		{
+			name:           "flow logs",
+			resourceConfig: flowLogsConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend, c, inventory := createResourceStack(t, tc.resourceConfig())
+			if snapshot := backend.Snapshot(); snapshot.Empty() {
+				t.Fatal("expected resources after create")
+			}
+			if inventory.Cluster.ClusterName == "" || inventory.VPCID == "" {
+				t.Fatalf("expected cluster and VPC in inventory, got %+v", inventory)
+			}
+			if tc.resourceConfig().FlowLogs != nil {
+				if snapshot := backend.Snapshot(); len(snapshot.LogGroupNames) != 1 ||
+					snapshot.LogGroupNames[0] != inventory.FlowLogGroupName {
+					t.Errorf("expected log group %s, got %v", inventory.FlowLogGroupName, snapshot.LogGroupNames)
+				}
+			}
+
+			deleteResourceStack(t, backend, c, inventory)
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// reconcileInventory checks the resources recorded in an inventory against
+// those that currently exist in AWS.  Resources that no longer exist are
+// removed from the inventory so they are created again.  The IDs of existing
+// subnets, elastic IPs, NAT gateways, private route tables and route table
+// associations are set on the availability zones they belong to.  Resources
+// are only read, never changed.
+func (c *ResourceClient) reconcileInventory(
+	ctx context.Context,
+	inventory *ResourceInventory,
+	availabilityZones *[]AvailabilityZone,
+) error {
+	azs := *availabilityZones
+	for i := range azs {
+		// existing subnets are used as they are rather than being recreated
+		if !containsString(inventory.ExistingSubnetIDs, azs[i].PrivateSubnetID) {
+			azs[i].PrivateSubnetID = ""
+		}
+		if !containsString(inventory.ExistingSubnetIDs, azs[i].PublicSubnetID) {
+			azs[i].PublicSubnetID = ""
+		}
+		azs[i].PodSubnetID = ""
+		azs[i].ElasticIPID = ""
+		azs[i].NATGatewayID = ""
+		azs[i].PrivateRouteTableID = ""
+		azs[i].PrivateRouteTableAssociationID = ""
+		azs[i].PublicRouteTableAssociationID = ""
+		azs[i].PodRouteTableAssociationID = ""
+	}
+
+	// VPC
+	if inventory.VPCID != "" {
+		vpc, err := c.getVPC(ctx, inventory.VPCID)
+		if err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			// all resources inside the VPC are gone along with it
+			inventory.VPCID = ""
+			inventory.SubnetIDs = []string{}
+			inventory.NATGatewayIDs = []string{}
+			inventory.NATGatewaysUntracked = false
+			inventory.PrivateRouteTableIDs = []string{}
+			inventory.PublicRouteTableID = ""
+			inventory.Routes = []RouteInventory{}
+			inventory.VPCEndpointSecurityGroupID = ""
+			inventory.VPCEndpoints = []VPCEndpointInventory{}
+			inventory.SecondaryCIDR = ""
+			inventory.SecondaryCIDRAssociationID = ""
+			inventory.FlowLogID = ""
+			inventory.TransitGatewayAttachmentID = ""
+			inventory.VPCPeeringConnectionID = ""
+			inventory.NetworkACLs = []NetworkACLInventory{}
+			// the subnet IPv6 CIDR blocks were from the VPC's
+			for i := range azs {
+				azs[i].PrivateSubnetIPv6CIDR = ""
+				azs[i].PublicSubnetIPv6CIDR = ""
+			}
+		} else if inventory.SecondaryCIDRAssociationID != "" {
+			// the secondary CIDR is associated again if the association is
+			// gone
+			if vpcCIDRBlockAssociation(vpc, inventory.SecondaryCIDRAssociationID) == nil {
+				inventory.SecondaryCIDR = ""
+				inventory.SecondaryCIDRAssociationID = ""
+			}
+		}
+	}
+
+	// Internet Gateway
+	if inventory.InternetGatewayID != "" {
+		if _, err := c.getInternetGateway(ctx, inventory.InternetGatewayID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.InternetGatewayID = ""
+		}
+	}
+
+	// Egress-only Internet Gateway
+	if inventory.EgressOnlyInternetGatewayID != "" {
+		if _, err := c.getEgressOnlyInternetGateway(ctx, inventory.EgressOnlyInternetGatewayID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.EgressOnlyInternetGatewayID = ""
+		}
+	}
+
+	// Subnets - matched to availability zones by zone and CIDR block
+	if inventory.VPCID != "" {
+		subnets, err := c.getSubnets(ctx, inventory.VPCID, inventory.SubnetIDs)
+		if err != nil {
+			return err
+		}
+		var subnetIDs []string
+		for _, subnet := range subnets {
+			subnetIDs = append(subnetIDs, *subnet.SubnetId)
+			for i, az := range azs {
+				if subnet.AvailabilityZone == nil || *subnet.AvailabilityZone != az.Zone || subnet.CidrBlock == nil {
+					continue
+				}
+				switch *subnet.CidrBlock {
+				case az.PrivateSubnetCIDR:
+					azs[i].PrivateSubnetID = *subnet.SubnetId
+					if ipv6CIDR := subnetIPv6CIDR(subnet); ipv6CIDR != "" {
+						azs[i].PrivateSubnetIPv6CIDR = ipv6CIDR
+					}
+				case az.PublicSubnetCIDR:
+					azs[i].PublicSubnetID = *subnet.SubnetId
+					if ipv6CIDR := subnetIPv6CIDR(subnet); ipv6CIDR != "" {
+						azs[i].PublicSubnetIPv6CIDR = ipv6CIDR
+					}
+				case az.PodSubnetCIDR:
+					azs[i].PodSubnetID = *subnet.SubnetId
+				}
+			}
+		}
+		inventory.SubnetIDs = subnetIDs
+	}
+
+	// Elastic IPs
+	addresses, err := c.getElasticIPs(ctx, inventory.ElasticIPIDs)
+	if err != nil {
+		return err
+	}
+	var elasticIPIDs []string
+	for _, address := range addresses {
+		elasticIPIDs = append(elasticIPIDs, *address.AllocationId)
+	}
+	inventory.ElasticIPIDs = elasticIPIDs
+
+	// NAT Gateways - matched to availability zones by public subnet.  If the
+	// inventory doesn't track NAT gateways those in the VPC are used.
+	usedElasticIPIDs := make(map[string]bool)
+	if inventory.VPCID != "" && (inventory.NATGatewaysUntracked || len(inventory.NATGatewayIDs) > 0) {
+		var trackedNATGatewayIDs []string
+		if !inventory.NATGatewaysUntracked {
+			trackedNATGatewayIDs = inventory.NATGatewayIDs
+		}
+		natGateways, err := c.getNATGateways(ctx, inventory.VPCID, trackedNATGatewayIDs)
+		if err != nil {
+			return err
+		}
+		var natGatewayIDs []string
+		for _, natGateway := range natGateways {
+			natGatewayIDs = append(natGatewayIDs, *natGateway.NatGatewayId)
+			for i, az := range azs {
+				if natGateway.SubnetId == nil || az.PublicSubnetID == "" || *natGateway.SubnetId != az.PublicSubnetID {
+					continue
+				}
+				azs[i].NATGatewayID = *natGateway.NatGatewayId
+				for _, natGatewayAddress := range natGateway.NatGatewayAddresses {
+					if natGatewayAddress.AllocationId != nil {
+						azs[i].ElasticIPID = *natGatewayAddress.AllocationId
+						usedElasticIPIDs[*natGatewayAddress.AllocationId] = true
+					}
+				}
+			}
+		}
+		inventory.NATGatewayIDs = natGatewayIDs
+		inventory.NATGatewaysUntracked = false
+	}
+	// assign unused elastic IPs to availability zones without a NAT gateway
+	for i, az := range azs {
+		if az.ElasticIPID != "" {
+			continue
+		}
+		for _, elasticIPID := range inventory.ElasticIPIDs {
+			if !usedElasticIPIDs[elasticIPID] {
+				azs[i].ElasticIPID = elasticIPID
+				usedElasticIPIDs[elasticIPID] = true
+				break
+			}
+		}
+	}
+
+	// Route Tables - private route tables matched to availability zones by
+	// subnet association
+	if inventory.VPCID != "" {
+		routeTableIDs := inventory.PrivateRouteTableIDs
+		if inventory.PublicRouteTableID != "" {
+			routeTableIDs = append(append([]string{}, routeTableIDs...), inventory.PublicRouteTableID)
+		}
+		routeTables, err := c.getRouteTables(ctx, inventory.VPCID, routeTableIDs)
+		if err != nil {
+			return err
+		}
+		publicRouteTableFound := false
+		var privateRouteTableIDs []string
+		var unassociatedRouteTableIDs []string
+		for _, routeTable := range routeTables {
+			routeTableID := *routeTable.RouteTableId
+			if routeTableID == inventory.PublicRouteTableID {
+				publicRouteTableFound = true
+				for _, association := range routeTable.Associations {
+					for i, az := range azs {
+						if association.SubnetId != nil && az.PublicSubnetID != "" && *association.SubnetId == az.PublicSubnetID {
+							azs[i].PublicRouteTableAssociationID = *association.RouteTableAssociationId
+						}
+					}
+				}
+				continue
+			}
+			privateRouteTableIDs = append(privateRouteTableIDs, routeTableID)
+			associated := false
+			for _, association := range routeTable.Associations {
+				for i, az := range azs {
+					if association.SubnetId != nil && az.PrivateSubnetID != "" && *association.SubnetId == az.PrivateSubnetID {
+						azs[i].PrivateRouteTableID = routeTableID
+						azs[i].PrivateRouteTableAssociationID = *association.RouteTableAssociationId
+						associated = true
+					}
+					if association.SubnetId != nil && az.PodSubnetID != "" && *association.SubnetId == az.PodSubnetID {
+						azs[i].PrivateRouteTableID = routeTableID
+						azs[i].PodRouteTableAssociationID = *association.RouteTableAssociationId
+						associated = true
+					}
+				}
+			}
+			if !associated {
+				unassociatedRouteTableIDs = append(unassociatedRouteTableIDs, routeTableID)
+			}
+		}
+		for i, az := range azs {
+			if az.PrivateRouteTableID == "" && len(unassociatedRouteTableIDs) > 0 {
+				azs[i].PrivateRouteTableID = unassociatedRouteTableIDs[0]
+				unassociatedRouteTableIDs = unassociatedRouteTableIDs[1:]
+			}
+		}
+		if !publicRouteTableFound {
+			inventory.PublicRouteTableID = ""
+		}
+		inventory.PrivateRouteTableIDs = privateRouteTableIDs
+
+		// routes are removed along with their route table
+		var routes []RouteInventory
+		for _, route := range inventory.Routes {
+			if route.RouteTableID == inventory.PublicRouteTableID || containsString(privateRouteTableIDs, route.RouteTableID) {
+				routes = append(routes, route)
+			}
+		}
+		inventory.Routes = routes
+	}
+
+	// VPC Endpoints
+	if inventory.VPCID != "" && inventory.VPCEndpointSecurityGroupID != "" {
+		if _, err := c.getSecurityGroup(ctx, inventory.VPCEndpointSecurityGroupID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.VPCEndpointSecurityGroupID = ""
+		}
+	}
+	if inventory.VPCID != "" && len(inventory.VPCEndpoints) > 0 {
+		vpcEndpoints, err := c.getVPCEndpoints(ctx, inventory.VPCID, getVPCEndpointIDs(inventory.VPCEndpoints))
+		if err != nil {
+			return err
+		}
+		existingVPCEndpointIDs := make(map[string]bool)
+		for _, vpcEndpoint := range vpcEndpoints {
+			existingVPCEndpointIDs[*vpcEndpoint.VpcEndpointId] = true
+		}
+		var recordedVPCEndpoints []VPCEndpointInventory
+		for _, vpcEndpoint := range inventory.VPCEndpoints {
+			if existingVPCEndpointIDs[vpcEndpoint.VPCEndpointID] {
+				recordedVPCEndpoints = append(recordedVPCEndpoints, vpcEndpoint)
+			}
+		}
+		inventory.VPCEndpoints = recordedVPCEndpoints
+	}
+
+	// Flow Log and its log group
+	if inventory.FlowLogID != "" {
+		if _, err := c.getFlowLog(ctx, inventory.FlowLogID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.FlowLogID = ""
+		}
+	}
+	if inventory.FlowLogGroupName != "" {
+		if _, err := c.getLogGroup(ctx, inventory.FlowLogGroupName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.FlowLogGroupName = ""
+		}
+	}
+
+	// Transit Gateway Attachment and VPC Peering Connection
+	if inventory.TransitGatewayAttachmentID != "" {
+		if _, err := c.getTransitGatewayAttachment(ctx, inventory.TransitGatewayAttachmentID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.TransitGatewayAttachmentID = ""
+		}
+	}
+	if inventory.VPCPeeringConnectionID != "" {
+		if _, err := c.getVPCPeeringConnection(ctx, inventory.VPCPeeringConnectionID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.VPCPeeringConnectionID = ""
+		}
+	}
+
+	// Network ACLs
+	if len(inventory.NetworkACLs) > 0 {
+		var recordedNetworkACLs []NetworkACLInventory
+		for _, networkACL := range inventory.NetworkACLs {
+			if _, err := c.getNetworkACL(ctx, networkACL.NetworkACLID); err != nil {
+				if !errors.Is(err, ErrResourceNotFound) {
+					return err
+				}
+				continue
+			}
+			recordedNetworkACLs = append(recordedNetworkACLs, networkACL)
+		}
+		inventory.NetworkACLs = recordedNetworkACLs
+	}
+
+	// IAM Policies
+	var policyARNs []string
+	for _, policyARN := range inventory.PolicyARNs {
+		if _, err := c.getPolicy(ctx, policyARN); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			continue
+		}
+		policyARNs = append(policyARNs, policyARN)
+	}
+	inventory.PolicyARNs = policyARNs
+
+	// IAM Roles
+	for _, role := range []*RoleInventory{
+		&inventory.ClusterRole,
+		&inventory.WorkerRole,
+		&inventory.DNSManagementRole,
+		&inventory.DNS01ChallengeRole,
+		&inventory.ClusterAutoscalingRole,
+		&inventory.StorageManagementRole,
+		&inventory.FlowLogsRole,
+	} {
+		if role.RoleName == "" {
+			continue
+		}
+		if _, err := c.getRole(ctx, role.RoleName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			*role = RoleInventory{}
+		}
+	}
+
+	// EKS Cluster
+	if inventory.Cluster.ClusterName != "" {
+		if _, err := c.getCluster(ctx, inventory.Cluster.ClusterName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			// node groups, addons and the cluster security group are gone
+			// along with the cluster
+			inventory.Cluster = ClusterInventory{}
+			inventory.NodeGroupNames = []string{}
+			inventory.AddonNames = []string{}
+			inventory.SecurityGroupID = ""
+		}
+	}
+
+	// Node Groups
+	var nodeGroupNames []string
+	for _, nodeGroupName := range inventory.NodeGroupNames {
+		if _, err := c.getNodeGroup(ctx, inventory.Cluster.ClusterName, nodeGroupName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			continue
+		}
+		nodeGroupNames = append(nodeGroupNames, nodeGroupName)
+	}
+	inventory.NodeGroupNames = nodeGroupNames
+
+	// Addons
+	var addonNames []string
+	for _, addonName := range inventory.AddonNames {
+		if _, err := c.getAddon(ctx, inventory.Cluster.ClusterName, addonName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			continue
+		}
+		addonNames = append(addonNames, addonName)
+	}
+	inventory.AddonNames = addonNames
+
+	// OIDC Provider
+	if inventory.OIDCProviderARN != "" {
+		if err := c.getOIDCProvider(ctx, inventory.OIDCProviderARN); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			inventory.OIDCProviderARN = ""
+		}
+	}
+
+	return nil
+}
+
+// findPolicyARN returns the ARN from a list of policy ARNs for the policy with
+// the given name.  If none is found it returns an empty string.
+func findPolicyARN(policyARNs []string, policyName string) string {
+	for _, policyARN := range policyARNs {
+		if strings.HasSuffix(policyARN, fmt.Sprintf(":policy/%s", policyName)) {
+			return policyARN
+		}
+	}
+
+	return ""
+}
+
+// copyAvailabilityZones returns a copy of a slice of availability zones so
+// the copy can be sent in the inventory while the original is updated.
+func copyAvailabilityZones(availabilityZones []AvailabilityZone) []AvailabilityZone {
+	return append([]AvailabilityZone{}, availabilityZones...)
+}
+
+// containsString returns true if a slice of strings contains the given string.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+func TestResumeResourceStack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation string
+		nodegroup bool
+	}{
+		{
+			name:      "NAT gateway",
+			operation: "CreateNatGateway",
+		},
+		{
+			name:      "role",
+			operation: "CreateRole",
+		},
+		{
+			name:      "cluster",
+			operation: "CreateCluster",
+		},
+		{
+			name:      "addon",
+			operation: "CreateAddon",
+		},
+		{
+			name:      "log group",
+			operation: "CreateLogGroup",
+		},
+		{
+			name:      "node group health",
+			nodegroup: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := fake.NewBackend(testRegion)
+			c := backend.ResourceClient()
+			c.FailurePolicy = resource.FailurePolicyKeep
+			if tc.nodegroup {
+				backend.FailNodegroups("instances failed to join the kubernetes cluster")
+			} else {
+				backend.Fail(tc.operation, errors.New("injected failure"))
+			}
+
+			var r fake.Recorder
+			r.Record(c)
+			err := c.CreateResourceStack(ctx, flowLogsConfig())
+			r.Stop()
+			if err == nil {
+				t.Fatal("expected create to fail")
+			}
+			backend.FailNodegroups("")
+
+			// resume the partial create
+			inventory := r.Inventory()
+			r.Record(c)
+			err = c.ResumeResourceStack(ctx, flowLogsConfig(), &inventory)
+			r.Stop()
+			if err != nil {
+				t.Fatalf("failed to resume resource stack: %v", err)
+			}
+			inventory = r.Inventory()
+			resumed := backend.Snapshot()
+
+			// the same resources exist as when the stack is created in one go
+			completeBackend, _, _ := createResourceStack(t, flowLogsConfig())
+			complete := completeBackend.Snapshot()
+			for name, counts := range map[string][2]int{
+				"VPCs":         {len(resumed.VPCIDs), len(complete.VPCIDs)},
+				"subnets":      {len(resumed.SubnetIDs), len(complete.SubnetIDs)},
+				"NAT gateways": {len(resumed.NATGatewayIDs), len(complete.NATGatewayIDs)},
+				"roles":        {len(resumed.RoleNames), len(complete.RoleNames)},
+				"clusters":     {len(resumed.ClusterNames), len(complete.ClusterNames)},
+				"node groups":  {len(resumed.NodegroupNames), len(complete.NodegroupNames)},
+				"addons":       {len(resumed.AddonNames), len(complete.AddonNames)},
+				"log groups":   {len(resumed.LogGroupNames), len(complete.LogGroupNames)},
+			} {
+				if counts[0] != counts[1] {
+					t.Errorf("expected %d %s after resume, got %d", counts[1], name, counts[0])
+				}
+			}
+
+			// resuming a complete create changes nothing
+			r.Record(c)
+			err = c.ResumeResourceStack(ctx, flowLogsConfig(), &inventory)
+			r.Stop()
+			if err != nil {
+				t.Fatalf("failed to resume complete resource stack: %v", err)
+			}
+			if !reflect.DeepEqual(resumed, backend.Snapshot()) {
+				t.Errorf("expected second resume to change nothing, got %+v", backend.Snapshot())
+			}
+
+			deleteResourceStack(t, backend, c, r.Inventory())
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -17,6 +18,7 @@ const (
 	DNS01ChallengeRoleName     = "dns-chlg-role"
 	ClusterAutoscalingRoleName = "ca-role"
 	StorageManagementRoleName  = "csi-role"
+	FlowLogsRoleName           = "flow-logs-role"
 	ClusterPolicyARN           = "arn:aws:iam::aws:policy/AmazonEKSClusterPolicy"
 	WorkerNodePolicyARN        = "arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy"
 	ContainerRegistryPolicyARN = "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly"
@@ -25,8 +27,8 @@ const (
 )
 
 // CreateRoles creates the IAM roles needed for EKS clusters and node groups.
-func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*types.Role, *types.Role, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) CreateRoles(ctx context.Context, tags *[]types.Tag, clusterName string) (*types.Role, *types.Role, error) {
+	svc := c.iamClient()
 
 	clusterRoleName := fmt.Sprintf("%s-%s", ClusterRoleName, clusterName)
 	if err := CheckRoleName(clusterRoleName); err != nil {
@@ -53,7 +55,7 @@ func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*ty
 		PermissionsBoundary:      &clusterPolicyARN,
 		Tags:                     *tags,
 	}
-	clusterRoleResp, err := svc.CreateRole(c.Context, &createClusterRoleInput)
+	clusterRoleResp, err := svc.CreateRole(ctx, &createClusterRoleInput)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create role %s: %w", clusterRoleName, err)
 	}
@@ -62,7 +64,7 @@ func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*ty
 		PolicyArn: &clusterPolicyARN,
 		RoleName:  clusterRoleResp.Role.RoleName,
 	}
-	_, err = svc.AttachRolePolicy(c.Context, &attachClusterRolePolicyInput)
+	_, err = svc.AttachRolePolicy(ctx, &attachClusterRolePolicyInput)
 	if err != nil {
 		return clusterRoleResp.Role, nil, fmt.Errorf("failed to attach role policy %s to %s: %w", clusterPolicyARN, clusterRoleName, err)
 	}
@@ -90,8 +92,9 @@ func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*ty
 	createWorkerRoleInput := iam.CreateRoleInput{
 		AssumeRolePolicyDocument: &workerRolePolicyDocument,
 		RoleName:                 &workerRoleName,
+		Tags:                     *tags,
 	}
-	workerRoleResp, err := svc.CreateRole(c.Context, &createWorkerRoleInput)
+	workerRoleResp, err := svc.CreateRole(ctx, &createWorkerRoleInput)
 	if err != nil {
 		return clusterRoleResp.Role, nil, fmt.Errorf("failed to create role %s: %w", workerRoleName, err)
 	}
@@ -101,7 +104,7 @@ func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*ty
 			PolicyArn: &policyARN,
 			RoleName:  workerRoleResp.Role.RoleName,
 		}
-		_, err = svc.AttachRolePolicy(c.Context, &attachRolePolicyInput)
+		_, err = svc.AttachRolePolicy(ctx, &attachRolePolicyInput)
 		if err != nil {
 			return clusterRoleResp.Role, workerRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", policyARN, workerRoleName, err)
 		}
@@ -114,6 +117,7 @@ func (c *ResourceClient) CreateRoles(tags *[]types.Tag, clusterName string) (*ty
 // the Kubernetes service account of an in-cluster supporting service such as
 // external-dns using IRSA (IAM role for service accounts).
 func (c *ResourceClient) CreateDNSManagementRole(
+	ctx context.Context,
 	tags *[]types.Tag,
 	dnsPolicyARN string,
 	awsAccountID string,
@@ -121,7 +125,7 @@ func (c *ResourceClient) CreateDNSManagementRole(
 	serviceAccount *DNSManagementServiceAccount,
 	clusterName string,
 ) (*types.Role, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	oidcProviderBare := strings.Trim(oidcProvider, "https://")
 	dnsManagementRoleName := fmt.Sprintf("%s-%s", DNSManagementRoleName, clusterName)
@@ -152,7 +156,7 @@ func (c *ResourceClient) CreateDNSManagementRole(
 		PermissionsBoundary:      &dnsPolicyARN,
 		Tags:                     *tags,
 	}
-	dnsManagementRoleResp, err := svc.CreateRole(c.Context, &createDNSManagementRoleInput)
+	dnsManagementRoleResp, err := svc.CreateRole(ctx, &createDNSManagementRoleInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role %s: %w", dnsManagementRoleName, err)
 	}
@@ -161,7 +165,7 @@ func (c *ResourceClient) CreateDNSManagementRole(
 		PolicyArn: &dnsPolicyARN,
 		RoleName:  dnsManagementRoleResp.Role.RoleName,
 	}
-	_, err = svc.AttachRolePolicy(c.Context, &attachDNSManagementRolePolicyInput)
+	_, err = svc.AttachRolePolicy(ctx, &attachDNSManagementRolePolicyInput)
 	if err != nil {
 		return dnsManagementRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", dnsPolicyARN, dnsManagementRoleName, err)
 	}
@@ -173,6 +177,7 @@ func (c *ResourceClient) CreateDNSManagementRole(
 // the Kubernetes service account of an in-cluster supporting service such as
 // cert-manager using IRSA (IAM role for service accounts).
 func (c *ResourceClient) CreateDNS01ChallengeRole(
+	ctx context.Context,
 	tags *[]types.Tag,
 	dnsPolicyARN string,
 	awsAccountID string,
@@ -180,7 +185,7 @@ func (c *ResourceClient) CreateDNS01ChallengeRole(
 	serviceAccount *DNS01ChallengeServiceAccount,
 	clusterName string,
 ) (*types.Role, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	oidcProviderBare := strings.Trim(oidcProvider, "https://")
 	dns01ChallengeRoleName := fmt.Sprintf("%s-%s", DNS01ChallengeRoleName, clusterName)
@@ -211,7 +216,7 @@ func (c *ResourceClient) CreateDNS01ChallengeRole(
 		PermissionsBoundary:      &dnsPolicyARN,
 		Tags:                     *tags,
 	}
-	dns01ChallengeRoleResp, err := svc.CreateRole(c.Context, &createdDNS01ChallengeRoleInput)
+	dns01ChallengeRoleResp, err := svc.CreateRole(ctx, &createdDNS01ChallengeRoleInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role %s: %w", dns01ChallengeRoleName, err)
 	}
@@ -220,7 +225,7 @@ func (c *ResourceClient) CreateDNS01ChallengeRole(
 		PolicyArn: &dnsPolicyARN,
 		RoleName:  dns01ChallengeRoleResp.Role.RoleName,
 	}
-	_, err = svc.AttachRolePolicy(c.Context, &attachDNSManagementRolePolicyInput)
+	_, err = svc.AttachRolePolicy(ctx, &attachDNSManagementRolePolicyInput)
 	if err != nil {
 		return dns01ChallengeRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", dnsPolicyARN, dns01ChallengeRoleName, err)
 	}
@@ -232,6 +237,7 @@ func (c *ResourceClient) CreateDNS01ChallengeRole(
 // autoscaler to manage node pool sizes using IRSA (IAM role for service
 // accounts).
 func (c *ResourceClient) CreateClusterAutoscalingRole(
+	ctx context.Context,
 	tags *[]types.Tag,
 	autoscalingPolicyARN string,
 	awsAccountID string,
@@ -239,7 +245,7 @@ func (c *ResourceClient) CreateClusterAutoscalingRole(
 	serviceAccount *ClusterAutoscalingServiceAccount,
 	clusterName string,
 ) (*types.Role, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	oidcProviderBare := strings.Trim(oidcProvider, "https://")
 	clusterAutoscalingRoleName := fmt.Sprintf("%s-%s", ClusterAutoscalingRoleName, clusterName)
@@ -270,7 +276,7 @@ func (c *ResourceClient) CreateClusterAutoscalingRole(
 		PermissionsBoundary:      &autoscalingPolicyARN,
 		Tags:                     *tags,
 	}
-	clusterAutoscalingRoleResp, err := svc.CreateRole(c.Context, &createClusterAutoscalingRoleInput)
+	clusterAutoscalingRoleResp, err := svc.CreateRole(ctx, &createClusterAutoscalingRoleInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role %s: %w", clusterAutoscalingRoleName, err)
 	}
@@ -279,7 +285,7 @@ func (c *ResourceClient) CreateClusterAutoscalingRole(
 		PolicyArn: &autoscalingPolicyARN,
 		RoleName:  clusterAutoscalingRoleResp.Role.RoleName,
 	}
-	_, err = svc.AttachRolePolicy(c.Context, &attachClusterAutoscalingRolePolicyInput)
+	_, err = svc.AttachRolePolicy(ctx, &attachClusterAutoscalingRolePolicyInput)
 	if err != nil {
 		return clusterAutoscalingRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", autoscalingPolicyARN, clusterAutoscalingRoleName, err)
 	}
@@ -291,13 +297,14 @@ func (c *ResourceClient) CreateClusterAutoscalingRole(
 // management by the CSI driver's service account using IRSA (IAM role for
 // service accounts).
 func (c *ResourceClient) CreateStorageManagementRole(
+	ctx context.Context,
 	tags *[]types.Tag,
 	awsAccountID string,
 	oidcProvider string,
 	serviceAccount *StorageManagementServiceAccount,
 	clusterName string,
 ) (*types.Role, error) {
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	oidcProviderBare := strings.Trim(oidcProvider, "https://")
 	storageManagementRoleName := fmt.Sprintf("%s-%s", StorageManagementRoleName, clusterName)
@@ -329,7 +336,7 @@ func (c *ResourceClient) CreateStorageManagementRole(
 		PermissionsBoundary:      &storagePolicyARN,
 		Tags:                     *tags,
 	}
-	storageManagementRoleResp, err := svc.CreateRole(c.Context, &createStorageManagementRoleInput)
+	storageManagementRoleResp, err := svc.CreateRole(ctx, &createStorageManagementRoleInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role %s: %w", storageManagementRoleName, err)
 	}
@@ -338,7 +345,7 @@ func (c *ResourceClient) CreateStorageManagementRole(
 		PolicyArn: &storagePolicyARN,
 		RoleName:  storageManagementRoleResp.Role.RoleName,
 	}
-	_, err = svc.AttachRolePolicy(c.Context, &attachStorageManagementRolePolicyInput)
+	_, err = svc.AttachRolePolicy(ctx, &attachStorageManagementRolePolicyInput)
 	if err != nil {
 		return storageManagementRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", storagePolicyARN, storageManagementRoleName, err)
 	}
@@ -346,15 +353,65 @@ func (c *ResourceClient) CreateStorageManagementRole(
 	return storageManagementRoleResp.Role, nil
 }
 
+// CreateFlowLogsRole creates the IAM role the VPC flow logs service assumes to
+// deliver flow logs to CloudWatch Logs.
+func (c *ResourceClient) CreateFlowLogsRole(
+	ctx context.Context,
+	tags *[]types.Tag,
+	flowLogsPolicyARN string,
+	clusterName string,
+) (*types.Role, error) {
+	svc := c.iamClient()
+
+	flowLogsRoleName := fmt.Sprintf("%s-%s", FlowLogsRoleName, clusterName)
+	if err := CheckRoleName(flowLogsRoleName); err != nil {
+		return nil, err
+	}
+	flowLogsRolePolicyDocument := `{
+  "Version": "2012-10-17",
+  "Statement": [
+	  {
+		  "Effect": "Allow",
+		  "Principal": {
+			  "Service": [
+				  "vpc-flow-logs.amazonaws.com"
+			  ]
+		  },
+		  "Action": "sts:AssumeRole"
+	  }
+  ]
+}`
+	createFlowLogsRoleInput := iam.CreateRoleInput{
+		AssumeRolePolicyDocument: &flowLogsRolePolicyDocument,
+		RoleName:                 &flowLogsRoleName,
+		Tags:                     *tags,
+	}
+	flowLogsRoleResp, err := svc.CreateRole(ctx, &createFlowLogsRoleInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role %s: %w", flowLogsRoleName, err)
+	}
+
+	attachFlowLogsRolePolicyInput := iam.AttachRolePolicyInput{
+		PolicyArn: &flowLogsPolicyARN,
+		RoleName:  flowLogsRoleResp.Role.RoleName,
+	}
+	_, err = svc.AttachRolePolicy(ctx, &attachFlowLogsRolePolicyInput)
+	if err != nil {
+		return flowLogsRoleResp.Role, fmt.Errorf("failed to attach role policy %s to %s: %w", flowLogsPolicyARN, flowLogsRoleName, err)
+	}
+
+	return flowLogsRoleResp.Role, nil
+}
+
 // DeleteRoles deletes the IAM roles used by EKS.  If empty role names are
 // provided, or if the roles are not found it returns without error.
-func (c *ResourceClient) DeleteRoles(roles *[]RoleInventory) error {
+func (c *ResourceClient) DeleteRoles(ctx context.Context, roles *[]RoleInventory) error {
 	// if roles are empty, there's nothing to delete
 	if len(*roles) == 0 {
 		return nil
 	}
 
-	svc := iam.NewFromConfig(*c.AWSConfig)
+	svc := c.iamClient()
 
 	for _, role := range *roles {
 		if role.RoleName == "" {
@@ -366,22 +423,24 @@ func (c *ResourceClient) DeleteRoles(roles *[]RoleInventory) error {
 				PolicyArn: &policyARN,
 				RoleName:  &role.RoleName,
 			}
-			_, err := svc.DetachRolePolicy(c.Context, &detachRolePolicyInput)
+			_, err := svc.DetachRolePolicy(ctx, &detachRolePolicyInput)
 			if err != nil {
 				var noSuchEntityErr *types.NoSuchEntityException
 				if errors.As(err, &noSuchEntityErr) {
-					return nil
+					// policy is not attached or role doesn't exist - the
+					// remaining policies and roles still need cleaning up
+					continue
 				} else {
 					return fmt.Errorf("failed to detach policy %s from role %s: %w", policyARN, role.RoleName, err)
 				}
 			}
 		}
 		deleteRoleInput := iam.DeleteRoleInput{RoleName: &role.RoleName}
-		_, err := svc.DeleteRole(c.Context, &deleteRoleInput)
+		_, err := svc.DeleteRole(ctx, &deleteRoleInput)
 		if err != nil {
 			var noSuchEntityErr *types.NoSuchEntityException
 			if errors.As(err, &noSuchEntityErr) {
-				return nil
+				continue
 			} else {
 				return fmt.Errorf("failed to delete role %s: %w", role.RoleName, err)
 			}
@@ -391,6 +450,44 @@ func (c *ResourceClient) DeleteRoles(roles *[]RoleInventory) error {
 	return nil
 }
 
+// getRole retrieves the IAM role with the given name.  If the role is not found
+// it returns ErrResourceNotFound.
+func (c *ResourceClient) getRole(ctx context.Context, roleName string) (*types.Role, error) {
+	svc := c.iamClient()
+
+	getRoleInput := iam.GetRoleInput{RoleName: &roleName}
+	resp, err := svc.GetRole(ctx, &getRoleInput)
+	if err != nil {
+		var noSuchEntityErr *types.NoSuchEntityException
+		if errors.As(err, &noSuchEntityErr) {
+			return nil, ErrResourceNotFound
+		} else {
+			return nil, fmt.Errorf("failed to get role %s: %w", roleName, err)
+		}
+	}
+
+	return resp.Role, nil
+}
+
+// attachRolePolicies attaches the policies recorded for a role.  Attaching a
+// policy that is already attached has no effect.
+func (c *ResourceClient) attachRolePolicies(ctx context.Context, role RoleInventory) error {
+	svc := c.iamClient()
+
+	for _, policyARN := range role.RolePolicyARNs {
+		attachRolePolicyInput := iam.AttachRolePolicyInput{
+			PolicyArn: &policyARN,
+			RoleName:  &role.RoleName,
+		}
+		_, err := svc.AttachRolePolicy(ctx, &attachRolePolicyInput)
+		if err != nil {
+			return fmt.Errorf("failed to attach role policy %s to %s: %w", policyARN, role.RoleName, err)
+		}
+	}
+
+	return nil
+}
+
 // getWorkerPolicyARNs returns the IAM policy ARNs needed for clusters and node
 // groups.
 func getWorkerPolicyARNs() []string {
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,52 +13,40 @@ import (
 // CreateRouteTables creates the route tables for the subnets used by the EKS
 // cluster.  A single route table is shared by all the public subnets, however a
 // separate route table is needed for each private subnet because they each get
-// a route to a different NAT gateway.
+// a route to a different NAT gateway.  A private subnet in an availability zone
+// without a NAT gateway gets a route to the NAT gateway of the first
+// availability zone that has one, or no default route if there are no NAT
+// gateways.  If an egress-only internet gateway ID is supplied, the public
+// route table also gets an IPv6 default route to the internet gateway and each
+// private route table an IPv6 default route to the egress-only internet
+// gateway.  The additional routes, e.g. to a transit gateway or VPC peering
+// connection, are added to the public and every private route table.  An
+// availability zone's pod subnet, if it has one, is associated with its
+// private route table.  If the public route table ID is supplied, or
+// a private route table ID is already set on an availability zone, that route
+// table is used rather than creating a new one.  Routes and associations that
+// already exist are left in place.  The IDs of the subnet
+// associations are set on the availability zones and the routes are returned
+// so they can be recorded in the inventory.
 func (c *ResourceClient) CreateRouteTables(
+	ctx context.Context,
 	tags *[]types.Tag,
 	vpcID string,
 	internetGatewayID string,
+	egressOnlyInternetGatewayID string,
+	publicRouteTableID string,
+	additionalRoutes []RouteInventory,
 	availabilityZones *[]AvailabilityZone,
-) (*[]types.RouteTable, *types.RouteTable, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
-
-	destinationCIDR := "0.0.0.0/0"
+) (*[]types.RouteTable, *types.RouteTable, *[]RouteInventory, error) {
+	svc := c.ec2Client()
 
 	var privateRouteTables []types.RouteTable
 	var publicRouteTable types.RouteTable
+	var routes []RouteInventory
 
 	// create a single route table for public subnets
-	createPublicRouteTableInput := ec2.CreateRouteTableInput{
-		VpcId: &vpcID,
-		TagSpecifications: []types.TagSpecification{
-			{
-				ResourceType: types.ResourceTypeRouteTable,
-				Tags:         *tags,
-			},
-		},
-	}
-	publicResp, err := svc.CreateRouteTable(c.Context, &createPublicRouteTableInput)
-	if err != nil {
-		return &privateRouteTables, &publicRouteTable, fmt.Errorf("failed to create public route table for VPC ID %s: %w", vpcID, err)
-	}
-	publicRouteTable = *publicResp.RouteTable
-
-	// add route to internet gateway for public subnets' route table
-	createRouteInput := ec2.CreateRouteInput{
-		RouteTableId:         publicRouteTable.RouteTableId,
-		GatewayId:            &internetGatewayID,
-		DestinationCidrBlock: &destinationCIDR,
-	}
-	_, err = svc.CreateRoute(c.Context, &createRouteInput)
-	if err != nil {
-		return &privateRouteTables, &publicRouteTable, fmt.Errorf(
-			"failed to create route to internet gateway with ID %s for route table with ID %s: %w",
-			internetGatewayID, *publicRouteTable.RouteTableId, err)
-	}
-
-	// create a route table for each private subnet
-	for _, az := range *availabilityZones {
-		createPrivateRouteTableInput := ec2.CreateRouteTableInput{
+	if publicRouteTableID == "" {
+		createPublicRouteTableInput := ec2.CreateRouteTableInput{
 			VpcId: &vpcID,
 			TagSpecifications: []types.TagSpecification{
 				{
@@ -66,59 +55,221 @@ func (c *ResourceClient) CreateRouteTables(
 				},
 			},
 		}
-		privateResp, err := svc.CreateRouteTable(c.Context, &createPrivateRouteTableInput)
+		publicResp, err := svc.CreateRouteTable(ctx, &createPublicRouteTableInput)
 		if err != nil {
-			return &privateRouteTables, &publicRouteTable, fmt.Errorf("failed to create private route table for VPC ID %s: %w", vpcID, err)
+			return &privateRouteTables, nil, &routes, fmt.Errorf("failed to create public route table for VPC ID %s: %w", vpcID, err)
+		}
+		publicRouteTable = *publicResp.RouteTable
+	} else {
+		publicRouteTable = types.RouteTable{RouteTableId: &publicRouteTableID, VpcId: &vpcID}
+	}
+
+	// add routes to internet gateway for public subnets' route table
+	destinationCIDRs := []string{ipv4DefaultRouteCIDR}
+	if egressOnlyInternetGatewayID != "" {
+		destinationCIDRs = append(destinationCIDRs, ipv6DefaultRouteCIDR)
+	}
+	for _, destinationCIDR := range destinationCIDRs {
+		route := RouteInventory{
+			RouteTableID:    *publicRouteTable.RouteTableId,
+			DestinationCIDR: destinationCIDR,
+			GatewayID:       internetGatewayID,
+		}
+		if err := c.createRoute(ctx, route); err != nil {
+			return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+				"failed to create route to %s via internet gateway with ID %s for route table with ID %s: %w",
+				destinationCIDR, internetGatewayID, *publicRouteTable.RouteTableId, err)
+		}
+		routes = append(routes, route)
+	}
+	for _, route := range additionalRoutes {
+		route.RouteTableID = *publicRouteTable.RouteTableId
+		if err := c.createRoute(ctx, route); err != nil {
+			return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+				"failed to create route to %s via %s for route table with ID %s: %w",
+				route.DestinationCIDR, routeInventoryTarget(route), *publicRouteTable.RouteTableId, err)
+		}
+		routes = append(routes, route)
+	}
+
+	// private subnets without a NAT gateway of their own share this one
+	azs := *availabilityZones
+	var sharedNATGatewayID string
+	for _, az := range azs {
+		if az.NATGatewayID != "" {
+			sharedNATGatewayID = az.NATGatewayID
+			break
+		}
+	}
+
+	// create a route table for each private subnet
+	for i, az := range azs {
+		if az.PrivateRouteTableID == "" {
+			createPrivateRouteTableInput := ec2.CreateRouteTableInput{
+				VpcId: &vpcID,
+				TagSpecifications: []types.TagSpecification{
+					{
+						ResourceType: types.ResourceTypeRouteTable,
+						Tags:         *tags,
+					},
+				},
+			}
+			privateResp, err := svc.CreateRouteTable(ctx, &createPrivateRouteTableInput)
+			if err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf("failed to create private route table for VPC ID %s: %w", vpcID, err)
+			}
+			azs[i].PrivateRouteTableID = *privateResp.RouteTable.RouteTableId
 		}
-		privateRouteTables = append(privateRouteTables, *privateResp.RouteTable)
+		privateRouteTableID := azs[i].PrivateRouteTableID
+		privateRouteTables = append(privateRouteTables, types.RouteTable{RouteTableId: &privateRouteTableID, VpcId: &vpcID})
 
 		// associate the private route table with the private subnet for this
 		// availability zone
-		associatePrivateRouteTableInput := ec2.AssociateRouteTableInput{
-			RouteTableId: privateResp.RouteTable.RouteTableId,
-			SubnetId:     &az.PrivateSubnetID,
+		if az.PrivateRouteTableAssociationID == "" {
+			associationID, err := c.associateRouteTable(ctx, privateRouteTableID, az.PrivateSubnetID)
+			if err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to associate private route table with ID %s to subnet with ID %s: %w",
+					privateRouteTableID, az.PrivateSubnetID, err)
+			}
+			azs[i].PrivateRouteTableAssociationID = associationID
 		}
-		_, err = svc.AssociateRouteTable(c.Context, &associatePrivateRouteTableInput)
-		if err != nil {
-			return &privateRouteTables, &publicRouteTable, fmt.Errorf(
-				"failed to associate private route table with ID %d to subnet with ID %s: %w",
-				privateResp.RouteTable.RouteTableId, az.PrivateSubnetID, err)
+
+		// pods in the pod subnet get the same routes as the nodes in the
+		// private subnet
+		if az.PodSubnetID != "" && az.PodRouteTableAssociationID == "" {
+			associationID, err := c.associateRouteTable(ctx, privateRouteTableID, az.PodSubnetID)
+			if err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to associate private route table with ID %s to pod subnet with ID %s: %w",
+					privateRouteTableID, az.PodSubnetID, err)
+			}
+			azs[i].PodRouteTableAssociationID = associationID
 		}
 
 		// add a route to the NAT gateway for the private subnet
-		createRouteInput := ec2.CreateRouteInput{
-			RouteTableId:         privateResp.RouteTable.RouteTableId,
-			NatGatewayId:         &az.NATGatewayID,
-			DestinationCidrBlock: &destinationCIDR,
+		natGatewayID := az.NATGatewayID
+		if natGatewayID == "" {
+			natGatewayID = sharedNATGatewayID
 		}
-		_, err = svc.CreateRoute(c.Context, &createRouteInput)
-		if err != nil {
-			return &privateRouteTables, &publicRouteTable, fmt.Errorf(
-				"failed to create route to NAT gateway with ID %s for route table with ID %s: %w",
-				az.NATGatewayID, *privateResp.RouteTable.RouteTableId, err)
+		if natGatewayID != "" {
+			route := RouteInventory{
+				RouteTableID:    privateRouteTableID,
+				DestinationCIDR: ipv4DefaultRouteCIDR,
+				NATGatewayID:    natGatewayID,
+			}
+			if err := c.createRoute(ctx, route); err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to create route to NAT gateway with ID %s for route table with ID %s: %w",
+					natGatewayID, privateRouteTableID, err)
+			}
+			routes = append(routes, route)
+		}
+
+		// add an IPv6 route to the egress-only internet gateway for the
+		// private subnet
+		if egressOnlyInternetGatewayID != "" {
+			route := RouteInventory{
+				RouteTableID:                privateRouteTableID,
+				DestinationCIDR:             ipv6DefaultRouteCIDR,
+				EgressOnlyInternetGatewayID: egressOnlyInternetGatewayID,
+			}
+			if err := c.createRoute(ctx, route); err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to create route to egress-only internet gateway with ID %s for route table with ID %s: %w",
+					egressOnlyInternetGatewayID, privateRouteTableID, err)
+			}
+			routes = append(routes, route)
+		}
+
+		// add the additional routes for the private subnet
+		for _, route := range additionalRoutes {
+			route.RouteTableID = privateRouteTableID
+			if err := c.createRoute(ctx, route); err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to create route to %s via %s for route table with ID %s: %w",
+					route.DestinationCIDR, routeInventoryTarget(route), privateRouteTableID, err)
+			}
+			routes = append(routes, route)
 		}
 
 		// associate the public route table with the public subnet for this
 		// availability zone
-		associatePublicRouteTableInput := ec2.AssociateRouteTableInput{
-			RouteTableId: publicRouteTable.RouteTableId,
-			SubnetId:     &az.PublicSubnetID,
+		if az.PublicRouteTableAssociationID == "" {
+			associationID, err := c.associateRouteTable(ctx, *publicRouteTable.RouteTableId, az.PublicSubnetID)
+			if err != nil {
+				return &privateRouteTables, &publicRouteTable, &routes, fmt.Errorf(
+					"failed to associate public route table with ID %s to subnet with ID %s: %w",
+					*publicRouteTable.RouteTableId, az.PublicSubnetID, err)
+			}
+			azs[i].PublicRouteTableAssociationID = associationID
+		}
+	}
+
+	return &privateRouteTables, &publicRouteTable, &routes, nil
+}
+
+// DeleteRoutes deletes routes from their route tables.  Routes and route
+// tables that are not found are skipped.
+func (c *ResourceClient) DeleteRoutes(ctx context.Context, routes []RouteInventory) error {
+	svc := c.ec2Client()
+
+	for _, route := range routes {
+		routeTableID := route.RouteTableID
+		destinationCIDR := route.DestinationCIDR
+		deleteRouteInput := ec2.DeleteRouteInput{RouteTableId: &routeTableID}
+		if isIPv6CIDR(destinationCIDR) {
+			deleteRouteInput.DestinationIpv6CidrBlock = &destinationCIDR
+		} else {
+			deleteRouteInput.DestinationCidrBlock = &destinationCIDR
+		}
+		_, err := svc.DeleteRoute(ctx, &deleteRouteInput)
+		if err != nil {
+			var ae smithy.APIError
+			if errors.As(err, &ae) {
+				if ae.ErrorCode() == "InvalidRoute.NotFound" || ae.ErrorCode() == "InvalidRouteTableID.NotFound" {
+					// attempting to delete a route that doesn't exist so
+					// move on to the next one
+					continue
+				} else {
+					return fmt.Errorf("failed to delete route to %s from route table with ID %s: %w",
+						destinationCIDR, routeTableID, err)
+				}
+			} else {
+				return fmt.Errorf("failed to delete route to %s from route table with ID %s: %w",
+					destinationCIDR, routeTableID, err)
+			}
 		}
-		_, err = svc.AssociateRouteTable(c.Context, &associatePublicRouteTableInput)
+	}
+
+	return nil
+}
+
+// DisassociateRouteTables removes the route table associations with the given
+// IDs.  Associations that are not found are skipped.
+func (c *ResourceClient) DisassociateRouteTables(ctx context.Context, associationIDs []string) error {
+	svc := c.ec2Client()
+
+	for _, associationID := range associationIDs {
+		disassociateRouteTableInput := ec2.DisassociateRouteTableInput{AssociationId: &associationID}
+		_, err := svc.DisassociateRouteTable(ctx, &disassociateRouteTableInput)
 		if err != nil {
-			return &privateRouteTables, &publicRouteTable, fmt.Errorf(
-				"failed to associate public route table with ID %d to subnet with ID %s: %w",
-				publicRouteTable.RouteTableId, az.PublicSubnetID, err)
+			var ae smithy.APIError
+			if errors.As(err, &ae) && ae.ErrorCode() == "InvalidAssociationID.NotFound" {
+				continue
+			}
+			return fmt.Errorf("failed to remove route table association with ID %s: %w", associationID, err)
 		}
 	}
 
-	return &privateRouteTables, &publicRouteTable, nil
+	return nil
 }
 
 // DeleteRouteTables deletes the route tables for the public and private subnets
-// that are used by EKS.
-func (c *ResourceClient) DeleteRouteTables(privateRouteTableIDs []string, publicRouteTable string) error {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+// that are used by EKS.  Each route table is disassociated from its subnets
+// before it is deleted.  Route tables that are not found are skipped.
+func (c *ResourceClient) DeleteRouteTables(ctx context.Context, privateRouteTableIDs []string, publicRouteTable string) error {
+	svc := c.ec2Client()
 
 	var allRouteTableIDs []string
 	switch {
@@ -130,19 +281,22 @@ func (c *ResourceClient) DeleteRouteTables(privateRouteTableIDs []string, public
 		allRouteTableIDs = privateRouteTableIDs
 	default:
 		// there are private and public route table IDs to delete
-		allRouteTableIDs = append(privateRouteTableIDs, publicRouteTable)
+		allRouteTableIDs = append(append([]string{}, privateRouteTableIDs...), publicRouteTable)
 	}
 
 	for _, routeTableID := range allRouteTableIDs {
+		if err := c.disassociateRouteTable(ctx, routeTableID); err != nil {
+			return err
+		}
 		deleteRouteTableInput := ec2.DeleteRouteTableInput{RouteTableId: &routeTableID}
-		_, err := svc.DeleteRouteTable(c.Context, &deleteRouteTableInput)
+		_, err := svc.DeleteRouteTable(ctx, &deleteRouteTableInput)
 		if err != nil {
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
 				if ae.ErrorCode() == "InvalidRouteTableID.NotFound" {
-					// attempting to delete a route table that doesn't exist so return
-					// without error
-					return nil
+					// attempting to delete a route table that doesn't exist so
+					// move on to the next one
+					continue
 				} else {
 					return fmt.Errorf("failed to delete route table with ID %s: %w", routeTableID, err)
 				}
@@ -154,3 +308,251 @@ func (c *ResourceClient) DeleteRouteTables(privateRouteTableIDs []string, public
 
 	return nil
 }
+
+// disassociateRouteTable removes all subnet associations from a route table.
+// If the route table is not found it returns without error.
+func (c *ResourceClient) disassociateRouteTable(ctx context.Context, routeTableID string) error {
+	svc := c.ec2Client()
+
+	routeTableFilterName := "route-table-id"
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &routeTableFilterName,
+				Values: []string{routeTableID},
+			},
+		},
+	}
+	resp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return fmt.Errorf("failed to describe route table with ID %s: %w", routeTableID, err)
+	}
+
+	for _, routeTable := range resp.RouteTables {
+		for _, association := range routeTable.Associations {
+			if association.Main != nil && *association.Main {
+				continue
+			}
+			disassociateRouteTableInput := ec2.DisassociateRouteTableInput{
+				AssociationId: association.RouteTableAssociationId,
+			}
+			_, err := svc.DisassociateRouteTable(ctx, &disassociateRouteTableInput)
+			if err != nil {
+				var ae smithy.APIError
+				if errors.As(err, &ae) && ae.ErrorCode() == "InvalidAssociationID.NotFound" {
+					continue
+				}
+				return fmt.Errorf("failed to disassociate route table with ID %s: %w", routeTableID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getRouteTables retrieves the route tables in a VPC with the given IDs.  Route
+// tables that are not found are not included in the result.
+func (c *ResourceClient) getRouteTables(ctx context.Context, vpcID string, routeTableIDs []string) ([]types.RouteTable, error) {
+	// if there are no route table IDs there is nothing to get
+	if len(routeTableIDs) == 0 {
+		return []types.RouteTable{}, nil
+	}
+
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	routeTableFilterName := "route-table-id"
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &vpcFilterName,
+				Values: []string{vpcID},
+			},
+			{
+				Name:   &routeTableFilterName,
+				Values: routeTableIDs,
+			},
+		},
+	}
+	resp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return resp.RouteTables, nil
+}
+
+// createRoute adds a route to an internet gateway, NAT gateway, egress-only
+// internet gateway, transit gateway or VPC peering connection to a route
+// table.  If a route for the destination already
+// exists it is replaced so that it points to the given target.
+func (c *ResourceClient) createRoute(ctx context.Context, route RouteInventory) error {
+	svc := c.ec2Client()
+
+	var destinationCIDR, destinationIPv6CIDR, gatewayID, natGatewayID, egressOnlyInternetGatewayID *string
+	var transitGatewayID, vpcPeeringConnectionID *string
+	if isIPv6CIDR(route.DestinationCIDR) {
+		destinationIPv6CIDR = &route.DestinationCIDR
+	} else {
+		destinationCIDR = &route.DestinationCIDR
+	}
+	if route.GatewayID != "" {
+		gatewayID = &route.GatewayID
+	}
+	if route.NATGatewayID != "" {
+		natGatewayID = &route.NATGatewayID
+	}
+	if route.EgressOnlyInternetGatewayID != "" {
+		egressOnlyInternetGatewayID = &route.EgressOnlyInternetGatewayID
+	}
+	if route.TransitGatewayID != "" {
+		transitGatewayID = &route.TransitGatewayID
+	}
+	if route.VPCPeeringConnectionID != "" {
+		vpcPeeringConnectionID = &route.VPCPeeringConnectionID
+	}
+
+	createRouteInput := ec2.CreateRouteInput{
+		RouteTableId:                &route.RouteTableID,
+		GatewayId:                   gatewayID,
+		NatGatewayId:                natGatewayID,
+		EgressOnlyInternetGatewayId: egressOnlyInternetGatewayID,
+		TransitGatewayId:            transitGatewayID,
+		VpcPeeringConnectionId:      vpcPeeringConnectionID,
+		DestinationCidrBlock:        destinationCIDR,
+		DestinationIpv6CidrBlock:    destinationIPv6CIDR,
+	}
+	_, err := svc.CreateRoute(ctx, &createRouteInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "RouteAlreadyExists" {
+			replaceRouteInput := ec2.ReplaceRouteInput{
+				RouteTableId:                &route.RouteTableID,
+				GatewayId:                   gatewayID,
+				NatGatewayId:                natGatewayID,
+				EgressOnlyInternetGatewayId: egressOnlyInternetGatewayID,
+				TransitGatewayId:            transitGatewayID,
+				VpcPeeringConnectionId:      vpcPeeringConnectionID,
+				DestinationCidrBlock:        destinationCIDR,
+				DestinationIpv6CidrBlock:    destinationIPv6CIDR,
+			}
+			if _, err := svc.ReplaceRoute(ctx, &replaceRouteInput); err != nil {
+				return err
+			}
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// routeDestination returns the IPv4 or IPv6 CIDR block a route is for, or an
+// empty string if its destination is a prefix list.
+func routeDestination(route types.Route) string {
+	if route.DestinationCidrBlock != nil {
+		return *route.DestinationCidrBlock
+	}
+	if route.DestinationIpv6CidrBlock != nil {
+		return *route.DestinationIpv6CidrBlock
+	}
+
+	return ""
+}
+
+// routeTarget returns the ID of the internet gateway, NAT gateway, egress-only
+// internet gateway, transit gateway or VPC peering connection a route targets,
+// or an empty string if it targets something else.
+func routeTarget(route types.Route) string {
+	switch {
+	case route.NatGatewayId != nil:
+		return *route.NatGatewayId
+	case route.EgressOnlyInternetGatewayId != nil:
+		return *route.EgressOnlyInternetGatewayId
+	case route.TransitGatewayId != nil:
+		return *route.TransitGatewayId
+	case route.VpcPeeringConnectionId != nil:
+		return *route.VpcPeeringConnectionId
+	case route.GatewayId != nil:
+		return *route.GatewayId
+	}
+
+	return ""
+}
+
+// routeInventoryTarget returns the ID of the target of a route recorded in the
+// inventory.
+func routeInventoryTarget(route RouteInventory) string {
+	switch {
+	case route.NATGatewayID != "":
+		return route.NATGatewayID
+	case route.EgressOnlyInternetGatewayID != "":
+		return route.EgressOnlyInternetGatewayID
+	case route.TransitGatewayID != "":
+		return route.TransitGatewayID
+	case route.VPCPeeringConnectionID != "":
+		return route.VPCPeeringConnectionID
+	}
+
+	return route.GatewayID
+}
+
+// associateRouteTable associates a route table with a subnet and returns the
+// association ID.  If the subnet is already associated with the route table
+// the existing association ID is returned.
+func (c *ResourceClient) associateRouteTable(ctx context.Context, routeTableID, subnetID string) (string, error) {
+	svc := c.ec2Client()
+
+	associateRouteTableInput := ec2.AssociateRouteTableInput{
+		RouteTableId: &routeTableID,
+		SubnetId:     &subnetID,
+	}
+	resp, err := svc.AssociateRouteTable(ctx, &associateRouteTableInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "Resource.AlreadyAssociated" {
+			return c.getRouteTableAssociationID(ctx, routeTableID, subnetID)
+		}
+		return "", err
+	}
+	if resp.AssociationId == nil {
+		return "", nil
+	}
+
+	return *resp.AssociationId, nil
+}
+
+// getRouteTableAssociationID returns the ID of the association between a
+// route table and a subnet.  If the subnet is associated with a different
+// route table it returns an error.
+func (c *ResourceClient) getRouteTableAssociationID(ctx context.Context, routeTableID, subnetID string) (string, error) {
+	svc := c.ec2Client()
+
+	associationFilterName := "association.subnet-id"
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &associationFilterName,
+				Values: []string{subnetID},
+			},
+		},
+	}
+	resp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe route tables for subnet with ID %s: %w", subnetID, err)
+	}
+
+	for _, routeTable := range resp.RouteTables {
+		for _, association := range routeTable.Associations {
+			if association.SubnetId == nil || *association.SubnetId != subnetID || association.RouteTableAssociationId == nil {
+				continue
+			}
+			if routeTable.RouteTableId == nil || *routeTable.RouteTableId != routeTableID {
+				return "", fmt.Errorf("subnet with ID %s is associated with another route table", subnetID)
+			}
+			return *association.RouteTableAssociationId, nil
+		}
+	}
+
+	return "", fmt.Errorf("no association found between route table with ID %s and subnet with ID %s", routeTableID, subnetID)
+}
@@ -0,0 +1,268 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	VPCCIDRBlockCheckInterval = 5  // check VPC CIDR block association every 5 seconds
+	VPCCIDRBlockCheckMaxCount = 24 // check 24 times before giving up (2 minutes)
+)
+
+// ENIConfig contains the VPC CNI custom networking configuration for the pods
+// in an availability zone.  The name is the availability zone so that the VPC
+// CNI can pick the ENIConfig for a node by its topology.kubernetes.io/zone
+// label.
+type ENIConfig struct {
+	Name           string   `json:"name"`
+	Subnet         string   `json:"subnet"`
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+}
+
+// ENIConfigs returns the ENIConfig for each availability zone with a pod
+// subnet in an inventory.  Pods are given the cluster security group, the
+// same as the nodes in managed node groups.  If the cluster security group is
+// not known, the security groups are left empty and the VPC CNI uses those of
+// the node's primary network interface.
+func ENIConfigs(inventory *ResourceInventory) []ENIConfig {
+	var eniConfigs []ENIConfig
+	for _, az := range inventory.AvailabilityZones {
+		if az.PodSubnetID == "" {
+			continue
+		}
+		eniConfig := ENIConfig{Name: az.Zone, Subnet: az.PodSubnetID}
+		if inventory.SecurityGroupID != "" {
+			eniConfig.SecurityGroups = []string{inventory.SecurityGroupID}
+		}
+		eniConfigs = append(eniConfigs, eniConfig)
+	}
+
+	return eniConfigs
+}
+
+// checkSecondaryCIDR checks the secondary CIDR in the resource config.  It
+// must be an IPv4 CIDR block that AWS allows for a VPC and must not overlap
+// the cluster CIDR.  Pod subnets are only needed for IPv4 - IPv6 pods get
+// their addresses from the subnets' IPv6 CIDR blocks - and can't be added to
+// an existing VPC.
+func (r *ResourceConfig) checkSecondaryCIDR() error {
+	if r.SecondaryCIDR == "" {
+		if r.PodSubnetPrefixLength != 0 {
+			return errors.New("pod subnet prefix length cannot be set in resource config without a secondary CIDR")
+		}
+		return nil
+	}
+	if r.UsesExistingVPC() {
+		return errors.New("secondary CIDR cannot be used in resource config with an existing VPC")
+	}
+	if r.IPFamily == IPFamilyIPv6 {
+		return errors.New("secondary CIDR cannot be used in resource config with IP family ipv6")
+	}
+
+	secondaryCIDR, err := netip.ParsePrefix(r.SecondaryCIDR)
+	if err != nil || !secondaryCIDR.Addr().Is4() || secondaryCIDR != secondaryCIDR.Masked() {
+		return fmt.Errorf("secondary CIDR %q is not a valid IPv4 CIDR block", r.SecondaryCIDR)
+	}
+	if secondaryCIDR.Bits() < minCIDRPrefixLength || secondaryCIDR.Bits() > maxCIDRPrefixLength {
+		return fmt.Errorf("secondary CIDR %s must have a prefix length between /%d and /%d",
+			r.SecondaryCIDR, minCIDRPrefixLength, maxCIDRPrefixLength)
+	}
+	if clusterCIDR, err := netip.ParsePrefix(r.ClusterCIDR); err == nil && clusterCIDR.Overlaps(secondaryCIDR) {
+		return fmt.Errorf("secondary CIDR %s overlaps cluster CIDR %s", r.SecondaryCIDR, r.ClusterCIDR)
+	}
+
+	return nil
+}
+
+// setPodSubnetCIDRs sets the CIDR blocks for the pod subnets in each
+// availability zone that doesn't already have one.  They are carved out of the
+// secondary CIDR, avoiding the CIDR blocks already set.  Without a secondary
+// CIDR there are no pod subnets.
+func (r *ResourceConfig) setPodSubnetCIDRs() error {
+	azs := r.AvailabilityZones
+	if r.SecondaryCIDR == "" {
+		for _, az := range azs {
+			if az.PodSubnetCIDR != "" {
+				return fmt.Errorf("pod subnet CIDR for availability zone %s cannot be set without a secondary CIDR", az.Zone)
+			}
+		}
+		return nil
+	}
+	secondaryCIDR, err := netip.ParsePrefix(r.SecondaryCIDR)
+	if err != nil || !secondaryCIDR.Addr().Is4() {
+		return fmt.Errorf("secondary CIDR %q is not a valid IPv4 CIDR block", r.SecondaryCIDR)
+	}
+	secondaryCIDR = secondaryCIDR.Masked()
+
+	prefixLength, err := podSubnetPrefixLength(r.PodSubnetPrefixLength, secondaryCIDR, len(azs))
+	if err != nil {
+		return fmt.Errorf("invalid pod subnet prefix length: %w", err)
+	}
+
+	// check the CIDR blocks that are already set
+	var usedCIDRs []netip.Prefix
+	for _, az := range azs {
+		if az.PodSubnetCIDR == "" {
+			continue
+		}
+		cidr, err := netip.ParsePrefix(az.PodSubnetCIDR)
+		if err != nil || !cidr.Addr().Is4() || cidr != cidr.Masked() {
+			return fmt.Errorf("pod subnet CIDR %q for availability zone %s is not a valid IPv4 CIDR block",
+				az.PodSubnetCIDR, az.Zone)
+		}
+		if cidr.Bits() < secondaryCIDR.Bits() || cidr.Bits() > maxCIDRPrefixLength || !secondaryCIDR.Contains(cidr.Addr()) {
+			return fmt.Errorf("pod subnet CIDR %s for availability zone %s is not within secondary CIDR %s",
+				az.PodSubnetCIDR, az.Zone, secondaryCIDR)
+		}
+		for _, usedCIDR := range usedCIDRs {
+			if cidr.Overlaps(usedCIDR) {
+				return fmt.Errorf("pod subnet CIDR %s for availability zone %s overlaps pod subnet CIDR %s",
+					az.PodSubnetCIDR, az.Zone, usedCIDR)
+			}
+		}
+		usedCIDRs = append(usedCIDRs, cidr)
+	}
+
+	next := ipv4ToUint64(secondaryCIDR.Addr())
+	for i := range azs {
+		if azs[i].PodSubnetCIDR != "" {
+			continue
+		}
+		cidr, err := carveSubnetCIDR(secondaryCIDR, &next, prefixLength, usedCIDRs)
+		if err != nil {
+			return fmt.Errorf("failed to carve out pod subnet CIDR for availability zone %s: %w", azs[i].Zone, err)
+		}
+		usedCIDRs = append(usedCIDRs, cidr)
+		azs[i].PodSubnetCIDR = cidr.String()
+	}
+
+	return nil
+}
+
+// podSubnetPrefixLength returns the prefix length to use for pod subnets
+// carved out of the secondary CIDR.  If none is configured, the secondary CIDR
+// is split evenly between the availability zones.
+func podSubnetPrefixLength(configured int32, secondaryCIDR netip.Prefix, azCount int) (int, error) {
+	if configured == 0 {
+		if azCount < 1 {
+			azCount = 1
+		}
+		prefixLength := secondaryCIDR.Bits() + bits.Len(uint(azCount-1))
+		if prefixLength > maxCIDRPrefixLength {
+			return 0, fmt.Errorf("secondary CIDR %s is too small for %d availability zones", secondaryCIDR, azCount)
+		}
+		return prefixLength, nil
+	}
+
+	if int(configured) < secondaryCIDR.Bits() || configured > maxCIDRPrefixLength {
+		return 0, fmt.Errorf("/%d must be between the secondary CIDR's /%d and /%d",
+			configured, secondaryCIDR.Bits(), maxCIDRPrefixLength)
+	}
+
+	return int(configured), nil
+}
+
+// AssociateVPCCIDRBlock associates a secondary IPv4 CIDR block with a VPC and
+// waits for the association to complete.  The association ID is returned,
+// even if waiting fails, so it can be recorded in the inventory.
+func (c *ResourceClient) AssociateVPCCIDRBlock(ctx context.Context, vpcID, cidrBlock string) (string, error) {
+	svc := c.ec2Client()
+
+	associateVPCCIDRBlockInput := ec2.AssociateVpcCidrBlockInput{
+		VpcId:     &vpcID,
+		CidrBlock: &cidrBlock,
+	}
+	resp, err := svc.AssociateVpcCidrBlock(ctx, &associateVPCCIDRBlockInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to associate CIDR block %s with VPC with ID %s: %w", cidrBlock, vpcID, err)
+	}
+	associationID := *resp.CidrBlockAssociation.AssociationId
+
+	vpcCIDRBlockCheckCount := 0
+	for {
+		vpcCIDRBlockCheckCount += 1
+		if vpcCIDRBlockCheckCount > VPCCIDRBlockCheckMaxCount {
+			return associationID, fmt.Errorf("CIDR block %s association check timed out for VPC with ID %s", cidrBlock, vpcID)
+		}
+
+		vpc, err := c.getVPC(ctx, vpcID)
+		if err != nil {
+			return associationID, err
+		}
+		association := vpcCIDRBlockAssociation(vpc, associationID)
+		if association == nil {
+			return associationID, fmt.Errorf("CIDR block association with ID %s not found for VPC with ID %s", associationID, vpcID)
+		}
+		switch association.CidrBlockState.State {
+		case types.VpcCidrBlockStateCodeAssociated:
+			return associationID, nil
+		case types.VpcCidrBlockStateCodeFailing, types.VpcCidrBlockStateCodeFailed:
+			return associationID, fmt.Errorf("failed to associate CIDR block %s with VPC with ID %s: %s",
+				cidrBlock, vpcID, aws.ToString(association.CidrBlockState.StatusMessage))
+		}
+
+		if err := c.waitCheckInterval(ctx, time.Second*VPCCIDRBlockCheckInterval); err != nil {
+			return associationID, fmt.Errorf("stopped waiting for CIDR block %s to be associated with VPC with ID %s: %w",
+				cidrBlock, vpcID, err)
+		}
+	}
+}
+
+// DisassociateVPCCIDRBlock removes a secondary CIDR block association from a
+// VPC.  If an empty association ID is supplied, or if the VPC or the
+// association is not found, it returns without error.
+func (c *ResourceClient) DisassociateVPCCIDRBlock(ctx context.Context, vpcID, associationID string) error {
+	// if associationID is empty, there's nothing to disassociate
+	if associationID == "" {
+		return nil
+	}
+
+	vpc, err := c.getVPC(ctx, vpcID)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil
+		}
+		return err
+	}
+	if vpcCIDRBlockAssociation(vpc, associationID) == nil {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	disassociateVPCCIDRBlockInput := ec2.DisassociateVpcCidrBlockInput{AssociationId: &associationID}
+	if _, err := svc.DisassociateVpcCidrBlock(ctx, &disassociateVPCCIDRBlockInput); err != nil {
+		return fmt.Errorf("failed to remove CIDR block association with ID %s from VPC with ID %s: %w",
+			associationID, vpcID, err)
+	}
+
+	return nil
+}
+
+// vpcCIDRBlockAssociation returns the IPv4 CIDR block association with the
+// given ID for a VPC, or nil if it has no such association or the association
+// is being removed.
+func vpcCIDRBlockAssociation(vpc *types.Vpc, associationID string) *types.VpcCidrBlockAssociation {
+	for i, association := range vpc.CidrBlockAssociationSet {
+		if association.AssociationId == nil || *association.AssociationId != associationID ||
+			association.CidrBlockState == nil {
+			continue
+		}
+		switch association.CidrBlockState.State {
+		case types.VpcCidrBlockStateCodeDisassociating, types.VpcCidrBlockStateCodeDisassociated:
+			return nil
+		}
+		return &vpc.CidrBlockAssociationSet[i]
+	}
+
+	return nil
+}
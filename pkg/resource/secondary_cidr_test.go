@@ -0,0 +1,277 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// secondaryCIDRConfig returns a resource config with a secondary CIDR for pod
+// subnets.
+func secondaryCIDRConfig() *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.DesiredAZCount = 3
+	resourceConfig.SecondaryCIDR = "100.64.0.0/16"
+
+	return resourceConfig
+}
+
+func TestSecondaryCIDR(t *testing.T) {
+	ctx := context.Background()
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, secondaryCIDRConfig())
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	if plan.VPC.SecondaryCIDR != "100.64.0.0/16" {
+		t.Errorf("expected secondary CIDR 100.64.0.0/16 in plan, got %q", plan.VPC.SecondaryCIDR)
+	}
+	for _, az := range plan.AvailabilityZones {
+		if az.PodSubnetCIDR == "" {
+			t.Errorf("expected pod subnet CIDR for availability zone %s in plan", az.Zone)
+		}
+	}
+
+	backend, c, inventory := createResourceStack(t, secondaryCIDRConfig())
+	if inventory.SecondaryCIDR != "100.64.0.0/16" || inventory.SecondaryCIDRAssociationID == "" {
+		t.Errorf("expected secondary CIDR association in inventory, got %q and %q",
+			inventory.SecondaryCIDR, inventory.SecondaryCIDRAssociationID)
+	}
+	if want := 3 * len(inventory.AvailabilityZones); len(inventory.SubnetIDs) != want {
+		t.Errorf("expected %d subnets, got %v", want, inventory.SubnetIDs)
+	}
+	for _, az := range inventory.AvailabilityZones {
+		if az.PodSubnetID == "" || az.PodRouteTableAssociationID == "" {
+			t.Errorf("expected pod subnet and route table association for availability zone %s, got %+v", az.Zone, az)
+		}
+	}
+
+	// pod subnets aren't tagged for load balancers
+	describeSubnetsInput := ec2.DescribeSubnetsInput{SubnetIds: []string{inventory.AvailabilityZones[0].PodSubnetID}}
+	describeSubnetsOutput, err := backend.EC2.DescribeSubnets(ctx, &describeSubnetsInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range describeSubnetsOutput.Subnets[0].Tags {
+		if strings.HasPrefix(*tag.Key, "kubernetes.io/role") {
+			t.Errorf("expected pod subnet not to be tagged for load balancers, got %s", *tag.Key)
+		}
+	}
+
+	eniConfigs := resource.ENIConfigs(&inventory)
+	if len(eniConfigs) != len(inventory.AvailabilityZones) {
+		t.Errorf("expected an ENIConfig for each availability zone, got %+v", eniConfigs)
+	}
+
+	report, err := c.VerifyResourceStack(ctx, &inventory)
+	if err != nil {
+		t.Fatalf("failed to verify resource stack: %v", err)
+	}
+	if report.Drifted {
+		t.Errorf("expected no drift, got %+v", report.Resources)
+	}
+	var verified bool
+	for _, drift := range report.Resources {
+		verified = verified || drift.Kind == resource.ResourceKindVPCCIDRBlock
+	}
+	if !verified {
+		t.Error("expected secondary CIDR association to be verified")
+	}
+
+	// resuming with another secondary CIDR is rejected
+	otherConfig := secondaryCIDRConfig()
+	otherConfig.SecondaryCIDR = "100.65.0.0/16"
+	c.FailurePolicy = resource.FailurePolicyKeep
+	var r fake.Recorder
+	r.Record(c)
+	err = c.ResumeResourceStack(ctx, otherConfig, &inventory)
+	r.Stop()
+	c.FailurePolicy = ""
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected secondary CIDR mismatch, got %v", err)
+	}
+
+	// the secondary CIDR association and pod subnets are recovered
+	r.Record(c)
+	recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+	r.Stop()
+	if err != nil {
+		t.Fatalf("failed to recover inventory: %v", err)
+	}
+	if recovered.SecondaryCIDR != inventory.SecondaryCIDR ||
+		recovered.SecondaryCIDRAssociationID != inventory.SecondaryCIDRAssociationID {
+		t.Errorf("expected secondary CIDR association %s to be recovered, got %s",
+			inventory.SecondaryCIDRAssociationID, recovered.SecondaryCIDRAssociationID)
+	}
+	for i, az := range recovered.AvailabilityZones {
+		if az.PodSubnetID != inventory.AvailabilityZones[i].PodSubnetID ||
+			az.PodRouteTableAssociationID != inventory.AvailabilityZones[i].PodRouteTableAssociationID {
+			t.Errorf("expected pod subnet %+v to be recovered, got %+v", inventory.AvailabilityZones[i], az)
+		}
+	}
+
+	deleteResourceStack(t, backend, c, inventory)
+	var disassociated bool
+	for _, call := range backend.Calls() {
+		disassociated = disassociated || call == "DisassociateVpcCidrBlock"
+	}
+	if !disassociated {
+		t.Error("expected secondary CIDR to be disassociated")
+	}
+}
+
+func TestSecondaryCIDRCreateFailure(t *testing.T) {
+	backend := fake.NewBackend(testRegion)
+	c := backend.ResourceClient()
+	c.FailurePolicy = resource.FailurePolicyDelete
+	backend.Fail("CreateCluster", errors.New("injected failure"))
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.CreateResourceStack(context.Background(), secondaryCIDRConfig())
+	r.Stop()
+	var failedErr *resource.CreateFailedError
+	if !errors.As(err, &failedErr) || !failedErr.Deleted {
+		t.Fatalf("expected resources to be deleted after failure, got %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+}
+
+func TestSecondaryCIDRInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*resource.ResourceConfig)
+		wantErr string
+	}{
+		{
+			name:    "overlaps cluster CIDR",
+			mutate:  func(r *resource.ResourceConfig) { r.SecondaryCIDR = "10.0.0.0/16" },
+			wantErr: "overlaps cluster CIDR 10.0.0.0/16",
+		},
+		{
+			name:    "IPv6",
+			mutate:  func(r *resource.ResourceConfig) { r.IPFamily = resource.IPFamilyIPv6 },
+			wantErr: "with IP family ipv6",
+		},
+		{
+			name:    "unmasked",
+			mutate:  func(r *resource.ResourceConfig) { r.SecondaryCIDR = "100.64.0.1/16" },
+			wantErr: "is not a valid IPv4 CIDR block",
+		},
+		{
+			name:    "too large",
+			mutate:  func(r *resource.ResourceConfig) { r.SecondaryCIDR = "100.0.0.0/10" },
+			wantErr: "must have a prefix length between",
+		},
+		{
+			name: "pod subnet prefix length without secondary CIDR",
+			mutate: func(r *resource.ResourceConfig) {
+				r.SecondaryCIDR = ""
+				r.PodSubnetPrefixLength = 20
+			},
+			wantErr: "pod subnet prefix length cannot be set",
+		},
+		{
+			name:    "pod subnet prefix length larger than secondary CIDR",
+			mutate:  func(r *resource.ResourceConfig) { r.PodSubnetPrefixLength = 15 },
+			wantErr: "invalid pod subnet prefix length",
+		},
+		{
+			name: "existing VPC",
+			mutate: func(r *resource.ResourceConfig) {
+				r.VPCID = "vpc-123"
+				r.PrivateSubnetIDs = []string{"subnet-1"}
+			},
+			wantErr: "with an existing VPC",
+		},
+		{
+			name: "pod subnet CIDR without secondary CIDR",
+			mutate: func(r *resource.ResourceConfig) {
+				r.SecondaryCIDR = ""
+				r.AvailabilityZones = []resource.AvailabilityZone{{Zone: "us-east-2a", PodSubnetCIDR: "100.64.0.0/18"}}
+			},
+			wantErr: "cannot be set without a secondary CIDR",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := secondaryCIDRConfig()
+			tc.mutate(resourceConfig)
+			_, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPodSubnetPrefixLength(t *testing.T) {
+	resourceConfig := secondaryCIDRConfig()
+	resourceConfig.PodSubnetPrefixLength = 20
+
+	plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(context.Background(), resourceConfig)
+	if err != nil {
+		t.Fatalf("failed to plan resource stack: %v", err)
+	}
+	var podSubnetCIDRs []string
+	for _, az := range plan.AvailabilityZones {
+		podSubnetCIDRs = append(podSubnetCIDRs, az.PodSubnetCIDR)
+	}
+	if want := []string{"100.64.0.0/20", "100.64.16.0/20", "100.64.32.0/20"}; !reflect.DeepEqual(podSubnetCIDRs, want) {
+		t.Errorf("expected pod subnet CIDRs %v, got %v", want, podSubnetCIDRs)
+	}
+}
+
+func TestENIConfigs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		inventory resource.ResourceInventory
+		want      []resource.ENIConfig
+	}{
+		{
+			name: "no pod subnets",
+			inventory: resource.ResourceInventory{
+				AvailabilityZones: []resource.AvailabilityZone{{Zone: "us-east-2a", PrivateSubnetID: "subnet-1"}},
+			},
+		},
+		{
+			name: "pod subnets",
+			inventory: resource.ResourceInventory{
+				SecurityGroupID: "sg-1",
+				AvailabilityZones: []resource.AvailabilityZone{
+					{Zone: "us-east-2a", PodSubnetID: "subnet-1"},
+					{Zone: "us-east-2b"},
+					{Zone: "us-east-2c", PodSubnetID: "subnet-3"},
+				},
+			},
+			want: []resource.ENIConfig{
+				{Name: "us-east-2a", Subnet: "subnet-1", SecurityGroups: []string{"sg-1"}},
+				{Name: "us-east-2c", Subnet: "subnet-3", SecurityGroups: []string{"sg-1"}},
+			},
+		},
+		{
+			name: "no cluster security group",
+			inventory: resource.ResourceInventory{
+				AvailabilityZones: []resource.AvailabilityZone{{Zone: "us-east-2a", PodSubnetID: "subnet-1"}},
+			},
+			want: []resource.ENIConfig{{Name: "us-east-2a", Subnet: "subnet-1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := resource.ENIConfigs(&tc.inventory); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected ENIConfigs %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
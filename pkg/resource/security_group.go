@@ -1,16 +1,19 @@
 package resource
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 )
 
 // GetClusterSecurityGroup retrieves the security group created for the EKS
 // cluster by AWS during provisioning.
-func (c *ResourceClient) GetClusterSecurityGroup(clusterName string) (string, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+func (c *ResourceClient) GetClusterSecurityGroup(ctx context.Context, clusterName string) (string, error) {
+	svc := c.ec2Client()
 
 	filterName := fmt.Sprintf("tag:aws:eks:cluster-name")
 	filters := []types.Filter{
@@ -22,7 +25,7 @@ func (c *ResourceClient) GetClusterSecurityGroup(clusterName string) (string, er
 	describeSecurityGroupsInput := ec2.DescribeSecurityGroupsInput{
 		Filters: filters,
 	}
-	resp, err := svc.DescribeSecurityGroups(c.Context, &describeSecurityGroupsInput)
+	resp, err := svc.DescribeSecurityGroups(ctx, &describeSecurityGroupsInput)
 	if err != nil {
 		return "", fmt.Errorf("failed to describe security groups filtered by cluster name %s: %w", clusterName, err)
 	}
@@ -36,3 +39,110 @@ func (c *ResourceClient) GetClusterSecurityGroup(clusterName string) (string, er
 
 	return *resp.SecurityGroups[0].GroupId, nil
 }
+
+// CreateVPCEndpointSecurityGroup creates the security group for the interface
+// VPC endpoints.  It allows HTTPS from anywhere in the VPC's CIDR block.  The
+// security group ID is returned even if adding the ingress rule fails so the
+// security group can be cleaned up.
+func (c *ResourceClient) CreateVPCEndpointSecurityGroup(
+	ctx context.Context,
+	tags *[]types.Tag,
+	vpcID string,
+	cidrBlock string,
+	clusterName string,
+) (string, error) {
+	svc := c.ec2Client()
+
+	groupName := fmt.Sprintf("%s-%s", VPCEndpointSecurityGroupName, clusterName)
+	description := fmt.Sprintf("VPC endpoints for EKS cluster %s", clusterName)
+	createSecurityGroupInput := ec2.CreateSecurityGroupInput{
+		GroupName:   &groupName,
+		Description: &description,
+		VpcId:       &vpcID,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeSecurityGroup,
+				Tags:         *tags,
+			},
+		},
+	}
+	resp, err := svc.CreateSecurityGroup(ctx, &createSecurityGroupInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VPC endpoint security group for VPC with ID %s: %w", vpcID, err)
+	}
+	securityGroupID := *resp.GroupId
+
+	protocol := "tcp"
+	port := int32(443)
+	authorizeIngressInput := ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId: &securityGroupID,
+		IpPermissions: []types.IpPermission{
+			{
+				IpProtocol: &protocol,
+				FromPort:   &port,
+				ToPort:     &port,
+				IpRanges:   []types.IpRange{{CidrIp: &cidrBlock}},
+			},
+		},
+	}
+	if _, err := svc.AuthorizeSecurityGroupIngress(ctx, &authorizeIngressInput); err != nil {
+		return securityGroupID, fmt.Errorf("failed to allow HTTPS from %s for security group with ID %s: %w",
+			cidrBlock, securityGroupID, err)
+	}
+
+	return securityGroupID, nil
+}
+
+// DeleteSecurityGroup deletes a security group.  If the security group ID is
+// empty, or if the security group is not found it returns without error.
+func (c *ResourceClient) DeleteSecurityGroup(ctx context.Context, securityGroupID string) error {
+	// if securityGroupID is empty, there's nothing to delete
+	if securityGroupID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteSecurityGroupInput := ec2.DeleteSecurityGroupInput{GroupId: &securityGroupID}
+	_, err := svc.DeleteSecurityGroup(ctx, &deleteSecurityGroupInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			if ae.ErrorCode() == "InvalidGroup.NotFound" {
+				// attempting to delete a security group that doesn't exist so
+				// return without error
+				return nil
+			} else {
+				return fmt.Errorf("failed to delete security group with ID %s: %w", securityGroupID, err)
+			}
+		} else {
+			return fmt.Errorf("failed to delete security group with ID %s: %w", securityGroupID, err)
+		}
+	}
+
+	return nil
+}
+
+// getSecurityGroup retrieves a security group by its ID.
+func (c *ResourceClient) getSecurityGroup(ctx context.Context, securityGroupID string) (*types.SecurityGroup, error) {
+	svc := c.ec2Client()
+
+	filterName := "group-id"
+	describeSecurityGroupsInput := ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &filterName,
+				Values: []string{securityGroupID},
+			},
+		},
+	}
+	resp, err := svc.DescribeSecurityGroups(ctx, &describeSecurityGroupsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe security group with ID %s: %w", securityGroupID, err)
+	}
+	if len(resp.SecurityGroups) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.SecurityGroups[0], nil
+}
@@ -0,0 +1,2144 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// Names of the nodes in the resource stack graph.
+const (
+	VPCNode                       = "vpc"
+	SecondaryCIDRNode             = "secondary-cidr"
+	InternetGatewayNode           = "internet-gateway"
+	EgressOnlyInternetGatewayNode = "egress-only-internet-gateway"
+	SubnetsNode                   = "subnets"
+	ElasticIPsNode                = "elastic-ips"
+	NATGatewaysNode               = "nat-gateways"
+	TransitGatewayAttachmentNode  = "transit-gateway-attachment"
+	VPCPeeringConnectionNode      = "vpc-peering-connection"
+	RouteTablesNode               = "route-tables"
+	VPCEndpointSecurityGroupNode  = "vpc-endpoint-security-group"
+	VPCEndpointsNode              = "vpc-endpoints"
+	NetworkACLsNode               = "network-acls"
+	PoliciesNode                  = "policies"
+	ClusterRolesNode              = "cluster-roles"
+	FlowLogGroupNode              = "flow-log-group"
+	FlowLogsRoleNode              = "flow-logs-role"
+	FlowLogNode                   = "flow-log"
+	ClusterNode                   = "cluster"
+	ClusterSecurityGroupNode      = "cluster-security-group"
+	NodeGroupsNode                = "node-groups"
+	OIDCProviderNode              = "oidc-provider"
+	DNSManagementRoleNode         = "dns-management-role"
+	DNS01ChallengeRoleNode        = "dns01-challenge-role"
+	ClusterAutoscalingRoleNode    = "cluster-autoscaling-role"
+	StorageManagementRoleNode     = "storage-management-role"
+	EBSStorageAddonNode           = "ebs-storage-addon"
+)
+
+// resourceStack holds the state shared by the nodes in the resource stack
+// graph.  The resource config and tags are only set when creating resources.
+// Nodes may run concurrently so the inventory and the availability zones in
+// the resource config are only changed while holding the lock.
+type resourceStack struct {
+	mu        sync.Mutex
+	config    *ResourceConfig
+	inventory *ResourceInventory
+	ec2Tags   *[]ec2types.Tag
+	iamTags   *[]iamtypes.Tag
+	mapTags   map[string]string
+}
+
+// availabilityZones returns a copy of the availability zones in the resource
+// config.
+func (s *resourceStack) availabilityZones() []AvailabilityZone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyAvailabilityZones(s.config.AvailabilityZones)
+}
+
+// updateInventory applies a change to the stack's inventory and sends the
+// updated inventory to the client.
+func (c *ResourceClient) updateInventory(stack *resourceStack, change func(inventory *ResourceInventory)) {
+	stack.mu.Lock()
+	defer stack.mu.Unlock()
+
+	change(stack.inventory)
+	c.sendInventory(stack.inventory)
+}
+
+// updateAvailabilityZones sets the availability zones in the resource config
+// and the inventory.
+func (c *ResourceClient) updateAvailabilityZones(stack *resourceStack, availabilityZones []AvailabilityZone) {
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		stack.config.AvailabilityZones = availabilityZones
+		inventory.AvailabilityZones = copyAvailabilityZones(availabilityZones)
+	})
+}
+
+// resourceStackGraph returns the graph of resources for an EKS cluster.
+func (c *ResourceClient) resourceStackGraph() *resourceGraph {
+	var g resourceGraph
+
+	// networking
+	g.add(resourceNode{
+		name:   VPCNode,
+		kind:   ResourceKindVPC,
+		create: c.createStackVPC,
+		delete: c.deleteStackVPC,
+	})
+	g.add(resourceNode{
+		name:      SecondaryCIDRNode,
+		kind:      ResourceKindVPCCIDRBlock,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackSecondaryCIDR,
+		delete:    c.deleteStackSecondaryCIDR,
+	})
+	g.add(resourceNode{
+		name:      InternetGatewayNode,
+		kind:      ResourceKindInternetGateway,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackInternetGateway,
+		delete:    c.deleteStackInternetGateway,
+	})
+	g.add(resourceNode{
+		name:      EgressOnlyInternetGatewayNode,
+		kind:      ResourceKindEgressOnlyInternetGateway,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackEgressOnlyInternetGateway,
+		delete:    c.deleteStackEgressOnlyInternetGateway,
+	})
+	g.add(resourceNode{
+		name:      SubnetsNode,
+		kind:      ResourceKindSubnet,
+		dependsOn: []string{VPCNode, SecondaryCIDRNode},
+		create:    c.createStackSubnets,
+		delete:    c.deleteStackSubnets,
+	})
+	// the VPC adds the cluster tags used by the other EC2 resources
+	g.add(resourceNode{
+		name:      ElasticIPsNode,
+		kind:      ResourceKindElasticIP,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackElasticIPs,
+		delete:    c.deleteStackElasticIPs,
+	})
+	g.add(resourceNode{
+		name:      NATGatewaysNode,
+		kind:      ResourceKindNATGateway,
+		dependsOn: []string{InternetGatewayNode, SubnetsNode, ElasticIPsNode},
+		create:    c.createStackNATGateways,
+		delete:    c.deleteStackNATGateways,
+	})
+	g.add(resourceNode{
+		name:      TransitGatewayAttachmentNode,
+		kind:      ResourceKindTransitGatewayAttachment,
+		dependsOn: []string{SubnetsNode},
+		create:    c.createStackTransitGatewayAttachment,
+		delete:    c.deleteStackTransitGatewayAttachment,
+	})
+	g.add(resourceNode{
+		name:      VPCPeeringConnectionNode,
+		kind:      ResourceKindVPCPeeringConnection,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackVPCPeeringConnection,
+		delete:    c.deleteStackVPCPeeringConnection,
+	})
+	// the routes to the transit gateway and VPC peering connection are removed
+	// along with the route tables before they are deleted
+	g.add(resourceNode{
+		name:      RouteTablesNode,
+		kind:      ResourceKindRouteTable,
+		dependsOn: []string{InternetGatewayNode, EgressOnlyInternetGatewayNode, SubnetsNode, NATGatewaysNode, TransitGatewayAttachmentNode, VPCPeeringConnectionNode},
+		create:    c.createStackRouteTables,
+		delete:    c.deleteStackRouteTables,
+	})
+	g.add(resourceNode{
+		name:      VPCEndpointSecurityGroupNode,
+		kind:      ResourceKindSecurityGroup,
+		dependsOn: []string{VPCNode},
+		create:    c.createStackVPCEndpointSecurityGroup,
+		delete:    c.deleteStackVPCEndpointSecurityGroup,
+	})
+	g.add(resourceNode{
+		name:      VPCEndpointsNode,
+		kind:      ResourceKindVPCEndpoint,
+		dependsOn: []string{SubnetsNode, RouteTablesNode, VPCEndpointSecurityGroupNode},
+		create:    c.createStackVPCEndpoints,
+		delete:    c.deleteStackVPCEndpoints,
+	})
+	// the subnets are associated with the default network ACL again before
+	// the network ACLs are deleted
+	g.add(resourceNode{
+		name:      NetworkACLsNode,
+		kind:      ResourceKindNetworkACL,
+		dependsOn: []string{SubnetsNode},
+		create:    c.createStackNetworkACLs,
+		delete:    c.deleteStackNetworkACLs,
+	})
+
+	// IAM policies and roles for the cluster and nodes
+	g.add(resourceNode{
+		name:   PoliciesNode,
+		kind:   ResourceKindPolicy,
+		create: c.createStackPolicies,
+		delete: c.deleteStackPolicies,
+	})
+	// the worker role gets the CNI IPv6 policy for the IPv6 IP family
+	g.add(resourceNode{
+		name:      ClusterRolesNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{PoliciesNode},
+		create:    c.createStackClusterRoles,
+		delete:    c.deleteStackClusterRoles,
+	})
+
+	// VPC flow logs - published to CloudWatch Logs through the delivery role
+	// or to an S3 bucket
+	g.add(resourceNode{
+		name:   FlowLogGroupNode,
+		kind:   ResourceKindLogGroup,
+		create: c.createStackFlowLogGroup,
+		delete: c.deleteStackFlowLogGroup,
+	})
+	g.add(resourceNode{
+		name:      FlowLogsRoleNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{PoliciesNode},
+		create:    c.createStackFlowLogsRole,
+		delete:    c.deleteStackFlowLogsRole,
+	})
+	g.add(resourceNode{
+		name:      FlowLogNode,
+		kind:      ResourceKindFlowLog,
+		dependsOn: []string{VPCNode, FlowLogGroupNode, FlowLogsRoleNode},
+		create:    c.createStackFlowLog,
+		delete:    c.deleteStackFlowLog,
+	})
+
+	// EKS
+	g.add(resourceNode{
+		name:      ClusterNode,
+		kind:      ResourceKindCluster,
+		dependsOn: []string{ClusterRolesNode, SubnetsNode},
+		create:    c.createStackCluster,
+		delete:    c.deleteStackCluster,
+	})
+	g.add(resourceNode{
+		name:      ClusterSecurityGroupNode,
+		kind:      ResourceKindSecurityGroup,
+		dependsOn: []string{ClusterNode},
+		create:    c.createStackClusterSecurityGroup,
+	})
+	g.add(resourceNode{
+		name:      NodeGroupsNode,
+		kind:      ResourceKindNodeGroup,
+		dependsOn: []string{ClusterNode, ClusterRolesNode, SubnetsNode, RouteTablesNode, VPCEndpointsNode},
+		create:    c.createStackNodeGroups,
+		delete:    c.deleteStackNodeGroups,
+	})
+	g.add(resourceNode{
+		name:      OIDCProviderNode,
+		kind:      ResourceKindOIDCProvider,
+		dependsOn: []string{ClusterNode},
+		create:    c.createStackOIDCProvider,
+		delete:    c.deleteStackOIDCProvider,
+	})
+
+	// IAM roles for service accounts
+	g.add(resourceNode{
+		name:      DNSManagementRoleNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{PoliciesNode, OIDCProviderNode},
+		create:    c.createStackDNSManagementRole,
+		delete:    c.deleteStackDNSManagementRole,
+	})
+	g.add(resourceNode{
+		name:      DNS01ChallengeRoleNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{PoliciesNode, OIDCProviderNode},
+		create:    c.createStackDNS01ChallengeRole,
+		delete:    c.deleteStackDNS01ChallengeRole,
+	})
+	g.add(resourceNode{
+		name:      ClusterAutoscalingRoleNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{PoliciesNode, OIDCProviderNode},
+		create:    c.createStackClusterAutoscalingRole,
+		delete:    c.deleteStackClusterAutoscalingRole,
+	})
+	g.add(resourceNode{
+		name:      StorageManagementRoleNode,
+		kind:      ResourceKindRole,
+		dependsOn: []string{OIDCProviderNode},
+		create:    c.createStackStorageManagementRole,
+		delete:    c.deleteStackStorageManagementRole,
+	})
+
+	// addons
+	g.add(resourceNode{
+		name:      EBSStorageAddonNode,
+		kind:      ResourceKindAddon,
+		dependsOn: []string{ClusterNode, NodeGroupsNode, StorageManagementRoleNode},
+		create:    c.createStackEBSStorageAddon,
+		delete:    c.deleteStackAddons,
+	})
+
+	return &g
+}
+
+// createStackVPC creates the VPC if it is not in the inventory.  Nothing is
+// created when using an existing VPC.
+func (c *ResourceClient) createStackVPC(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		c.sendMessage(fmt.Sprintf("Using existing VPC: %s\n", stack.config.VPCID))
+		return nil
+	}
+	if stack.inventory.VPCID != "" {
+		c.sendMessage(fmt.Sprintf("VPC already exists: %s\n", stack.inventory.VPCID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindVPC, Action: EventActionCreate, Phase: EventPhaseStarted})
+	vpc, err := c.CreateVPC(ctx, stack.ec2Tags, stack.config.ClusterCIDR, stack.config.IPFamily, stack.config.Name)
+	if vpc != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.VPCID = *vpc.VpcId
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC created: %s\n", *vpc.VpcId))
+	c.sendResourceEvents(ResourceKindVPC, EventActionCreate, EventPhaseSucceeded, *vpc.VpcId)
+
+	return nil
+}
+
+// deleteStackVPC deletes the VPC.
+func (c *ResourceClient) deleteStackVPC(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindVPC, EventActionDelete, EventPhaseStarted, stack.inventory.VPCID)
+	if err := c.DeleteVPC(ctx, stack.inventory.VPCID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC deleted: %s\n", stack.inventory.VPCID))
+	c.sendResourceEvents(ResourceKindVPC, EventActionDelete, EventPhaseSucceeded, stack.inventory.VPCID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.VPCID = ""
+	})
+
+	return nil
+}
+
+// createStackSecondaryCIDR associates the secondary CIDR with the VPC if it is
+// configured and not in the inventory.
+func (c *ResourceClient) createStackSecondaryCIDR(ctx context.Context, stack *resourceStack) error {
+	if stack.config.SecondaryCIDR == "" {
+		return nil
+	}
+	if stack.inventory.SecondaryCIDRAssociationID != "" {
+		c.sendMessage(fmt.Sprintf("Secondary CIDR already associated: %s\n", stack.inventory.SecondaryCIDRAssociationID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindVPCCIDRBlock, Action: EventActionCreate, Phase: EventPhaseStarted})
+	associationID, err := c.AssociateVPCCIDRBlock(ctx, stack.inventory.VPCID, stack.config.SecondaryCIDR)
+	if associationID != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.SecondaryCIDR = stack.config.SecondaryCIDR
+			inventory.SecondaryCIDRAssociationID = associationID
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Secondary CIDR %s associated: %s\n", stack.config.SecondaryCIDR, associationID))
+	c.sendResourceEvents(ResourceKindVPCCIDRBlock, EventActionCreate, EventPhaseSucceeded, associationID)
+
+	return nil
+}
+
+// deleteStackSecondaryCIDR removes the secondary CIDR association from the
+// VPC.
+func (c *ResourceClient) deleteStackSecondaryCIDR(ctx context.Context, stack *resourceStack) error {
+	associationID := stack.inventory.SecondaryCIDRAssociationID
+	if associationID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindVPCCIDRBlock, EventActionDelete, EventPhaseStarted, associationID)
+	if err := c.DisassociateVPCCIDRBlock(ctx, stack.inventory.VPCID, associationID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Secondary CIDR association removed: %s\n", associationID))
+	c.sendResourceEvents(ResourceKindVPCCIDRBlock, EventActionDelete, EventPhaseSucceeded, associationID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.SecondaryCIDR = ""
+		inventory.SecondaryCIDRAssociationID = ""
+	})
+
+	return nil
+}
+
+// createStackInternetGateway creates the internet gateway if it is not in the
+// inventory.  An existing internet gateway is attached to the VPC if needed.
+// Nothing is created when using an existing VPC.
+func (c *ResourceClient) createStackInternetGateway(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		return nil
+	}
+	if stack.inventory.InternetGatewayID != "" {
+		igw, err := c.getInternetGateway(ctx, stack.inventory.InternetGatewayID)
+		if err != nil {
+			return err
+		}
+		if !internetGatewayAttached(igw, stack.inventory.VPCID) {
+			if err := c.AttachInternetGateway(ctx, stack.inventory.InternetGatewayID, stack.inventory.VPCID); err != nil {
+				return err
+			}
+		}
+		c.sendMessage(fmt.Sprintf("Internet gateway already exists: %s\n", stack.inventory.InternetGatewayID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindInternetGateway, Action: EventActionCreate, Phase: EventPhaseStarted})
+	igw, err := c.CreateInternetGateway(ctx, stack.ec2Tags, stack.inventory.VPCID, stack.config.Name)
+	if igw != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.InternetGatewayID = *igw.InternetGatewayId
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Internet gateway created: %s\n", *igw.InternetGatewayId))
+	c.sendResourceEvents(ResourceKindInternetGateway, EventActionCreate, EventPhaseSucceeded, *igw.InternetGatewayId)
+
+	return nil
+}
+
+// deleteStackInternetGateway detaches and deletes the internet gateway.
+func (c *ResourceClient) deleteStackInternetGateway(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindInternetGateway, EventActionDelete, EventPhaseStarted, stack.inventory.InternetGatewayID)
+	if err := c.DeleteInternetGateway(ctx, stack.inventory.InternetGatewayID, stack.inventory.VPCID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Internet gateway deleted: %s\n", stack.inventory.InternetGatewayID))
+	c.sendResourceEvents(ResourceKindInternetGateway, EventActionDelete, EventPhaseSucceeded, stack.inventory.InternetGatewayID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.InternetGatewayID = ""
+	})
+
+	return nil
+}
+
+// createStackEgressOnlyInternetGateway creates the egress-only internet
+// gateway for the IPv6 IP family if it is not in the inventory.  Nothing is
+// created when using an existing VPC.
+func (c *ResourceClient) createStackEgressOnlyInternetGateway(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() || stack.config.IPFamily != IPFamilyIPv6 {
+		return nil
+	}
+	if stack.inventory.EgressOnlyInternetGatewayID != "" {
+		c.sendMessage(fmt.Sprintf("Egress-only internet gateway already exists: %s\n", stack.inventory.EgressOnlyInternetGatewayID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindEgressOnlyInternetGateway, Action: EventActionCreate, Phase: EventPhaseStarted})
+	eigw, err := c.CreateEgressOnlyInternetGateway(ctx, stack.ec2Tags, stack.inventory.VPCID)
+	if eigw != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.EgressOnlyInternetGatewayID = *eigw.EgressOnlyInternetGatewayId
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Egress-only internet gateway created: %s\n", *eigw.EgressOnlyInternetGatewayId))
+	c.sendResourceEvents(ResourceKindEgressOnlyInternetGateway, EventActionCreate, EventPhaseSucceeded,
+		*eigw.EgressOnlyInternetGatewayId)
+
+	return nil
+}
+
+// deleteStackEgressOnlyInternetGateway deletes the egress-only internet
+// gateway.
+func (c *ResourceClient) deleteStackEgressOnlyInternetGateway(ctx context.Context, stack *resourceStack) error {
+	eigwID := stack.inventory.EgressOnlyInternetGatewayID
+	if eigwID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindEgressOnlyInternetGateway, EventActionDelete, EventPhaseStarted, eigwID)
+	if err := c.DeleteEgressOnlyInternetGateway(ctx, eigwID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Egress-only internet gateway deleted: %s\n", eigwID))
+	c.sendResourceEvents(ResourceKindEgressOnlyInternetGateway, EventActionDelete, EventPhaseSucceeded, eigwID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.EgressOnlyInternetGatewayID = ""
+	})
+
+	return nil
+}
+
+// createStackSubnets creates the subnets that are not set on the availability
+// zones.  For the IPv6 IP family, subnets are given IPv6 CIDR blocks from the
+// VPC's once it has been associated.  With a secondary CIDR, a pod subnet is
+// also created in each availability zone.  Nothing is created when using an
+// existing VPC.
+func (c *ResourceClient) createStackSubnets(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		c.sendMessage(fmt.Sprintf("Using existing subnets: %s\n", stack.inventory.ExistingSubnetIDs))
+		return nil
+	}
+	azs := stack.availabilityZones()
+	if stack.config.IPFamily == IPFamilyIPv6 && !subnetIPv6CIDRsSet(azs) {
+		vpcIPv6CIDR, err := c.WaitForVPCIPv6CIDR(ctx, stack.inventory.VPCID)
+		if err != nil {
+			return err
+		}
+		if err := setSubnetIPv6CIDRs(vpcIPv6CIDR, azs); err != nil {
+			return err
+		}
+		c.updateAvailabilityZones(stack, azs)
+	}
+
+	var createdSubnetIDs []string
+	c.sendEvent(Event{Kind: ResourceKindSubnet, Action: EventActionCreate, Phase: EventPhaseStarted})
+	privateSubnets, publicSubnets, err := c.CreateSubnets(ctx, stack.ec2Tags, stack.inventory.VPCID,
+		stack.config.Name, &azs)
+	if privateSubnets != nil {
+		for _, subnet := range *privateSubnets {
+			createdSubnetIDs = append(createdSubnetIDs, *subnet.SubnetId)
+		}
+	}
+	if publicSubnets != nil {
+		for _, subnet := range *publicSubnets {
+			createdSubnetIDs = append(createdSubnetIDs, *subnet.SubnetId)
+		}
+	}
+	if err == nil {
+		var podSubnets *[]ec2types.Subnet
+		podSubnets, err = c.CreatePodSubnets(ctx, stack.ec2Tags, stack.inventory.VPCID, &azs)
+		if podSubnets != nil {
+			for _, subnet := range *podSubnets {
+				createdSubnetIDs = append(createdSubnetIDs, *subnet.SubnetId)
+			}
+		}
+	}
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.SubnetIDs = append(inventory.SubnetIDs, createdSubnetIDs...)
+	})
+	c.updateAvailabilityZones(stack, azs)
+	if err != nil {
+		return err
+	}
+	if len(createdSubnetIDs) > 0 {
+		c.sendMessage(fmt.Sprintf("Subnets created: %s\n", createdSubnetIDs))
+		c.sendResourceEvents(ResourceKindSubnet, EventActionCreate, EventPhaseSucceeded, createdSubnetIDs...)
+	} else {
+		c.sendMessage(fmt.Sprintf("Subnets already exist: %s\n", stack.inventory.SubnetIDs))
+	}
+
+	return nil
+}
+
+// deleteStackSubnets deletes the subnets.
+func (c *ResourceClient) deleteStackSubnets(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindSubnet, EventActionDelete, EventPhaseStarted, stack.inventory.SubnetIDs...)
+	if err := c.DeleteSubnets(ctx, stack.inventory.SubnetIDs); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Subnets deleted: %s\n", stack.inventory.SubnetIDs))
+	c.sendResourceEvents(ResourceKindSubnet, EventActionDelete, EventPhaseSucceeded, stack.inventory.SubnetIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.SubnetIDs = []string{}
+		inventory.AvailabilityZones = []AvailabilityZone{}
+	})
+
+	return nil
+}
+
+// createStackElasticIPs allocates an elastic IP for each availability zone
+// that gets a NAT gateway and doesn't have one.  The elastic IPs are assigned
+// to availability zones when the NAT gateways are created.  Nothing is
+// created when using an existing VPC.
+func (c *ResourceClient) createStackElasticIPs(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		return nil
+	}
+	azs := stack.availabilityZones()
+	natAZs := azs[:natGatewayCount(stack.config.NATGatewayMode, len(azs))]
+	if len(natAZs) == 0 {
+		return nil
+	}
+	var count int
+	for _, az := range natAZs {
+		if az.ElasticIPID == "" {
+			count++
+		}
+	}
+
+	// an elastic IP is allocated for each public subnet ID passed in, whether
+	// or not the subnet has been created yet
+	if count > 0 {
+		c.sendEvent(Event{Kind: ResourceKindElasticIP, Action: EventActionCreate, Phase: EventPhaseStarted})
+	}
+	elasticIPIDs, err := c.CreateElasticIPs(ctx, stack.ec2Tags, make([]string, count))
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.ElasticIPIDs = append(inventory.ElasticIPIDs, elasticIPIDs...)
+	})
+	if err != nil {
+		return err
+	}
+	if len(elasticIPIDs) > 0 {
+		c.sendMessage(fmt.Sprintf("Elastic IPs created: %s\n", elasticIPIDs))
+		c.sendResourceEvents(ResourceKindElasticIP, EventActionCreate, EventPhaseSucceeded, elasticIPIDs...)
+	} else {
+		c.sendMessage(fmt.Sprintf("Elastic IPs already exist: %s\n", stack.inventory.ElasticIPIDs))
+	}
+
+	return nil
+}
+
+// deleteStackElasticIPs releases the elastic IPs.
+func (c *ResourceClient) deleteStackElasticIPs(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindElasticIP, EventActionDelete, EventPhaseStarted, stack.inventory.ElasticIPIDs...)
+	if err := c.DeleteElasticIPs(ctx, stack.inventory.ElasticIPIDs); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Elastic IPs deleted: %s\n", stack.inventory.ElasticIPIDs))
+	c.sendResourceEvents(ResourceKindElasticIP, EventActionDelete, EventPhaseSucceeded, stack.inventory.ElasticIPIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.ElasticIPIDs = []string{}
+		azs := copyAvailabilityZones(inventory.AvailabilityZones)
+		for i := range azs {
+			azs[i].ElasticIPID = ""
+		}
+		inventory.AvailabilityZones = azs
+	})
+
+	return nil
+}
+
+// createStackNATGateways assigns the elastic IPs to the availability zones
+// that get a NAT gateway, creates a NAT gateway for each of them that doesn't
+// have one and waits for them to become available.  Nothing is created when
+// using an existing VPC or the NAT gateway mode is none.
+func (c *ResourceClient) createStackNATGateways(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		return nil
+	}
+	azs := stack.availabilityZones()
+
+	// the NAT gateway availability zones share azs' backing array so NAT
+	// gateway IDs set on them are set on azs too
+	natAZs := azs[:natGatewayCount(stack.config.NATGatewayMode, len(azs))]
+	if len(natAZs) == 0 {
+		return nil
+	}
+
+	assignedElasticIPIDs := make(map[string]bool)
+	for _, az := range natAZs {
+		assignedElasticIPIDs[az.ElasticIPID] = true
+	}
+	var unassignedElasticIPIDs []string
+	for _, elasticIPID := range stack.inventory.ElasticIPIDs {
+		if !assignedElasticIPIDs[elasticIPID] {
+			unassignedElasticIPIDs = append(unassignedElasticIPIDs, elasticIPID)
+		}
+	}
+	for i, az := range natAZs {
+		if az.ElasticIPID == "" && len(unassignedElasticIPIDs) > 0 {
+			natAZs[i].ElasticIPID = unassignedElasticIPIDs[0]
+			unassignedElasticIPIDs = unassignedElasticIPIDs[1:]
+		}
+	}
+
+	privateSubnetIDs := getPrivateSubnetIDs(azs)
+	existingNATGatewayIDs := getNATGatewayIDs(natAZs)
+	if len(existingNATGatewayIDs) < len(natAZs) {
+		c.sendEvent(Event{Kind: ResourceKindNATGateway, Action: EventActionCreate, Phase: EventPhaseStarted})
+	}
+	err := c.CreateNATGateways(ctx, stack.ec2Tags, &natAZs)
+	natGatewayIDs := getNATGatewayIDs(natAZs)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		for _, natGatewayID := range natGatewayIDs {
+			if !containsString(inventory.NATGatewayIDs, natGatewayID) {
+				inventory.NATGatewayIDs = append(inventory.NATGatewayIDs, natGatewayID)
+			}
+		}
+	})
+	c.updateAvailabilityZones(stack, azs)
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("NAT gateways created for subnets: %s\n", privateSubnetIDs))
+	c.sendMessage(fmt.Sprintf("Waiting for NAT gateways to become active for subnets: %s\n", privateSubnetIDs))
+	c.sendResourceEvents(ResourceKindNATGateway, EventActionCreate, EventPhaseWaiting, natGatewayIDs...)
+	if err := c.WaitForNATGateways(ctx, stack.inventory.VPCID, natGatewayIDs, NATGatewayConditionCreated); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("NAT gateways ready for subnets: %s\n", privateSubnetIDs))
+	for _, natGatewayID := range natGatewayIDs {
+		if !containsString(existingNATGatewayIDs, natGatewayID) {
+			c.sendResourceEvents(ResourceKindNATGateway, EventActionCreate, EventPhaseSucceeded, natGatewayID)
+		}
+	}
+
+	return nil
+}
+
+// deleteStackNATGateways deletes the NAT gateways in the inventory and waits
+// for them to be deleted.  If the inventory doesn't track NAT gateways, those
+// in the VPC are deleted.
+func (c *ResourceClient) deleteStackNATGateways(ctx context.Context, stack *resourceStack) error {
+	vpcID := stack.inventory.VPCID
+	natGatewayIDs := append([]string{}, stack.inventory.NATGatewayIDs...)
+	for _, natGatewayID := range getNATGatewayIDs(stack.inventory.AvailabilityZones) {
+		if !containsString(natGatewayIDs, natGatewayID) {
+			natGatewayIDs = append(natGatewayIDs, natGatewayID)
+		}
+	}
+	if stack.inventory.NATGatewaysUntracked && vpcID != "" {
+		natGateways, err := c.getNATGateways(ctx, vpcID, nil)
+		if err != nil {
+			return err
+		}
+		for _, natGateway := range natGateways {
+			if !containsString(natGatewayIDs, *natGateway.NatGatewayId) {
+				natGatewayIDs = append(natGatewayIDs, *natGateway.NatGatewayId)
+			}
+		}
+	}
+
+	c.sendResourceEvents(ResourceKindNATGateway, EventActionDelete, EventPhaseStarted, natGatewayIDs...)
+	if err := c.DeleteNATGateways(ctx, natGatewayIDs); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("NAT gateways deletion initiated: %s\n", natGatewayIDs))
+	c.sendMessage(fmt.Sprintf("Waiting for NAT gateways to be deleted: %s\n", natGatewayIDs))
+	c.sendResourceEvents(ResourceKindNATGateway, EventActionDelete, EventPhaseWaiting, natGatewayIDs...)
+	if err := c.WaitForNATGateways(ctx, vpcID, natGatewayIDs, NATGatewayConditionDeleted); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("NAT gateway deletion complete: %s\n", natGatewayIDs))
+	c.sendResourceEvents(ResourceKindNATGateway, EventActionDelete, EventPhaseSucceeded, natGatewayIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.NATGatewayIDs = []string{}
+		inventory.NATGatewaysUntracked = false
+		azs := copyAvailabilityZones(inventory.AvailabilityZones)
+		for i := range azs {
+			azs[i].NATGatewayID = ""
+		}
+		inventory.AvailabilityZones = azs
+	})
+
+	return nil
+}
+
+// createStackTransitGatewayAttachment attaches the VPC to the transit gateway
+// if one is configured and the attachment is not in the inventory, and waits
+// for the attachment to become available.  The attachment is given a network
+// interface in each private subnet.
+func (c *ResourceClient) createStackTransitGatewayAttachment(ctx context.Context, stack *resourceStack) error {
+	transitGateway := stack.config.TransitGateway
+	if transitGateway == nil {
+		return nil
+	}
+	attachmentID := stack.inventory.TransitGatewayAttachmentID
+	if attachmentID != "" {
+		attachment, err := c.getTransitGatewayAttachment(ctx, attachmentID)
+		if err != nil {
+			return err
+		}
+		if attachment.TransitGatewayId == nil || *attachment.TransitGatewayId != transitGateway.TransitGatewayID {
+			return fmt.Errorf("config transit gateway ID %s does not match transit gateway of inventory attachment %s",
+				transitGateway.TransitGatewayID, attachmentID)
+		}
+		c.sendMessage(fmt.Sprintf("Transit gateway attachment already exists: %s\n", attachmentID))
+	} else {
+		c.sendEvent(Event{Kind: ResourceKindTransitGatewayAttachment, Action: EventActionCreate, Phase: EventPhaseStarted})
+		newAttachmentID, err := c.CreateTransitGatewayAttachment(ctx, stack.ec2Tags, transitGateway.TransitGatewayID,
+			stack.inventory.VPCID, getPrivateSubnetIDs(stack.availabilityZones()))
+		if newAttachmentID != "" {
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.TransitGatewayAttachmentID = newAttachmentID
+			})
+		}
+		if err != nil {
+			return err
+		}
+		attachmentID = newAttachmentID
+		c.sendMessage(fmt.Sprintf("Transit gateway attachment created: %s\n", attachmentID))
+	}
+
+	c.sendMessage(fmt.Sprintf("Waiting for transit gateway attachment to become available: %s\n", attachmentID))
+	c.sendResourceEvents(ResourceKindTransitGatewayAttachment, EventActionCreate, EventPhaseWaiting, attachmentID)
+	if err := c.WaitForTransitGatewayAttachment(ctx, attachmentID, TransitGatewayAttachmentConditionCreated); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Transit gateway attachment ready: %s\n", attachmentID))
+	c.sendResourceEvents(ResourceKindTransitGatewayAttachment, EventActionCreate, EventPhaseSucceeded, attachmentID)
+
+	return nil
+}
+
+// deleteStackTransitGatewayAttachment detaches the VPC from the transit
+// gateway and waits for the attachment to be deleted.
+func (c *ResourceClient) deleteStackTransitGatewayAttachment(ctx context.Context, stack *resourceStack) error {
+	attachmentID := stack.inventory.TransitGatewayAttachmentID
+	if attachmentID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindTransitGatewayAttachment, EventActionDelete, EventPhaseStarted, attachmentID)
+	if err := c.DeleteTransitGatewayAttachment(ctx, attachmentID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Transit gateway attachment deletion initiated: %s\n", attachmentID))
+	c.sendMessage(fmt.Sprintf("Waiting for transit gateway attachment to be deleted: %s\n", attachmentID))
+	c.sendResourceEvents(ResourceKindTransitGatewayAttachment, EventActionDelete, EventPhaseWaiting, attachmentID)
+	if err := c.WaitForTransitGatewayAttachment(ctx, attachmentID, TransitGatewayAttachmentConditionDeleted); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Transit gateway attachment deletion complete: %s\n", attachmentID))
+	c.sendResourceEvents(ResourceKindTransitGatewayAttachment, EventActionDelete, EventPhaseSucceeded, attachmentID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.TransitGatewayAttachmentID = ""
+	})
+
+	return nil
+}
+
+// createStackVPCPeeringConnection requests a peering connection to the peer
+// VPC if one is configured and the connection is not in the inventory.  A
+// connection to a VPC in the same account and region is accepted and waited
+// on, otherwise the peer VPC's owner must accept it.
+func (c *ResourceClient) createStackVPCPeeringConnection(ctx context.Context, stack *resourceStack) error {
+	vpcPeering := stack.config.VPCPeering
+	if vpcPeering == nil {
+		return nil
+	}
+	vpcPeeringConnectionID := stack.inventory.VPCPeeringConnectionID
+	if vpcPeeringConnectionID != "" {
+		vpcPeeringConnection, err := c.getVPCPeeringConnection(ctx, vpcPeeringConnectionID)
+		if err != nil {
+			return err
+		}
+		if vpcPeeringConnectionPeerVPCID(vpcPeeringConnection) != vpcPeering.PeerVPCID {
+			return fmt.Errorf("config peer VPC ID %s does not match peer VPC of inventory VPC peering connection %s",
+				vpcPeering.PeerVPCID, vpcPeeringConnectionID)
+		}
+		c.sendMessage(fmt.Sprintf("VPC peering connection already exists: %s\n", vpcPeeringConnectionID))
+	} else {
+		c.sendEvent(Event{Kind: ResourceKindVPCPeeringConnection, Action: EventActionCreate, Phase: EventPhaseStarted})
+		newVPCPeeringConnectionID, err := c.CreateVPCPeeringConnection(ctx, stack.ec2Tags, stack.inventory.VPCID, vpcPeering)
+		if newVPCPeeringConnectionID != "" {
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.VPCPeeringConnectionID = newVPCPeeringConnectionID
+			})
+		}
+		if err != nil {
+			return err
+		}
+		vpcPeeringConnectionID = newVPCPeeringConnectionID
+		c.sendMessage(fmt.Sprintf("VPC peering connection requested: %s\n", vpcPeeringConnectionID))
+	}
+
+	if !vpcPeering.autoAccept(stack.config.AWSAccountID, stack.config.Region) {
+		c.sendMessage(fmt.Sprintf("VPC peering connection %s must be accepted by the owner of VPC %s\n",
+			vpcPeeringConnectionID, vpcPeering.PeerVPCID))
+		c.sendResourceEvents(ResourceKindVPCPeeringConnection, EventActionCreate, EventPhaseSucceeded, vpcPeeringConnectionID)
+		return nil
+	}
+	c.sendMessage(fmt.Sprintf("Waiting for VPC peering connection to become active: %s\n", vpcPeeringConnectionID))
+	c.sendResourceEvents(ResourceKindVPCPeeringConnection, EventActionCreate, EventPhaseWaiting, vpcPeeringConnectionID)
+	if err := c.AcceptVPCPeeringConnection(ctx, vpcPeeringConnectionID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC peering connection active: %s\n", vpcPeeringConnectionID))
+	c.sendResourceEvents(ResourceKindVPCPeeringConnection, EventActionCreate, EventPhaseSucceeded, vpcPeeringConnectionID)
+
+	return nil
+}
+
+// deleteStackVPCPeeringConnection deletes the VPC peering connection.
+func (c *ResourceClient) deleteStackVPCPeeringConnection(ctx context.Context, stack *resourceStack) error {
+	vpcPeeringConnectionID := stack.inventory.VPCPeeringConnectionID
+	if vpcPeeringConnectionID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindVPCPeeringConnection, EventActionDelete, EventPhaseStarted, vpcPeeringConnectionID)
+	if err := c.DeleteVPCPeeringConnection(ctx, vpcPeeringConnectionID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC peering connection deleted: %s\n", vpcPeeringConnectionID))
+	c.sendResourceEvents(ResourceKindVPCPeeringConnection, EventActionDelete, EventPhaseSucceeded, vpcPeeringConnectionID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.VPCPeeringConnectionID = ""
+	})
+
+	return nil
+}
+
+// createStackRouteTables creates the public route table and a private route
+// table for each availability zone if they don't exist, along with their
+// routes and subnet associations.  The routes are recorded in the inventory
+// and the association IDs on the availability zones.  Nothing is created when
+// using an existing VPC.
+func (c *ResourceClient) createStackRouteTables(ctx context.Context, stack *resourceStack) error {
+	if stack.config.UsesExistingVPC() {
+		return nil
+	}
+	azs := stack.availabilityZones()
+
+	privateRouteTableIDs := stack.inventory.PrivateRouteTableIDs
+	existingRouteTableIDs := append([]string{stack.inventory.PublicRouteTableID}, privateRouteTableIDs...)
+	c.sendEvent(Event{Kind: ResourceKindRouteTable, Action: EventActionCreate, Phase: EventPhaseStarted})
+	privateRouteTables, publicRouteTable, routes, err := c.CreateRouteTables(ctx, stack.ec2Tags, stack.inventory.VPCID,
+		stack.inventory.InternetGatewayID, stack.inventory.EgressOnlyInternetGatewayID, stack.inventory.PublicRouteTableID,
+		networkAttachmentRoutes(stack.config, stack.inventory.VPCPeeringConnectionID), &azs,
+	)
+	if privateRouteTables != nil {
+		for _, rt := range *privateRouteTables {
+			if !containsString(privateRouteTableIDs, *rt.RouteTableId) {
+				privateRouteTableIDs = append(privateRouteTableIDs, *rt.RouteTableId)
+			}
+		}
+	}
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.PrivateRouteTableIDs = privateRouteTableIDs
+		if publicRouteTable != nil {
+			inventory.PublicRouteTableID = *publicRouteTable.RouteTableId
+		}
+		if routes != nil {
+			inventory.Routes = mergeRoutes(inventory.Routes, *routes)
+		}
+	})
+	c.updateAvailabilityZones(stack, azs)
+	if err != nil {
+		return err
+	}
+	c.sendMessage(
+		fmt.Sprintf(
+			"Route tables ready: [%s %s]\n",
+			privateRouteTableIDs, stack.inventory.PublicRouteTableID,
+		),
+	)
+	for _, routeTableID := range append([]string{stack.inventory.PublicRouteTableID}, privateRouteTableIDs...) {
+		if !containsString(existingRouteTableIDs, routeTableID) {
+			c.sendResourceEvents(ResourceKindRouteTable, EventActionCreate, EventPhaseSucceeded, routeTableID)
+		}
+	}
+
+	return nil
+}
+
+// deleteStackRouteTables deletes the routes and subnet associations recorded
+// in the inventory and then the route tables.
+func (c *ResourceClient) deleteStackRouteTables(ctx context.Context, stack *resourceStack) error {
+	routeTableIDs := append([]string{stack.inventory.PublicRouteTableID}, stack.inventory.PrivateRouteTableIDs...)
+	c.sendResourceEvents(ResourceKindRouteTable, EventActionDelete, EventPhaseStarted, routeTableIDs...)
+	if err := c.DeleteRoutes(ctx, stack.inventory.Routes); err != nil {
+		return err
+	}
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.Routes = []RouteInventory{}
+	})
+	if err := c.DisassociateRouteTables(ctx, getRouteTableAssociationIDs(stack.inventory.AvailabilityZones)); err != nil {
+		return err
+	}
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		azs := copyAvailabilityZones(inventory.AvailabilityZones)
+		for i := range azs {
+			azs[i].PrivateRouteTableAssociationID = ""
+			azs[i].PublicRouteTableAssociationID = ""
+			azs[i].PodRouteTableAssociationID = ""
+		}
+		inventory.AvailabilityZones = azs
+	})
+	if err := c.DeleteRouteTables(ctx, stack.inventory.PrivateRouteTableIDs, stack.inventory.PublicRouteTableID); err != nil {
+		return err
+	}
+	c.sendMessage(
+		fmt.Sprintf("Route tables deleted: [%s %s]\n",
+			stack.inventory.PrivateRouteTableIDs, stack.inventory.PublicRouteTableID,
+		),
+	)
+	c.sendResourceEvents(ResourceKindRouteTable, EventActionDelete, EventPhaseSucceeded, routeTableIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.PrivateRouteTableIDs = []string{}
+		inventory.PublicRouteTableID = ""
+		azs := copyAvailabilityZones(inventory.AvailabilityZones)
+		for i := range azs {
+			azs[i].PrivateRouteTableID = ""
+		}
+		inventory.AvailabilityZones = azs
+	})
+
+	return nil
+}
+
+// createStackVPCEndpointSecurityGroup creates the security group for the
+// interface VPC endpoints if VPC endpoints are enabled and it is not in the
+// inventory.
+func (c *ResourceClient) createStackVPCEndpointSecurityGroup(ctx context.Context, stack *resourceStack) error {
+	if !stack.config.VPCEndpoints {
+		return nil
+	}
+	if stack.inventory.VPCEndpointSecurityGroupID != "" {
+		c.sendMessage(fmt.Sprintf("VPC endpoint security group already exists: %s\n", stack.inventory.VPCEndpointSecurityGroupID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindSecurityGroup, Action: EventActionCreate, Phase: EventPhaseStarted})
+	securityGroupID, err := c.CreateVPCEndpointSecurityGroup(ctx, stack.ec2Tags, stack.inventory.VPCID,
+		stack.config.ClusterCIDR, stack.config.Name)
+	if securityGroupID != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.VPCEndpointSecurityGroupID = securityGroupID
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC endpoint security group created: %s\n", securityGroupID))
+	c.sendResourceEvents(ResourceKindSecurityGroup, EventActionCreate, EventPhaseSucceeded, securityGroupID)
+
+	return nil
+}
+
+// deleteStackVPCEndpointSecurityGroup deletes the security group for the
+// interface VPC endpoints.
+func (c *ResourceClient) deleteStackVPCEndpointSecurityGroup(ctx context.Context, stack *resourceStack) error {
+	securityGroupID := stack.inventory.VPCEndpointSecurityGroupID
+	if securityGroupID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindSecurityGroup, EventActionDelete, EventPhaseStarted, securityGroupID)
+	if err := c.DeleteSecurityGroup(ctx, securityGroupID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC endpoint security group deleted: %s\n", securityGroupID))
+	c.sendResourceEvents(ResourceKindSecurityGroup, EventActionDelete, EventPhaseSucceeded, securityGroupID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.VPCEndpointSecurityGroupID = ""
+	})
+
+	return nil
+}
+
+// createStackVPCEndpoints creates the VPC endpoints that are not in the
+// inventory if VPC endpoints are enabled and waits for them to become
+// available.
+func (c *ResourceClient) createStackVPCEndpoints(ctx context.Context, stack *resourceStack) error {
+	if !stack.config.VPCEndpoints {
+		return nil
+	}
+	existingVPCEndpointIDs := getVPCEndpointIDs(stack.inventory.VPCEndpoints)
+	if len(existingVPCEndpointIDs) < len(vpcEndpointServices) {
+		c.sendEvent(Event{Kind: ResourceKindVPCEndpoint, Action: EventActionCreate, Phase: EventPhaseStarted})
+	}
+	vpcEndpoints, err := c.CreateVPCEndpoints(ctx, stack.ec2Tags, stack.config.Region, stack.inventory.VPCID,
+		stack.inventory.VPCEndpointSecurityGroupID, stack.inventory.PrivateRouteTableIDs,
+		getPrivateSubnetIDs(stack.inventory.AvailabilityZones), stack.inventory.VPCEndpoints)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.VPCEndpoints = append(inventory.VPCEndpoints, vpcEndpoints...)
+	})
+	if err != nil {
+		return err
+	}
+	vpcEndpointIDs := getVPCEndpointIDs(stack.inventory.VPCEndpoints)
+	c.sendMessage(fmt.Sprintf("Waiting for VPC endpoints to become available: %s\n", vpcEndpointIDs))
+	c.sendResourceEvents(ResourceKindVPCEndpoint, EventActionCreate, EventPhaseWaiting, getVPCEndpointIDs(vpcEndpoints)...)
+	if err := c.WaitForVPCEndpoints(ctx, stack.inventory.VPCID, vpcEndpointIDs, VPCEndpointConditionCreated); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC endpoints ready: %s\n", vpcEndpointIDs))
+	c.sendResourceEvents(ResourceKindVPCEndpoint, EventActionCreate, EventPhaseSucceeded, getVPCEndpointIDs(vpcEndpoints)...)
+
+	return nil
+}
+
+// deleteStackVPCEndpoints deletes the VPC endpoints in the inventory and
+// waits for them to be deleted.
+func (c *ResourceClient) deleteStackVPCEndpoints(ctx context.Context, stack *resourceStack) error {
+	vpcEndpointIDs := getVPCEndpointIDs(stack.inventory.VPCEndpoints)
+	if len(vpcEndpointIDs) == 0 {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindVPCEndpoint, EventActionDelete, EventPhaseStarted, vpcEndpointIDs...)
+	if err := c.DeleteVPCEndpoints(ctx, vpcEndpointIDs); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC endpoints deletion initiated: %s\n", vpcEndpointIDs))
+	c.sendMessage(fmt.Sprintf("Waiting for VPC endpoints to be deleted: %s\n", vpcEndpointIDs))
+	c.sendResourceEvents(ResourceKindVPCEndpoint, EventActionDelete, EventPhaseWaiting, vpcEndpointIDs...)
+	if err := c.WaitForVPCEndpoints(ctx, stack.inventory.VPCID, vpcEndpointIDs, VPCEndpointConditionDeleted); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("VPC endpoints deletion complete: %s\n", vpcEndpointIDs))
+	c.sendResourceEvents(ResourceKindVPCEndpoint, EventActionDelete, EventPhaseSucceeded, vpcEndpointIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.VPCEndpoints = []VPCEndpointInventory{}
+	})
+
+	return nil
+}
+
+// createStackNetworkACLs creates a network ACL for each tier of subnets with
+// rules that doesn't have one in the inventory.  The rules of each network ACL
+// are set to those in the config and it is associated with the tier's
+// subnets.  Nothing is created when no rules are configured.
+func (c *ResourceClient) createStackNetworkACLs(ctx context.Context, stack *resourceStack) error {
+	networkACLs := stack.config.NetworkACLs
+	if networkACLs == nil {
+		return nil
+	}
+	azs := stack.availabilityZones()
+
+	for _, tier := range networkACLs.tiers() {
+		networkACLID := findNetworkACLID(stack.inventory.NetworkACLs, tier.tier)
+		if networkACLID != "" {
+			c.sendMessage(fmt.Sprintf("Network ACL for %s subnets already exists: %s\n", tier.tier, networkACLID))
+		} else {
+			c.sendEvent(Event{Kind: ResourceKindNetworkACL, Action: EventActionCreate, Phase: EventPhaseStarted})
+			newNetworkACLID, err := c.CreateNetworkACL(ctx, stack.ec2Tags, stack.inventory.VPCID)
+			if newNetworkACLID != "" {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.NetworkACLs = append(inventory.NetworkACLs, NetworkACLInventory{
+						Tier:         tier.tier,
+						NetworkACLID: newNetworkACLID,
+					})
+				})
+			}
+			if err != nil {
+				return err
+			}
+			networkACLID = newNetworkACLID
+			c.sendMessage(fmt.Sprintf("Network ACL for %s subnets created: %s\n", tier.tier, networkACLID))
+		}
+
+		if err := c.SetNetworkACLRules(ctx, networkACLID, tier.rules); err != nil {
+			return err
+		}
+		if err := c.AssociateNetworkACL(ctx, stack.inventory.VPCID, networkACLID, tierSubnetIDs(tier.tier, azs)); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("Network ACL rules set and associated with %s subnets: %s\n", tier.tier, networkACLID))
+		c.sendResourceEvents(ResourceKindNetworkACL, EventActionCreate, EventPhaseSucceeded, networkACLID)
+	}
+
+	return nil
+}
+
+// deleteStackNetworkACLs associates the subnets with the VPC's default
+// network ACL and deletes the network ACLs in the inventory.
+func (c *ResourceClient) deleteStackNetworkACLs(ctx context.Context, stack *resourceStack) error {
+	networkACLIDs := getNetworkACLIDs(stack.inventory.NetworkACLs)
+	if len(networkACLIDs) == 0 {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindNetworkACL, EventActionDelete, EventPhaseStarted, networkACLIDs...)
+	for _, networkACLID := range networkACLIDs {
+		if err := c.DeleteNetworkACL(ctx, networkACLID); err != nil {
+			return err
+		}
+	}
+	c.sendMessage(fmt.Sprintf("Network ACLs deleted: %s\n", networkACLIDs))
+	c.sendResourceEvents(ResourceKindNetworkACL, EventActionDelete, EventPhaseSucceeded, networkACLIDs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.NetworkACLs = []NetworkACLInventory{}
+	})
+
+	return nil
+}
+
+// createStackPolicies creates the IAM policies for the enabled supporting
+// services that are not in the inventory.
+func (c *ResourceClient) createStackPolicies(ctx context.Context, stack *resourceStack) error {
+	// IAM Policy for DNS Management
+	if stack.config.DNSManagement {
+		dnsPolicyName := fmt.Sprintf("%s-%s", DNSPolicyName, stack.config.Name)
+		if findPolicyARN(stack.inventory.PolicyARNs, dnsPolicyName) == "" {
+			c.sendEvent(Event{Kind: ResourceKindPolicy, Action: EventActionCreate, Phase: EventPhaseStarted})
+			dnsPolicy, err := c.CreateDNSManagementPolicy(ctx, stack.iamTags, stack.config.Name)
+			if dnsPolicy != nil {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.PolicyARNs = append(inventory.PolicyARNs, *dnsPolicy.Arn)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.sendMessage(fmt.Sprintf("IAM policy created: %s\n", *dnsPolicy.PolicyName))
+			c.sendResourceEvents(ResourceKindPolicy, EventActionCreate, EventPhaseSucceeded, *dnsPolicy.Arn)
+		} else {
+			c.sendMessage(fmt.Sprintf("IAM policy already exists: %s\n", dnsPolicyName))
+		}
+	}
+
+	// IAM Policy for DNS01 Challenge
+	if stack.config.DNS01Challenge {
+		dns01ChallengePolicyName := fmt.Sprintf("%s-%s", DNS01ChallengePolicyName, stack.config.Name)
+		if findPolicyARN(stack.inventory.PolicyARNs, dns01ChallengePolicyName) == "" {
+			c.sendEvent(Event{Kind: ResourceKindPolicy, Action: EventActionCreate, Phase: EventPhaseStarted})
+			dns01ChallengePolicy, err := c.CreateDNS01ChallengePolicy(ctx, stack.iamTags, stack.config.Name)
+			if dns01ChallengePolicy != nil {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.PolicyARNs = append(inventory.PolicyARNs, *dns01ChallengePolicy.Arn)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.sendMessage(fmt.Sprintf("IAM policy created: %s\n", *dns01ChallengePolicy.PolicyName))
+			c.sendResourceEvents(ResourceKindPolicy, EventActionCreate, EventPhaseSucceeded, *dns01ChallengePolicy.Arn)
+		} else {
+			c.sendMessage(fmt.Sprintf("IAM policy already exists: %s\n", dns01ChallengePolicyName))
+		}
+	}
+
+	// IAM Policy for Cluster Autoscaling
+	if stack.config.ClusterAutoscaling {
+		clusterAutoscalingPolicyName := fmt.Sprintf("%s-%s", AutoscalingPolicyName, stack.config.Name)
+		if findPolicyARN(stack.inventory.PolicyARNs, clusterAutoscalingPolicyName) == "" {
+			c.sendEvent(Event{Kind: ResourceKindPolicy, Action: EventActionCreate, Phase: EventPhaseStarted})
+			clusterAutoscalingPolicy, err := c.CreateClusterAutoscalingPolicy(ctx, stack.iamTags, stack.config.Name)
+			if clusterAutoscalingPolicy != nil {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.PolicyARNs = append(inventory.PolicyARNs, *clusterAutoscalingPolicy.Arn)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.sendMessage(fmt.Sprintf("IAM policy created: %s\n", *clusterAutoscalingPolicy.PolicyName))
+			c.sendResourceEvents(ResourceKindPolicy, EventActionCreate, EventPhaseSucceeded, *clusterAutoscalingPolicy.Arn)
+		} else {
+			c.sendMessage(fmt.Sprintf("IAM policy already exists: %s\n", clusterAutoscalingPolicyName))
+		}
+	}
+
+	// IAM Policy for the VPC CNI plugin to assign IPv6 addresses
+	if stack.config.IPFamily == IPFamilyIPv6 {
+		cniIPv6PolicyName := fmt.Sprintf("%s-%s", CNIIPv6PolicyName, stack.config.Name)
+		if findPolicyARN(stack.inventory.PolicyARNs, cniIPv6PolicyName) == "" {
+			c.sendEvent(Event{Kind: ResourceKindPolicy, Action: EventActionCreate, Phase: EventPhaseStarted})
+			cniIPv6Policy, err := c.CreateCNIIPv6Policy(ctx, stack.iamTags, stack.config.Name)
+			if cniIPv6Policy != nil {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.PolicyARNs = append(inventory.PolicyARNs, *cniIPv6Policy.Arn)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.sendMessage(fmt.Sprintf("IAM policy created: %s\n", *cniIPv6Policy.PolicyName))
+			c.sendResourceEvents(ResourceKindPolicy, EventActionCreate, EventPhaseSucceeded, *cniIPv6Policy.Arn)
+		} else {
+			c.sendMessage(fmt.Sprintf("IAM policy already exists: %s\n", cniIPv6PolicyName))
+		}
+	}
+
+	// IAM Policy for delivering flow logs to CloudWatch Logs
+	if flowLogs := stack.config.FlowLogs; flowLogs != nil && flowLogs.destination() == FlowLogDestinationCloudWatchLogs {
+		flowLogsPolicyName := fmt.Sprintf("%s-%s", FlowLogsPolicyName, stack.config.Name)
+		if findPolicyARN(stack.inventory.PolicyARNs, flowLogsPolicyName) == "" {
+			c.sendEvent(Event{Kind: ResourceKindPolicy, Action: EventActionCreate, Phase: EventPhaseStarted})
+			flowLogsPolicy, err := c.CreateFlowLogsPolicy(ctx, stack.iamTags, flowLogs.logGroupName(stack.config.Name),
+				stack.config.Name)
+			if flowLogsPolicy != nil {
+				c.updateInventory(stack, func(inventory *ResourceInventory) {
+					inventory.PolicyARNs = append(inventory.PolicyARNs, *flowLogsPolicy.Arn)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.sendMessage(fmt.Sprintf("IAM policy created: %s\n", *flowLogsPolicy.PolicyName))
+			c.sendResourceEvents(ResourceKindPolicy, EventActionCreate, EventPhaseSucceeded, *flowLogsPolicy.Arn)
+		} else {
+			c.sendMessage(fmt.Sprintf("IAM policy already exists: %s\n", flowLogsPolicyName))
+		}
+	}
+
+	return nil
+}
+
+// deleteStackPolicies deletes the IAM policies.
+func (c *ResourceClient) deleteStackPolicies(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindPolicy, EventActionDelete, EventPhaseStarted, stack.inventory.PolicyARNs...)
+	if err := c.DeletePolicies(ctx, stack.inventory.PolicyARNs); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM policies deleted: %s\n", stack.inventory.PolicyARNs))
+	c.sendResourceEvents(ResourceKindPolicy, EventActionDelete, EventPhaseSucceeded, stack.inventory.PolicyARNs...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.PolicyARNs = []string{}
+	})
+
+	return nil
+}
+
+// createStackClusterRoles creates the IAM roles for the cluster and worker
+// nodes.  If both are in the inventory their policies are attached again.
+// For the IPv6 IP family the CNI IPv6 policy is attached to the worker role as
+// well.
+func (c *ResourceClient) createStackClusterRoles(ctx context.Context, stack *resourceStack) error {
+	clusterRoleInventory := stack.inventory.ClusterRole
+	workerRoleInventory := stack.inventory.WorkerRole
+	if clusterRoleInventory.RoleName != "" && workerRoleInventory.RoleName != "" {
+		// the worker role may be missing the CNI IPv6 policy if it was
+		// created before the policy
+		workerPolicyARNs := append([]string{}, workerRoleInventory.RolePolicyARNs...)
+		for _, policyARN := range stackWorkerPolicyARNs(stack) {
+			if !containsString(workerPolicyARNs, policyARN) {
+				workerPolicyARNs = append(workerPolicyARNs, policyARN)
+			}
+		}
+		if len(workerPolicyARNs) > len(workerRoleInventory.RolePolicyARNs) {
+			workerRoleInventory.RolePolicyARNs = workerPolicyARNs
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.WorkerRole.RolePolicyARNs = workerPolicyARNs
+			})
+		}
+		for _, role := range []RoleInventory{clusterRoleInventory, workerRoleInventory} {
+			if err := c.attachRolePolicies(ctx, role); err != nil {
+				return err
+			}
+		}
+		c.sendMessage(fmt.Sprintf("IAM roles already exist: [%s %s]\n",
+			clusterRoleInventory.RoleName, workerRoleInventory.RoleName))
+		return nil
+	}
+
+	// the cluster and worker roles are created together so if only one of
+	// them exists it is deleted first
+	existingRoles := []RoleInventory{clusterRoleInventory, workerRoleInventory}
+	if err := c.DeleteRoles(ctx, &existingRoles); err != nil {
+		return err
+	}
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.ClusterRole = RoleInventory{}
+		inventory.WorkerRole = RoleInventory{}
+	})
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	clusterRole, workerRole, err := c.CreateRoles(ctx, stack.iamTags, stack.config.Name)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		if clusterRole != nil {
+			inventory.ClusterRole = RoleInventory{
+				RoleName:       *clusterRole.RoleName,
+				RoleARN:        *clusterRole.Arn,
+				RolePolicyARNs: []string{ClusterPolicyARN},
+			}
+		}
+		if workerRole != nil {
+			inventory.WorkerRole = RoleInventory{
+				RoleName:       *workerRole.RoleName,
+				RoleARN:        *workerRole.Arn,
+				RolePolicyARNs: stackWorkerPolicyARNs(stack),
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// CreateRoles only attaches the AWS managed policies to the worker role
+	if err := c.attachRolePolicies(ctx, stack.inventory.WorkerRole); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM roles created: [%s %s]\n", *clusterRole.RoleName, *workerRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *clusterRole.RoleName, *workerRole.RoleName)
+
+	return nil
+}
+
+// deleteStackClusterRoles deletes the IAM roles for the cluster and worker
+// nodes.
+func (c *ResourceClient) deleteStackClusterRoles(ctx context.Context, stack *resourceStack) error {
+	roles := []RoleInventory{stack.inventory.ClusterRole, stack.inventory.WorkerRole}
+	c.sendResourceEvents(ResourceKindRole, EventActionDelete, EventPhaseStarted, roles[0].RoleName, roles[1].RoleName)
+	if err := c.DeleteRoles(ctx, &roles); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM roles deleted: %s\n", roles))
+	c.sendResourceEvents(ResourceKindRole, EventActionDelete, EventPhaseSucceeded, roles[0].RoleName, roles[1].RoleName)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.ClusterRole = RoleInventory{}
+		inventory.WorkerRole = RoleInventory{}
+	})
+
+	return nil
+}
+
+// createStackFlowLogGroup creates the CloudWatch Logs log group for flow logs
+// if flow logs are published to CloudWatch Logs and it is not in the
+// inventory.
+func (c *ResourceClient) createStackFlowLogGroup(ctx context.Context, stack *resourceStack) error {
+	flowLogs := stack.config.FlowLogs
+	if flowLogs == nil || flowLogs.destination() != FlowLogDestinationCloudWatchLogs {
+		return nil
+	}
+	if stack.inventory.FlowLogGroupName != "" {
+		c.sendMessage(fmt.Sprintf("Flow log group already exists: %s\n", stack.inventory.FlowLogGroupName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindLogGroup, Action: EventActionCreate, Phase: EventPhaseStarted})
+	logGroupName, err := c.CreateLogGroup(ctx, stack.mapTags, flowLogs.logGroupName(stack.config.Name), flowLogs.RetentionDays)
+	if logGroupName != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.FlowLogGroupName = logGroupName
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Flow log group created: %s\n", logGroupName))
+	c.sendResourceEvents(ResourceKindLogGroup, EventActionCreate, EventPhaseSucceeded, logGroupName)
+
+	return nil
+}
+
+// deleteStackFlowLogGroup deletes the CloudWatch Logs log group for flow logs
+// along with the flow logs in it.
+func (c *ResourceClient) deleteStackFlowLogGroup(ctx context.Context, stack *resourceStack) error {
+	logGroupName := stack.inventory.FlowLogGroupName
+	if logGroupName == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindLogGroup, EventActionDelete, EventPhaseStarted, logGroupName)
+	if err := c.DeleteLogGroup(ctx, logGroupName); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Flow log group deleted: %s\n", logGroupName))
+	c.sendResourceEvents(ResourceKindLogGroup, EventActionDelete, EventPhaseSucceeded, logGroupName)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.FlowLogGroupName = ""
+	})
+
+	return nil
+}
+
+// createStackFlowLogsRole creates the IAM role that delivers flow logs to
+// CloudWatch Logs if flow logs are published to CloudWatch Logs and it is not
+// in the inventory.
+func (c *ResourceClient) createStackFlowLogsRole(ctx context.Context, stack *resourceStack) error {
+	flowLogs := stack.config.FlowLogs
+	if flowLogs == nil || flowLogs.destination() != FlowLogDestinationCloudWatchLogs {
+		return nil
+	}
+
+	flowLogsPolicyARN := findPolicyARN(stack.inventory.PolicyARNs, fmt.Sprintf("%s-%s", FlowLogsPolicyName, stack.config.Name))
+	if flowLogsPolicyARN == "" {
+		return errors.New("no flow logs policy ARN to attach to flow logs role")
+	}
+	if role := stack.inventory.FlowLogsRole; role.RoleName != "" {
+		if err := c.attachRolePolicies(ctx, role); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("IAM role for flow logs already exists: %s\n", role.RoleName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	flowLogsRole, err := c.CreateFlowLogsRole(ctx, stack.iamTags, flowLogsPolicyARN, stack.config.Name)
+	if flowLogsRole != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.FlowLogsRole = RoleInventory{
+				RoleName:       *flowLogsRole.RoleName,
+				RoleARN:        *flowLogsRole.Arn,
+				RolePolicyARNs: []string{flowLogsPolicyARN},
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for flow logs created: %s\n", *flowLogsRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *flowLogsRole.RoleName)
+
+	return nil
+}
+
+// deleteStackFlowLogsRole deletes the IAM role that delivers flow logs to
+// CloudWatch Logs.
+func (c *ResourceClient) deleteStackFlowLogsRole(ctx context.Context, stack *resourceStack) error {
+	return c.deleteStackRole(ctx, stack, &stack.inventory.FlowLogsRole, "flow logs")
+}
+
+// createStackFlowLog creates the flow log for the VPC if flow logs are
+// configured and it is not in the inventory.
+func (c *ResourceClient) createStackFlowLog(ctx context.Context, stack *resourceStack) error {
+	if stack.config.FlowLogs == nil {
+		return nil
+	}
+	if stack.inventory.FlowLogID != "" {
+		c.sendMessage(fmt.Sprintf("Flow log already exists: %s\n", stack.inventory.FlowLogID))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindFlowLog, Action: EventActionCreate, Phase: EventPhaseStarted})
+	flowLogID, err := c.CreateFlowLog(ctx, stack.ec2Tags, stack.inventory.VPCID, stack.config.FlowLogs,
+		stack.inventory.FlowLogGroupName, stack.inventory.FlowLogsRole.RoleARN)
+	if flowLogID != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.FlowLogID = flowLogID
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Flow log created: %s\n", flowLogID))
+	c.sendResourceEvents(ResourceKindFlowLog, EventActionCreate, EventPhaseSucceeded, flowLogID)
+
+	return nil
+}
+
+// deleteStackFlowLog deletes the flow log for the VPC.
+func (c *ResourceClient) deleteStackFlowLog(ctx context.Context, stack *resourceStack) error {
+	flowLogID := stack.inventory.FlowLogID
+	if flowLogID == "" {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindFlowLog, EventActionDelete, EventPhaseStarted, flowLogID)
+	if err := c.DeleteFlowLog(ctx, flowLogID); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Flow log deleted: %s\n", flowLogID))
+	c.sendResourceEvents(ResourceKindFlowLog, EventActionDelete, EventPhaseSucceeded, flowLogID)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.FlowLogID = ""
+	})
+
+	return nil
+}
+
+// createStackCluster creates the EKS cluster if it is not in the inventory
+// and waits for it to become active.  A cluster that failed to create is
+// deleted and created again.
+func (c *ResourceClient) createStackCluster(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	if clusterName != "" {
+		cluster, err := c.getCluster(ctx, clusterName)
+		if err != nil {
+			return err
+		}
+		if cluster.Status == ekstypes.ClusterStatusFailed || cluster.Status == ekstypes.ClusterStatusDeleting {
+			if cluster.Status == ekstypes.ClusterStatusFailed {
+				c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseStarted, clusterName)
+				if err := c.DeleteCluster(ctx, clusterName); err != nil {
+					return err
+				}
+				c.sendMessage(fmt.Sprintf("Failed EKS cluster deletion initiated: %s\n", clusterName))
+			}
+			c.sendMessage(fmt.Sprintf("Waiting for EKS cluster to be deleted: %s\n", clusterName))
+			c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseWaiting, clusterName)
+			if _, err := c.WaitForCluster(ctx, clusterName, ClusterConditionDeleted); err != nil {
+				return err
+			}
+			c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseSucceeded, clusterName)
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.Cluster = ClusterInventory{}
+				inventory.NodeGroupNames = []string{}
+				inventory.AddonNames = []string{}
+				inventory.SecurityGroupID = ""
+			})
+			clusterName = ""
+		} else {
+			c.sendMessage(fmt.Sprintf("EKS cluster already exists: %s\n", clusterName))
+		}
+	}
+
+	if clusterName == "" {
+		c.sendEvent(Event{Kind: ResourceKindCluster, Action: EventActionCreate, Phase: EventPhaseStarted})
+		cluster, err := c.CreateCluster(ctx, &stack.mapTags, stack.config.Name, stack.config.KubernetesVersion,
+			stack.inventory.ClusterRole.RoleARN, getPrivateSubnetIDs(stack.availabilityZones()), stack.config.IPFamily)
+		if cluster != nil {
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.Cluster.ClusterName = *cluster.Name
+				inventory.Cluster.ClusterARN = *cluster.Arn
+			})
+			clusterName = *cluster.Name
+		}
+		if err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("EKS cluster created: %s\n", clusterName))
+	}
+
+	c.sendMessage(fmt.Sprintf("Waiting for EKS cluster to become active: %s\n", clusterName))
+	c.sendResourceEvents(ResourceKindCluster, EventActionCreate, EventPhaseWaiting, clusterName)
+	oidcIssuer, err := c.WaitForCluster(ctx, clusterName, ClusterConditionCreated)
+	if oidcIssuer != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.Cluster.OIDCProviderURL = oidcIssuer
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("EKS cluster ready: %s\n", clusterName))
+	c.sendResourceEvents(ResourceKindCluster, EventActionCreate, EventPhaseSucceeded, clusterName)
+
+	return nil
+}
+
+// deleteStackCluster deletes the EKS cluster and waits for it to be deleted.
+func (c *ResourceClient) deleteStackCluster(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseStarted, clusterName)
+	if err := c.DeleteCluster(ctx, clusterName); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("EKS cluster deletion initiated: %s\n", clusterName))
+	c.sendMessage(fmt.Sprintf("Waiting for EKS cluster to be deleted: %s\n", clusterName))
+	c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseWaiting, clusterName)
+	if _, err := c.WaitForCluster(ctx, clusterName, ClusterConditionDeleted); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("EKS cluster deletion complete: %s\n", clusterName))
+	c.sendResourceEvents(ResourceKindCluster, EventActionDelete, EventPhaseSucceeded, clusterName)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		// the cluster security group is deleted by EKS with the cluster
+		inventory.Cluster = ClusterInventory{}
+		inventory.SecurityGroupID = ""
+	})
+
+	return nil
+}
+
+// createStackClusterSecurityGroup records the security group EKS created for
+// the cluster.  EKS owns the security group and deletes it along with the
+// cluster so it has no delete function.
+func (c *ResourceClient) createStackClusterSecurityGroup(ctx context.Context, stack *resourceStack) error {
+	securityGroupID, err := c.GetClusterSecurityGroup(ctx, stack.config.Name)
+	if securityGroupID != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.SecurityGroupID = securityGroupID
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("EKS cluster security group ID %s retrieved", securityGroupID))
+	c.sendResourceEvents(ResourceKindSecurityGroup, EventActionCreate, EventPhaseSucceeded, securityGroupID)
+
+	return nil
+}
+
+// createStackNodeGroups creates the node groups if they are not in the
+// inventory and waits for them to become active.  Node groups that failed to
+// create are deleted and created again.
+func (c *ResourceClient) createStackNodeGroups(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	nodeGroupNames := stack.inventory.NodeGroupNames
+
+	var failedNodeGroupNames []string
+	var removedNodeGroupNames []string
+	for _, nodeGroupName := range nodeGroupNames {
+		nodeGroup, err := c.getNodeGroup(ctx, clusterName, nodeGroupName)
+		if err != nil {
+			return err
+		}
+		switch nodeGroup.Status {
+		case ekstypes.NodegroupStatusCreateFailed:
+			failedNodeGroupNames = append(failedNodeGroupNames, nodeGroupName)
+			removedNodeGroupNames = append(removedNodeGroupNames, nodeGroupName)
+		case ekstypes.NodegroupStatusDeleting:
+			removedNodeGroupNames = append(removedNodeGroupNames, nodeGroupName)
+		}
+	}
+	if len(removedNodeGroupNames) > 0 {
+		c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseStarted, failedNodeGroupNames...)
+		if err := c.DeleteNodeGroups(ctx, clusterName, failedNodeGroupNames); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("Waiting for failed node groups to be deleted: %s\n", removedNodeGroupNames))
+		c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseWaiting, removedNodeGroupNames...)
+		if err := c.WaitForNodeGroups(ctx, clusterName, removedNodeGroupNames, NodeGroupConditionDeleted); err != nil {
+			return err
+		}
+		c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseSucceeded, removedNodeGroupNames...)
+		var remainingNodeGroupNames []string
+		for _, nodeGroupName := range nodeGroupNames {
+			if !containsString(removedNodeGroupNames, nodeGroupName) {
+				remainingNodeGroupNames = append(remainingNodeGroupNames, nodeGroupName)
+			}
+		}
+		nodeGroupNames = remainingNodeGroupNames
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.NodeGroupNames = nodeGroupNames
+		})
+	}
+
+	if len(nodeGroupNames) == 0 {
+		c.sendEvent(Event{Kind: ResourceKindNodeGroup, Action: EventActionCreate, Phase: EventPhaseStarted})
+		nodeGroups, err := c.CreateNodeGroups(ctx, &stack.mapTags, clusterName, stack.config.KubernetesVersion,
+			stack.inventory.WorkerRole.RoleARN, getPrivateSubnetIDs(stack.availabilityZones()),
+			stack.config.InstanceTypes, stack.config.InitialNodes, stack.config.MinNodes,
+			stack.config.MaxNodes, stack.config.KeyPair)
+		if nodeGroups != nil {
+			for _, nodeGroup := range *nodeGroups {
+				nodeGroupNames = append(nodeGroupNames, *nodeGroup.NodegroupName)
+			}
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				inventory.NodeGroupNames = nodeGroupNames
+			})
+		}
+		if err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("EKS node group created: %s\n", nodeGroupNames))
+	} else {
+		c.sendMessage(fmt.Sprintf("EKS node group already exists: %s\n", nodeGroupNames))
+	}
+
+	c.sendMessage(fmt.Sprintf("Waiting for EKS node group to become active: %s\n", nodeGroupNames))
+	c.sendResourceEvents(ResourceKindNodeGroup, EventActionCreate, EventPhaseWaiting, nodeGroupNames...)
+	if err := c.WaitForNodeGroups(ctx, clusterName, nodeGroupNames, NodeGroupConditionCreated); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("EKS node group ready: %s\n", nodeGroupNames))
+	c.sendResourceEvents(ResourceKindNodeGroup, EventActionCreate, EventPhaseSucceeded, nodeGroupNames...)
+
+	return nil
+}
+
+// deleteStackNodeGroups deletes the node groups and waits for them to be
+// deleted.
+func (c *ResourceClient) deleteStackNodeGroups(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	nodeGroupNames := stack.inventory.NodeGroupNames
+	c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseStarted, nodeGroupNames...)
+	if err := c.DeleteNodeGroups(ctx, clusterName, nodeGroupNames); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Node groups deletion initiated: %s\n", nodeGroupNames))
+	c.sendMessage(fmt.Sprintf("Waiting for node groups to be deleted: %s\n", nodeGroupNames))
+	c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseWaiting, nodeGroupNames...)
+	if err := c.WaitForNodeGroups(ctx, clusterName, nodeGroupNames, NodeGroupConditionDeleted); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Node groups deletion complete: %s\n", nodeGroupNames))
+	c.sendResourceEvents(ResourceKindNodeGroup, EventActionDelete, EventPhaseSucceeded, nodeGroupNames...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.NodeGroupNames = []string{}
+	})
+
+	return nil
+}
+
+// createStackOIDCProvider creates the OIDC provider for the cluster if it is
+// not in the inventory.
+func (c *ResourceClient) createStackOIDCProvider(ctx context.Context, stack *resourceStack) error {
+	if stack.inventory.OIDCProviderARN != "" {
+		c.sendMessage(fmt.Sprintf("OIDC provider already exists: %s\n", stack.inventory.OIDCProviderARN))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindOIDCProvider, Action: EventActionCreate, Phase: EventPhaseStarted})
+	oidcProviderARN, err := c.CreateOIDCProvider(ctx, stack.iamTags, stack.inventory.Cluster.OIDCProviderURL)
+	if oidcProviderARN != "" {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.OIDCProviderARN = oidcProviderARN
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("OIDC provider created: %s\n", oidcProviderARN))
+	c.sendResourceEvents(ResourceKindOIDCProvider, EventActionCreate, EventPhaseSucceeded, oidcProviderARN)
+
+	return nil
+}
+
+// deleteStackOIDCProvider deletes the OIDC provider.
+func (c *ResourceClient) deleteStackOIDCProvider(ctx context.Context, stack *resourceStack) error {
+	c.sendResourceEvents(ResourceKindOIDCProvider, EventActionDelete, EventPhaseStarted, stack.inventory.OIDCProviderARN)
+	if err := c.DeleteOIDCProvider(ctx, stack.inventory.OIDCProviderARN); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("OIDC provider deleted: %s\n", stack.inventory.OIDCProviderARN))
+	c.sendResourceEvents(ResourceKindOIDCProvider, EventActionDelete, EventPhaseSucceeded, stack.inventory.OIDCProviderARN)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.OIDCProviderARN = ""
+	})
+
+	return nil
+}
+
+// createStackDNSManagementRole creates the IAM role for DNS management if
+// enabled and not in the inventory.
+func (c *ResourceClient) createStackDNSManagementRole(ctx context.Context, stack *resourceStack) error {
+	if !stack.config.DNSManagement {
+		return nil
+	}
+
+	dnsPolicyARN := findPolicyARN(stack.inventory.PolicyARNs, fmt.Sprintf("%s-%s", DNSPolicyName, stack.config.Name))
+	if dnsPolicyARN == "" {
+		return errors.New("no DNS policy ARN to attach to DNS management role")
+	}
+	if role := stack.inventory.DNSManagementRole; role.RoleName != "" {
+		if err := c.attachRolePolicies(ctx, role); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("IAM role for DNS management already exists: %s\n", role.RoleName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	dnsManagementRole, err := c.CreateDNSManagementRole(ctx, stack.iamTags, dnsPolicyARN,
+		stack.config.AWSAccountID, stack.inventory.Cluster.OIDCProviderURL,
+		&stack.config.DNSManagementServiceAccount, stack.config.Name)
+	if dnsManagementRole != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.DNSManagementRole = RoleInventory{
+				RoleName:       *dnsManagementRole.RoleName,
+				RoleARN:        *dnsManagementRole.Arn,
+				RolePolicyARNs: []string{dnsPolicyARN},
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for DNS management created: %s\n", *dnsManagementRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *dnsManagementRole.RoleName)
+
+	return nil
+}
+
+// deleteStackDNSManagementRole deletes the IAM role for DNS management.
+func (c *ResourceClient) deleteStackDNSManagementRole(ctx context.Context, stack *resourceStack) error {
+	return c.deleteStackRole(ctx, stack, &stack.inventory.DNSManagementRole, "DNS management")
+}
+
+// createStackDNS01ChallengeRole creates the IAM role for DNS01 challenges if
+// enabled and not in the inventory.
+func (c *ResourceClient) createStackDNS01ChallengeRole(ctx context.Context, stack *resourceStack) error {
+	if !stack.config.DNS01Challenge {
+		return nil
+	}
+
+	dns01ChallengePolicyARN := findPolicyARN(stack.inventory.PolicyARNs,
+		fmt.Sprintf("%s-%s", DNS01ChallengePolicyName, stack.config.Name))
+	if dns01ChallengePolicyARN == "" {
+		return errors.New("no DNS01 challenge policy ARN to attach to DNS challenge role")
+	}
+	if role := stack.inventory.DNS01ChallengeRole; role.RoleName != "" {
+		if err := c.attachRolePolicies(ctx, role); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("IAM role for DNS01 challenges already exists: %s\n", role.RoleName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	dns01ChallengeRole, err := c.CreateDNS01ChallengeRole(ctx, stack.iamTags, dns01ChallengePolicyARN,
+		stack.config.AWSAccountID, stack.inventory.Cluster.OIDCProviderURL,
+		&stack.config.DNS01ChallengeServiceAccount, stack.config.Name)
+	if dns01ChallengeRole != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.DNS01ChallengeRole = RoleInventory{
+				RoleName:       *dns01ChallengeRole.RoleName,
+				RoleARN:        *dns01ChallengeRole.Arn,
+				RolePolicyARNs: []string{dns01ChallengePolicyARN},
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for DNS01 challenges created: %s\n", *dns01ChallengeRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *dns01ChallengeRole.RoleName)
+
+	return nil
+}
+
+// deleteStackDNS01ChallengeRole deletes the IAM role for DNS01 challenges.
+func (c *ResourceClient) deleteStackDNS01ChallengeRole(ctx context.Context, stack *resourceStack) error {
+	return c.deleteStackRole(ctx, stack, &stack.inventory.DNS01ChallengeRole, "DNS01 challenges")
+}
+
+// createStackClusterAutoscalingRole creates the IAM role for cluster
+// autoscaling if enabled and not in the inventory.
+func (c *ResourceClient) createStackClusterAutoscalingRole(ctx context.Context, stack *resourceStack) error {
+	if !stack.config.ClusterAutoscaling {
+		return nil
+	}
+
+	clusterAutoscalingPolicyARN := findPolicyARN(stack.inventory.PolicyARNs,
+		fmt.Sprintf("%s-%s", AutoscalingPolicyName, stack.config.Name))
+	if clusterAutoscalingPolicyARN == "" {
+		return errors.New("no cluster autoscaling policy ARN to attach to cluster autoscaling role")
+	}
+	if role := stack.inventory.ClusterAutoscalingRole; role.RoleName != "" {
+		if err := c.attachRolePolicies(ctx, role); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("IAM role for cluster autoscaling already exists: %s\n", role.RoleName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	clusterAutoscalingRole, err := c.CreateClusterAutoscalingRole(ctx, stack.iamTags, clusterAutoscalingPolicyARN,
+		stack.config.AWSAccountID, stack.inventory.Cluster.OIDCProviderURL,
+		&stack.config.ClusterAutoscalingServiceAccount, stack.config.Name)
+	if clusterAutoscalingRole != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.ClusterAutoscalingRole = RoleInventory{
+				RoleName:       *clusterAutoscalingRole.RoleName,
+				RoleARN:        *clusterAutoscalingRole.Arn,
+				RolePolicyARNs: []string{*clusterAutoscalingRole.PermissionsBoundary.PermissionsBoundaryArn},
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for cluster autoscaling created: %s\n", *clusterAutoscalingRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *clusterAutoscalingRole.RoleName)
+
+	return nil
+}
+
+// deleteStackClusterAutoscalingRole deletes the IAM role for cluster
+// autoscaling.
+func (c *ResourceClient) deleteStackClusterAutoscalingRole(ctx context.Context, stack *resourceStack) error {
+	return c.deleteStackRole(ctx, stack, &stack.inventory.ClusterAutoscalingRole, "cluster autoscaling")
+}
+
+// createStackStorageManagementRole creates the IAM role for storage
+// management if it is not in the inventory.
+func (c *ResourceClient) createStackStorageManagementRole(ctx context.Context, stack *resourceStack) error {
+	if role := stack.inventory.StorageManagementRole; role.RoleName != "" {
+		if err := c.attachRolePolicies(ctx, role); err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("IAM role for storage management already exists: %s\n", role.RoleName))
+		return nil
+	}
+
+	c.sendEvent(Event{Kind: ResourceKindRole, Action: EventActionCreate, Phase: EventPhaseStarted})
+	storageManagementRole, err := c.CreateStorageManagementRole(ctx, stack.iamTags, stack.config.AWSAccountID,
+		stack.inventory.Cluster.OIDCProviderURL, &stack.config.StorageManagementServiceAccount, stack.config.Name)
+	if storageManagementRole != nil {
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			inventory.StorageManagementRole = RoleInventory{
+				RoleName:       *storageManagementRole.RoleName,
+				RoleARN:        *storageManagementRole.Arn,
+				RolePolicyARNs: []string{*storageManagementRole.PermissionsBoundary.PermissionsBoundaryArn},
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for storage management created: %s\n", *storageManagementRole.RoleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionCreate, EventPhaseSucceeded, *storageManagementRole.RoleName)
+
+	return nil
+}
+
+// deleteStackStorageManagementRole deletes the IAM role for storage
+// management.
+func (c *ResourceClient) deleteStackStorageManagementRole(ctx context.Context, stack *resourceStack) error {
+	return c.deleteStackRole(ctx, stack, &stack.inventory.StorageManagementRole, "storage management")
+}
+
+// deleteStackRole deletes an IAM role for a service account and clears it in
+// the inventory.
+func (c *ResourceClient) deleteStackRole(ctx context.Context, stack *resourceStack, role *RoleInventory, purpose string) error {
+	if role.RoleName == "" {
+		return nil
+	}
+
+	roleName := role.RoleName
+	roles := []RoleInventory{*role}
+	c.sendResourceEvents(ResourceKindRole, EventActionDelete, EventPhaseStarted, roleName)
+	if err := c.DeleteRoles(ctx, &roles); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("IAM role for %s deleted: %s\n", purpose, roleName))
+	c.sendResourceEvents(ResourceKindRole, EventActionDelete, EventPhaseSucceeded, roleName)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		*role = RoleInventory{}
+	})
+
+	return nil
+}
+
+// createStackEBSStorageAddon installs the EBS CSI driver addon if it isn't
+// already installed.
+func (c *ResourceClient) createStackEBSStorageAddon(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	ebsStorageAddon, err := c.getAddon(ctx, clusterName, EBSStorageAddonName)
+	if err != nil && !errors.Is(err, ErrResourceNotFound) {
+		return err
+	}
+
+	switch {
+	case ebsStorageAddon == nil:
+		c.sendEvent(Event{Kind: ResourceKindAddon, Action: EventActionCreate, Phase: EventPhaseStarted})
+		ebsStorageAddon, err = c.CreateEBSStorageAddon(ctx, &stack.mapTags, clusterName,
+			stack.inventory.StorageManagementRole.RoleARN)
+		if ebsStorageAddon != nil {
+			c.updateInventory(stack, func(inventory *ResourceInventory) {
+				if !containsString(inventory.AddonNames, EBSStorageAddonName) {
+					inventory.AddonNames = append(inventory.AddonNames, EBSStorageAddonName)
+				}
+			})
+		}
+		if err != nil {
+			return err
+		}
+		c.sendMessage(fmt.Sprintf("EBS storage addon created: %s\n", *ebsStorageAddon.AddonName))
+		c.sendResourceEvents(ResourceKindAddon, EventActionCreate, EventPhaseSucceeded, *ebsStorageAddon.AddonName)
+	case ebsStorageAddon.Status == ekstypes.AddonStatusCreateFailed:
+		return fmt.Errorf("EBS storage addon %s failed to create on cluster %s", EBSStorageAddonName, clusterName)
+	default:
+		c.updateInventory(stack, func(inventory *ResourceInventory) {
+			if !containsString(inventory.AddonNames, EBSStorageAddonName) {
+				inventory.AddonNames = append(inventory.AddonNames, EBSStorageAddonName)
+			}
+		})
+		c.sendMessage(fmt.Sprintf("EBS storage addon already exists: %s\n", *ebsStorageAddon.AddonName))
+	}
+
+	return nil
+}
+
+// deleteStackAddons deletes the addons and waits for them to be deleted.
+func (c *ResourceClient) deleteStackAddons(ctx context.Context, stack *resourceStack) error {
+	clusterName := stack.inventory.Cluster.ClusterName
+	addonNames := stack.inventory.AddonNames
+	if clusterName == "" || len(addonNames) == 0 {
+		return nil
+	}
+
+	c.sendResourceEvents(ResourceKindAddon, EventActionDelete, EventPhaseStarted, addonNames...)
+	if err := c.DeleteAddons(ctx, clusterName, addonNames); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Addons deletion initiated: %s\n", addonNames))
+	c.sendResourceEvents(ResourceKindAddon, EventActionDelete, EventPhaseWaiting, addonNames...)
+	if err := c.WaitForAddonsDeleted(ctx, clusterName, addonNames); err != nil {
+		return err
+	}
+	c.sendMessage(fmt.Sprintf("Addons deletion complete: %s\n", addonNames))
+	c.sendResourceEvents(ResourceKindAddon, EventActionDelete, EventPhaseSucceeded, addonNames...)
+	c.updateInventory(stack, func(inventory *ResourceInventory) {
+		inventory.AddonNames = []string{}
+	})
+
+	return nil
+}
+
+// stackWorkerPolicyARNs returns the IAM policy ARNs for the worker role - the
+// AWS managed policies plus, for the IPv6 IP family, the CNI IPv6 policy
+// created for the cluster.
+func stackWorkerPolicyARNs(stack *resourceStack) []string {
+	policyARNs := getWorkerPolicyARNs()
+	if stack.config.IPFamily == IPFamilyIPv6 {
+		cniIPv6PolicyARN := findPolicyARN(stack.inventory.PolicyARNs, fmt.Sprintf("%s-%s", CNIIPv6PolicyName, stack.config.Name))
+		if cniIPv6PolicyARN != "" {
+			policyARNs = append(policyARNs, cniIPv6PolicyARN)
+		}
+	}
+
+	return policyARNs
+}
+
+// subnetIPv6CIDRsSet returns true if the private and public subnets in every
+// availability zone have IPv6 CIDR blocks.
+func subnetIPv6CIDRsSet(availabilityZones []AvailabilityZone) bool {
+	for _, az := range availabilityZones {
+		if az.PrivateSubnetIPv6CIDR == "" || az.PublicSubnetIPv6CIDR == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getPrivateSubnetIDs returns the private subnet IDs for the availability
+// zones.
+func getPrivateSubnetIDs(availabilityZones []AvailabilityZone) []string {
+	var privateSubnetIDs []string
+	for _, az := range availabilityZones {
+		privateSubnetIDs = append(privateSubnetIDs, az.PrivateSubnetID)
+	}
+
+	return privateSubnetIDs
+}
+
+// getNATGatewayIDs returns the NAT gateway IDs for the availability zones that
+// have one.
+func getNATGatewayIDs(availabilityZones []AvailabilityZone) []string {
+	var natGatewayIDs []string
+	for _, az := range availabilityZones {
+		if az.NATGatewayID != "" {
+			natGatewayIDs = append(natGatewayIDs, az.NATGatewayID)
+		}
+	}
+
+	return natGatewayIDs
+}
+
+// getRouteTableAssociationIDs returns the IDs of the route table associations
+// for the availability zones' subnets.
+func getRouteTableAssociationIDs(availabilityZones []AvailabilityZone) []string {
+	var associationIDs []string
+	for _, az := range availabilityZones {
+		for _, associationID := range []string{
+			az.PrivateRouteTableAssociationID,
+			az.PublicRouteTableAssociationID,
+			az.PodRouteTableAssociationID,
+		} {
+			if associationID != "" {
+				associationIDs = append(associationIDs, associationID)
+			}
+		}
+	}
+
+	return associationIDs
+}
+
+// mergeRoutes adds routes to a list of recorded routes.  A route replaces a
+// recorded route for the same route table and destination.
+func mergeRoutes(recordedRoutes, routes []RouteInventory) []RouteInventory {
+	merged := append([]RouteInventory{}, recordedRoutes...)
+	for _, route := range routes {
+		replaced := false
+		for i, recordedRoute := range merged {
+			if recordedRoute.RouteTableID == route.RouteTableID && recordedRoute.DestinationCIDR == route.DestinationCIDR {
+				merged[i] = route
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, route)
+		}
+	}
+
+	return merged
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,14 +13,18 @@ import (
 // CreateSubnets creates the subnets used by an EKS clusters.  It creates a
 // public and private subnet for each availability zone being used by the
 // cluster.  It also tags each subnet so the load balancers may be correctly
-// applied to them.
+// applied to them.  Subnets whose IDs are already set on the availability zone
+// are not created again.  Subnets with an IPv6 CIDR block set on the
+// availability zone are dual-stack and assign IPv6 addresses to the network
+// interfaces created in them.
 func (c *ResourceClient) CreateSubnets(
+	ctx context.Context,
 	tags *[]types.Tag,
 	vpcID string,
 	clusterName string,
 	availabilityZones *[]AvailabilityZone,
 ) (*[]types.Subnet, *[]types.Subnet, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	var privateSubnets []types.Subnet
 	var publicSubnets []types.Subnet
@@ -30,7 +35,7 @@ func (c *ResourceClient) CreateSubnets(
 		Key:   &internalELBTagKey,
 		Value: &internalELBTagValue,
 	}
-	privateTags := append(*tags, internalELBTag)
+	privateTags := append(append([]types.Tag{}, *tags...), internalELBTag)
 
 	elbTagKey := "kubernetes.io/role/elb"
 	elbTagValue := "1"
@@ -38,55 +43,87 @@ func (c *ResourceClient) CreateSubnets(
 		Key:   &elbTagKey,
 		Value: &elbTagValue,
 	}
-	publicTags := append(*tags, elbTag)
+	publicTags := append(append([]types.Tag{}, *tags...), elbTag)
 
 	azs := *availabilityZones
 	for i, az := range azs {
-		privateCreateSubnetInput := ec2.CreateSubnetInput{
-			VpcId:            &vpcID,
-			AvailabilityZone: &az.Zone,
-			CidrBlock:        &az.PrivateSubnetCIDR,
-			TagSpecifications: []types.TagSpecification{
-				{
-					ResourceType: types.ResourceTypeSubnet,
-					Tags:         privateTags,
+		if az.PrivateSubnetID == "" {
+			privateCreateSubnetInput := ec2.CreateSubnetInput{
+				VpcId:            &vpcID,
+				AvailabilityZone: &az.Zone,
+				CidrBlock:        &az.PrivateSubnetCIDR,
+				TagSpecifications: []types.TagSpecification{
+					{
+						ResourceType: types.ResourceTypeSubnet,
+						Tags:         privateTags,
+					},
 				},
-			},
-		}
-		privateResp, err := svc.CreateSubnet(c.Context, &privateCreateSubnetInput)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create private subnet for VPC with ID %s: %w", vpcID, err)
+			}
+			if az.PrivateSubnetIPv6CIDR != "" {
+				privateCreateSubnetInput.Ipv6CidrBlock = &azs[i].PrivateSubnetIPv6CIDR
+			}
+			privateResp, err := svc.CreateSubnet(ctx, &privateCreateSubnetInput)
+			if err != nil {
+				return &privateSubnets, &publicSubnets, fmt.Errorf("failed to create private subnet for VPC with ID %s: %w", vpcID, err)
+			}
+			azs[i].PrivateSubnetID = *privateResp.Subnet.SubnetId
+			privateSubnets = append(privateSubnets, *privateResp.Subnet)
 		}
-		azs[i].PrivateSubnetID = *privateResp.Subnet.SubnetId
-		privateSubnets = append(privateSubnets, *privateResp.Subnet)
 
-		publicCreateSubnetInput := ec2.CreateSubnetInput{
-			VpcId:            &vpcID,
-			AvailabilityZone: &az.Zone,
-			CidrBlock:        &az.PublicSubnetCIDR,
-			TagSpecifications: []types.TagSpecification{
-				{
-					ResourceType: types.ResourceTypeSubnet,
-					Tags:         publicTags,
+		if az.PublicSubnetID == "" {
+			publicCreateSubnetInput := ec2.CreateSubnetInput{
+				VpcId:            &vpcID,
+				AvailabilityZone: &az.Zone,
+				CidrBlock:        &az.PublicSubnetCIDR,
+				TagSpecifications: []types.TagSpecification{
+					{
+						ResourceType: types.ResourceTypeSubnet,
+						Tags:         publicTags,
+					},
 				},
-			},
-		}
-		publicResp, err := svc.CreateSubnet(c.Context, &publicCreateSubnetInput)
-		if err != nil {
-			return &privateSubnets, nil, fmt.Errorf("failed to create public subnet for VPC with ID %s: %w", vpcID, err)
+			}
+			if az.PublicSubnetIPv6CIDR != "" {
+				publicCreateSubnetInput.Ipv6CidrBlock = &azs[i].PublicSubnetIPv6CIDR
+			}
+			publicResp, err := svc.CreateSubnet(ctx, &publicCreateSubnetInput)
+			if err != nil {
+				return &privateSubnets, &publicSubnets, fmt.Errorf("failed to create public subnet for VPC with ID %s: %w", vpcID, err)
+			}
+			azs[i].PublicSubnetID = *publicResp.Subnet.SubnetId
+			publicSubnets = append(publicSubnets, *publicResp.Subnet)
 		}
-		azs[i].PublicSubnetID = *publicResp.Subnet.SubnetId
-		publicSubnets = append(publicSubnets, *publicResp.Subnet)
 
 		mapPublicIP := true
 		modifySubnetAttributeInput := ec2.ModifySubnetAttributeInput{
-			SubnetId:            publicResp.Subnet.SubnetId,
+			SubnetId:            &azs[i].PublicSubnetID,
 			MapPublicIpOnLaunch: &types.AttributeBooleanValue{Value: &mapPublicIP},
 		}
-		_, err = svc.ModifySubnetAttribute(c.Context, &modifySubnetAttributeInput)
+		_, err := svc.ModifySubnetAttribute(ctx, &modifySubnetAttributeInput)
 		if err != nil {
 			return &privateSubnets, &publicSubnets, fmt.Errorf("failed to modify subnet attribute for subnet with ID %s: %w",
-				*publicResp.Subnet.SubnetId, err)
+				azs[i].PublicSubnetID, err)
+		}
+
+		// dual-stack subnets assign IPv6 addresses to new network interfaces
+		for _, subnet := range []struct {
+			id       string
+			ipv6CIDR string
+		}{
+			{azs[i].PrivateSubnetID, azs[i].PrivateSubnetIPv6CIDR},
+			{azs[i].PublicSubnetID, azs[i].PublicSubnetIPv6CIDR},
+		} {
+			if subnet.ipv6CIDR == "" {
+				continue
+			}
+			assignIPv6Address := true
+			modifySubnetAttributeInput := ec2.ModifySubnetAttributeInput{
+				SubnetId:                    &subnet.id,
+				AssignIpv6AddressOnCreation: &types.AttributeBooleanValue{Value: &assignIPv6Address},
+			}
+			if _, err := svc.ModifySubnetAttribute(ctx, &modifySubnetAttributeInput); err != nil {
+				return &privateSubnets, &publicSubnets, fmt.Errorf("failed to modify subnet attribute for subnet with ID %s: %w",
+					subnet.id, err)
+			}
 		}
 	}
 	availabilityZones = &azs
@@ -94,19 +131,61 @@ func (c *ResourceClient) CreateSubnets(
 	return &privateSubnets, &publicSubnets, nil
 }
 
+// CreatePodSubnets creates the pod subnets used by VPC CNI custom networking.
+// It creates a subnet for each availability zone with a pod subnet CIDR block
+// in the VPC's secondary CIDR.  The pod subnets aren't tagged for load
+// balancers as only pods get addresses in them.  Subnets whose IDs are
+// already set on the availability zone are not created again.
+func (c *ResourceClient) CreatePodSubnets(
+	ctx context.Context,
+	tags *[]types.Tag,
+	vpcID string,
+	availabilityZones *[]AvailabilityZone,
+) (*[]types.Subnet, error) {
+	svc := c.ec2Client()
+
+	var podSubnets []types.Subnet
+
+	azs := *availabilityZones
+	for i, az := range azs {
+		if az.PodSubnetCIDR == "" || az.PodSubnetID != "" {
+			continue
+		}
+		podCreateSubnetInput := ec2.CreateSubnetInput{
+			VpcId:            &vpcID,
+			AvailabilityZone: &az.Zone,
+			CidrBlock:        &azs[i].PodSubnetCIDR,
+			TagSpecifications: []types.TagSpecification{
+				{
+					ResourceType: types.ResourceTypeSubnet,
+					Tags:         *tags,
+				},
+			},
+		}
+		podResp, err := svc.CreateSubnet(ctx, &podCreateSubnetInput)
+		if err != nil {
+			return &podSubnets, fmt.Errorf("failed to create pod subnet for VPC with ID %s: %w", vpcID, err)
+		}
+		azs[i].PodSubnetID = *podResp.Subnet.SubnetId
+		podSubnets = append(podSubnets, *podResp.Subnet)
+	}
+
+	return &podSubnets, nil
+}
+
 // DeleteSubnets deletes the subnets used by the EKS cluster.  If no subnet IDs
 // are supplied, or if the subnets are not found it returns without error.
-func (c *ResourceClient) DeleteSubnets(subnetIDs []string) error {
+func (c *ResourceClient) DeleteSubnets(ctx context.Context, subnetIDs []string) error {
 	// if there are no subnet IDs there is nothing to do
 	if len(subnetIDs) == 0 {
 		return nil
 	}
 
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	for _, id := range subnetIDs {
 		deleteSubnetInput := ec2.DeleteSubnetInput{SubnetId: &id}
-		_, err := svc.DeleteSubnet(c.Context, &deleteSubnetInput)
+		_, err := svc.DeleteSubnet(ctx, &deleteSubnetInput)
 		if err != nil {
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
@@ -125,3 +204,35 @@ func (c *ResourceClient) DeleteSubnets(subnetIDs []string) error {
 
 	return nil
 }
+
+// getSubnets retrieves the subnets in a VPC with the given IDs.  Subnets that
+// are not found are not included in the result.
+func (c *ResourceClient) getSubnets(ctx context.Context, vpcID string, subnetIDs []string) ([]types.Subnet, error) {
+	// if there are no subnet IDs there is nothing to get
+	if len(subnetIDs) == 0 {
+		return []types.Subnet{}, nil
+	}
+
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	subnetFilterName := "subnet-id"
+	describeSubnetsInput := ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &vpcFilterName,
+				Values: []string{vpcID},
+			},
+			{
+				Name:   &subnetFilterName,
+				Values: subnetIDs,
+			},
+		},
+	}
+	resp, err := svc.DescribeSubnets(ctx, &describeSubnetsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnets for VPC with ID %s: %w", vpcID, err)
+	}
+
+	return resp.Subnets, nil
+}
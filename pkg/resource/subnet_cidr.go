@@ -0,0 +1,194 @@
+package resource
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"sort"
+)
+
+const (
+	// DefaultSubnetPrefixLength is the prefix length of the subnets carved out
+	// of the cluster CIDR unless another is configured or the cluster CIDR is
+	// too small for it.
+	DefaultSubnetPrefixLength = int32(22)
+
+	// the range of prefix lengths AWS allows for VPC and subnet CIDR blocks
+	minCIDRPrefixLength = 16
+	maxCIDRPrefixLength = 28
+)
+
+// subnetCIDRBlock is a subnet CIDR block to be carved out of the cluster CIDR
+// for an availability zone.
+type subnetCIDRBlock struct {
+	azIndex      int
+	tier         string
+	prefixLength int
+}
+
+// setSubnetCIDRs sets the CIDR blocks for the private and public subnets in
+// each availability zone that doesn't already have them.  They are carved out
+// of the cluster CIDR using the configured prefix lengths, avoiding the CIDR
+// blocks already set.  All subnet CIDR blocks must be inside the cluster CIDR
+// and must not overlap.
+func (r *ResourceConfig) setSubnetCIDRs() error {
+	clusterCIDR, err := netip.ParsePrefix(r.ClusterCIDR)
+	if err != nil || !clusterCIDR.Addr().Is4() {
+		return fmt.Errorf("cluster CIDR %q is not a valid IPv4 CIDR block", r.ClusterCIDR)
+	}
+	if clusterCIDR.Bits() < minCIDRPrefixLength || clusterCIDR.Bits() > maxCIDRPrefixLength {
+		return fmt.Errorf("cluster CIDR %s must have a prefix length between /%d and /%d",
+			r.ClusterCIDR, minCIDRPrefixLength, maxCIDRPrefixLength)
+	}
+	clusterCIDR = clusterCIDR.Masked()
+
+	privatePrefixLength, err := subnetPrefixLength(r.PrivateSubnetPrefixLength, clusterCIDR, len(r.AvailabilityZones))
+	if err != nil {
+		return fmt.Errorf("invalid private subnet prefix length: %w", err)
+	}
+	publicPrefixLength, err := subnetPrefixLength(r.PublicSubnetPrefixLength, clusterCIDR, len(r.AvailabilityZones))
+	if err != nil {
+		return fmt.Errorf("invalid public subnet prefix length: %w", err)
+	}
+
+	// check the CIDR blocks that are already set and collect those that need
+	// to be carved out
+	azs := r.AvailabilityZones
+	var usedCIDRs []netip.Prefix
+	var blocks []subnetCIDRBlock
+	for i, az := range azs {
+		for _, block := range []struct {
+			tier         string
+			cidr         string
+			prefixLength int
+		}{
+			{"private", az.PrivateSubnetCIDR, privatePrefixLength},
+			{"public", az.PublicSubnetCIDR, publicPrefixLength},
+		} {
+			if block.cidr == "" {
+				blocks = append(blocks, subnetCIDRBlock{azIndex: i, tier: block.tier, prefixLength: block.prefixLength})
+				continue
+			}
+			cidr, err := netip.ParsePrefix(block.cidr)
+			if err != nil || !cidr.Addr().Is4() || cidr != cidr.Masked() {
+				return fmt.Errorf("%s subnet CIDR %q for availability zone %s is not a valid IPv4 CIDR block",
+					block.tier, block.cidr, az.Zone)
+			}
+			if cidr.Bits() < clusterCIDR.Bits() || cidr.Bits() > maxCIDRPrefixLength || !clusterCIDR.Contains(cidr.Addr()) {
+				return fmt.Errorf("%s subnet CIDR %s for availability zone %s is not within cluster CIDR %s",
+					block.tier, block.cidr, az.Zone, clusterCIDR)
+			}
+			for _, usedCIDR := range usedCIDRs {
+				if cidr.Overlaps(usedCIDR) {
+					return fmt.Errorf("%s subnet CIDR %s for availability zone %s overlaps subnet CIDR %s",
+						block.tier, block.cidr, az.Zone, usedCIDR)
+				}
+			}
+			usedCIDRs = append(usedCIDRs, cidr)
+		}
+	}
+
+	// the largest subnets are carved out first so that aligning each subnet
+	// to its size leaves no gaps between them
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].prefixLength < blocks[j].prefixLength
+	})
+	next := ipv4ToUint64(clusterCIDR.Addr())
+	for _, block := range blocks {
+		cidr, err := carveSubnetCIDR(clusterCIDR, &next, block.prefixLength, usedCIDRs)
+		if err != nil {
+			return fmt.Errorf("failed to carve out %s subnet CIDR for availability zone %s: %w",
+				block.tier, azs[block.azIndex].Zone, err)
+		}
+		usedCIDRs = append(usedCIDRs, cidr)
+		if block.tier == "private" {
+			azs[block.azIndex].PrivateSubnetCIDR = cidr.String()
+		} else {
+			azs[block.azIndex].PublicSubnetCIDR = cidr.String()
+		}
+	}
+
+	return nil
+}
+
+// subnetPrefixLength returns the prefix length to use for subnets carved out
+// of the cluster CIDR.  If none is configured, the default is used unless the
+// cluster CIDR is too small to hold a private and public subnet of that size
+// for each availability zone, in which case the largest size that fits is
+// used.
+func subnetPrefixLength(configured int32, clusterCIDR netip.Prefix, azCount int) (int, error) {
+	if configured == 0 {
+		// a private and public subnet for each availability zone
+		if azCount < 1 {
+			azCount = 1
+		}
+		subnetBits := bits.Len(uint(2*azCount - 1))
+		prefixLength := clusterCIDR.Bits() + subnetBits
+		if prefixLength < int(DefaultSubnetPrefixLength) {
+			prefixLength = int(DefaultSubnetPrefixLength)
+		}
+		if prefixLength > maxCIDRPrefixLength {
+			return 0, fmt.Errorf("cluster CIDR %s is too small for %d availability zones", clusterCIDR, azCount)
+		}
+		return prefixLength, nil
+	}
+
+	if int(configured) < clusterCIDR.Bits() || configured > maxCIDRPrefixLength {
+		return 0, fmt.Errorf("/%d must be between the cluster CIDR's /%d and /%d",
+			configured, clusterCIDR.Bits(), maxCIDRPrefixLength)
+	}
+
+	return int(configured), nil
+}
+
+// carveSubnetCIDR returns the first CIDR block with the given prefix length in
+// the parent CIDR block, at or after next, that doesn't overlap the used CIDR
+// blocks.  next is moved past the returned CIDR block.
+func carveSubnetCIDR(parentCIDR netip.Prefix, next *uint64, prefixLength int, usedCIDRs []netip.Prefix) (netip.Prefix, error) {
+	parentEnd := ipv4ToUint64(parentCIDR.Addr()) + cidrSize(parentCIDR.Bits())
+	size := cidrSize(prefixLength)
+	start := *next
+	for {
+		// align the start to the subnet's size
+		start = (start + size - 1) / size * size
+		if start+size > parentEnd {
+			return netip.Prefix{}, fmt.Errorf("no room left for a /%d subnet in CIDR %s", prefixLength, parentCIDR)
+		}
+		cidr := netip.PrefixFrom(uint64ToIPv4(start), prefixLength)
+
+		overlapped := false
+		for _, usedCIDR := range usedCIDRs {
+			if cidr.Overlaps(usedCIDR) {
+				start = ipv4ToUint64(usedCIDR.Addr()) + cidrSize(usedCIDR.Bits())
+				overlapped = true
+				break
+			}
+		}
+		if !overlapped {
+			*next = start + size
+			return cidr, nil
+		}
+	}
+}
+
+// cidrSize returns the number of addresses in an IPv4 CIDR block with the
+// given prefix length.
+func cidrSize(prefixLength int) uint64 {
+	return uint64(1) << (32 - prefixLength)
+}
+
+// ipv4ToUint64 returns an IPv4 address as an integer.
+func ipv4ToUint64(addr netip.Addr) uint64 {
+	a := addr.As4()
+
+	return uint64(binary.BigEndian.Uint32(a[:]))
+}
+
+// uint64ToIPv4 returns the IPv4 address for an integer.
+func uint64ToIPv4(value uint64) netip.Addr {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], uint32(value))
+
+	return netip.AddrFrom4(a)
+}
@@ -0,0 +1,306 @@
+package resource
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testAvailabilityZones returns availability zones with the given names.
+func testAvailabilityZones(zones ...string) []AvailabilityZone {
+	var azs []AvailabilityZone
+	for _, zone := range zones {
+		azs = append(azs, AvailabilityZone{Zone: zone})
+	}
+
+	return azs
+}
+
+func TestSetSubnetCIDRs(t *testing.T) {
+	testCases := []struct {
+		name                string
+		clusterCIDR         string
+		privatePrefixLength int32
+		publicPrefixLength  int32
+		availabilityZones   []AvailabilityZone
+		wantPrivate         []string
+		wantPublic          []string
+		wantErr             string
+	}{
+		{
+			name:              "default cluster CIDR",
+			clusterCIDR:       "10.0.0.0/16",
+			availabilityZones: testAvailabilityZones("a", "b", "c"),
+			wantPrivate:       []string{"10.0.0.0/22", "10.0.8.0/22", "10.0.16.0/22"},
+			wantPublic:        []string{"10.0.4.0/22", "10.0.12.0/22", "10.0.20.0/22"},
+		},
+		{
+			name:              "non-default cluster CIDR",
+			clusterCIDR:       "172.20.0.0/16",
+			availabilityZones: testAvailabilityZones("a", "b"),
+			wantPrivate:       []string{"172.20.0.0/22", "172.20.8.0/22"},
+			wantPublic:        []string{"172.20.4.0/22", "172.20.12.0/22"},
+		},
+		{
+			name:              "unmasked cluster CIDR",
+			clusterCIDR:       "172.20.1.0/16",
+			availabilityZones: testAvailabilityZones("a"),
+			wantPrivate:       []string{"172.20.0.0/22"},
+			wantPublic:        []string{"172.20.4.0/22"},
+		},
+		{
+			name:              "small cluster CIDR",
+			clusterCIDR:       "10.0.0.0/20",
+			availabilityZones: testAvailabilityZones("a", "b", "c"),
+			wantPrivate:       []string{"10.0.0.0/23", "10.0.4.0/23", "10.0.8.0/23"},
+			wantPublic:        []string{"10.0.2.0/23", "10.0.6.0/23", "10.0.10.0/23"},
+		},
+		{
+			name:                "mixed prefix lengths",
+			clusterCIDR:         "10.0.0.0/16",
+			privatePrefixLength: 20,
+			publicPrefixLength:  24,
+			availabilityZones:   testAvailabilityZones("a", "b"),
+			wantPrivate:         []string{"10.0.0.0/20", "10.0.16.0/20"},
+			wantPublic:          []string{"10.0.32.0/24", "10.0.33.0/24"},
+		},
+		{
+			name:        "preset CIDR overlapping carved ranges",
+			clusterCIDR: "10.0.0.0/16",
+			availabilityZones: []AvailabilityZone{
+				{Zone: "a", PrivateSubnetCIDR: "10.0.4.0/22"},
+				{Zone: "b", PublicSubnetCIDR: "10.0.9.0/24"},
+			},
+			wantPrivate: []string{"10.0.4.0/22", "10.0.12.0/22"},
+			wantPublic:  []string{"10.0.0.0/22", "10.0.9.0/24"},
+		},
+		{
+			name:        "overlapping preset CIDRs",
+			clusterCIDR: "10.0.0.0/16",
+			availabilityZones: []AvailabilityZone{
+				{Zone: "a", PrivateSubnetCIDR: "10.0.0.0/22"},
+				{Zone: "b", PrivateSubnetCIDR: "10.0.2.0/24"},
+			},
+			wantErr: "private subnet CIDR 10.0.2.0/24 for availability zone b overlaps subnet CIDR 10.0.0.0/22",
+		},
+		{
+			name:        "preset CIDR outside cluster CIDR",
+			clusterCIDR: "10.0.0.0/16",
+			availabilityZones: []AvailabilityZone{
+				{Zone: "a", PublicSubnetCIDR: "10.1.0.0/22"},
+			},
+			wantErr: "public subnet CIDR 10.1.0.0/22 for availability zone a is not within cluster CIDR 10.0.0.0/16",
+		},
+		{
+			name:        "unmasked preset CIDR",
+			clusterCIDR: "10.0.0.0/16",
+			availabilityZones: []AvailabilityZone{
+				{Zone: "a", PublicSubnetCIDR: "10.0.1.0/22"},
+			},
+			wantErr: "public subnet CIDR \"10.0.1.0/22\" for availability zone a is not a valid IPv4 CIDR block",
+		},
+		{
+			name:              "cluster CIDR too small",
+			clusterCIDR:       "10.0.0.0/26",
+			availabilityZones: testAvailabilityZones("a", "b", "c"),
+			wantErr:           "cluster CIDR 10.0.0.0/26 is too small for 3 availability zones",
+		},
+		{
+			name:                "no room for configured prefix lengths",
+			clusterCIDR:         "10.0.0.0/24",
+			privatePrefixLength: 25,
+			publicPrefixLength:  25,
+			availabilityZones:   testAvailabilityZones("a", "b"),
+			wantErr:             "no room left for a /25 subnet in CIDR 10.0.0.0/24",
+		},
+		{
+			name:                "configured prefix length larger than cluster CIDR",
+			clusterCIDR:         "10.0.0.0/20",
+			privatePrefixLength: 18,
+			availabilityZones:   testAvailabilityZones("a"),
+			wantErr:             "invalid private subnet prefix length: /18 must be between the cluster CIDR's /20 and /28",
+		},
+		{
+			name:              "cluster CIDR prefix length out of range",
+			clusterCIDR:       "10.0.0.0/8",
+			availabilityZones: testAvailabilityZones("a"),
+			wantErr:           "must have a prefix length between /16 and /28",
+		},
+		{
+			name:              "IPv6 cluster CIDR",
+			clusterCIDR:       "fd00::/56",
+			availabilityZones: testAvailabilityZones("a"),
+			wantErr:           "is not a valid IPv4 CIDR block",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := ResourceConfig{
+				ClusterCIDR:               tc.clusterCIDR,
+				PrivateSubnetPrefixLength: tc.privatePrefixLength,
+				PublicSubnetPrefixLength:  tc.publicPrefixLength,
+				AvailabilityZones:         tc.availabilityZones,
+			}
+			err := r.setSubnetCIDRs()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to set subnet CIDRs: %v", err)
+			}
+
+			var private, public []string
+			for _, az := range r.AvailabilityZones {
+				private = append(private, az.PrivateSubnetCIDR)
+				public = append(public, az.PublicSubnetCIDR)
+			}
+			if !reflect.DeepEqual(private, tc.wantPrivate) {
+				t.Errorf("expected private subnet CIDRs %v, got %v", tc.wantPrivate, private)
+			}
+			if !reflect.DeepEqual(public, tc.wantPublic) {
+				t.Errorf("expected public subnet CIDRs %v, got %v", tc.wantPublic, public)
+			}
+		})
+	}
+}
+
+func TestSubnetPrefixLength(t *testing.T) {
+	testCases := []struct {
+		name        string
+		configured  int32
+		clusterCIDR string
+		azCount     int
+		want        int
+		wantErr     bool
+	}{
+		{
+			name:        "default",
+			clusterCIDR: "10.0.0.0/16",
+			azCount:     3,
+			want:        int(DefaultSubnetPrefixLength),
+		},
+		{
+			name:        "no availability zones",
+			clusterCIDR: "10.0.0.0/26",
+			want:        27,
+		},
+		{
+			name:        "largest size that fits",
+			clusterCIDR: "10.0.0.0/21",
+			azCount:     4,
+			want:        24,
+		},
+		{
+			name:        "too small",
+			clusterCIDR: "10.0.0.0/27",
+			azCount:     2,
+			wantErr:     true,
+		},
+		{
+			name:        "configured",
+			configured:  26,
+			clusterCIDR: "10.0.0.0/16",
+			azCount:     3,
+			want:        26,
+		},
+		{
+			name:        "configured too small",
+			configured:  29,
+			clusterCIDR: "10.0.0.0/16",
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := subnetPrefixLength(tc.configured, netip.MustParsePrefix(tc.clusterCIDR), tc.azCount)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got /%d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get subnet prefix length: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected /%d, got /%d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCarveSubnetCIDR(t *testing.T) {
+	parentCIDR := netip.MustParsePrefix("10.0.0.0/16")
+	testCases := []struct {
+		name         string
+		next         string
+		prefixLength int
+		usedCIDRs    []string
+		want         string
+		wantNext     string
+		wantErr      bool
+	}{
+		{
+			name:         "start of parent",
+			next:         "10.0.0.0",
+			prefixLength: 22,
+			want:         "10.0.0.0/22",
+			wantNext:     "10.0.4.0",
+		},
+		{
+			name:         "aligned to size",
+			next:         "10.0.1.0",
+			prefixLength: 22,
+			want:         "10.0.4.0/22",
+			wantNext:     "10.0.8.0",
+		},
+		{
+			name:         "after used CIDRs",
+			next:         "10.0.0.0",
+			prefixLength: 24,
+			usedCIDRs:    []string{"10.0.0.0/24", "10.0.1.0/25"},
+			want:         "10.0.2.0/24",
+			wantNext:     "10.0.3.0",
+		},
+		{
+			name:         "no room left",
+			next:         "10.0.252.0",
+			prefixLength: 22,
+			usedCIDRs:    []string{"10.0.252.0/24"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := ipv4ToUint64(netip.MustParseAddr(tc.next))
+			var usedCIDRs []netip.Prefix
+			for _, usedCIDR := range tc.usedCIDRs {
+				usedCIDRs = append(usedCIDRs, netip.MustParsePrefix(usedCIDR))
+			}
+
+			got, err := carveSubnetCIDR(parentCIDR, &next, tc.prefixLength, usedCIDRs)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to carve subnet CIDR: %v", err)
+			}
+			if got.String() != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+			if nextAddr := uint64ToIPv4(next).String(); nextAddr != tc.wantNext {
+				t.Errorf("expected next %s, got %s", tc.wantNext, nextAddr)
+			}
+		})
+	}
+}
@@ -25,6 +25,18 @@ func CreateEC2Tags(name string, tags map[string]string) *[]ec2types.Tag {
 	return &ec2Tags
 }
 
+// ec2TagMap returns EC2 tags in map[string]string format.
+func ec2TagMap(tags []ec2types.Tag) map[string]string {
+	tagMap := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			tagMap[*tag.Key] = *tag.Value
+		}
+	}
+
+	return tagMap
+}
+
 // CreateIAMTags creates tags for IAM resources.
 func CreateIAMTags(name string, tags map[string]string) *[]iamtypes.Tag {
 	nameKey := "Name"
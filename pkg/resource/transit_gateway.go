@@ -0,0 +1,159 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+type TransitGatewayAttachmentCondition string
+
+const (
+	TransitGatewayAttachmentConditionCreated = "TransitGatewayAttachmentCreated"
+	TransitGatewayAttachmentConditionDeleted = "TransitGatewayAttachmentDeleted"
+	TransitGatewayAttachmentCheckInterval    = 15 // check transit gateway attachment status every 15 seconds
+	TransitGatewayAttachmentCheckMaxCount    = 40 // check 40 times before giving up (10 minutes)
+)
+
+// CreateTransitGatewayAttachment attaches a VPC to an existing transit
+// gateway.  The attachment is given a network interface in each of the
+// subnets, which must be in different availability zones.
+func (c *ResourceClient) CreateTransitGatewayAttachment(
+	ctx context.Context,
+	tags *[]types.Tag,
+	transitGatewayID string,
+	vpcID string,
+	subnetIDs []string,
+) (string, error) {
+	svc := c.ec2Client()
+
+	createTransitGatewayVpcAttachmentInput := ec2.CreateTransitGatewayVpcAttachmentInput{
+		TransitGatewayId: &transitGatewayID,
+		VpcId:            &vpcID,
+		SubnetIds:        subnetIDs,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeTransitGatewayAttachment,
+				Tags:         *tags,
+			},
+		},
+	}
+	resp, err := svc.CreateTransitGatewayVpcAttachment(ctx, &createTransitGatewayVpcAttachmentInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to attach VPC with ID %s to transit gateway with ID %s: %w",
+			vpcID, transitGatewayID, err)
+	}
+
+	return *resp.TransitGatewayVpcAttachment.TransitGatewayAttachmentId, nil
+}
+
+// DeleteTransitGatewayAttachment detaches a VPC from a transit gateway by
+// deleting the attachment.  If an empty attachment ID is supplied, or if the
+// attachment is not found, it returns without error.
+func (c *ResourceClient) DeleteTransitGatewayAttachment(ctx context.Context, attachmentID string) error {
+	// if attachmentID is empty, there's nothing to delete
+	if attachmentID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteTransitGatewayVpcAttachmentInput := ec2.DeleteTransitGatewayVpcAttachmentInput{
+		TransitGatewayAttachmentId: &attachmentID,
+	}
+	_, err := svc.DeleteTransitGatewayVpcAttachment(ctx, &deleteTransitGatewayVpcAttachmentInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "InvalidTransitGatewayAttachmentID.NotFound" {
+			// attempting to delete an attachment that doesn't exist so return
+			// without error
+			return nil
+		}
+		return fmt.Errorf("failed to delete transit gateway attachment with ID %s: %w", attachmentID, err)
+	}
+
+	return nil
+}
+
+// WaitForTransitGatewayAttachment waits for a transit gateway attachment to
+// reach a given condition.  One of:
+// * TransitGatewayAttachmentConditionCreated
+// * TransitGatewayAttachmentConditionDeleted
+// An attachment to a transit gateway shared from another account may need to
+// be accepted by the transit gateway's owner before it becomes available.
+func (c *ResourceClient) WaitForTransitGatewayAttachment(
+	ctx context.Context,
+	attachmentID string,
+	attachmentCondition TransitGatewayAttachmentCondition,
+) error {
+	attachmentCheckCount := 0
+
+	for {
+		attachmentCheckCount += 1
+		if attachmentCheckCount > TransitGatewayAttachmentCheckMaxCount {
+			return errors.New("transit gateway attachment condition check timed out")
+		}
+
+		attachment, err := c.getTransitGatewayAttachment(ctx, attachmentID)
+		if err != nil && !errors.Is(err, ErrResourceNotFound) {
+			return err
+		}
+
+		if attachmentCondition == TransitGatewayAttachmentConditionDeleted && attachment == nil {
+			break
+		}
+		if attachmentCondition == TransitGatewayAttachmentConditionCreated {
+			if attachment == nil {
+				return fmt.Errorf("transit gateway attachment with ID %s not found", attachmentID)
+			}
+			switch attachment.State {
+			case types.TransitGatewayAttachmentStateAvailable:
+				return nil
+			case types.TransitGatewayAttachmentStateFailed, types.TransitGatewayAttachmentStateFailing,
+				types.TransitGatewayAttachmentStateRejected, types.TransitGatewayAttachmentStateRejecting:
+				return fmt.Errorf("transit gateway attachment with ID %s failed to create: %s",
+					attachmentID, attachment.State)
+			}
+		}
+
+		if err := c.waitCheckInterval(ctx, time.Second*TransitGatewayAttachmentCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for transit gateway attachment with ID %s: %w", attachmentID, err)
+		}
+	}
+
+	return nil
+}
+
+// getTransitGatewayAttachment retrieves the transit gateway attachment with
+// the given ID.  If the attachment is not found, or has been deleted, it
+// returns ErrResourceNotFound.
+func (c *ResourceClient) getTransitGatewayAttachment(
+	ctx context.Context,
+	attachmentID string,
+) (*types.TransitGatewayVpcAttachment, error) {
+	svc := c.ec2Client()
+
+	describeTransitGatewayVpcAttachmentsInput := ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		TransitGatewayAttachmentIds: []string{attachmentID},
+	}
+	resp, err := svc.DescribeTransitGatewayVpcAttachments(ctx, &describeTransitGatewayVpcAttachmentsInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "InvalidTransitGatewayAttachmentID.NotFound" {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to describe transit gateway attachment with ID %s: %w", attachmentID, err)
+	}
+	for _, attachment := range resp.TransitGatewayVpcAttachments {
+		if attachment.State != types.TransitGatewayAttachmentStateDeleted {
+			return &attachment, nil
+		}
+	}
+
+	return nil, ErrResourceNotFound
+}
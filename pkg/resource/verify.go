@@ -0,0 +1,834 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// DriftStatus is the result of checking a resource recorded in an inventory
+// against AWS.
+type DriftStatus string
+
+const (
+	// DriftStatusInSync means the resource exists and matches the inventory.
+	DriftStatusInSync DriftStatus = "in-sync"
+
+	// DriftStatusMissing means the resource recorded in the inventory no
+	// longer exists.
+	DriftStatusMissing DriftStatus = "missing"
+
+	// DriftStatusModified means the resource exists but has been changed
+	// since it was recorded.
+	DriftStatusModified DriftStatus = "modified"
+
+	// DriftStatusExtra means the resource exists inside a recorded resource,
+	// e.g. a subnet in the VPC, but is not recorded in the inventory.  Extra
+	// resources can prevent the recorded resources from being deleted.
+	DriftStatusExtra DriftStatus = "extra"
+)
+
+// DriftReport is the result of checking each resource recorded in an
+// inventory against AWS.
+type DriftReport struct {
+	Region      string          `json:"region"`
+	ClusterName string          `json:"clusterName"`
+	Drifted     bool            `json:"drifted"`
+	Resources   []ResourceDrift `json:"resources"`
+}
+
+// ResourceDrift is the result of checking a single resource.
+type ResourceDrift struct {
+	// The kind of resource.
+	Kind ResourceKind `json:"kind"`
+
+	// The ID, name or ARN of the resource.
+	ID string `json:"id"`
+
+	// The result of the check.
+	Status DriftStatus `json:"status"`
+
+	// How a modified resource differs from the inventory.
+	Details []string `json:"details,omitempty"`
+}
+
+// add records the result of checking a resource.
+func (r *DriftReport) add(kind ResourceKind, id string, status DriftStatus, details ...string) {
+	if status != DriftStatusInSync {
+		r.Drifted = true
+	}
+	r.Resources = append(r.Resources, ResourceDrift{
+		Kind:    kind,
+		ID:      id,
+		Status:  status,
+		Details: details,
+	})
+}
+
+// addChecked records a resource as modified if any details were found, and
+// in sync otherwise.
+func (r *DriftReport) addChecked(kind ResourceKind, id string, details []string) {
+	if len(details) > 0 {
+		r.add(kind, id, DriftStatusModified, details...)
+		return
+	}
+	r.add(kind, id, DriftStatusInSync)
+}
+
+// VerifyResourceStack checks each resource recorded in an inventory against
+// AWS and reports resources that are missing, have been modified or that
+// exist inside recorded resources without being recorded.  Resources are only
+// read, never changed.
+func (c *ResourceClient) VerifyResourceStack(ctx context.Context, inventory *ResourceInventory) (*DriftReport, error) {
+	c.AWSConfig.Region = inventory.Region
+
+	report := DriftReport{
+		Region:      inventory.Region,
+		ClusterName: inventory.Cluster.ClusterName,
+		Resources:   []ResourceDrift{},
+	}
+
+	if err := c.verifyNetwork(ctx, inventory, &report); err != nil {
+		return nil, err
+	}
+	if err := c.verifyIAM(ctx, inventory, &report); err != nil {
+		return nil, err
+	}
+	if err := c.verifyCluster(ctx, inventory, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// verifyNetwork checks the VPC and the networking resources recorded in an
+// inventory.
+func (c *ResourceClient) verifyNetwork(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	// Internet Gateway
+	if inventory.InternetGatewayID != "" {
+		internetGateway, err := c.getInternetGateway(ctx, inventory.InternetGatewayID)
+		switch {
+		case errors.Is(err, ErrResourceNotFound):
+			report.add(ResourceKindInternetGateway, inventory.InternetGatewayID, DriftStatusMissing)
+		case err != nil:
+			return err
+		case inventory.VPCID != "" && !internetGatewayAttached(internetGateway, inventory.VPCID):
+			report.add(ResourceKindInternetGateway, inventory.InternetGatewayID, DriftStatusModified,
+				fmt.Sprintf("not attached to VPC %s", inventory.VPCID))
+		default:
+			report.add(ResourceKindInternetGateway, inventory.InternetGatewayID, DriftStatusInSync)
+		}
+	}
+
+	// Egress-only Internet Gateway
+	if inventory.EgressOnlyInternetGatewayID != "" {
+		egressOnlyInternetGateway, err := c.getEgressOnlyInternetGateway(ctx, inventory.EgressOnlyInternetGatewayID)
+		switch {
+		case errors.Is(err, ErrResourceNotFound):
+			report.add(ResourceKindEgressOnlyInternetGateway, inventory.EgressOnlyInternetGatewayID, DriftStatusMissing)
+		case err != nil:
+			return err
+		case inventory.VPCID != "" && !egressOnlyInternetGatewayAttached(egressOnlyInternetGateway, inventory.VPCID):
+			report.add(ResourceKindEgressOnlyInternetGateway, inventory.EgressOnlyInternetGatewayID, DriftStatusModified,
+				fmt.Sprintf("not attached to VPC %s", inventory.VPCID))
+		default:
+			report.add(ResourceKindEgressOnlyInternetGateway, inventory.EgressOnlyInternetGatewayID, DriftStatusInSync)
+		}
+	}
+
+	// Elastic IPs
+	addresses, err := c.getElasticIPs(ctx, inventory.ElasticIPIDs)
+	if err != nil {
+		return err
+	}
+	foundElasticIPIDs := make(map[string]bool)
+	for _, address := range addresses {
+		foundElasticIPIDs[*address.AllocationId] = true
+	}
+	for _, elasticIPID := range inventory.ElasticIPIDs {
+		if foundElasticIPIDs[elasticIPID] {
+			report.add(ResourceKindElasticIP, elasticIPID, DriftStatusInSync)
+		} else {
+			report.add(ResourceKindElasticIP, elasticIPID, DriftStatusMissing)
+		}
+	}
+
+	// Flow Log Group
+	if inventory.FlowLogGroupName != "" {
+		if _, err := c.getLogGroup(ctx, inventory.FlowLogGroupName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindLogGroup, inventory.FlowLogGroupName, DriftStatusMissing)
+		} else {
+			report.add(ResourceKindLogGroup, inventory.FlowLogGroupName, DriftStatusInSync)
+		}
+	}
+
+	// existing VPC and subnets - only checked to see that they still exist as
+	// they're not managed by eks-cluster
+	if inventory.ExistingVPCID != "" {
+		if err := c.verifyExistingNetwork(ctx, inventory, report); err != nil {
+			return err
+		}
+	}
+
+	if inventory.VPCID == "" {
+		return nil
+	}
+
+	// VPC - if it is gone, so is everything inside it
+	vpc, err := c.getVPC(ctx, inventory.VPCID)
+	if err != nil {
+		if !errors.Is(err, ErrResourceNotFound) {
+			return err
+		}
+		report.add(ResourceKindVPC, inventory.VPCID, DriftStatusMissing)
+		if inventory.SecondaryCIDRAssociationID != "" {
+			report.add(ResourceKindVPCCIDRBlock, inventory.SecondaryCIDRAssociationID, DriftStatusMissing)
+		}
+		for _, subnetID := range inventory.SubnetIDs {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusMissing)
+		}
+		for _, natGatewayID := range inventory.NATGatewayIDs {
+			report.add(ResourceKindNATGateway, natGatewayID, DriftStatusMissing)
+		}
+		for _, routeTableID := range inventoryRouteTableIDs(inventory) {
+			report.add(ResourceKindRouteTable, routeTableID, DriftStatusMissing)
+		}
+		if inventory.VPCEndpointSecurityGroupID != "" {
+			report.add(ResourceKindSecurityGroup, inventory.VPCEndpointSecurityGroupID, DriftStatusMissing)
+		}
+		for _, vpcEndpoint := range inventory.VPCEndpoints {
+			report.add(ResourceKindVPCEndpoint, vpcEndpoint.VPCEndpointID, DriftStatusMissing)
+		}
+		if inventory.FlowLogID != "" {
+			report.add(ResourceKindFlowLog, inventory.FlowLogID, DriftStatusMissing)
+		}
+		if inventory.TransitGatewayAttachmentID != "" {
+			report.add(ResourceKindTransitGatewayAttachment, inventory.TransitGatewayAttachmentID, DriftStatusMissing)
+		}
+		if inventory.VPCPeeringConnectionID != "" {
+			report.add(ResourceKindVPCPeeringConnection, inventory.VPCPeeringConnectionID, DriftStatusMissing)
+		}
+		for _, networkACLID := range getNetworkACLIDs(inventory.NetworkACLs) {
+			report.add(ResourceKindNetworkACL, networkACLID, DriftStatusMissing)
+		}
+		return nil
+	}
+	var vpcDetails []string
+	vpcTags := ec2TagMap(vpc.Tags)
+	for _, key := range sortedMapKeys(inventory.Tags) {
+		value, ok := vpcTags[key]
+		switch {
+		case !ok:
+			vpcDetails = append(vpcDetails, fmt.Sprintf("tag %s removed", key))
+		case value != inventory.Tags[key]:
+			vpcDetails = append(vpcDetails, fmt.Sprintf("tag %s changed from %q to %q", key, inventory.Tags[key], value))
+		}
+	}
+	report.addChecked(ResourceKindVPC, inventory.VPCID, vpcDetails)
+
+	// Secondary CIDR
+	if inventory.SecondaryCIDRAssociationID != "" {
+		association := vpcCIDRBlockAssociation(vpc, inventory.SecondaryCIDRAssociationID)
+		switch {
+		case association == nil:
+			report.add(ResourceKindVPCCIDRBlock, inventory.SecondaryCIDRAssociationID, DriftStatusMissing)
+		case aws.ToString(association.CidrBlock) != inventory.SecondaryCIDR:
+			report.add(ResourceKindVPCCIDRBlock, inventory.SecondaryCIDRAssociationID, DriftStatusModified,
+				fmt.Sprintf("CIDR block changed from %s to %s", inventory.SecondaryCIDR, aws.ToString(association.CidrBlock)))
+		default:
+			report.add(ResourceKindVPCCIDRBlock, inventory.SecondaryCIDRAssociationID, DriftStatusInSync)
+		}
+	}
+
+	svc := c.ec2Client()
+	vpcFilterName := "vpc-id"
+	vpcFilter := ec2types.Filter{
+		Name:   &vpcFilterName,
+		Values: []string{inventory.VPCID},
+	}
+
+	// Subnets - the CIDR blocks recorded for each availability zone are
+	// compared with the subnet's
+	subnetCIDRs := make(map[string]string)
+	subnetIPv6CIDRs := make(map[string]string)
+	for _, az := range inventory.AvailabilityZones {
+		if az.PrivateSubnetID != "" {
+			subnetCIDRs[az.PrivateSubnetID] = az.PrivateSubnetCIDR
+			subnetIPv6CIDRs[az.PrivateSubnetID] = az.PrivateSubnetIPv6CIDR
+		}
+		if az.PublicSubnetID != "" {
+			subnetCIDRs[az.PublicSubnetID] = az.PublicSubnetCIDR
+			subnetIPv6CIDRs[az.PublicSubnetID] = az.PublicSubnetIPv6CIDR
+		}
+		if az.PodSubnetID != "" {
+			subnetCIDRs[az.PodSubnetID] = az.PodSubnetCIDR
+		}
+	}
+	describeSubnetsInput := ec2.DescribeSubnetsInput{
+		Filters: []ec2types.Filter{vpcFilter},
+	}
+	subnetsResp, err := svc.DescribeSubnets(ctx, &describeSubnetsInput)
+	if err != nil {
+		return fmt.Errorf("failed to describe subnets for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	subnets := make(map[string]ec2types.Subnet)
+	for _, subnet := range subnetsResp.Subnets {
+		subnets[*subnet.SubnetId] = subnet
+	}
+	for _, subnetID := range inventory.SubnetIDs {
+		subnet, ok := subnets[subnetID]
+		if !ok {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusMissing)
+			continue
+		}
+		var subnetDetails []string
+		if cidr, ok := subnetCIDRs[subnetID]; ok && subnet.CidrBlock != nil && *subnet.CidrBlock != cidr {
+			subnetDetails = append(subnetDetails, fmt.Sprintf("CIDR block changed from %s to %s", cidr, *subnet.CidrBlock))
+		}
+		if cidr := subnetIPv6CIDRs[subnetID]; cidr != "" && subnetIPv6CIDR(subnet) != cidr {
+			subnetDetails = append(subnetDetails, fmt.Sprintf("IPv6 CIDR block changed from %s to %q", cidr, subnetIPv6CIDR(subnet)))
+		}
+		report.addChecked(ResourceKindSubnet, subnetID, subnetDetails)
+	}
+	for _, subnetID := range sortedMapKeys(subnets) {
+		if !containsString(inventory.SubnetIDs, subnetID) {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusExtra)
+		}
+	}
+
+	// NAT Gateways
+	natGateways, err := c.getNATGateways(ctx, inventory.VPCID, nil)
+	if err != nil {
+		return err
+	}
+	var natGatewayIDs []string
+	for _, natGateway := range natGateways {
+		natGatewayIDs = append(natGatewayIDs, *natGateway.NatGatewayId)
+	}
+	for _, natGatewayID := range inventory.NATGatewayIDs {
+		if containsString(natGatewayIDs, natGatewayID) {
+			report.add(ResourceKindNATGateway, natGatewayID, DriftStatusInSync)
+		} else {
+			report.add(ResourceKindNATGateway, natGatewayID, DriftStatusMissing)
+		}
+	}
+	if !inventory.NATGatewaysUntracked {
+		for _, natGatewayID := range natGatewayIDs {
+			if !containsString(inventory.NATGatewayIDs, natGatewayID) {
+				report.add(ResourceKindNATGateway, natGatewayID, DriftStatusExtra)
+			}
+		}
+	}
+
+	// VPC Peering Connection - one waiting to be accepted by the peer VPC's
+	// owner is in sync, though routes to it are blackholes until it is
+	var vpcPeeringConnectionPending bool
+	if inventory.VPCPeeringConnectionID != "" {
+		vpcPeeringConnection, err := c.getVPCPeeringConnection(ctx, inventory.VPCPeeringConnectionID)
+		switch {
+		case errors.Is(err, ErrResourceNotFound):
+			report.add(ResourceKindVPCPeeringConnection, inventory.VPCPeeringConnectionID, DriftStatusMissing)
+		case err != nil:
+			return err
+		default:
+			var vpcPeeringConnectionDetails []string
+			var requesterVPCID string
+			if vpcPeeringConnection.RequesterVpcInfo != nil {
+				requesterVPCID = aws.ToString(vpcPeeringConnection.RequesterVpcInfo.VpcId)
+			}
+			if requesterVPCID != inventory.VPCID {
+				vpcPeeringConnectionDetails = append(vpcPeeringConnectionDetails,
+					fmt.Sprintf("requester VPC changed from %s to %s", inventory.VPCID, requesterVPCID))
+			}
+			vpcPeeringConnectionPending = vpcPeeringConnectionState(vpcPeeringConnection) !=
+				ec2types.VpcPeeringConnectionStateReasonCodeActive
+			report.addChecked(ResourceKindVPCPeeringConnection, inventory.VPCPeeringConnectionID, vpcPeeringConnectionDetails)
+		}
+	}
+
+	// Route Tables - recorded routes and subnet associations are compared
+	// with each route table's
+	describeRouteTablesInput := ec2.DescribeRouteTablesInput{
+		Filters: []ec2types.Filter{vpcFilter},
+	}
+	routeTablesResp, err := svc.DescribeRouteTables(ctx, &describeRouteTablesInput)
+	if err != nil {
+		return fmt.Errorf("failed to describe route tables for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	routeTables := make(map[string]ec2types.RouteTable)
+	for _, routeTable := range routeTablesResp.RouteTables {
+		routeTables[*routeTable.RouteTableId] = routeTable
+	}
+	recordedRouteTableIDs := inventoryRouteTableIDs(inventory)
+	for _, routeTableID := range recordedRouteTableIDs {
+		routeTable, ok := routeTables[routeTableID]
+		if !ok {
+			report.add(ResourceKindRouteTable, routeTableID, DriftStatusMissing)
+			continue
+		}
+		var routeTableDetails []string
+		for _, route := range inventory.Routes {
+			if route.RouteTableID == routeTableID {
+				blackholeExpected := route.VPCPeeringConnectionID != "" && vpcPeeringConnectionPending
+				if detail := routeDrift(routeTable, route, blackholeExpected); detail != "" {
+					routeTableDetails = append(routeTableDetails, detail)
+				}
+			}
+		}
+		for _, az := range inventory.AvailabilityZones {
+			if az.PrivateRouteTableID == routeTableID && az.PrivateRouteTableAssociationID != "" &&
+				!routeTableAssociated(routeTable, az.PrivateRouteTableAssociationID, az.PrivateSubnetID) {
+				routeTableDetails = append(routeTableDetails, fmt.Sprintf("no longer associated with subnet %s", az.PrivateSubnetID))
+			}
+			if az.PrivateRouteTableID == routeTableID && az.PodRouteTableAssociationID != "" &&
+				!routeTableAssociated(routeTable, az.PodRouteTableAssociationID, az.PodSubnetID) {
+				routeTableDetails = append(routeTableDetails, fmt.Sprintf("no longer associated with subnet %s", az.PodSubnetID))
+			}
+			if inventory.PublicRouteTableID == routeTableID && az.PublicRouteTableAssociationID != "" &&
+				!routeTableAssociated(routeTable, az.PublicRouteTableAssociationID, az.PublicSubnetID) {
+				routeTableDetails = append(routeTableDetails, fmt.Sprintf("no longer associated with subnet %s", az.PublicSubnetID))
+			}
+		}
+		report.addChecked(ResourceKindRouteTable, routeTableID, routeTableDetails)
+	}
+	for _, routeTableID := range sortedMapKeys(routeTables) {
+		if containsString(recordedRouteTableIDs, routeTableID) || mainRouteTable(routeTables[routeTableID]) {
+			continue
+		}
+		report.add(ResourceKindRouteTable, routeTableID, DriftStatusExtra)
+	}
+
+	// VPC Endpoints - an endpoint that is not recorded keeps its security
+	// group and subnets from being deleted
+	if inventory.VPCEndpointSecurityGroupID != "" {
+		if _, err := c.getSecurityGroup(ctx, inventory.VPCEndpointSecurityGroupID); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindSecurityGroup, inventory.VPCEndpointSecurityGroupID, DriftStatusMissing)
+		} else {
+			report.add(ResourceKindSecurityGroup, inventory.VPCEndpointSecurityGroupID, DriftStatusInSync)
+		}
+	}
+	vpcEndpoints, err := c.getVPCEndpoints(ctx, inventory.VPCID, nil)
+	if err != nil {
+		return err
+	}
+	vpcEndpointServiceNames := make(map[string]string)
+	for _, vpcEndpoint := range vpcEndpoints {
+		vpcEndpointServiceNames[*vpcEndpoint.VpcEndpointId] = aws.ToString(vpcEndpoint.ServiceName)
+	}
+	recordedVPCEndpointIDs := getVPCEndpointIDs(inventory.VPCEndpoints)
+	for _, vpcEndpoint := range inventory.VPCEndpoints {
+		serviceName, ok := vpcEndpointServiceNames[vpcEndpoint.VPCEndpointID]
+		if !ok {
+			report.add(ResourceKindVPCEndpoint, vpcEndpoint.VPCEndpointID, DriftStatusMissing)
+			continue
+		}
+		var vpcEndpointDetails []string
+		if serviceName != vpcEndpoint.ServiceName {
+			vpcEndpointDetails = append(vpcEndpointDetails, fmt.Sprintf("service changed from %s to %s",
+				vpcEndpoint.ServiceName, serviceName))
+		}
+		report.addChecked(ResourceKindVPCEndpoint, vpcEndpoint.VPCEndpointID, vpcEndpointDetails)
+	}
+	for _, vpcEndpointID := range sortedMapKeys(vpcEndpointServiceNames) {
+		if !containsString(recordedVPCEndpointIDs, vpcEndpointID) {
+			report.add(ResourceKindVPCEndpoint, vpcEndpointID, DriftStatusExtra)
+		}
+	}
+
+	// Flow Log - the log group it publishes to is compared with the one
+	// recorded
+	if inventory.FlowLogID != "" {
+		flowLog, err := c.getFlowLog(ctx, inventory.FlowLogID)
+		switch {
+		case errors.Is(err, ErrResourceNotFound):
+			report.add(ResourceKindFlowLog, inventory.FlowLogID, DriftStatusMissing)
+		case err != nil:
+			return err
+		default:
+			var flowLogDetails []string
+			if resourceID := aws.ToString(flowLog.ResourceId); resourceID != inventory.VPCID {
+				flowLogDetails = append(flowLogDetails, fmt.Sprintf("resource changed from %s to %s", inventory.VPCID, resourceID))
+			}
+			if logGroupName := aws.ToString(flowLog.LogGroupName); inventory.FlowLogGroupName != "" &&
+				logGroupName != inventory.FlowLogGroupName {
+				flowLogDetails = append(flowLogDetails, fmt.Sprintf("log group changed from %s to %s",
+					inventory.FlowLogGroupName, logGroupName))
+			}
+			report.addChecked(ResourceKindFlowLog, inventory.FlowLogID, flowLogDetails)
+		}
+	}
+
+	// Transit Gateway Attachment - one that is no longer available doesn't
+	// carry traffic
+	if inventory.TransitGatewayAttachmentID != "" {
+		attachment, err := c.getTransitGatewayAttachment(ctx, inventory.TransitGatewayAttachmentID)
+		switch {
+		case errors.Is(err, ErrResourceNotFound):
+			report.add(ResourceKindTransitGatewayAttachment, inventory.TransitGatewayAttachmentID, DriftStatusMissing)
+		case err != nil:
+			return err
+		default:
+			var attachmentDetails []string
+			if vpcID := aws.ToString(attachment.VpcId); vpcID != inventory.VPCID {
+				attachmentDetails = append(attachmentDetails, fmt.Sprintf("VPC changed from %s to %s", inventory.VPCID, vpcID))
+			}
+			if attachment.State != ec2types.TransitGatewayAttachmentStateAvailable {
+				attachmentDetails = append(attachmentDetails, fmt.Sprintf("state is %s", attachment.State))
+			}
+			report.addChecked(ResourceKindTransitGatewayAttachment, inventory.TransitGatewayAttachmentID, attachmentDetails)
+		}
+	}
+
+	// Network ACLs - each must still be associated with its tier's subnets,
+	// and one that is not recorded keeps the VPC from being deleted
+	describeNetworkACLsInput := ec2.DescribeNetworkAclsInput{
+		Filters: []ec2types.Filter{vpcFilter},
+	}
+	networkACLsResp, err := svc.DescribeNetworkAcls(ctx, &describeNetworkACLsInput)
+	if err != nil {
+		return fmt.Errorf("failed to describe network ACLs for VPC with ID %s: %w", inventory.VPCID, err)
+	}
+	networkACLs := make(map[string]ec2types.NetworkAcl)
+	for _, networkACL := range networkACLsResp.NetworkAcls {
+		networkACLs[*networkACL.NetworkAclId] = networkACL
+	}
+	recordedNetworkACLIDs := getNetworkACLIDs(inventory.NetworkACLs)
+	for _, recordedNetworkACL := range inventory.NetworkACLs {
+		networkACL, ok := networkACLs[recordedNetworkACL.NetworkACLID]
+		if !ok {
+			report.add(ResourceKindNetworkACL, recordedNetworkACL.NetworkACLID, DriftStatusMissing)
+			continue
+		}
+		var networkACLDetails []string
+		associatedSubnetIDs := networkACLSubnetIDs(&networkACL)
+		for _, subnetID := range tierSubnetIDs(recordedNetworkACL.Tier, inventory.AvailabilityZones) {
+			if !containsString(associatedSubnetIDs, subnetID) {
+				networkACLDetails = append(networkACLDetails, fmt.Sprintf("no longer associated with subnet %s", subnetID))
+			}
+		}
+		report.addChecked(ResourceKindNetworkACL, recordedNetworkACL.NetworkACLID, networkACLDetails)
+	}
+	for _, networkACLID := range sortedMapKeys(networkACLs) {
+		if containsString(recordedNetworkACLIDs, networkACLID) || aws.ToBool(networkACLs[networkACLID].IsDefault) {
+			continue
+		}
+		report.add(ResourceKindNetworkACL, networkACLID, DriftStatusExtra)
+	}
+
+	return nil
+}
+
+// verifyExistingNetwork checks that the existing VPC and subnets recorded in
+// an inventory still exist.
+func (c *ResourceClient) verifyExistingNetwork(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	if _, err := c.getVPC(ctx, inventory.ExistingVPCID); err != nil {
+		if !errors.Is(err, ErrResourceNotFound) {
+			return err
+		}
+		report.add(ResourceKindVPC, inventory.ExistingVPCID, DriftStatusMissing)
+		for _, subnetID := range inventory.ExistingSubnetIDs {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusMissing)
+		}
+		return nil
+	}
+	report.add(ResourceKindVPC, inventory.ExistingVPCID, DriftStatusInSync)
+
+	subnets, err := c.getSubnets(ctx, inventory.ExistingVPCID, inventory.ExistingSubnetIDs)
+	if err != nil {
+		return err
+	}
+	var subnetIDs []string
+	for _, subnet := range subnets {
+		subnetIDs = append(subnetIDs, *subnet.SubnetId)
+	}
+	for _, subnetID := range inventory.ExistingSubnetIDs {
+		if containsString(subnetIDs, subnetID) {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusInSync)
+		} else {
+			report.add(ResourceKindSubnet, subnetID, DriftStatusMissing)
+		}
+	}
+
+	return nil
+}
+
+// verifyIAM checks the IAM policies and roles recorded in an inventory.
+func (c *ResourceClient) verifyIAM(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	// IAM Policies
+	for _, policyARN := range inventory.PolicyARNs {
+		if _, err := c.getPolicy(ctx, policyARN); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindPolicy, policyARN, DriftStatusMissing)
+			continue
+		}
+		report.add(ResourceKindPolicy, policyARN, DriftStatusInSync)
+	}
+
+	// IAM Roles - a policy attached outside of eks-cluster prevents the role
+	// from being deleted
+	for _, role := range []RoleInventory{
+		inventory.ClusterRole,
+		inventory.WorkerRole,
+		inventory.DNSManagementRole,
+		inventory.DNS01ChallengeRole,
+		inventory.ClusterAutoscalingRole,
+		inventory.StorageManagementRole,
+		inventory.FlowLogsRole,
+	} {
+		if role.RoleName == "" {
+			continue
+		}
+		if _, err := c.getRole(ctx, role.RoleName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindRole, role.RoleName, DriftStatusMissing)
+			continue
+		}
+		attachedPolicyARNs, err := c.getAttachedRolePolicyARNs(ctx, role.RoleName)
+		if err != nil {
+			return err
+		}
+		var roleDetails []string
+		for _, policyARN := range role.RolePolicyARNs {
+			if !containsString(attachedPolicyARNs, policyARN) {
+				roleDetails = append(roleDetails, fmt.Sprintf("policy %s detached", policyARN))
+			}
+		}
+		for _, policyARN := range attachedPolicyARNs {
+			if !containsString(role.RolePolicyARNs, policyARN) {
+				roleDetails = append(roleDetails, fmt.Sprintf("policy %s attached", policyARN))
+			}
+		}
+		report.addChecked(ResourceKindRole, role.RoleName, roleDetails)
+	}
+
+	return nil
+}
+
+// verifyCluster checks the EKS cluster, its node groups and addons, and the
+// OIDC provider recorded in an inventory.
+func (c *ResourceClient) verifyCluster(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	clusterName := inventory.Cluster.ClusterName
+	if clusterName != "" {
+		cluster, err := c.getCluster(ctx, clusterName)
+		if err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			// node groups and addons are gone along with the cluster
+			report.add(ResourceKindCluster, clusterName, DriftStatusMissing)
+			for _, nodeGroupName := range inventory.NodeGroupNames {
+				report.add(ResourceKindNodeGroup, nodeGroupName, DriftStatusMissing)
+			}
+			for _, addonName := range inventory.AddonNames {
+				report.add(ResourceKindAddon, addonName, DriftStatusMissing)
+			}
+		} else {
+			var clusterDetails []string
+			if cluster.Status != ekstypes.ClusterStatusActive {
+				clusterDetails = append(clusterDetails, fmt.Sprintf("status is %s", cluster.Status))
+			}
+			if cluster.ResourcesVpcConfig != nil && cluster.ResourcesVpcConfig.VpcId != nil &&
+				clusterVPCID(inventory) != "" && *cluster.ResourcesVpcConfig.VpcId != clusterVPCID(inventory) {
+				clusterDetails = append(clusterDetails, fmt.Sprintf("VPC changed from %s to %s", clusterVPCID(inventory),
+					*cluster.ResourcesVpcConfig.VpcId))
+			}
+			report.addChecked(ResourceKindCluster, clusterName, clusterDetails)
+
+			if err := c.verifyNodeGroups(ctx, inventory, report); err != nil {
+				return err
+			}
+			if err := c.verifyAddons(ctx, inventory, report); err != nil {
+				return err
+			}
+		}
+	}
+
+	// OIDC Provider
+	if inventory.OIDCProviderARN != "" {
+		if err := c.getOIDCProvider(ctx, inventory.OIDCProviderARN); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindOIDCProvider, inventory.OIDCProviderARN, DriftStatusMissing)
+		} else {
+			report.add(ResourceKindOIDCProvider, inventory.OIDCProviderARN, DriftStatusInSync)
+		}
+	}
+
+	return nil
+}
+
+// verifyNodeGroups checks the node groups recorded in an inventory.  Node
+// groups that are not recorded prevent the cluster from being deleted.
+func (c *ResourceClient) verifyNodeGroups(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	clusterName := inventory.Cluster.ClusterName
+
+	for _, nodeGroupName := range inventory.NodeGroupNames {
+		nodeGroup, err := c.getNodeGroup(ctx, clusterName, nodeGroupName)
+		if err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindNodeGroup, nodeGroupName, DriftStatusMissing)
+			continue
+		}
+		var nodeGroupDetails []string
+		if nodeGroup.Status != ekstypes.NodegroupStatusActive {
+			nodeGroupDetails = append(nodeGroupDetails, fmt.Sprintf("status is %s", nodeGroup.Status))
+		}
+		report.addChecked(ResourceKindNodeGroup, nodeGroupName, nodeGroupDetails)
+	}
+
+	svc := c.eksClient()
+	listNodegroupsInput := eks.ListNodegroupsInput{ClusterName: &clusterName}
+	for {
+		resp, err := svc.ListNodegroups(ctx, &listNodegroupsInput)
+		if err != nil {
+			return fmt.Errorf("failed to list node groups for cluster %s: %w", clusterName, err)
+		}
+		for _, nodeGroupName := range resp.Nodegroups {
+			if !containsString(inventory.NodeGroupNames, nodeGroupName) {
+				report.add(ResourceKindNodeGroup, nodeGroupName, DriftStatusExtra)
+			}
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		listNodegroupsInput.NextToken = resp.NextToken
+	}
+
+	return nil
+}
+
+// verifyAddons checks the addons recorded in an inventory.
+func (c *ResourceClient) verifyAddons(ctx context.Context, inventory *ResourceInventory, report *DriftReport) error {
+	clusterName := inventory.Cluster.ClusterName
+
+	for _, addonName := range inventory.AddonNames {
+		if _, err := c.getAddon(ctx, clusterName, addonName); err != nil {
+			if !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			report.add(ResourceKindAddon, addonName, DriftStatusMissing)
+			continue
+		}
+		report.add(ResourceKindAddon, addonName, DriftStatusInSync)
+	}
+
+	svc := c.eksClient()
+	listAddonsInput := eks.ListAddonsInput{ClusterName: &clusterName}
+	for {
+		resp, err := svc.ListAddons(ctx, &listAddonsInput)
+		if err != nil {
+			return fmt.Errorf("failed to list addons for cluster %s: %w", clusterName, err)
+		}
+		for _, addonName := range resp.Addons {
+			if !containsString(inventory.AddonNames, addonName) {
+				report.add(ResourceKindAddon, addonName, DriftStatusExtra)
+			}
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		listAddonsInput.NextToken = resp.NextToken
+	}
+
+	return nil
+}
+
+// MarshalDriftReport returns the JSON representation of a drift report.
+func MarshalDriftReport(report *DriftReport) ([]byte, error) {
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal drift report to JSON: %w", err)
+	}
+
+	return reportJSON, nil
+}
+
+// inventoryRouteTableIDs returns the IDs of the private and public route
+// tables recorded in an inventory.
+func inventoryRouteTableIDs(inventory *ResourceInventory) []string {
+	routeTableIDs := append([]string{}, inventory.PrivateRouteTableIDs...)
+	if inventory.PublicRouteTableID != "" {
+		routeTableIDs = append(routeTableIDs, inventory.PublicRouteTableID)
+	}
+
+	return routeTableIDs
+}
+
+// routeDrift returns how a route recorded in the inventory differs from the
+// route in the route table.  If the route matches it returns an empty string.
+// A route that is a blackhole only matches if one is expected.
+func routeDrift(routeTable ec2types.RouteTable, route RouteInventory, blackholeExpected bool) string {
+	target := routeInventoryTarget(route)
+
+	for _, r := range routeTable.Routes {
+		if routeDestination(r) != route.DestinationCIDR {
+			continue
+		}
+		if currentTarget := routeTarget(r); currentTarget != target {
+			return fmt.Sprintf("route to %s changed from %s to %s", route.DestinationCIDR, target, currentTarget)
+		}
+		if r.State == ec2types.RouteStateBlackhole && !blackholeExpected {
+			return fmt.Sprintf("route to %s via %s is a blackhole", route.DestinationCIDR, target)
+		}
+		return ""
+	}
+
+	return fmt.Sprintf("route to %s via %s removed", route.DestinationCIDR, target)
+}
+
+// routeTableAssociated returns true if a route table has the association with
+// the given ID to the given subnet.
+func routeTableAssociated(routeTable ec2types.RouteTable, associationID, subnetID string) bool {
+	for _, association := range routeTable.Associations {
+		if association.RouteTableAssociationId != nil && *association.RouteTableAssociationId == associationID &&
+			association.SubnetId != nil && *association.SubnetId == subnetID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mainRouteTable returns true if the route table is the main route table for
+// its VPC, which is created and deleted along with the VPC.
+func mainRouteTable(routeTable ec2types.RouteTable) bool {
+	for _, association := range routeTable.Associations {
+		if association.Main != nil && *association.Main {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedMapKeys returns the keys of a map in sorted order.
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,67 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+func TestVerifyResourceStack(t *testing.T) {
+	testCases := []struct {
+		name        string
+		change      func(t *testing.T, backend *fake.Backend, inventory resource.ResourceInventory)
+		wantDrifted bool
+		wantKind    resource.ResourceKind
+	}{
+		{
+			name: "no changes",
+		},
+		{
+			name: "log group deleted",
+			change: func(t *testing.T, backend *fake.Backend, inventory resource.ResourceInventory) {
+				deleteLogGroupInput := cloudwatchlogs.DeleteLogGroupInput{
+					LogGroupName: aws.String(inventory.FlowLogGroupName),
+				}
+				if _, err := backend.CloudWatchLogs.DeleteLogGroup(context.Background(), &deleteLogGroupInput); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantDrifted: true,
+			wantKind:    resource.ResourceKindLogGroup,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend, c, inventory := createResourceStack(t, flowLogsConfig())
+			if tc.change != nil {
+				tc.change(t, backend, inventory)
+			}
+
+			report, err := c.VerifyResourceStack(context.Background(), &inventory)
+			if err != nil {
+				t.Fatalf("failed to verify resource stack: %v", err)
+			}
+			if len(report.Resources) == 0 {
+				t.Fatal("expected resources to be checked")
+			}
+			if report.Drifted != tc.wantDrifted {
+				t.Errorf("expected drifted %t, got %t", tc.wantDrifted, report.Drifted)
+			}
+			for _, drift := range report.Resources {
+				wantStatus := resource.DriftStatusInSync
+				if tc.wantDrifted && drift.Kind == tc.wantKind {
+					wantStatus = resource.DriftStatusMissing
+				}
+				if drift.Status != wantStatus {
+					t.Errorf("expected %s %s to be %s, got %s: %s", drift.Kind, drift.ID, wantStatus, drift.Status, drift.Details)
+				}
+			}
+		})
+	}
+}
@@ -1,38 +1,35 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go"
 )
 
-// CreateVPC creates a VPC for an EKS cluster.  It adds the necessary tags and
-// enables the DNS attributes.
+const (
+	VPCIPv6CIDRCheckInterval = 5  // check VPC IPv6 CIDR block association every 5 seconds
+	VPCIPv6CIDRCheckMaxCount = 24 // check 24 times before giving up (2 minutes)
+)
+
+// CreateVPC creates a VPC for an EKS cluster.  It adds the cluster tags to the
+// tags, so they are used for the other EC2 resources, and enables the DNS
+// attributes.  For the IPv6 IP family an Amazon-provided IPv6 CIDR block is
+// requested for the VPC as well.
 func (c *ResourceClient) CreateVPC(
+	ctx context.Context,
 	tags *[]types.Tag,
 	cidrBlock string,
+	ipFamily IPFamily,
 	clusterName string,
 ) (*types.Vpc, error) {
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
-	clusterNameTagKey := "kubernetes.io/cluster/cluster-name"
-	clusterNameTagValue := clusterName
-	clusterNameTag := types.Tag{
-		Key:   &clusterNameTagKey,
-		Value: &clusterNameTagValue,
-	}
-	*tags = append(*tags, clusterNameTag)
-
-	clusterNameSharedTagKey := fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)
-	clusterNameSharedTagValue := "shared"
-	clusterNameSharedTag := types.Tag{
-		Key:   &clusterNameSharedTagKey,
-		Value: &clusterNameSharedTagValue,
-	}
-	*tags = append(*tags, clusterNameSharedTag)
+	addClusterTags(tags, clusterName)
 
 	createVPCInput := ec2.CreateVpcInput{
 		CidrBlock: &cidrBlock,
@@ -43,7 +40,11 @@ func (c *ResourceClient) CreateVPC(
 			},
 		},
 	}
-	resp, err := svc.CreateVpc(c.Context, &createVPCInput)
+	if ipFamily == IPFamilyIPv6 {
+		amazonProvidedIPv6CIDRBlock := true
+		createVPCInput.AmazonProvidedIpv6CidrBlock = &amazonProvidedIPv6CIDRBlock
+	}
+	resp, err := svc.CreateVpc(ctx, &createVPCInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create VPC for cluster %s: %w", clusterName, err)
 	}
@@ -54,7 +55,7 @@ func (c *ResourceClient) CreateVPC(
 		VpcId:              resp.Vpc.VpcId,
 		EnableDnsHostnames: &attributeTrue,
 	}
-	_, err = svc.ModifyVpcAttribute(c.Context, &modifyVPCAttributeDNSHostnamesInput)
+	_, err = svc.ModifyVpcAttribute(ctx, &modifyVPCAttributeDNSHostnamesInput)
 	if err != nil {
 		return resp.Vpc, fmt.Errorf("failed to modify VPC attribute to enable DNS hostnames for VPC with ID %d: %w",
 			resp.Vpc.VpcId, err)
@@ -63,7 +64,7 @@ func (c *ResourceClient) CreateVPC(
 		VpcId:            resp.Vpc.VpcId,
 		EnableDnsSupport: &attributeTrue,
 	}
-	_, err = svc.ModifyVpcAttribute(c.Context, &modifyVPCAttributeDNSSupportInput)
+	_, err = svc.ModifyVpcAttribute(ctx, &modifyVPCAttributeDNSSupportInput)
 	if err != nil {
 		return resp.Vpc, fmt.Errorf("failed to modify VPC attribute to enable DNS support for VPC with ID %d: %w",
 			resp.Vpc.VpcId, err)
@@ -74,16 +75,16 @@ func (c *ResourceClient) CreateVPC(
 
 // DeleteVPC deletes the VPC used by an EKS cluster.  If the VPC ID is empty, or
 // if the VPC is not found it returns without error.
-func (c *ResourceClient) DeleteVPC(vpcID string) error {
+func (c *ResourceClient) DeleteVPC(ctx context.Context, vpcID string) error {
 	// if both vpcID is empty, there's nothing to delete
 	if vpcID == "" {
 		return nil
 	}
 
-	svc := ec2.NewFromConfig(*c.AWSConfig)
+	svc := c.ec2Client()
 
 	deleteVPCInput := ec2.DeleteVpcInput{VpcId: &vpcID}
-	_, err := svc.DeleteVpc(c.Context, &deleteVPCInput)
+	_, err := svc.DeleteVpc(ctx, &deleteVPCInput)
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
@@ -101,3 +102,81 @@ func (c *ResourceClient) DeleteVPC(vpcID string) error {
 
 	return nil
 }
+
+// WaitForVPCIPv6CIDR waits for the Amazon-provided IPv6 CIDR block requested
+// for a VPC to be associated with it and returns the CIDR block.
+func (c *ResourceClient) WaitForVPCIPv6CIDR(ctx context.Context, vpcID string) (string, error) {
+	vpcIPv6CIDRCheckCount := 0
+
+	for {
+		vpcIPv6CIDRCheckCount += 1
+		if vpcIPv6CIDRCheckCount > VPCIPv6CIDRCheckMaxCount {
+			return "", fmt.Errorf("VPC IPv6 CIDR block association check timed out for VPC with ID %s", vpcID)
+		}
+
+		vpc, err := c.getVPC(ctx, vpcID)
+		if err != nil {
+			return "", err
+		}
+		if cidr := vpcIPv6CIDR(vpc); cidr != "" {
+			return cidr, nil
+		}
+		if len(vpc.Ipv6CidrBlockAssociationSet) == 0 {
+			return "", fmt.Errorf("VPC with ID %s has no IPv6 CIDR block", vpcID)
+		}
+
+		if err := c.waitCheckInterval(ctx, time.Second*VPCIPv6CIDRCheckInterval); err != nil {
+			return "", fmt.Errorf("stopped waiting for IPv6 CIDR block for VPC with ID %s: %w", vpcID, err)
+		}
+	}
+}
+
+// getVPC retrieves the VPC with the given ID.  If the VPC is not found it
+// returns ErrResourceNotFound.
+func (c *ResourceClient) getVPC(ctx context.Context, vpcID string) (*types.Vpc, error) {
+	svc := c.ec2Client()
+
+	filterName := "vpc-id"
+	describeVPCsInput := ec2.DescribeVpcsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &filterName,
+				Values: []string{vpcID},
+			},
+		},
+	}
+	resp, err := svc.DescribeVpcs(ctx, &describeVPCsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPC with ID %s: %w", vpcID, err)
+	}
+	if len(resp.Vpcs) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return &resp.Vpcs[0], nil
+}
+
+// addClusterTags adds the kubernetes.io/cluster tags that identify the EKS
+// cluster's resources to EC2 tags.  Tags that are already present are not
+// added again.
+func addClusterTags(tags *[]types.Tag, clusterName string) {
+	clusterTags := []struct{ key, value string }{
+		{"kubernetes.io/cluster/cluster-name", clusterName},
+		{fmt.Sprintf("kubernetes.io/cluster/%s", clusterName), "shared"},
+	}
+	for _, clusterTag := range clusterTags {
+		found := false
+		for _, tag := range *tags {
+			if tag.Key != nil && *tag.Key == clusterTag.key {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		key := clusterTag.key
+		value := clusterTag.value
+		*tags = append(*tags, types.Tag{Key: &key, Value: &value})
+	}
+}
@@ -0,0 +1,301 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// VPCEndpointSecurityGroupName is the prefix of the name of the security group
+// for the interface VPC endpoints.  The cluster name is appended to it.
+const VPCEndpointSecurityGroupName = "vpc-endpoints-sg"
+
+type VPCEndpointCondition string
+
+const (
+	VPCEndpointConditionCreated = "VPCEndpointCreated"
+	VPCEndpointConditionDeleted = "VPCEndpointDeleted"
+	VPCEndpointCheckInterval    = 15 // check VPC endpoint status every 15 seconds
+	VPCEndpointCheckMaxCount    = 20 // check 20 times before giving up (5 minutes)
+)
+
+// vpcEndpointService is an AWS service that a VPC endpoint is created for.
+type vpcEndpointService struct {
+	name         string
+	endpointType types.VpcEndpointType
+}
+
+// vpcEndpointServices are the AWS services that nodes in private subnets
+// need to reach to join the cluster, pull images from ECR, get credentials
+// for service accounts, manage load balancers, ship logs and scale node
+// groups.  S3 uses a gateway endpoint, as ECR image layers are stored in S3,
+// and the rest use interface endpoints.
+var vpcEndpointServices = []vpcEndpointService{
+	{name: "s3", endpointType: types.VpcEndpointTypeGateway},
+	{name: "ecr.api", endpointType: types.VpcEndpointTypeInterface},
+	{name: "ecr.dkr", endpointType: types.VpcEndpointTypeInterface},
+	{name: "sts", endpointType: types.VpcEndpointTypeInterface},
+	{name: "ec2", endpointType: types.VpcEndpointTypeInterface},
+	{name: "elasticloadbalancing", endpointType: types.VpcEndpointTypeInterface},
+	{name: "logs", endpointType: types.VpcEndpointTypeInterface},
+	{name: "autoscaling", endpointType: types.VpcEndpointTypeInterface},
+}
+
+// vpcEndpointServiceName returns the name of an AWS service's VPC endpoint
+// service in a region, e.g. com.amazonaws.us-east-1.s3.
+func vpcEndpointServiceName(region, service string) string {
+	return fmt.Sprintf("com.amazonaws.%s.%s", region, service)
+}
+
+// checkVPCEndpoints checks that VPC endpoints can be created for the resource
+// config.  They can't be created when using an existing VPC as they are
+// associated with the private subnets and route tables created for the
+// cluster.
+func (r *ResourceConfig) checkVPCEndpoints() error {
+	if r.VPCEndpoints && r.UsesExistingVPC() {
+		return errors.New("VPC endpoints cannot be created when using an existing VPC")
+	}
+
+	return nil
+}
+
+// CreateVPCEndpoints creates a VPC endpoint for each of the AWS services that
+// nodes need that doesn't already have one in the given VPC endpoints.  The
+// S3 gateway endpoint is added to the private route tables and the interface
+// endpoints are placed in the private subnets with private DNS enabled.  The
+// VPC endpoints created are returned, even if creating a later one fails, so
+// they can be recorded in the inventory.
+func (c *ResourceClient) CreateVPCEndpoints(
+	ctx context.Context,
+	tags *[]types.Tag,
+	region string,
+	vpcID string,
+	securityGroupID string,
+	privateRouteTableIDs []string,
+	privateSubnetIDs []string,
+	existingVPCEndpoints []VPCEndpointInventory,
+) ([]VPCEndpointInventory, error) {
+	svc := c.ec2Client()
+
+	var vpcEndpoints []VPCEndpointInventory
+
+	for _, service := range vpcEndpointServices {
+		serviceName := vpcEndpointServiceName(region, service.name)
+		if findVPCEndpointID(existingVPCEndpoints, serviceName) != "" {
+			continue
+		}
+		createVPCEndpointInput := ec2.CreateVpcEndpointInput{
+			VpcId:           &vpcID,
+			ServiceName:     &serviceName,
+			VpcEndpointType: service.endpointType,
+			TagSpecifications: []types.TagSpecification{
+				{
+					ResourceType: types.ResourceTypeVpcEndpoint,
+					Tags:         *tags,
+				},
+			},
+		}
+		if service.endpointType == types.VpcEndpointTypeGateway {
+			createVPCEndpointInput.RouteTableIds = privateRouteTableIDs
+		} else {
+			privateDNSEnabled := true
+			createVPCEndpointInput.SubnetIds = privateSubnetIDs
+			createVPCEndpointInput.SecurityGroupIds = []string{securityGroupID}
+			createVPCEndpointInput.PrivateDnsEnabled = &privateDNSEnabled
+		}
+		resp, err := svc.CreateVpcEndpoint(ctx, &createVPCEndpointInput)
+		if err != nil {
+			return vpcEndpoints, fmt.Errorf("failed to create VPC endpoint for service %s in VPC with ID %s: %w",
+				serviceName, vpcID, err)
+		}
+		vpcEndpoints = append(vpcEndpoints, VPCEndpointInventory{
+			VPCEndpointID: *resp.VpcEndpoint.VpcEndpointId,
+			ServiceName:   serviceName,
+		})
+	}
+
+	return vpcEndpoints, nil
+}
+
+// DeleteVPCEndpoints deletes the VPC endpoints with the given IDs.  VPC
+// endpoints that are not found are skipped.
+func (c *ResourceClient) DeleteVPCEndpoints(ctx context.Context, vpcEndpointIDs []string) error {
+	// if vpcEndpointIDs are empty, there's nothing to delete
+	if len(vpcEndpointIDs) == 0 {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteVPCEndpointsInput := ec2.DeleteVpcEndpointsInput{VpcEndpointIds: vpcEndpointIDs}
+	resp, err := svc.DeleteVpcEndpoints(ctx, &deleteVPCEndpointsInput)
+	if err != nil {
+		return fmt.Errorf("failed to delete VPC endpoints with IDs %s: %w", vpcEndpointIDs, err)
+	}
+
+	// VPC endpoints that couldn't be deleted are reported individually rather
+	// than as an error
+	var problems []string
+	for _, item := range resp.Unsuccessful {
+		if item.Error == nil || item.Error.Code == nil {
+			continue
+		}
+		if *item.Error.Code == "InvalidVpcEndpointId.NotFound" || *item.Error.Code == "InvalidVpcEndpoint.NotFound" {
+			// attempting to delete a VPC endpoint that doesn't exist so move
+			// on to the next one
+			continue
+		}
+		var message string
+		if item.Error.Message != nil {
+			message = *item.Error.Message
+		}
+		var resourceID string
+		if item.ResourceId != nil {
+			resourceID = *item.ResourceId
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s %s", resourceID, *item.Error.Code, message))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to delete VPC endpoints: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// WaitForVPCEndpoints waits for the VPC endpoints with the given IDs to reach a
+// given condition.  One of:
+// * VPCEndpointConditionCreated
+// * VPCEndpointConditionDeleted
+// If no VPC endpoint IDs are supplied it returns without error.
+func (c *ResourceClient) WaitForVPCEndpoints(
+	ctx context.Context,
+	vpcID string,
+	vpcEndpointIDs []string,
+	vpcEndpointCondition VPCEndpointCondition,
+) error {
+	// if no VPC endpoint IDs, there's nothing to wait for
+	if len(vpcEndpointIDs) == 0 {
+		return nil
+	}
+
+	vpcEndpointCheckCount := 0
+
+	for {
+		vpcEndpointCheckCount += 1
+		if vpcEndpointCheckCount > VPCEndpointCheckMaxCount {
+			return errors.New("VPC endpoint condition check timed out")
+		}
+
+		vpcEndpoints, err := c.getVPCEndpoints(ctx, vpcID, vpcEndpointIDs)
+		if err != nil {
+			return err
+		}
+
+		allConditionsMet := true
+		for _, vpcEndpoint := range vpcEndpoints {
+			if vpcEndpointCondition == VPCEndpointConditionCreated && !vpcEndpointStateIs(vpcEndpoint.State, types.StateAvailable) {
+				// VPC endpoint is not available but we're waiting for it to
+				// be created so condition is not met
+				if vpcEndpointStateIs(vpcEndpoint.State, types.StateFailed) {
+					return fmt.Errorf("VPC endpoint with ID %s failed to create", *vpcEndpoint.VpcEndpointId)
+				}
+				allConditionsMet = false
+				break
+			} else if vpcEndpointCondition == VPCEndpointConditionDeleted {
+				// VPC endpoint still exists but we're waiting for it to be
+				// deleted so condition is not met
+				allConditionsMet = false
+				break
+			}
+		}
+
+		if allConditionsMet {
+			break
+		}
+
+		if err := c.waitCheckInterval(ctx, time.Second*VPCEndpointCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for VPC endpoints in VPC with ID %s: %w", vpcID, err)
+		}
+	}
+
+	return nil
+}
+
+// getVPCEndpoints returns the VPC endpoints in a VPC that have not been
+// deleted.  If VPC endpoint IDs are given, only those VPC endpoints are
+// returned.
+func (c *ResourceClient) getVPCEndpoints(ctx context.Context, vpcID string, vpcEndpointIDs []string) ([]types.VpcEndpoint, error) {
+	svc := c.ec2Client()
+
+	vpcFilterName := "vpc-id"
+	describeVPCEndpointsInput := ec2.DescribeVpcEndpointsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &vpcFilterName,
+				Values: []string{vpcID},
+			},
+		},
+	}
+	if len(vpcEndpointIDs) > 0 {
+		vpcEndpointFilterName := "vpc-endpoint-id"
+		describeVPCEndpointsInput.Filters = append(describeVPCEndpointsInput.Filters, types.Filter{
+			Name:   &vpcEndpointFilterName,
+			Values: vpcEndpointIDs,
+		})
+	}
+
+	var vpcEndpoints []types.VpcEndpoint
+	for {
+		resp, err := svc.DescribeVpcEndpoints(ctx, &describeVPCEndpointsInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPC endpoints for VPC with ID %s: %w", vpcID, err)
+		}
+		for _, vpcEndpoint := range resp.VpcEndpoints {
+			if vpcEndpointStateIs(vpcEndpoint.State, types.StateDeleted) ||
+				vpcEndpointStateIs(vpcEndpoint.State, types.StateRejected) {
+				continue
+			}
+			vpcEndpoints = append(vpcEndpoints, vpcEndpoint)
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		describeVPCEndpointsInput.NextToken = resp.NextToken
+	}
+
+	return vpcEndpoints, nil
+}
+
+// vpcEndpointStateIs returns true if a VPC endpoint is in the given state.
+// The API returns VPC endpoint states in lower case, unlike the SDK's
+// constants, so they are compared without regard to case.
+func vpcEndpointStateIs(state types.State, want types.State) bool {
+	return strings.EqualFold(string(state), string(want))
+}
+
+// findVPCEndpointID returns the ID of the VPC endpoint for a service, or an
+// empty string if there isn't one.
+func findVPCEndpointID(vpcEndpoints []VPCEndpointInventory, serviceName string) string {
+	for _, vpcEndpoint := range vpcEndpoints {
+		if vpcEndpoint.ServiceName == serviceName {
+			return vpcEndpoint.VPCEndpointID
+		}
+	}
+
+	return ""
+}
+
+// getVPCEndpointIDs returns the IDs of the VPC endpoints in an inventory.
+func getVPCEndpointIDs(vpcEndpoints []VPCEndpointInventory) []string {
+	var vpcEndpointIDs []string
+	for _, vpcEndpoint := range vpcEndpoints {
+		vpcEndpointIDs = append(vpcEndpointIDs, vpcEndpoint.VPCEndpointID)
+	}
+
+	return vpcEndpointIDs
+}
@@ -0,0 +1,164 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+	"github.com/nukleros/eks-cluster/pkg/resource/fake"
+)
+
+// vpcEndpointsConfig returns a resource config with VPC endpoints and the
+// given NAT gateway mode.
+func vpcEndpointsConfig(mode resource.NATGatewayMode) *resource.ResourceConfig {
+	resourceConfig := testConfig()
+	resourceConfig.DesiredAZCount = 3
+	resourceConfig.NATGatewayMode = mode
+	resourceConfig.VPCEndpoints = true
+
+	return resourceConfig
+}
+
+func TestVPCEndpoints(t *testing.T) {
+	for _, mode := range []resource.NATGatewayMode{resource.NATGatewayModePerAZ, resource.NATGatewayModeNone} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			plan, err := fake.NewBackend(testRegion).ResourceClient().PlanResourceStack(ctx, vpcEndpointsConfig(mode))
+			if err != nil {
+				t.Fatalf("failed to plan resource stack: %v", err)
+			}
+			backend, c, inventory := createResourceStack(t, vpcEndpointsConfig(mode))
+
+			var planned, created []string
+			for _, endpoint := range plan.VPCEndpoints {
+				planned = append(planned, endpoint.ServiceName)
+			}
+			for _, endpoint := range inventory.VPCEndpoints {
+				created = append(created, endpoint.ServiceName)
+			}
+			sort.Strings(planned)
+			sort.Strings(created)
+			if len(created) == 0 || !equalStrings(created, planned) {
+				t.Errorf("expected VPC endpoints %v, got %v", planned, created)
+			}
+			if inventory.VPCEndpointSecurityGroupID == "" {
+				t.Error("expected VPC endpoint security group in inventory")
+			}
+
+			// gateway endpoints are added to the private route tables and
+			// interface endpoints are placed in the private subnets
+			describeVpcEndpointsOutput, err := backend.EC2.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, endpoint := range describeVpcEndpointsOutput.VpcEndpoints {
+				switch endpoint.VpcEndpointType {
+				case types.VpcEndpointTypeGateway:
+					if len(endpoint.RouteTableIds) != len(inventory.PrivateRouteTableIDs) {
+						t.Errorf("expected %s in %d route tables, got %v", *endpoint.ServiceName,
+							len(inventory.PrivateRouteTableIDs), endpoint.RouteTableIds)
+					}
+				case types.VpcEndpointTypeInterface:
+					if len(endpoint.SubnetIds) != len(inventory.AvailabilityZones) || len(endpoint.Groups) != 1 ||
+						*endpoint.Groups[0].GroupId != inventory.VPCEndpointSecurityGroupID {
+						t.Errorf("expected %s in each private subnet with the endpoint security group, got %v and %+v",
+							*endpoint.ServiceName, endpoint.SubnetIds, endpoint.Groups)
+					}
+				}
+			}
+
+			// an endpoint that wasn't created for the cluster is reported
+			createVpcEndpointInput := ec2.CreateVpcEndpointInput{
+				VpcId:       aws.String(inventory.VPCID),
+				ServiceName: aws.String("com.amazonaws." + testRegion + ".sqs"),
+			}
+			extra, err := backend.EC2.CreateVpcEndpoint(ctx, &createVpcEndpointInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := c.VerifyResourceStack(ctx, &inventory)
+			if err != nil {
+				t.Fatalf("failed to verify resource stack: %v", err)
+			}
+			var extraReported bool
+			for _, drift := range report.Resources {
+				if drift.ID == *extra.VpcEndpoint.VpcEndpointId {
+					extraReported = drift.Status == resource.DriftStatusExtra
+				} else if drift.Status != resource.DriftStatusInSync {
+					t.Errorf("expected %s %s to be in sync, got %s", drift.Kind, drift.ID, drift.Status)
+				}
+			}
+			if !extraReported {
+				t.Errorf("expected extra VPC endpoint %s to be reported", *extra.VpcEndpoint.VpcEndpointId)
+			}
+			deleteVpcEndpointsInput := ec2.DeleteVpcEndpointsInput{VpcEndpointIds: []string{*extra.VpcEndpoint.VpcEndpointId}}
+			if _, err := backend.EC2.DeleteVpcEndpoints(ctx, &deleteVpcEndpointsInput); err != nil {
+				t.Fatal(err)
+			}
+			describeVpcEndpointsInput := ec2.DescribeVpcEndpointsInput{VpcEndpointIds: deleteVpcEndpointsInput.VpcEndpointIds}
+			for i := 0; i <= backend.Polls; i++ {
+				if _, err := backend.EC2.DescribeVpcEndpoints(ctx, &describeVpcEndpointsInput); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the endpoints are recovered from their tags
+			var r fake.Recorder
+			r.Record(c)
+			recovered, err := c.RecoverInventory(ctx, inventory.Cluster.ClusterName)
+			r.Stop()
+			if err != nil {
+				t.Fatalf("failed to recover inventory: %v", err)
+			}
+			if recovered.VPCEndpointSecurityGroupID != inventory.VPCEndpointSecurityGroupID ||
+				len(recovered.VPCEndpoints) != len(inventory.VPCEndpoints) {
+				t.Errorf("expected VPC endpoints %+v to be recovered, got %+v", inventory.VPCEndpoints, recovered.VPCEndpoints)
+			}
+
+			deleteResourceStack(t, backend, c, inventory)
+		})
+	}
+}
+
+func TestVPCEndpointsCreateFailure(t *testing.T) {
+	backend := fake.NewBackend(testRegion)
+	c := backend.ResourceClient()
+	c.FailurePolicy = resource.FailurePolicyDelete
+
+	// fail part way through creating the endpoints
+	backend.Fail("CreateVpcEndpoint", nil)
+	backend.Fail("CreateVpcEndpoint", nil)
+	backend.Fail("CreateVpcEndpoint", errors.New("injected failure"))
+
+	var r fake.Recorder
+	r.Record(c)
+	err := c.CreateResourceStack(context.Background(), vpcEndpointsConfig(resource.NATGatewayModeNone))
+	r.Stop()
+	var failedErr *resource.CreateFailedError
+	if !errors.As(err, &failedErr) || !failedErr.Deleted {
+		t.Fatalf("expected resources to be deleted after failure, got %v", err)
+	}
+	if snapshot := backend.Snapshot(); !snapshot.Empty() {
+		t.Errorf("expected no resources, got %+v", snapshot)
+	}
+}
+
+// equalStrings returns true if two lists of strings are the same.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
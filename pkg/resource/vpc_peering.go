@@ -0,0 +1,198 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	VPCPeeringConnectionCheckInterval = 5  // check VPC peering connection status every 5 seconds
+	VPCPeeringConnectionCheckMaxCount = 24 // check 24 times before giving up (2 minutes)
+)
+
+// autoAccept returns true if the peering connection can be accepted by the
+// account that requests it.  That is the case when the peer VPC is in the
+// same account and region as the cluster.
+func (v *VPCPeeringConfig) autoAccept(accountID, region string) bool {
+	sameAccount := v.PeerOwnerID == "" || v.PeerOwnerID == accountID
+	sameRegion := v.PeerRegion == "" || v.PeerRegion == region
+
+	return sameAccount && sameRegion
+}
+
+// CreateVPCPeeringConnection requests a peering connection from a VPC to the
+// peer VPC.  If the peer owner ID or region are not set the peer VPC is in the
+// same account or region as the VPC.
+func (c *ResourceClient) CreateVPCPeeringConnection(
+	ctx context.Context,
+	tags *[]types.Tag,
+	vpcID string,
+	vpcPeering *VPCPeeringConfig,
+) (string, error) {
+	svc := c.ec2Client()
+
+	createVPCPeeringConnectionInput := ec2.CreateVpcPeeringConnectionInput{
+		VpcId:     &vpcID,
+		PeerVpcId: &vpcPeering.PeerVPCID,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeVpcPeeringConnection,
+				Tags:         *tags,
+			},
+		},
+	}
+	if vpcPeering.PeerOwnerID != "" {
+		createVPCPeeringConnectionInput.PeerOwnerId = &vpcPeering.PeerOwnerID
+	}
+	if vpcPeering.PeerRegion != "" {
+		createVPCPeeringConnectionInput.PeerRegion = &vpcPeering.PeerRegion
+	}
+	resp, err := svc.CreateVpcPeeringConnection(ctx, &createVPCPeeringConnectionInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to create peering connection from VPC with ID %s to VPC with ID %s: %w",
+			vpcID, vpcPeering.PeerVPCID, err)
+	}
+
+	return *resp.VpcPeeringConnection.VpcPeeringConnectionId, nil
+}
+
+// AcceptVPCPeeringConnection waits for a peering connection request to reach
+// the peer VPC, accepts it and waits for the connection to become active.  A
+// connection that is already active is left as it is.
+func (c *ResourceClient) AcceptVPCPeeringConnection(ctx context.Context, vpcPeeringConnectionID string) error {
+	svc := c.ec2Client()
+
+	vpcPeeringConnectionCheckCount := 0
+	accepted := false
+
+	for {
+		vpcPeeringConnectionCheckCount += 1
+		if vpcPeeringConnectionCheckCount > VPCPeeringConnectionCheckMaxCount {
+			return errors.New("VPC peering connection condition check timed out")
+		}
+
+		vpcPeeringConnection, err := c.getVPCPeeringConnection(ctx, vpcPeeringConnectionID)
+		if err != nil {
+			return err
+		}
+
+		switch vpcPeeringConnectionState(vpcPeeringConnection) {
+		case types.VpcPeeringConnectionStateReasonCodeActive:
+			return nil
+		case types.VpcPeeringConnectionStateReasonCodePendingAcceptance:
+			if !accepted {
+				acceptVPCPeeringConnectionInput := ec2.AcceptVpcPeeringConnectionInput{
+					VpcPeeringConnectionId: &vpcPeeringConnectionID,
+				}
+				if _, err := svc.AcceptVpcPeeringConnection(ctx, &acceptVPCPeeringConnectionInput); err != nil {
+					return fmt.Errorf("failed to accept VPC peering connection with ID %s: %w", vpcPeeringConnectionID, err)
+				}
+				accepted = true
+			}
+		case types.VpcPeeringConnectionStateReasonCodeInitiatingRequest, types.VpcPeeringConnectionStateReasonCodeProvisioning:
+		default:
+			return fmt.Errorf("VPC peering connection with ID %s failed: %s", vpcPeeringConnectionID,
+				vpcPeeringConnectionState(vpcPeeringConnection))
+		}
+
+		if err := c.waitCheckInterval(ctx, time.Second*VPCPeeringConnectionCheckInterval); err != nil {
+			return fmt.Errorf("stopped waiting for VPC peering connection with ID %s: %w", vpcPeeringConnectionID, err)
+		}
+	}
+}
+
+// DeleteVPCPeeringConnection deletes a VPC peering connection.  If an empty
+// VPC peering connection ID is supplied, or if the connection is not found, it
+// returns without error.
+func (c *ResourceClient) DeleteVPCPeeringConnection(ctx context.Context, vpcPeeringConnectionID string) error {
+	// if vpcPeeringConnectionID is empty, there's nothing to delete
+	if vpcPeeringConnectionID == "" {
+		return nil
+	}
+
+	svc := c.ec2Client()
+
+	deleteVPCPeeringConnectionInput := ec2.DeleteVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: &vpcPeeringConnectionID,
+	}
+	_, err := svc.DeleteVpcPeeringConnection(ctx, &deleteVPCPeeringConnectionInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "InvalidVpcPeeringConnectionID.NotFound" {
+			// attempting to delete a VPC peering connection that doesn't
+			// exist so return without error
+			return nil
+		}
+		return fmt.Errorf("failed to delete VPC peering connection with ID %s: %w", vpcPeeringConnectionID, err)
+	}
+
+	return nil
+}
+
+// getVPCPeeringConnection retrieves the VPC peering connection with the given
+// ID.  If the connection is not found, or can no longer be used because it has
+// been deleted, rejected or has expired, it returns ErrResourceNotFound.
+func (c *ResourceClient) getVPCPeeringConnection(
+	ctx context.Context,
+	vpcPeeringConnectionID string,
+) (*types.VpcPeeringConnection, error) {
+	svc := c.ec2Client()
+
+	describeVPCPeeringConnectionsInput := ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: []string{vpcPeeringConnectionID},
+	}
+	resp, err := svc.DescribeVpcPeeringConnections(ctx, &describeVPCPeeringConnectionsInput)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "InvalidVpcPeeringConnectionID.NotFound" {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to describe VPC peering connection with ID %s: %w", vpcPeeringConnectionID, err)
+	}
+	for _, vpcPeeringConnection := range resp.VpcPeeringConnections {
+		if vpcPeeringConnectionUsable(&vpcPeeringConnection) {
+			return &vpcPeeringConnection, nil
+		}
+	}
+
+	return nil, ErrResourceNotFound
+}
+
+// vpcPeeringConnectionState returns the state of a VPC peering connection.
+func vpcPeeringConnectionState(vpcPeeringConnection *types.VpcPeeringConnection) types.VpcPeeringConnectionStateReasonCode {
+	if vpcPeeringConnection.Status == nil {
+		return ""
+	}
+
+	return vpcPeeringConnection.Status.Code
+}
+
+// vpcPeeringConnectionUsable returns true if a VPC peering connection is
+// active or on its way to becoming active.
+func vpcPeeringConnectionUsable(vpcPeeringConnection *types.VpcPeeringConnection) bool {
+	switch vpcPeeringConnectionState(vpcPeeringConnection) {
+	case types.VpcPeeringConnectionStateReasonCodeActive,
+		types.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+		types.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+		types.VpcPeeringConnectionStateReasonCodeProvisioning:
+		return true
+	}
+
+	return false
+}
+
+// vpcPeeringConnectionPeerVPCID returns the ID of the peer VPC of a VPC
+// peering connection.
+func vpcPeeringConnectionPeerVPCID(vpcPeeringConnection *types.VpcPeeringConnection) string {
+	if vpcPeeringConnection.AccepterVpcInfo == nil || vpcPeeringConnection.AccepterVpcInfo.VpcId == nil {
+		return ""
+	}
+
+	return *vpcPeeringConnection.AccepterVpcInfo.VpcId
+}